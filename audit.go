@@ -0,0 +1,68 @@
+package henge
+
+import (
+	"context"
+	"strings"
+)
+
+// PrincipalFunc extracts the principal (e.g. user id or service name) performing a DAO operation from the operation's
+// context. UniversalDao implementations of this package use it to fill in BO's audit fields on writes:
+//   - Create: both created-by and updated-by are set to the principal.
+//   - Update: updated-by is set to the principal.
+//   - Save: updated-by is set to the principal; created-by is set to the principal if the BO does not exist yet,
+//     otherwise it is kept (copied from the existing BO if blank).
+//
+// Audit fields are left untouched if the function returns an empty string. They are filled in after before-hooks have
+// been invoked (see DaoHooks), and are not covered by BO's checksum, hence writes skipped because the BO is unchanged
+// (see UniversalBo.WasUnchanged) do not update them.
+//
+// Available since v0.7.0
+type PrincipalFunc func(ctx context.Context) string
+
+type principalCtxKey struct{}
+
+// ContextWithPrincipal returns a copy of ctx carrying the specified principal, to be extracted by PrincipalFromContext.
+//
+// Available since v0.7.0
+func ContextWithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalCtxKey{}, principal)
+}
+
+// PrincipalFromContext is a PrincipalFunc that returns the principal attached to ctx by ContextWithPrincipal.
+//
+// Available since v0.7.0
+func PrincipalFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	principal, _ := ctx.Value(principalCtxKey{}).(string)
+	return principal
+}
+
+// principal returns the principal extracted from ctx, empty if fn is nil.
+func (fn PrincipalFunc) principal(ctx context.Context) string {
+	if fn == nil {
+		return ""
+	}
+	return strings.TrimSpace(fn(ctx))
+}
+
+// stampAudit fills in bo's audit fields with the principal; created-by is set only if isNew is true.
+func stampAudit(bo *UniversalBo, principal string, isNew bool) {
+	if bo == nil || principal == "" {
+		return
+	}
+	if isNew {
+		bo.SetCreatedBy(principal)
+	}
+	bo.SetUpdatedBy(principal)
+}
+
+// stampAuditOnSave fills in bo's audit fields before it is saved, existing being the BO currently in storage (nil if
+// none).
+func stampAuditOnSave(bo, existing *UniversalBo, principal string) {
+	if bo != nil && existing != nil && bo.GetCreatedBy() == "" {
+		bo.SetCreatedBy(existing.GetCreatedBy())
+	}
+	stampAudit(bo, principal, existing == nil)
+}
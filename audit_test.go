@@ -0,0 +1,104 @@
+package henge
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestPrincipalFromContext(t *testing.T) {
+	name := "TestPrincipalFromContext"
+	if v := PrincipalFromContext(context.Background()); v != "" {
+		t.Fatalf("%s failed: expected empty principal but received %#v", name, v)
+	}
+	ctx := ContextWithPrincipal(context.Background(), "alice")
+	if v := PrincipalFromContext(ctx); v != "alice" {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, "alice", v)
+	}
+	var fn PrincipalFunc
+	if v := fn.principal(ctx); v != "" {
+		t.Fatalf("%s failed: nil PrincipalFunc should return empty principal, received %#v", name, v)
+	}
+	fn = func(ctx context.Context) string { return "  bob  " }
+	if v := fn.principal(ctx); v != "bob" {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, "bob", v)
+	}
+}
+
+func TestStampAudit(t *testing.T) {
+	name := "TestStampAudit"
+	bo := NewUniversalBo("id", 1)
+	stampAudit(bo, "", true)
+	if bo.GetCreatedBy() != "" || bo.GetUpdatedBy() != "" {
+		t.Fatalf("%s failed: empty principal should not fill in audit fields", name)
+	}
+	stampAudit(bo, "alice", true)
+	if bo.GetCreatedBy() != "alice" || bo.GetUpdatedBy() != "alice" || bo.IsDirty() {
+		t.Fatalf("%s failed: expected alice/alice but received %#v/%#v", name, bo.GetCreatedBy(), bo.GetUpdatedBy())
+	}
+	stampAudit(bo, "bob", false)
+	if bo.GetCreatedBy() != "alice" || bo.GetUpdatedBy() != "bob" {
+		t.Fatalf("%s failed: expected alice/bob but received %#v/%#v", name, bo.GetCreatedBy(), bo.GetUpdatedBy())
+	}
+
+	// save: created-by is kept from the existing BO
+	existing := bo.Clone()
+	bo = NewUniversalBo("id", 1)
+	stampAuditOnSave(bo, existing, "carol")
+	if bo.GetCreatedBy() != "alice" || bo.GetUpdatedBy() != "carol" {
+		t.Fatalf("%s failed: expected alice/carol but received %#v/%#v", name, bo.GetCreatedBy(), bo.GetUpdatedBy())
+	}
+	bo = NewUniversalBo("id", 1)
+	stampAuditOnSave(bo, nil, "carol")
+	if bo.GetCreatedBy() != "carol" || bo.GetUpdatedBy() != "carol" {
+		t.Fatalf("%s failed: expected carol/carol but received %#v/%#v", name, bo.GetCreatedBy(), bo.GetUpdatedBy())
+	}
+}
+
+func TestUniversalBo_AuditFields(t *testing.T) {
+	name := "TestUniversalBo_AuditFields"
+	ubo := NewUniversalBo("id", 1)
+	ubo.SetDataJson(`{"a":1}`)
+	ubo.SetCreatedBy(" alice ").SetUpdatedBy("bob")
+	ubo.Sync()
+	checksum := ubo.GetChecksum()
+	ubo.SetUpdatedBy("carol")
+	if ubo.GetCreatedBy() != "alice" || ubo.GetUpdatedBy() != "carol" || ubo.IsDirty() || ubo.GetChecksum() != checksum {
+		t.Fatalf("%s failed: audit fields should not affect dirty flag and checksum", name)
+	}
+
+	if gbo := ubo.ToGenericBo(); gbo.GboGetAttrUnsafe(FieldCreatedBy, nil) != "alice" || gbo.GboGetAttrUnsafe(FieldUpdatedBy, nil) != "carol" {
+		t.Fatalf("%s failed: audit fields should be exported to generic BO", name)
+	} else if bo := NewUniversalBoFromGbo(gbo); bo.GetCreatedBy() != "alice" || bo.GetUpdatedBy() != "carol" {
+		t.Fatalf("%s failed: expected alice/carol but received %#v/%#v", name, bo.GetCreatedBy(), bo.GetUpdatedBy())
+	}
+	if clone := ubo.Clone(); clone.GetCreatedBy() != "alice" || clone.GetUpdatedBy() != "carol" {
+		t.Fatalf("%s failed: audit fields should be cloned", name)
+	}
+
+	js, _ := json.Marshal(ubo)
+	bo := &UniversalBo{}
+	if err := json.Unmarshal(js, bo); err != nil || bo.GetCreatedBy() != "alice" || bo.GetUpdatedBy() != "carol" {
+		t.Fatalf("%s failed: expected alice/carol but received %#v/%#v (error: %s)", name, bo.GetCreatedBy(), bo.GetUpdatedBy(), err)
+	}
+
+	data, err := ubo.MarshalBinary()
+	if err != nil || data[0] != cborArrayAudit {
+		t.Fatalf("%s failed: expected binary format with audit fields (error: %s)", name, err)
+	}
+	bo = &UniversalBo{}
+	if err := bo.UnmarshalBinary(data); err != nil || bo.GetCreatedBy() != "alice" || bo.GetUpdatedBy() != "carol" || bo.GetDataJson() != ubo.GetDataJson() {
+		t.Fatalf("%s failed: expected alice/carol but received %#v/%#v (error: %s)", name, bo.GetCreatedBy(), bo.GetUpdatedBy(), err)
+	}
+
+	// BOs without audit fields keep the original formats
+	plain := NewUniversalBo("id", 1)
+	if m := plain.ToMap(nil, nil); m[FieldCreatedBy] != nil || m[FieldUpdatedBy] != nil {
+		t.Fatalf("%s failed: empty audit fields should be omitted, received %#v", name, m)
+	}
+	if data, _ := plain.MarshalBinary(); data[0] == cborArrayAudit {
+		t.Fatalf("%s failed: BOs without audit fields should be encoded in the original binary format", name)
+	} else if err := bo.UnmarshalBinary(data); err != nil || bo.GetCreatedBy() != "" || bo.GetUpdatedBy() != "" {
+		t.Fatalf("%s failed: expected empty audit fields but received %#v/%#v (error: %s)", name, bo.GetCreatedBy(), bo.GetUpdatedBy(), err)
+	}
+}
@@ -0,0 +1,98 @@
+package henge
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+// benchmarkDataJson returns a JSON document of about the specified size (in KB).
+func benchmarkDataJson(sizeKb int) string {
+	items := make([]interface{}, 0)
+	for i := 0; len(items)*200 < sizeKb*1024; i++ {
+		items = append(items, map[string]interface{}{
+			"id":    fmt.Sprintf("item-%06d", i),
+			"name":  fmt.Sprintf("This is the name of item number %d", i),
+			"price": float64(i) * 1.25,
+			"tags":  []interface{}{"tag-a", "tag-b", fmt.Sprintf("tag-%d", i%10)},
+			"attrs": map[string]interface{}{"active": i%2 == 0, "rank": i},
+		})
+	}
+	js, _ := json.Marshal(map[string]interface{}{"name": "benchmark", "items": items})
+	return string(js)
+}
+
+func benchmarkUbo(b *testing.B, opts ...UboOpt) *UniversalBo {
+	ubo := NewUniversalBo("id", 1, opts...)
+	ubo.SetDataJson(benchmarkDataJson(200))
+	ubo.SetExtraAttr("owner", "me")
+	return ubo.Sync()
+}
+
+func BenchmarkNewUniversalBoFromGbo(b *testing.B) {
+	for _, alg := range []ChecksumAlgorithm{ChecksumMd5, ChecksumSha256} {
+		b.Run(string(alg), func(b *testing.B) {
+			opts := UboOpt{ChecksumAlgorithm: alg}
+			gbo := benchmarkUbo(b, opts).ToGenericBo()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				NewUniversalBoFromGbo(gbo, opts)
+			}
+		})
+	}
+}
+
+func BenchmarkNewUniversalBoFromGbo_GetDataAttr(b *testing.B) {
+	for _, alg := range []ChecksumAlgorithm{ChecksumMd5, ChecksumSha256} {
+		b.Run(string(alg), func(b *testing.B) {
+			opts := UboOpt{ChecksumAlgorithm: alg}
+			gbo := benchmarkUbo(b, opts).ToGenericBo()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				NewUniversalBoFromGbo(gbo, opts).GetDataAttr("items[0].name")
+			}
+		})
+	}
+}
+
+func BenchmarkUniversalBo_Clone(b *testing.B) {
+	ubo := benchmarkUbo(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ubo.Clone()
+	}
+}
+
+func BenchmarkUniversalBo_ToGenericBo(b *testing.B) {
+	ubo := benchmarkUbo(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ubo.ToGenericBo()
+	}
+}
+
+func BenchmarkUniversalBo_ToMap(b *testing.B) {
+	ubo := benchmarkUbo(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ubo.ToMap(nil, nil)
+	}
+}
+
+func BenchmarkUniversalBo_SetExtraAttr_Sync(b *testing.B) {
+	ubo := benchmarkUbo(b, UboOpt{ChecksumAlgorithm: ChecksumSha256})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ubo.SetExtraAttr("counter", i)
+		ubo.Sync()
+	}
+}
+
+func BenchmarkUniversalBo_SetDataAttr_Sync(b *testing.B) {
+	ubo := benchmarkUbo(b, UboOpt{ChecksumAlgorithm: ChecksumSha256})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ubo.SetDataAttr("counter", i)
+		ubo.Sync()
+	}
+}
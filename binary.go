@@ -0,0 +1,168 @@
+package henge
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// uboBinaryVersion is the version of UniversalBo's binary representation.
+	uboBinaryVersion = 1
+
+	// uboBinaryVersionAudit is the version of UniversalBo's binary representation that also carries the audit fields.
+	// It is used only for BOs having audit fields, so that the binary representation of other BOs does not change.
+	uboBinaryVersionAudit = 2
+)
+
+// uboBinaryCodec encodes/decodes UniversalBo's binary representation.
+var uboBinaryCodec = newCborDataCodec()
+
+// uboWireTime is the binary representation of a timestamp: seconds and nanoseconds since Unix epoch, and the offset
+// of its time zone (in seconds east of UTC).
+type uboWireTime struct {
+	_      struct{} `cbor:",toarray"`
+	Sec    int64
+	Nsec   int64
+	Offset int
+}
+
+func toWireTime(t time.Time) uboWireTime {
+	_, offset := t.Zone()
+	return uboWireTime{Sec: t.Unix(), Nsec: int64(t.Nanosecond()), Offset: offset}
+}
+
+func (w uboWireTime) toTime() time.Time {
+	t := time.Unix(w.Sec, w.Nsec)
+	if w.Offset == 0 {
+		return t.UTC()
+	}
+	return t.In(time.FixedZone("", w.Offset))
+}
+
+// uboWire is the binary representation of UniversalBo, encoded as a CBOR (RFC 8949) array:
+//
+//	[version, id, tag-version, checksum, [tcre-sec, tcre-nsec, tcre-offset], [tupd-sec, tupd-nsec, tupd-offset], data, extras]
+//
+// where data is the JSON-encoded BO's data as a byte string, and extras is a map of extra attributes.
+//
+// BOs having audit fields (see UniversalBo.GetCreatedBy) are encoded as uboWireAudit instead.
+type uboWire struct {
+	_           struct{} `cbor:",toarray"`
+	Version     uint
+	Id          string
+	TagVersion  uint64
+	Checksum    string
+	TimeCreated uboWireTime
+	TimeUpdated uboWireTime
+	Data        []byte
+	Extras      map[string]interface{}
+}
+
+// uboWireAudit is the binary representation of UniversalBo having audit fields, version uboBinaryVersionAudit:
+//
+//	[version, id, tag-version, checksum, [tcre...], [tupd...], data, extras, created-by, updated-by]
+type uboWireAudit struct {
+	_           struct{} `cbor:",toarray"`
+	Version     uint
+	Id          string
+	TagVersion  uint64
+	Checksum    string
+	TimeCreated uboWireTime
+	TimeUpdated uboWireTime
+	Data        []byte
+	Extras      map[string]interface{}
+	CreatedBy   string
+	UpdatedBy   string
+}
+
+// cborArrayAudit is the initial byte of a CBOR array of 10 elements (major type 4), i.e. uboWireAudit.
+const cborArrayAudit = 0x80 | 10
+
+// MarshalBinary implements encoding.BinaryMarshaler.MarshalBinary.
+//
+// The binary representation is a compact and stable CBOR encoding (RFC 8949) of BO's top-level attributes, data and
+// extra attributes. Checksum, tag-version and timestamps are preserved at full precision. Like MarshalJSON, the BO is
+// not modified: pending changes are exported as if the BO had been synced.
+//
+// Available since v0.7.0
+func (ubo *UniversalBo) MarshalBinary() ([]byte, error) {
+	ubo._lock.RLock()
+	s := ubo._snapshot()
+	ubo._lock.RUnlock()
+	w := uboWire{
+		Version:     uboBinaryVersion,
+		Id:          s.id,
+		TagVersion:  s.tagVersion,
+		Checksum:    s.checksum,
+		TimeCreated: toWireTime(s.timeCreated),
+		TimeUpdated: toWireTime(s.timeUpdated),
+		Data:        []byte(s.dataJson),
+		Extras:      s.extras,
+	}
+	if s.createdBy != "" || s.updatedBy != "" {
+		return uboBinaryCodec.encMode.Marshal(uboWireAudit{
+			Version: uboBinaryVersionAudit, Id: w.Id, TagVersion: w.TagVersion, Checksum: w.Checksum,
+			TimeCreated: w.TimeCreated, TimeUpdated: w.TimeUpdated, Data: w.Data, Extras: w.Extras,
+			CreatedBy: s.createdBy, UpdatedBy: s.updatedBy,
+		})
+	}
+	return uboBinaryCodec.encMode.Marshal(w)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.UnmarshalBinary, decoding the binary representation produced by
+// MarshalBinary.
+//
+// BO's settings (e.g. timestamp-rounding, checksum algorithm) are kept. The decoded checksum is verified against BO's
+// content (see IsChecksumMismatched) and kept if it matches, the last-updated timestamp is kept as-is.
+//
+// Available since v0.7.0
+func (ubo *UniversalBo) UnmarshalBinary(data []byte) error {
+	var w uboWireAudit
+	if len(data) > 0 && data[0] == cborArrayAudit {
+		if err := uboBinaryCodec.decMode.Unmarshal(data, &w); err != nil {
+			return err
+		}
+		if w.Version != uboBinaryVersionAudit {
+			return fmt.Errorf("unsupported binary representation version %d", w.Version)
+		}
+	} else {
+		var w1 uboWire
+		if err := uboBinaryCodec.decMode.Unmarshal(data, &w1); err != nil {
+			return err
+		}
+		if w1.Version != uboBinaryVersion {
+			return fmt.Errorf("unsupported binary representation version %d", w1.Version)
+		}
+		w = uboWireAudit{Version: w1.Version, Id: w1.Id, TagVersion: w1.TagVersion, Checksum: w1.Checksum,
+			TimeCreated: w1.TimeCreated, TimeUpdated: w1.TimeUpdated, Data: w1.Data, Extras: w1.Extras}
+	}
+	if len(w.Data) == 0 {
+		return errors.New("invalid binary representation: missing data")
+	}
+	extras := make(map[string]interface{}, len(w.Extras))
+	for k, v := range w.Extras {
+		extras[k] = normalizeIntegers(v)
+	}
+
+	ubo._lock.Lock()
+	defer ubo._lock.Unlock()
+	ubo.id = w.Id
+	ubo.tagVersion = w.TagVersion
+	ubo.checksum = w.Checksum
+	ubo.timeCreated = w.TimeCreated.toTime()
+	ubo.timeUpdated = w.TimeUpdated.toTime()
+	ubo.createdBy = w.CreatedBy
+	ubo.updatedBy = w.UpdatedBy
+	if ubo._checksumAlgorithm == "" {
+		ubo._checksumAlgorithm = supportedChecksumAlgorithmOf(w.Checksum)
+	}
+	ubo._extraAttrs = extras
+	if err := ubo._loadDataJson(string(w.Data)); err != nil {
+		return err
+	}
+	ubo._storedChecksum = w.Checksum
+	ubo._original = ubo._takeContent()
+	ubo._syncLoaded(w.Checksum)
+	return nil
+}
@@ -0,0 +1,93 @@
+package henge
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestUniversalBo_MarshalBinary(t *testing.T) {
+	name := "TestUniversalBo_MarshalBinary"
+	tcreated := time.Date(2021, 1, 2, 3, 4, 5, 123456789, time.FixedZone("", 7*3600))
+	ubo := NewUniversalBo("id", 1357, UboOpt{Clock: NewFixedClock(tcreated), TimestampRounding: TimestampRoundingSettingNone, ChecksumAlgorithm: ChecksumSha256})
+	ubo.SetDataJson(`{"a":{"b":[1,"x",true,null]},"n":9007199254740993}`)
+	ubo.SetExtraAttr("owner", "me")
+	ubo.SetExtraAttr("count", 3)
+	ubo.SetExtraAttr("seen", tcreated)
+	ubo.Sync()
+	data, err := ubo.MarshalBinary()
+	if err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	js, _ := json.Marshal(ubo)
+	if len(data) >= len(js) {
+		t.Fatalf("%s failed: binary representation (%d bytes) should be smaller than JSON (%d bytes)", name, len(data), len(js))
+	}
+	if again, _ := ubo.MarshalBinary(); !bytes.Equal(again, data) {
+		t.Fatalf("%s failed: binary representation should be stable", name)
+	}
+
+	bo := &UniversalBo{}
+	if err := bo.UnmarshalBinary(data); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if bo.GetId() != "id" || bo.GetTagVersion() != 1357 || bo.GetDataJson() != ubo.GetDataJson() {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, ubo, bo)
+	}
+	if bo.GetChecksum() != ubo.GetChecksum() || bo.IsChecksumMismatched() || bo.GetChecksumAlgorithm() != ChecksumSha256 {
+		t.Fatalf("%s failed: expected checksum %#v but received %#v", name, ubo.GetChecksum(), bo.GetChecksum())
+	}
+	if !bo.GetTimeCreated().Equal(tcreated) || !bo.GetTimeUpdated().Equal(tcreated) || bo.GetTimeCreated().Nanosecond() != 123456789 {
+		t.Fatalf("%s failed: expected %s but received %s / %s", name, tcreated, bo.GetTimeCreated(), bo.GetTimeUpdated())
+	}
+	if _, offset := bo.GetTimeCreated().Zone(); offset != 7*3600 {
+		t.Fatalf("%s failed: expected offset %d but received %d", name, 7*3600, offset)
+	}
+	extras := bo.GetExtraAttrs()
+	if seen, ok := extras["seen"].(time.Time); !ok || !seen.Equal(tcreated) || extras["owner"] != "me" || extras["count"] != int64(3) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, ubo.GetExtraAttrs(), extras)
+	}
+
+	// tampered checksum is detected
+	ubo.checksum = "sha256:00"
+	data, _ = ubo.MarshalBinary()
+	if err := bo.UnmarshalBinary(data); err != nil || !bo.IsChecksumMismatched() {
+		t.Fatalf("%s failed: checksum mismatch should be detected (error: %s)", name, err)
+	}
+	for _, invalid := range [][]byte{nil, []byte("{}"), {0x83, 0x02, 0x60, 0x00}} {
+		if err := bo.UnmarshalBinary(invalid); err == nil {
+			t.Fatalf("%s failed: expected error for %#v", name, invalid)
+		}
+	}
+}
+
+func TestUniversalBo_MarshalBinary_md5(t *testing.T) {
+	name := "TestUniversalBo_MarshalBinary_md5"
+	clock := NewFixedClock(time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC))
+	ubo := NewUniversalBo("id", 1357, UboOpt{Clock: clock})
+	// MD5 checksum depends on the Go types of numbers, which are decoded as float64
+	ubo.SetDataAttr("x", 1)
+	ubo.SetExtraAttr("count", 3)
+	ubo.Sync()
+	data, err := ubo.MarshalBinary()
+	if err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	bo := &UniversalBo{}
+	if err := bo.UnmarshalBinary(data); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if bo.GetChecksum() != ubo.GetChecksum() || bo.IsChecksumMismatched() || bo.GetChecksumAlgorithm() != ChecksumMd5 {
+		t.Fatalf("%s failed: expected checksum %#v but received %#v", name, ubo.GetChecksum(), bo.GetChecksum())
+	}
+	if again, _ := bo.MarshalBinary(); !bytes.Equal(again, data) {
+		t.Fatalf("%s failed: binary representation should be preserved", name)
+	}
+
+	ubo.checksum = "00"
+	data, _ = ubo.MarshalBinary()
+	if err := bo.UnmarshalBinary(data); err != nil || !bo.IsChecksumMismatched() || bo.GetChecksum() == "00" {
+		t.Fatalf("%s failed: checksum mismatch should be detected (error: %s)", name, err)
+	}
+}
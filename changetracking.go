@@ -0,0 +1,159 @@
+package henge
+
+import (
+	"errors"
+	"sort"
+)
+
+// _originalContent returns the snapshot of bo's content used as baseline for change tracking.
+//
+// Caller is responsible for locking the BO.
+func (ubo *UniversalBo) _originalContent() *uboContent {
+	if ubo._original == nil {
+		return &uboContent{extras: map[string]interface{}{}}
+	}
+	return ubo._original
+}
+
+// ChangedPaths returns paths (in the same syntax as of GetDataAttr, e.g. "a.b[0].c") of data attributes that have been
+// added, removed or changed since the BO was loaded from storage (or since the last call to ResetChangeTracking).
+//
+// The returned paths point to the deepest changed attributes and are sorted. Values are compared after being normalized
+// to JSON-compatible types, hence setting an attribute to its current value does not count as a change.
+//
+// Available since v0.7.0
+func (ubo *UniversalBo) ChangedPaths() []string {
+	ubo._lock.RLock()
+	defer ubo._lock.RUnlock()
+	original, current := ubo._originalContent(), ubo._takeContent()
+	origData, currentData := original.getData(), current.getData()
+	if origData == nil {
+		// data of a new BO is lazily initialized, treat it as an empty container
+		switch currentData.(type) {
+		case map[string]interface{}:
+			origData = map[string]interface{}{}
+		case []interface{}:
+			origData = []interface{}{}
+		}
+	}
+	result := make([]string, 0)
+	for _, change := range diffContentTree(FieldData, origData, currentData) {
+		result = append(result, change.Path)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// ChangedExtraAttrs returns keys of extra attributes that have been added, removed or changed since the BO was loaded
+// from storage (or since the last call to ResetChangeTracking). The returned keys are sorted.
+//
+// Available since v0.7.0
+func (ubo *UniversalBo) ChangedExtraAttrs() []string {
+	ubo._lock.RLock()
+	defer ubo._lock.RUnlock()
+	origExtras, _ := ubo._originalContent().extras.(map[string]interface{})
+	currentExtras, _ := ubo._takeContent().extras.(map[string]interface{})
+	result := make([]string, 0)
+	for k, v := range currentExtras {
+		if ov, ok := origExtras[k]; !ok || !jsonValuesEqual(ov, v) {
+			result = append(result, k)
+		}
+	}
+	for k := range origExtras {
+		if _, ok := currentExtras[k]; !ok {
+			result = append(result, k)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// HasChanges returns true if id, tag-version, data or extra attributes of the BO have been changed since the BO was
+// loaded from storage (or since the last call to ResetChangeTracking).
+//
+// Unlike IsDirty, HasChanges compares the actual content, hence setting an attribute to its current value does not count.
+//
+// Available since v0.7.0
+func (ubo *UniversalBo) HasChanges() bool {
+	ubo._lock.RLock()
+	defer ubo._lock.RUnlock()
+	original, current := ubo._originalContent(), ubo._takeContent()
+	return original.id != current.id || original.tagVersion != current.tagVersion ||
+		!original.sameData(current) || !jsonValuesEqual(original.extras, current.extras)
+}
+
+// OriginalValue returns value of the data attribute located at 'path' (in the same syntax as of
+// UniversalBo.GetDataAttr) at the time the BO was loaded from storage (or at the last call to ResetChangeTracking).
+//
+// The returned value is normalized to JSON-compatible types (e.g. numbers are float64).
+//
+// Available since v0.7.0
+func (ubo *UniversalBo) OriginalValue(path string) (interface{}, error) {
+	ubo._lock.RLock()
+	data := cloneJsonValue(ubo._originalContent().getData())
+	ubo._lock.RUnlock()
+	if data == nil {
+		return nil, errors.New("cannot get original data at path [" + path + "]")
+	}
+	return getDataValue(&data, path)
+}
+
+// OriginalExtraAttr returns value of the extra attribute specified by 'key' at the time the BO was loaded from storage
+// (or at the last call to ResetChangeTracking).
+//
+// The returned value is normalized to JSON-compatible types (e.g. numbers are float64, time.Time values are strings).
+//
+// Available since v0.7.0
+func (ubo *UniversalBo) OriginalExtraAttr(key string) interface{} {
+	ubo._lock.RLock()
+	defer ubo._lock.RUnlock()
+	if extras, ok := ubo._originalContent().extras.(map[string]interface{}); ok {
+		return cloneJsonValue(extras[key])
+	}
+	return nil
+}
+
+// ResetChangeTracking takes the current content of the BO as the new baseline for change tracking.
+//
+// UniversalDao implementations call this function after the BO has been successfully persisted to storage.
+//
+// Available since v0.7.0
+func (ubo *UniversalBo) ResetChangeTracking() *UniversalBo {
+	ubo._lock.Lock()
+	defer ubo._lock.Unlock()
+	ubo._original = ubo._takeContent()
+	return ubo
+}
+
+// WasUnchanged returns true if the last Update/Save of the BO was skipped because "skip unchanged writes" is enabled
+// on the DAO and the stored record has the same content as the BO (e.g. UniversalDaoSql.SetSkipUnchangedWrites).
+//
+// Skipped writes are not errors: Update/Save return false and a nil error, as for a write that did not affect any
+// record; this function tells both cases apart.
+//
+// Available since v0.7.0
+func (ubo *UniversalBo) WasUnchanged() bool {
+	ubo._lock.RLock()
+	defer ubo._lock.RUnlock()
+	return ubo._unchangedWrite
+}
+
+func (ubo *UniversalBo) _setUnchangedWrite(value bool) *UniversalBo {
+	ubo._lock.Lock()
+	defer ubo._lock.Unlock()
+	ubo._unchangedWrite = value
+	return ubo
+}
+
+// afterWrite resets change tracking of the BO if it has been successfully written to storage.
+// The write result is passed through as-is.
+func afterWrite(bo *UniversalBo, ok bool, err error) (bool, error) {
+	if bo == nil {
+		return ok, err
+	}
+	bo._setUnchangedWrite(false)
+	if ok && err == nil {
+		bo.ResetChangeTracking()._markPersisted()
+	}
+	return ok, err
+}
@@ -0,0 +1,121 @@
+package henge
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/btnguyen2k/godal"
+)
+
+func TestUniversalBo_ChangedPaths_new(t *testing.T) {
+	name := "TestUniversalBo_ChangedPaths_new"
+	ubo := NewUniversalBo("id", 0)
+	if v := ubo.ChangedPaths(); len(v) != 0 {
+		t.Fatalf("%s failed: expected no change but received %#v", name, v)
+	}
+	if ubo.HasChanges() {
+		t.Fatalf("%s failed: expected no change", name)
+	}
+	ubo.SetDataAttr("a.b", 1)
+	ubo.SetDataAttr("c[0]", "x")
+	ubo.SetExtraAttr("email", "a@example.com")
+	expected := []string{"a", "c"}
+	if v := ubo.ChangedPaths(); !reflect.DeepEqual(v, expected) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, expected, v)
+	}
+	expected = []string{"email"}
+	if v := ubo.ChangedExtraAttrs(); !reflect.DeepEqual(v, expected) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, expected, v)
+	}
+	if !ubo.HasChanges() {
+		t.Fatalf("%s failed: expected changes", name)
+	}
+
+	ubo.ResetChangeTracking()
+	if v := ubo.ChangedPaths(); len(v) != 0 {
+		t.Fatalf("%s failed: expected no change but received %#v", name, v)
+	}
+	if v := ubo.ChangedExtraAttrs(); len(v) != 0 {
+		t.Fatalf("%s failed: expected no change but received %#v", name, v)
+	}
+}
+
+func TestUniversalBo_ChangedPaths_loaded(t *testing.T) {
+	name := "TestUniversalBo_ChangedPaths_loaded"
+	gbo := godal.NewGenericBo()
+	gbo.GboSetAttr(FieldId, "id")
+	gbo.GboSetAttr(FieldData, `{"name":{"first":"Thanh","last":"Nguyen"},"tags":["a","b"],"age":35}`)
+	gbo.GboSetAttr(FieldChecksum, "")
+	gbo.GboSetAttr(FieldTagVersion, 0)
+	gbo.GboSetAttr("email", "a@example.com")
+	gbo.GboSetAttr("rank", 1)
+	ubo := NewUniversalBoFromGbo(gbo)
+	if v := ubo.ChangedPaths(); len(v) != 0 {
+		t.Fatalf("%s failed: expected no change but received %#v", name, v)
+	}
+
+	ubo.SetDataAttr("name.first", "Thanh2")
+	ubo.SetDataAttr("age", 35.0) // same value, not a change
+	ubo.SetDataAttr("tags[2]", "c")
+	ubo.SetExtraAttr("email", "b@example.com")
+	ubo.SetExtraAttr("rank", 1.0) // same value, not a change
+	ubo.SetExtraAttr("active", true)
+	expected := []string{"name.first", "tags[2]"}
+	if v := ubo.ChangedPaths(); !reflect.DeepEqual(v, expected) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, expected, v)
+	}
+	expected = []string{"active", "email"}
+	if v := ubo.ChangedExtraAttrs(); !reflect.DeepEqual(v, expected) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, expected, v)
+	}
+	if v, err := ubo.OriginalValue("name.first"); err != nil || v != "Thanh" {
+		t.Fatalf("%s failed: expected %#v but received %#v (error: %s)", name, "Thanh", v, err)
+	}
+	if v, err := ubo.OriginalValue("tags[1]"); err != nil || v != "b" {
+		t.Fatalf("%s failed: expected %#v but received %#v (error: %s)", name, "b", v, err)
+	}
+	if v, err := ubo.OriginalValue("/name/first"); err != nil || v != "Thanh" {
+		t.Fatalf("%s failed: expected %#v but received %#v (error: %s)", name, "Thanh", v, err)
+	}
+	if v := ubo.OriginalExtraAttr("email"); v != "a@example.com" {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, "a@example.com", v)
+	}
+	if v := ubo.OriginalExtraAttr("active"); v != nil {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, nil, v)
+	}
+
+	clone := ubo.Clone()
+	expected = []string{"name.first", "tags[2]"}
+	if v := clone.ChangedPaths(); !reflect.DeepEqual(v, expected) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, expected, v)
+	}
+
+	ubo.SetDataAttr("name.first", "Thanh")
+	ubo.SetDataJson(`{"name":{"first":"Thanh","last":"Nguyen"},"tags":["a","b"],"age":35}`)
+	ubo.SetExtraAttr("email", "a@example.com")
+	if v := ubo.ChangedPaths(); len(v) != 0 {
+		t.Fatalf("%s failed: expected no change but received %#v", name, v)
+	}
+	expected = []string{"active"}
+	if v := ubo.ChangedExtraAttrs(); !reflect.DeepEqual(v, expected) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, expected, v)
+	}
+}
+
+func TestUniversalBo_HasChanges_numbers(t *testing.T) {
+	name := "TestUniversalBo_HasChanges_numbers"
+	gbo := godal.NewGenericBo()
+	gbo.GboSetAttr(FieldId, "id")
+	gbo.GboSetAttr(FieldData, `{"age":35.0,"scores":[1.50]}`)
+	gbo.GboSetAttr(FieldChecksum, "")
+	gbo.GboSetAttr(FieldTagVersion, 0)
+	ubo := NewUniversalBoFromGbo(gbo, UboOpt{UseNumber: true})
+	ubo.SetDataJson(`{"age":35,"scores":[1.5]}`)
+	if ubo.HasChanges() || len(ubo.ChangedPaths()) != 0 {
+		t.Fatalf("%s failed: expected no change but received %#v", name, ubo.ChangedPaths())
+	}
+	ubo.SetDataAttr("age", 36)
+	if !ubo.HasChanges() {
+		t.Fatalf("%s failed: expected changes", name)
+	}
+}
@@ -0,0 +1,375 @@
+package henge
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/btnguyen2k/consu/checksum"
+	"github.com/btnguyen2k/godal"
+	"github.com/cespare/xxhash/v2"
+	"golang.org/x/crypto/blake2b"
+)
+
+// ChecksumAlgorithm specifies the algorithm used to calculate BO's checksum.
+//
+// Checksums calculated by algorithms other than ChecksumMd5 are prefixed with the algorithm's name (e.g. "sha256:...")
+// so that BOs with checksums of different algorithms can co-exist in the same storage.
+//
+// Available since v0.7.0
+type ChecksumAlgorithm string
+
+const (
+	// ChecksumMd5 is the legacy checksum algorithm: MD5 hash over BO's attributes (checksum has no prefix).
+	ChecksumMd5 ChecksumAlgorithm = "md5"
+
+	// ChecksumSha256 calculates checksum as SHA-256 hash of the JSON-encoded BO's attributes.
+	ChecksumSha256 ChecksumAlgorithm = "sha256"
+
+	// ChecksumXxhash64 calculates checksum as xxHash64 hash of the JSON-encoded BO's attributes.
+	// It is fast but not a cryptographic hash.
+	ChecksumXxhash64 ChecksumAlgorithm = "xxh64"
+
+	// ChecksumBlake2b256 calculates checksum as BLAKE2b-256 hash of the JSON-encoded BO's attributes.
+	ChecksumBlake2b256 ChecksumAlgorithm = "blake2b"
+
+	// DefaultChecksumAlgorithm is the algorithm used if none is specified.
+	DefaultChecksumAlgorithm = ChecksumMd5
+)
+
+var checksumHashFuncs = map[ChecksumAlgorithm]checksum.HashFunc{
+	ChecksumSha256: func(data []byte) []byte {
+		h := sha256.Sum256(data)
+		return h[:]
+	},
+	ChecksumXxhash64: func(data []byte) []byte {
+		h := xxhash.New()
+		h.Write(data)
+		return h.Sum(nil)
+	},
+	ChecksumBlake2b256: func(data []byte) []byte {
+		h := blake2b.Sum256(data)
+		return h[:]
+	},
+}
+
+// ErrUnsupportedChecksumAlgorithm is returned when setting a ChecksumAlgorithm that is not supported (e.g. "sha-256"
+// instead of ChecksumSha256).
+//
+// Available since v0.7.0
+var ErrUnsupportedChecksumAlgorithm = errors.New("unsupported checksum algorithm")
+
+// IsSupported returns true if the checksum algorithm is supported.
+//
+// Available since v0.7.0
+func (alg ChecksumAlgorithm) IsSupported() bool {
+	_, ok := checksumHashFuncs[alg]
+	return ok || alg == ChecksumMd5
+}
+
+// supportedChecksumAlgorithmOf returns the algorithm used to calculate the specified checksum if it is supported,
+// empty (i.e. DefaultChecksumAlgorithm) otherwise.
+func supportedChecksumAlgorithmOf(csum string) ChecksumAlgorithm {
+	if alg := ChecksumAlgorithmOf(csum); alg.IsSupported() {
+		return alg
+	}
+	return ""
+}
+
+// ChecksumAlgorithmOf returns the algorithm used to calculate the specified checksum, based on its prefix.
+//
+// Available since v0.7.0
+func ChecksumAlgorithmOf(csum string) ChecksumAlgorithm {
+	if i := strings.IndexByte(csum, ':'); i > 0 {
+		return ChecksumAlgorithm(csum[:i])
+	}
+	return ChecksumMd5
+}
+
+func _extractChecksumAlgorithm(opts ...UboOpt) ChecksumAlgorithm {
+	for _, opt := range opts {
+		if opt.ChecksumAlgorithm != "" && opt.ChecksumAlgorithm.IsSupported() {
+			return opt.ChecksumAlgorithm
+		}
+	}
+	return ""
+}
+
+// ChecksumCoverage specifies which parts of BO's data and extra attributes are taken into account when calculating
+// BO's checksum. BO's id, tag-version and creation timestamp are always covered.
+//
+// Data paths are in semita syntax (e.g. "a.b[0].c") or JSON Pointer (e.g. "/a/b/0/c"). If IncludeDataPaths
+// (resp. IncludeExtraKeys) is not empty, only the listed data paths (resp. extra attributes) are covered; excluded data
+// paths and extra attributes are then removed from the covered ones.
+//
+// Modifying attributes not covered by the checksum does not change it, hence does not bump BO's last-updated timestamp.
+// DAOs with "skip unchanged writes" enabled compare the stored content too when the checksum does not cover all of it,
+// so that BOs whose only changes are in non-covered attributes are still written.
+//
+// A ChecksumCoverage should not be modified once in use.
+//
+// Available since v0.7.0
+type ChecksumCoverage struct {
+	IncludeDataPaths []string // if not empty, only these data paths are covered
+	ExcludeDataPaths []string // data paths not covered
+	IncludeExtraKeys []string // if not empty, only these extra attributes are covered
+	ExcludeExtraKeys []string // extra attributes not covered
+}
+
+// coversAllData returns true if the whole BO's data is covered.
+func (cc *ChecksumCoverage) coversAllData() bool {
+	return cc == nil || (len(cc.IncludeDataPaths) == 0 && len(cc.ExcludeDataPaths) == 0)
+}
+
+// coversAll returns true if the whole BO's data and all extra attributes are covered.
+func (cc *ChecksumCoverage) coversAll() bool {
+	return cc.coversAllData() && (cc == nil || (len(cc.IncludeExtraKeys) == 0 && len(cc.ExcludeExtraKeys) == 0))
+}
+
+// coverData returns the part of data covered by the checksum. The input data is not modified.
+func (cc *ChecksumCoverage) coverData(data interface{}) interface{} {
+	if cc.coversAllData() {
+		return data
+	}
+	var covered interface{}
+	if len(cc.IncludeDataPaths) == 0 {
+		covered = cloneDataTree(data)
+	} else {
+		covered = make(map[string]interface{})
+		for _, path := range cc.IncludeDataPaths {
+			tokens, err := dataPathTokens(path)
+			if err != nil {
+				continue
+			}
+			if v, err := jsonTreeGet(data, tokens); err == nil {
+				if root, err := jsonTreeSet(covered, tokens, cloneDataTree(v)); err == nil {
+					covered = root
+				}
+			}
+		}
+	}
+	for _, path := range cc.ExcludeDataPaths {
+		tokens, err := dataPathTokens(path)
+		if err != nil || len(tokens) == 0 {
+			continue
+		}
+		// object members are removed while array elements are set to nil, so that other elements keep their positions
+		root, err := jsonTreeModify(covered, tokens, func(container interface{}, key string) (interface{}, error) {
+			switch c := container.(type) {
+			case map[string]interface{}:
+				delete(c, key)
+			case []interface{}:
+				if index, err := parseJsonArrayIndex(key, len(c), false); err == nil {
+					c[index] = nil
+				}
+			}
+			return container, nil
+		})
+		if err == nil {
+			covered = root
+		}
+	}
+	return covered
+}
+
+// coversExtra returns true if the extra attribute is covered by the checksum.
+func (cc *ChecksumCoverage) coversExtra(key string) bool {
+	if cc == nil {
+		return true
+	}
+	if len(cc.IncludeExtraKeys) > 0 && !containsString(cc.IncludeExtraKeys, key) {
+		return false
+	}
+	return !containsString(cc.ExcludeExtraKeys, key)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func _extractChecksumCoverage(opts ...UboOpt) *ChecksumCoverage {
+	for _, opt := range opts {
+		if opt.ChecksumCoverage != nil {
+			return opt.ChecksumCoverage
+		}
+	}
+	return nil
+}
+
+// _checksumMap returns the attributes bo's checksum is calculated over, data being bo's data.
+//   - (since v0.7.0) only data and extra attributes covered by bo's ChecksumCoverage are taken into account.
+//
+// Caller is responsible for locking the BO.
+func (ubo *UniversalBo) _checksumMap(data interface{}) map[string]interface{} {
+	cc := ubo._checksumCoverage
+	csumExtras := make(map[string]interface{}, len(ubo._extraAttrs))
+	for k, v := range ubo._extraAttrs {
+		if cc.coversExtra(k) {
+			csumExtras[k] = v
+		}
+	}
+	return map[string]interface{}{
+		"id":          ubo.id,
+		"app_version": ubo.tagVersion,
+		"t_created":   ubo.timeCreated.In(time.UTC).Format(DefaultTimeLayout),
+		"data":        cc.coverData(data),
+		"extra":       csumExtras,
+	}
+}
+
+// _calcChecksum calculates bo's checksum using the specified algorithm.
+//   - dataJs is the JSON-encoded form of bo's data.
+//   - alg must be supported or empty (i.e. DefaultChecksumAlgorithm), unsupported algorithms are rejected where they
+//     are set (see SetChecksumAlgorithm).
+//
+// Caller is responsible for locking the BO.
+func (ubo *UniversalBo) _calcChecksum(alg ChecksumAlgorithm, dataJs []byte) string {
+	hf, ok := checksumHashFuncs[alg]
+	if !ok {
+		data := ubo._data
+		if !ubo._dataParsed.Load() {
+			// data that has not been parsed yet is parsed the same way it is when first accessed
+			data = nil
+			unmarshalJsonData(ubo._jsonCodec, dataJs, ubo._useNumber, &data)
+		}
+		return fmt.Sprintf("%x", checksum.Md5Checksum(ubo._checksumMap(data)))
+	}
+	cc := ubo._checksumCoverage
+	csumMap := ubo._checksumMap(nil)
+	csumMap["data"] = json.RawMessage(dataJs)
+	if _, std := ubo._json().(stdJsonCodec); !std || !cc.coversAllData() {
+		// data encoded by another JsonCodec might differ in form (e.g. key order, escaping), hence is re-encoded with
+		// numbers kept as-is
+		var data interface{}
+		if unmarshalJsonData(JsonCodecStd, dataJs, true, &data) == nil {
+			csumMap["data"] = cc.coverData(data)
+		}
+	}
+	// checksum input is always encoded by encoding/json so that checksum does not depend on the JsonCodec in use
+	js, _ := json.Marshal(csumMap)
+	return string(alg) + ":" + hex.EncodeToString(hf(js))
+}
+
+// _verifyMd5Checksum checks the specified MD5 checksum against bo's data decoded with other number types, dataJs being
+// the JSON-encoded form of bo's data.
+//
+// MD5 checksum depends on the Go types of numbers, which are not preserved when data is reloaded from JSON: BOs usually
+// hold integers when written, while data parsed from JSON holds float64 or json.Number (see UboOpt.UseNumber).
+//
+// Caller is responsible for locking the BO.
+func (ubo *UniversalBo) _verifyMd5Checksum(csum string, dataJs []byte) bool {
+	for _, integral := range []bool{false, true} {
+		var data interface{}
+		if unmarshalJsonData(ubo._jsonCodec, dataJs, integral, &data) != nil {
+			return false
+		}
+		if integral {
+			data = jsonNumbersToNative(data)
+		}
+		if csum == fmt.Sprintf("%x", checksum.Md5Checksum(ubo._checksumMap(data))) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetChecksumAlgorithm returns the algorithm used to calculate this BO's checksum.
+//
+// Available since v0.7.0
+func (ubo *UniversalBo) GetChecksumAlgorithm() ChecksumAlgorithm {
+	if ubo._checksumAlgorithm == "" {
+		return DefaultChecksumAlgorithm
+	}
+	return ubo._checksumAlgorithm
+}
+
+// SetChecksumAlgorithm changes the algorithm used to calculate this BO's checksum.
+// The checksum is recalculated on the next sync. Unsupported algorithms are ignored (see ChecksumAlgorithm.IsSupported).
+//
+// Available since v0.7.0
+func (ubo *UniversalBo) SetChecksumAlgorithm(alg ChecksumAlgorithm) *UniversalBo {
+	if alg != "" && !alg.IsSupported() {
+		return ubo
+	}
+	ubo._lock.Lock()
+	defer ubo._lock.Unlock()
+	ubo._checksumAlgorithm = alg
+	ubo._dirty = true
+	return ubo
+}
+
+// GetChecksumCoverage returns the ChecksumCoverage of this BO (nil means all data and extra attributes are covered).
+//
+// Available since v0.7.0
+func (ubo *UniversalBo) GetChecksumCoverage() *ChecksumCoverage {
+	ubo._lock.RLock()
+	defer ubo._lock.RUnlock()
+	return ubo._checksumCoverage
+}
+
+// SetChecksumCoverage changes the parts of data and extra attributes covered by this BO's checksum (nil means all).
+// The checksum is recalculated on the next sync.
+//
+// Available since v0.7.0
+func (ubo *UniversalBo) SetChecksumCoverage(cc *ChecksumCoverage) *UniversalBo {
+	ubo._lock.Lock()
+	defer ubo._lock.Unlock()
+	ubo._checksumCoverage = cc
+	ubo._dirty = true
+	return ubo
+}
+
+// _adoptChecksumCoverage sets the default ChecksumCoverage (e.g. the DAO's one) if the BO has none. The checksum is
+// recalculated right away so that adopting the coverage does not count as a change of BO's content.
+func (ubo *UniversalBo) _adoptChecksumCoverage(cc *ChecksumCoverage) {
+	ubo._lock.Lock()
+	defer ubo._lock.Unlock()
+	if ubo._checksumCoverage != nil {
+		return
+	}
+	ubo._resolveChecksum()
+	ubo._checksumCoverage = cc
+	ubo.checksum = ubo._calcChecksum(ubo._checksumAlgorithm, ubo._checksumInput(ubo._checksumAlgorithm))
+}
+
+// DefaultScanBatchSize is the default number of BOs RecomputeChecksums and VerifyAll load per batch.
+//
+// Available since v0.7.0
+const DefaultScanBatchSize = 100
+
+// RecomputeChecksums scans BOs matching the filter (nil means all BOs) and writes back those whose stored checksum
+// differs from the one calculated when loaded, e.g. to migrate stored checksums to a new ChecksumAlgorithm.
+//   - BOs are loaded in batches of batchSize (DefaultScanBatchSize if batchSize <= 0), bypassing the DAO's strict-load mode.
+//   - The checksum algorithm is the one configured via the DAO's SetChecksumAlgorithm or default UboOpt; if not
+//     configured, BOs keep the algorithm of their stored checksums.
+//   - The filter should not depend on the checksum as paging is offset-based.
+//
+// This function returns the number of updated BOs.
+//
+// Available since v0.7.0
+func RecomputeChecksums(dao UniversalDao, filter godal.FilterOpt, batchSize int) (int, error) {
+	numUpdated := 0
+	err := scanBos(dao, filter, batchSize, func(bo *UniversalBo) error {
+		if bo.GetStoredChecksum() == bo.GetChecksum() {
+			return nil
+		}
+		ok, err := dao.Update(bo)
+		if err != nil {
+			return err
+		}
+		if ok {
+			numUpdated++
+		}
+		return nil
+	})
+	return numUpdated, err
+}
@@ -0,0 +1,220 @@
+package henge
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/btnguyen2k/consu/checksum"
+)
+
+func TestChecksumAlgorithmOf(t *testing.T) {
+	name := "TestChecksumAlgorithmOf"
+	testCases := map[string]ChecksumAlgorithm{
+		"":                                 ChecksumMd5,
+		"0123456789abcdef0123456789abcdef": ChecksumMd5,
+		"sha256:0123":                      ChecksumSha256,
+		"xxh64:0123":                       ChecksumXxhash64,
+		"blake2b:0123":                     ChecksumBlake2b256,
+	}
+	for csum, expected := range testCases {
+		if alg := ChecksumAlgorithmOf(csum); alg != expected {
+			t.Fatalf("%s failed: expected %#v but received %#v", name, expected, alg)
+		}
+	}
+}
+
+func TestUniversalBo_ChecksumAlgorithm(t *testing.T) {
+	name := "TestUniversalBo_ChecksumAlgorithm"
+	testCases := map[ChecksumAlgorithm]int{
+		"":                 32,
+		ChecksumMd5:        32,
+		ChecksumSha256:     len("sha256:") + 64,
+		ChecksumXxhash64:   len("xxh64:") + 16,
+		ChecksumBlake2b256: len("blake2b:") + 64,
+	}
+	for alg, csumLen := range testCases {
+		if alg != "" && !alg.IsSupported() {
+			t.Fatalf("%s failed: algorithm %#v should be supported", name, alg)
+		}
+		ubo := NewUniversalBo("id", 1, UboOpt{ChecksumAlgorithm: alg, TimestampRounding: TimestampRoundingSettingSecond})
+		ubo.SetDataAttr("a", "1")
+		ubo.SetExtraAttr("b", "x")
+		csum := ubo.Sync().GetChecksum()
+		if len(csum) != csumLen || ChecksumAlgorithmOf(csum) != ubo.GetChecksumAlgorithm() {
+			t.Fatalf("%s failed: invalid checksum %#v for algorithm %#v", name, csum, alg)
+		}
+
+		clone := ubo.Clone()
+		if v := clone.Sync().GetChecksum(); v != csum {
+			t.Fatalf("%s failed: expected %#v but received %#v", name, csum, v)
+		}
+		clone.SetDataAttr("a", "2")
+		if v := clone.Sync().GetChecksum(); v == csum || ChecksumAlgorithmOf(v) != ubo.GetChecksumAlgorithm() {
+			t.Fatalf("%s failed: checksum should have changed: %#v", name, v)
+		}
+
+		// algorithm is inferred from stored checksum
+		loaded := NewUniversalBoFromGbo(ubo.ToGenericBo())
+		if loaded.GetChecksumAlgorithm() != ubo.GetChecksumAlgorithm() || loaded.GetChecksum() != csum {
+			t.Fatalf("%s failed: expected %#v/%#v but received %#v/%#v", name, ubo.GetChecksumAlgorithm(), csum, loaded.GetChecksumAlgorithm(), loaded.GetChecksum())
+		}
+		js, _ := ubo.MarshalJSON()
+		unmarshaled := &UniversalBo{}
+		if err := unmarshaled.UnmarshalJSON(js); err != nil || unmarshaled.GetChecksum() != csum {
+			t.Fatalf("%s failed: expected %#v but received %#v (error: %s)", name, csum, unmarshaled.GetChecksum(), err)
+		}
+	}
+}
+
+func TestUniversalBo_ChecksumMd5Legacy(t *testing.T) {
+	name := "TestUniversalBo_ChecksumMd5Legacy"
+	ubo := NewUniversalBo("id", 1, UboOpt{TimestampRounding: TimestampRoundingSettingSecond})
+	ubo.SetDataAttr("a", 1)
+	ubo.SetDataAttr("b", 2.5)
+	ubo.SetExtraAttr("c", "x")
+	ubo.Sync()
+	// same input as MD5 checksums calculated before v0.7.0
+	csumMap := map[string]interface{}{
+		"id":          "id",
+		"app_version": uint64(1),
+		"t_created":   ubo.GetTimeCreated().In(time.UTC).Format(DefaultTimeLayout),
+		"data":        map[string]interface{}{"a": 1, "b": 2.5},
+		"extra":       map[string]interface{}{"c": "x"},
+	}
+	if expected := fmt.Sprintf("%x", checksum.Md5Checksum(csumMap)); ubo.GetChecksum() != expected {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, expected, ubo.GetChecksum())
+	}
+
+	// numbers are reloaded as float64 or json.Number
+	for _, useNumber := range []bool{false, true} {
+		loaded := NewUniversalBoFromGbo(ubo.ToGenericBo(), UboOpt{UseNumber: useNumber})
+		if loaded.IsChecksumMismatched() {
+			t.Fatalf("%s failed: loaded BO should not be flagged (UseNumber: %#v)", name, useNumber)
+		}
+	}
+}
+
+func TestUniversalBo_SetChecksumAlgorithm(t *testing.T) {
+	name := "TestUniversalBo_SetChecksumAlgorithm"
+	ubo := NewUniversalBo("id", 1)
+	ubo.SetDataAttr("a", "1")
+	md5 := ubo.Sync().GetChecksum()
+	tupd := ubo.GetTimeUpdated()
+	ubo.SetChecksumAlgorithm(ChecksumSha256)
+	if csum := ubo.Sync().GetChecksum(); !strings.HasPrefix(csum, "sha256:") {
+		t.Fatalf("%s failed: expected sha256 checksum but received %#v", name, csum)
+	}
+	if ubo.GetTimeUpdated() != tupd {
+		t.Fatalf("%s failed: last-updated timestamp should not change", name)
+	}
+	ubo.SetChecksumAlgorithm(ChecksumMd5)
+	if csum := ubo.Sync().GetChecksum(); csum != md5 {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, md5, csum)
+	}
+
+	// unsupported algorithms are ignored
+	invalid := ChecksumAlgorithm("sha-256")
+	if invalid.IsSupported() {
+		t.Fatalf("%s failed: algorithm %#v should not be supported", name, invalid)
+	}
+	if alg := ubo.SetChecksumAlgorithm(invalid).GetChecksumAlgorithm(); alg != ChecksumMd5 || ubo.Sync().GetChecksum() != md5 {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, ChecksumMd5, alg)
+	}
+	if alg := NewUniversalBo("id", 1, UboOpt{ChecksumAlgorithm: invalid}).GetChecksumAlgorithm(); alg != DefaultChecksumAlgorithm {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, DefaultChecksumAlgorithm, alg)
+	}
+	gbo := ubo.ToGenericBo()
+	gbo.GboSetAttr(FieldChecksum, "sha-256:0123")
+	if loaded := NewUniversalBoFromGbo(gbo); loaded.GetChecksumAlgorithm() != DefaultChecksumAlgorithm || !loaded.IsChecksumMismatched() {
+		t.Fatalf("%s failed: checksum of unsupported algorithm should not match", name)
+	}
+}
+
+func TestChecksumCoverage(t *testing.T) {
+	name := "TestChecksumCoverage"
+	testCases := []struct {
+		coverage  *ChecksumCoverage
+		unchanged func(bo *UniversalBo)
+		changed   func(bo *UniversalBo)
+	}{
+		{
+			&ChecksumCoverage{ExcludeExtraKeys: []string{"last_seen"}},
+			func(bo *UniversalBo) { bo.SetExtraAttr("last_seen", "now") },
+			func(bo *UniversalBo) { bo.SetExtraAttr("owner", "me") },
+		},
+		{
+			&ChecksumCoverage{IncludeExtraKeys: []string{"owner"}},
+			func(bo *UniversalBo) { bo.SetExtraAttr("last_seen", "now") },
+			func(bo *UniversalBo) { bo.SetExtraAttr("owner", "me") },
+		},
+		{
+			&ChecksumCoverage{IncludeDataPaths: []string{"a.b", "/list/0"}},
+			func(bo *UniversalBo) { bo.SetDataAttr("a.c", 1); bo.SetDataAttr("list[1]", 1) },
+			func(bo *UniversalBo) { bo.SetDataAttr("/list/0", "y") },
+		},
+		{
+			&ChecksumCoverage{ExcludeDataPaths: []string{"/a/c", "list[1]"}},
+			func(bo *UniversalBo) { bo.SetDataAttr("a.c", 1); bo.SetDataAttr("list[1]", 1) },
+			func(bo *UniversalBo) { bo.SetDataAttr("a.b", 2) },
+		},
+	}
+	now := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i, tc := range testCases {
+		for _, alg := range []ChecksumAlgorithm{ChecksumMd5, ChecksumSha256} {
+			clock := NewFixedClock(now)
+			bo := NewUniversalBo("id", 1, UboOpt{ChecksumAlgorithm: alg, ChecksumCoverage: tc.coverage, Clock: clock})
+			bo.SetDataJson(`{"a":{"b":1,"c":0},"list":["x",0]}`)
+			bo.SetExtraAttr("last_seen", "yesterday")
+			csum := bo.Sync(UboSyncOpts{UpdateTimestampIfChecksumChange: true}).GetChecksum()
+			clock.Advance(time.Hour)
+			tc.unchanged(bo)
+			if bo.Sync(UboSyncOpts{UpdateTimestampIfChecksumChange: true}).GetChecksum() != csum || !bo.GetTimeUpdated().Equal(now) {
+				t.Fatalf("%s failed [%d/%s]: checksum should not change", name, i, alg)
+			}
+			clone := NewUniversalBoFromGbo(bo.ToGenericBo(), UboOpt{ChecksumCoverage: tc.coverage})
+			if clone.IsChecksumMismatched() || clone.GetChecksum() != csum {
+				t.Fatalf("%s failed [%d/%s]: checksum should match when loaded", name, i, alg)
+			}
+			tc.changed(bo)
+			if bo.Sync(UboSyncOpts{UpdateTimestampIfChecksumChange: true}).GetChecksum() == csum || !bo.GetTimeUpdated().Equal(now.Add(time.Hour)) {
+				t.Fatalf("%s failed [%d/%s]: checksum should change", name, i, alg)
+			}
+		}
+	}
+}
+
+func TestBoCodec_ChecksumCoverage(t *testing.T) {
+	name := "TestBoCodec_ChecksumCoverage"
+	coverage := &ChecksumCoverage{ExcludeExtraKeys: []string{"last_seen"}}
+	codec := boCodec{csumCoverage: coverage}
+	clock := NewFixedClock(time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC))
+	ubo := NewUniversalBo("id", 1, UboOpt{Clock: clock})
+	ubo.SetExtraAttr("last_seen", "now")
+	tupdated := ubo.Sync().GetTimeUpdated()
+	clock.Advance(time.Hour)
+	if _, err := codec.toGenericBo(ubo); err != nil || ubo.GetChecksumCoverage() != nil {
+		t.Fatalf("%s failed: BO should be left untouched (error: %s)", name, err)
+	}
+	codec.prepareWrite(ubo)
+	gbo, err := codec.toGenericBo(ubo)
+	if err != nil || ubo.GetChecksumCoverage() != coverage || !ubo.GetTimeUpdated().Equal(tupdated) {
+		t.Fatalf("%s failed: BO should adopt the codec's coverage without bumping its timestamp (error: %s)", name, err)
+	}
+	expected := NewUniversalBo("id", 1, UboOpt{Clock: clock.Advance(-time.Hour), ChecksumCoverage: coverage}).GetChecksum()
+	if csum := ubo.GetChecksum(); csum != expected {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, expected, csum)
+	}
+	if bo, err := codec.toUniversalBo(gbo); err != nil || bo.GetChecksumCoverage() != coverage || bo.IsChecksumMismatched() {
+		t.Fatalf("%s failed: loaded BO should use the codec's coverage (error: %s)", name, err)
+	}
+}
+
+func TestRecomputeChecksums_unchanged(t *testing.T) {
+	name := "TestRecomputeChecksums_unchanged"
+	dao := Chain(&memDao{bos: map[string]*UniversalBo{"id": newMismatchedBo("id")}}, unchangedInterceptor)
+	if numUpdated, err := RecomputeChecksums(dao, nil, 0); err != nil || numUpdated != 0 {
+		t.Fatalf("%s failed: expected %#v/%#v but received %#v/%#v", name, 0, nil, numUpdated, err)
+	}
+}
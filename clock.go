@@ -0,0 +1,110 @@
+package henge
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock provides the current time to UniversalBo and UniversalDao implementations, e.g. when a BO is created or its
+// last-updated timestamp is bumped. Supply a custom Clock (see UboOpt.Clock) to get deterministic timestamps in tests
+// or to use a different time source.
+//
+// Available since v0.7.0
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+// Now implements Clock.Now.
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// SystemClock is the default Clock, backed by time.Now.
+//
+// Available since v0.7.0
+var SystemClock Clock = systemClock{}
+
+// FixedClock is a Clock that always returns the same time until it is changed by Set or Advance. It is safe for
+// concurrent use and mainly intended for tests.
+//
+// Available since v0.7.0
+type FixedClock struct {
+	lock sync.RWMutex
+	now  time.Time
+}
+
+// NewFixedClock creates a new FixedClock instance returning the specified time.
+//
+// Available since v0.7.0
+func NewFixedClock(now time.Time) *FixedClock {
+	return &FixedClock{now: now}
+}
+
+// Now implements Clock.Now.
+func (c *FixedClock) Now() time.Time {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.now
+}
+
+// Set changes the time returned by the clock.
+func (c *FixedClock) Set(now time.Time) *FixedClock {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.now = now
+	return c
+}
+
+// Advance moves the clock forward (or backward if d is negative) by the specified duration.
+func (c *FixedClock) Advance(d time.Duration) *FixedClock {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.now = c.now.Add(d)
+	return c
+}
+
+func _extractClock(opts ...UboOpt) Clock {
+	for _, opt := range opts {
+		if opt.Clock != nil {
+			return opt.Clock
+		}
+	}
+	return nil
+}
+
+// clockOrDefault returns the clock if not nil, SystemClock otherwise.
+func clockOrDefault(clock Clock) Clock {
+	if clock != nil {
+		return clock
+	}
+	return SystemClock
+}
+
+// clockSource is implemented by UniversalDao implementations having a configurable clock (see UniversalDaoSql.SetClock).
+type clockSource interface {
+	GetClock() Clock
+}
+
+// _clockWithDefault returns BO's own clock or, if BO has none, the specified default clock (SystemClock if nil).
+func (ubo *UniversalBo) _clockWithDefault(clock Clock) Clock {
+	ubo._lock.RLock()
+	defer ubo._lock.RUnlock()
+	if ubo._clock != nil {
+		return ubo._clock
+	}
+	return clockOrDefault(clock)
+}
+
+// _syncWithDefaultClock syncs the BO, bumping its last-updated timestamp if checksum changes, with time taken from
+// BO's own clock or, if BO has none, the specified default clock.
+func (ubo *UniversalBo) _syncWithDefaultClock(clock Clock) {
+	ubo._lock.Lock()
+	defer ubo._lock.Unlock()
+	if ubo._clock != nil {
+		clock = ubo._clock
+	}
+	ubo._syncWithClock(clock, UboSyncOpts{UpdateTimestampIfChecksumChange: true})
+}
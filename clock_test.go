@@ -0,0 +1,92 @@
+package henge
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFixedClock(t *testing.T) {
+	name := "TestFixedClock"
+	now := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	clock := NewFixedClock(now)
+	if v := clock.Now(); !v.Equal(now) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, now, v)
+	}
+	if v := clock.Advance(time.Hour).Now(); !v.Equal(now.Add(time.Hour)) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, now.Add(time.Hour), v)
+	}
+	if v := clock.Set(now).Now(); !v.Equal(now) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, now, v)
+	}
+}
+
+func TestUboOpt_Clock(t *testing.T) {
+	name := "TestUboOpt_Clock"
+	now := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	clock := NewFixedClock(now)
+	ubo := NewUniversalBo("id", 1, UboOpt{Clock: clock})
+	if !ubo.GetTimeCreated().Equal(now) || !ubo.GetTimeUpdated().Equal(now) {
+		t.Fatalf("%s failed: expected %#v but received %#v / %#v", name, now, ubo.GetTimeCreated(), ubo.GetTimeUpdated())
+	}
+
+	// checksum-triggered bump uses the clock
+	clock.Advance(time.Minute)
+	ubo.SetDataAttr("a", 1)
+	ubo.Sync(UboSyncOpts{UpdateTimestampIfChecksumChange: true})
+	if !ubo.GetTimeCreated().Equal(now) || !ubo.GetTimeUpdated().Equal(now.Add(time.Minute)) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, now.Add(time.Minute), ubo.GetTimeUpdated())
+	}
+
+	// clone shares the clock
+	clone := ubo.Clone()
+	clock.Advance(time.Minute)
+	clone.SetDataAttr("a", 2)
+	if v := clone.ToGenericBo().GboGetAttrUnsafe(FieldTimeUpdated, nil); !v.(time.Time).Equal(now.Add(2 * time.Minute)) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, now.Add(2*time.Minute), v)
+	}
+
+	// BO created from gbo uses the clock as well
+	bo := NewUniversalBoFromGbo(ubo.ToGenericBo(), UboOpt{Clock: clock})
+	clock.Advance(time.Minute)
+	bo.SetDataAttr("a", 3)
+	if v := bo.Sync(UboSyncOpts{UpdateTimestamp: true}).GetTimeUpdated(); !v.Equal(now.Add(3 * time.Minute)) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, now.Add(3*time.Minute), v)
+	}
+}
+
+func TestBoCodec_Clock(t *testing.T) {
+	name := "TestBoCodec_Clock"
+	now := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	clock := NewFixedClock(now)
+	codec := boCodec{clock: clock}
+
+	// BO without clock of its own is bumped with the codec's clock when written
+	ubo := NewUniversalBo("id", 1)
+	ubo.SetDataAttr("a", 1)
+	codec.prepareWrite(ubo)
+	gbo, err := codec.toGenericBo(ubo)
+	if err != nil || !ubo.GetTimeUpdated().Equal(now) || !gbo.GboGetAttrUnsafe(FieldTimeUpdated, nil).(time.Time).Equal(now) {
+		t.Fatalf("%s failed: expected %#v but received %#v (error: %s)", name, now, ubo.GetTimeUpdated(), err)
+	}
+
+	// BO's own clock takes precedence
+	own := NewFixedClock(now.Add(time.Hour))
+	ubo = NewUniversalBo("id", 1, UboOpt{Clock: own})
+	ubo.SetDataAttr("a", 1)
+	codec.prepareWrite(ubo)
+	if !ubo.GetTimeUpdated().Equal(now.Add(time.Hour)) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, now.Add(time.Hour), ubo.GetTimeUpdated())
+	}
+
+	// loaded BO uses the codec's clock, overriding the default options
+	bo, err := codec.toUniversalBo(gbo, UboOpt{Clock: own})
+	if err != nil || bo._clock != clock {
+		t.Fatalf("%s failed: expected clock %#v but received %#v (error: %s)", name, clock, bo._clock, err)
+	}
+
+	// the other default options are kept
+	bo, err = codec.toUniversalBo(gbo, UboOpt{TimestampRounding: TimestampRoundingSettingSecond, UseNumber: true})
+	if err != nil || bo.GetTimestampRounding() != TimestampRoundingSettingSecond || !bo._useNumber {
+		t.Fatalf("%s failed: expected %#v but received %#v (error: %s)", name, TimestampRoundingSettingSecond, bo.GetTimestampRounding(), err)
+	}
+}
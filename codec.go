@@ -0,0 +1,402 @@
+package henge
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/btnguyen2k/consu/semita"
+	"github.com/btnguyen2k/godal"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// DataCodec encodes/decodes BO's user-defined data to/from the form stored in the storage.
+//
+// Available since v0.7.0
+type DataCodec interface {
+	// Name returns the unique name of the codec, which is stored along with the encoded data. It must not contain the character ':'.
+	Name() string
+
+	// Marshal encodes the data tree.
+	Marshal(data interface{}) ([]byte, error)
+
+	// Unmarshal decodes the encoded data to a data tree, whose maps must be of type map[string]interface{} and arrays
+	// of type []interface{}.
+	Unmarshal(encoded []byte) (interface{}, error)
+}
+
+var (
+	// DataCodecJson encodes data in JSON format. This is the default codec.
+	//
+	// Available since v0.7.0
+	DataCodecJson DataCodec = jsonDataCodec{}
+
+	// DataCodecMsgpack encodes data in MessagePack format. Integers are decoded as int64 (uint64 if beyond the range of
+	// int64) and binary data as []byte.
+	//
+	// Available since v0.7.0
+	DataCodecMsgpack DataCodec = msgpackDataCodec{}
+
+	// DataCodecCbor encodes data in CBOR format. Integers are decoded as int64 (uint64 if beyond the range of int64),
+	// binary data as []byte and timestamps as time.Time.
+	//
+	// Available since v0.7.0
+	DataCodecCbor DataCodec = newCborDataCodec()
+)
+
+type jsonDataCodec struct{}
+
+// Name implements DataCodec.Name.
+func (jsonDataCodec) Name() string { return "json" }
+
+// Marshal implements DataCodec.Marshal.
+func (jsonDataCodec) Marshal(data interface{}) ([]byte, error) { return json.Marshal(data) }
+
+// Unmarshal implements DataCodec.Unmarshal.
+func (jsonDataCodec) Unmarshal(encoded []byte) (interface{}, error) {
+	var data interface{}
+	err := json.Unmarshal(encoded, &data)
+	return data, err
+}
+
+type msgpackDataCodec struct{}
+
+// Name implements DataCodec.Name.
+func (msgpackDataCodec) Name() string { return "msgpack" }
+
+// Marshal implements DataCodec.Marshal.
+func (msgpackDataCodec) Marshal(data interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	enc.SetSortMapKeys(true)
+	err := enc.Encode(data)
+	return buf.Bytes(), err
+}
+
+// Unmarshal implements DataCodec.Unmarshal.
+func (msgpackDataCodec) Unmarshal(encoded []byte) (interface{}, error) {
+	dec := msgpack.NewDecoder(bytes.NewReader(encoded))
+	dec.SetCustomStructTag("json")
+	var data interface{}
+	err := dec.Decode(&data)
+	return normalizeIntegers(data), err
+}
+
+type cborDataCodec struct {
+	encMode cbor.EncMode
+	decMode cbor.DecMode
+}
+
+func newCborDataCodec() cborDataCodec {
+	encMode, _ := cbor.EncOptions{Sort: cbor.SortCanonical, Time: cbor.TimeRFC3339Nano, TimeTag: cbor.EncTagRequired}.EncMode()
+	decMode, _ := cbor.DecOptions{DefaultMapType: reflect.TypeOf(map[string]interface{}(nil))}.DecMode()
+	return cborDataCodec{encMode: encMode, decMode: decMode}
+}
+
+// Name implements DataCodec.Name.
+func (cborDataCodec) Name() string { return "cbor" }
+
+// Marshal implements DataCodec.Marshal.
+func (c cborDataCodec) Marshal(data interface{}) ([]byte, error) { return c.encMode.Marshal(data) }
+
+// Unmarshal implements DataCodec.Unmarshal.
+func (c cborDataCodec) Unmarshal(encoded []byte) (interface{}, error) {
+	var data interface{}
+	err := c.decMode.Unmarshal(encoded, &data)
+	return normalizeIntegers(data), err
+}
+
+// normalizeIntegers converts, in place, integers of the decoded data tree to int64 (or uint64 if beyond the range of int64).
+func normalizeIntegers(data interface{}) interface{} {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = normalizeIntegers(e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = normalizeIntegers(e)
+		}
+	case int8, int16, int32, int, int64:
+		return reflect.ValueOf(v).Int()
+	case uint8, uint16, uint32, uint, uint64:
+		if u := reflect.ValueOf(v).Uint(); u <= math.MaxInt64 {
+			return int64(u)
+		}
+	}
+	return data
+}
+
+var (
+	dataCodecsLock sync.RWMutex
+	dataCodecs     = map[string]DataCodec{
+		DataCodecJson.Name():    DataCodecJson,
+		DataCodecMsgpack.Name(): DataCodecMsgpack,
+		DataCodecCbor.Name():    DataCodecCbor,
+	}
+)
+
+// RegisterDataCodec registers a custom DataCodec so that data encoded by it can be decoded by DecodeData.
+// Built-in codecs are registered out of the box.
+//
+// Available since v0.7.0
+func RegisterDataCodec(codec DataCodec) error {
+	name := codec.Name()
+	if name == "" || strings.Contains(name, ":") {
+		return fmt.Errorf("invalid codec name [%s]", name)
+	}
+	dataCodecsLock.Lock()
+	defer dataCodecsLock.Unlock()
+	dataCodecs[name] = codec
+	return nil
+}
+
+// lookupDataCodec returns the registered DataCodec by name (nil if not found).
+func lookupDataCodec(name string) DataCodec {
+	dataCodecsLock.RLock()
+	defer dataCodecsLock.RUnlock()
+	return dataCodecs[name]
+}
+
+// isBinaryDataCodec returns true if data is stored in binary form with the codec (i.e. codec is not JSON).
+func isBinaryDataCodec(codec DataCodec) bool {
+	return codec != nil && codec.Name() != DataCodecJson.Name()
+}
+
+const encodedDataPrefix = "$c:"
+
+// EncodeData encodes the data tree with the codec, in the format "$c:<codec-name>:<encoded-data>".
+//
+// Available since v0.7.0
+func EncodeData(codec DataCodec, data interface{}) ([]byte, error) {
+	encoded, err := codec.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	prefix := encodedDataPrefix + codec.Name() + ":"
+	return append([]byte(prefix), encoded...), nil
+}
+
+// IsEncodedData returns true if the data (string or []byte) is in the format produced by EncodeData.
+//
+// Available since v0.7.0
+func IsEncodedData(data interface{}) bool {
+	switch v := data.(type) {
+	case []byte:
+		return bytes.HasPrefix(v, []byte(encodedDataPrefix))
+	case string:
+		return strings.HasPrefix(v, encodedDataPrefix)
+	}
+	return false
+}
+
+// DecodeData decodes data (string or []byte) encoded by EncodeData, using the codec it was encoded with.
+//
+// Available since v0.7.0
+func DecodeData(data interface{}) (interface{}, error) {
+	var raw []byte
+	switch v := data.(type) {
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	}
+	if !bytes.HasPrefix(raw, []byte(encodedDataPrefix)) {
+		return nil, fmt.Errorf("invalid encoded data format")
+	}
+	tokens := bytes.SplitN(raw[len(encodedDataPrefix):], []byte(":"), 2)
+	if len(tokens) != 2 {
+		return nil, fmt.Errorf("invalid encoded data format")
+	}
+	codec := lookupDataCodec(string(tokens[0]))
+	if codec == nil {
+		return nil, fmt.Errorf("unsupported data codec [%s]", tokens[0])
+	}
+	return codec.Unmarshal(tokens[1])
+}
+
+// cloneDataTree deep clones a data tree, if possible.
+func cloneDataTree(data interface{}) interface{} {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		return cloneMap(v)
+	case []interface{}:
+		return cloneSlice(v)
+	}
+	return data
+}
+
+// boCodec holds the settings used to convert BOs to/from godal.IGenericBo by UniversalDao implementations.
+type boCodec struct {
+	fieldEncryptor *FieldEncryptor
+	dataCodec      DataCodec
+	dataCompressor *DataCompressor
+	clock          Clock
+	csumAlgorithm  ChecksumAlgorithm
+	csumCoverage   *ChecksumCoverage
+	jsonCodec      JsonCodec
+}
+
+// prepareWrite prepares a BO right before it is written to the storage: the DAO's ChecksumCoverage is adopted and the
+// BO is synced with the DAO's clock.
+func (c boCodec) prepareWrite(ubo *UniversalBo) {
+	if ubo == nil {
+		return
+	}
+	if c.csumCoverage != nil {
+		ubo._adoptChecksumCoverage(c.csumCoverage)
+	}
+	if c.clock != nil {
+		ubo._syncWithDefaultClock(c.clock)
+	}
+}
+
+// toGenericBo transforms a BO to godal.IGenericBo to be written to the storage: configured attributes are encrypted,
+// then data is encoded with the binary codec or, in JSON format, compressed.
+//
+// The BO is left untouched: if not done by the caller (see prepareWrite), a clone of the BO is prepared instead.
+func (c boCodec) toGenericBo(ubo *UniversalBo) (godal.IGenericBo, error) {
+	if ubo == nil {
+		return nil, nil
+	}
+	if c.csumCoverage != nil || c.clock != nil {
+		ubo = ubo.Clone()
+		c.prepareWrite(ubo)
+	}
+	gbo := ubo.ToGenericBo()
+	// the stored checksum of a BO written back as loaded is verified so that a mismatching one is not written again
+	gbo.GboSetAttr(FieldChecksum, ubo.GetChecksum())
+	if !isBinaryDataCodec(c.dataCodec) {
+		if err := c.fieldEncryptor.encryptGbo(gbo); err != nil {
+			return nil, err
+		}
+		if err := c.dataCompressor.compressGbo(gbo); err != nil {
+			return nil, err
+		}
+		return gbo, nil
+	}
+	ubo._lock.RLock()
+	ubo._ensureData()
+	data := jsonNumbersToNative(cloneDataTree(ubo._data))
+	ubo._lock.RUnlock()
+	if err := c.fieldEncryptor.transformData(data, c.fieldEncryptor.encryptAttr); err != nil {
+		return nil, err
+	}
+	if err := c.fieldEncryptor.transformExtras(gbo, c.fieldEncryptor.encryptAttr); err != nil {
+		return nil, err
+	}
+	encoded, err := EncodeData(c.dataCodec, data)
+	if err != nil {
+		return nil, err
+	}
+	gbo.GboSetAttr(FieldData, encoded)
+	return gbo, nil
+}
+
+// isUnchanged returns true if the stored record exists and has the same checksum as bo, gbo being bo transformed by
+// toGenericBo. If bo's checksum does not cover all of its content, the stored content must be the same too.
+func (c boCodec) isUnchanged(existing, gbo godal.IGenericBo, bo *UniversalBo) bool {
+	if !isUnchanged(existing, gbo) {
+		return false
+	}
+	if bo.GetChecksumCoverage().coversAll() {
+		return true
+	}
+	stored, err := c.toUniversalBo(existing)
+	return err == nil && isUnchangedContent(stored, bo)
+}
+
+// uboOpts resolves opts into a single UboOpt with the DAO's clock, checksum algorithm, checksum coverage and JSON codec,
+// if set, taking precedence. Only set DAO values are merged so that the other options (e.g. timestamp rounding) are kept.
+func (c boCodec) uboOpts(opts ...UboOpt) []UboOpt {
+	if c.clock == nil && c.csumAlgorithm == "" && c.csumCoverage == nil && c.jsonCodec == nil {
+		return opts
+	}
+	opt := UboOpt{
+		TimeLayout:        _extractTimeLayout(opts...),
+		TimestampRounding: _extractTimestampRounding(opts...),
+		ChecksumAlgorithm: _extractChecksumAlgorithm(opts...),
+		UseNumber:         _extractUseNumber(opts...),
+		Clock:             _extractClock(opts...),
+		ChecksumCoverage:  _extractChecksumCoverage(opts...),
+		JsonCodec:         _extractJsonCodec(opts...),
+	}
+	if c.clock != nil {
+		opt.Clock = c.clock
+	}
+	if c.csumAlgorithm != "" {
+		opt.ChecksumAlgorithm = c.csumAlgorithm
+	}
+	if c.csumCoverage != nil {
+		opt.ChecksumCoverage = c.csumCoverage
+	}
+	if c.jsonCodec != nil {
+		opt.JsonCodec = c.jsonCodec
+	}
+	return []UboOpt{opt}
+}
+
+// toUniversalBo transforms godal.IGenericBo loaded from the storage to BO, decoding/decompressing data and decrypting
+// configured attributes. Data encoded with a binary codec is decoded regardless of the codec currently configured.
+func (c boCodec) toUniversalBo(gbo godal.IGenericBo, opts ...UboOpt) (*UniversalBo, error) {
+	if gbo == nil {
+		return nil, nil
+	}
+	opts = c.uboOpts(opts...)
+	raw := gbo.GboGetAttrUnsafe(FieldData, nil)
+	if !IsEncodedData(raw) {
+		if err := decompressGbo(gbo); err != nil {
+			return nil, err
+		}
+		if err := c.fieldEncryptor.decryptGbo(gbo); err != nil {
+			return nil, err
+		}
+		return NewUniversalBoFromGbo(gbo, opts...), nil
+	}
+	data, err := DecodeData(raw)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.fieldEncryptor.transformData(data, c.fieldEncryptor.decryptAttr); err != nil {
+		return nil, err
+	}
+	if err = c.fieldEncryptor.transformExtras(gbo, c.fieldEncryptor.decryptAttr); err != nil {
+		return nil, err
+	}
+	js, err := jsonCodecOrDefault(c.jsonCodec).Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	gbo.GboSetAttr(FieldData, string(js))
+	bo := NewUniversalBoFromGbo(gbo, opts...)
+	if bo != nil {
+		bo._lock.Lock()
+		bo._setDecodedData(data, js)
+		bo._lock.Unlock()
+	}
+	return bo, nil
+}
+
+// _setDecodedData replaces bo's data tree, parsed from JSON, with the one decoded by a binary DataCodec so that value
+// types (e.g. int64, []byte) are preserved, then verifies the stored checksum against it.
+//
+// Caller is responsible for locking the BO.
+func (ubo *UniversalBo) _setDecodedData(data interface{}, js []byte) {
+	ubo._data, ubo._sdata = data, nil
+	if data != nil {
+		ubo._sdata = semita.NewSemita(&ubo._data)
+	}
+	ubo._dataParsed.Store(true)
+	ubo._dataShared.Store(false)
+	ubo._dataPristine, ubo._dataChanged, ubo._dataRaw = false, false, false
+	ubo.dataJson = string(js)
+	ubo._pendingChecksum = nil
+	ubo.checksum = ubo._calcChecksum(ubo._checksumAlgorithm, js)
+	ubo._checksumMismatch = !ubo._verifyLoadedChecksum(ubo._storedChecksum, js)
+}
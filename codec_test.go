@@ -0,0 +1,136 @@
+package henge
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDataCodec_Roundtrip(t *testing.T) {
+	name := "TestDataCodec_Roundtrip"
+	data := map[string]interface{}{
+		"name":  "henge",
+		"count": int64(1 << 60),
+		"ratio": 1.5,
+		"tags":  []interface{}{"a", "b"},
+		"nested": map[string]interface{}{
+			"active": true,
+			"none":   nil,
+		},
+	}
+	for _, codec := range []DataCodec{DataCodecJson, DataCodecMsgpack, DataCodecCbor} {
+		encoded, err := EncodeData(codec, data)
+		if err != nil || !IsEncodedData(encoded) || !IsEncodedData(string(encoded)) || !bytes.HasPrefix(encoded, []byte("$c:"+codec.Name()+":")) {
+			t.Fatalf("%s failed [%s]: %#v / %s", name, codec.Name(), encoded, err)
+		}
+		decoded, err := DecodeData(encoded)
+		if err != nil {
+			t.Fatalf("%s failed [%s]: %s", name, codec.Name(), err)
+		}
+		m := decoded.(map[string]interface{})
+		if m["name"] != "henge" || m["ratio"] != 1.5 || !reflect.DeepEqual(m["tags"], []interface{}{"a", "b"}) ||
+			!reflect.DeepEqual(m["nested"], map[string]interface{}{"active": true, "none": nil}) {
+			t.Fatalf("%s failed [%s]: received %#v", name, codec.Name(), decoded)
+		}
+		if codec != DataCodecJson && m["count"] != int64(1<<60) {
+			t.Fatalf("%s failed [%s]: expected %#v but received %#v", name, codec.Name(), int64(1<<60), m["count"])
+		}
+	}
+}
+
+func TestDataCodec_BinaryTypes(t *testing.T) {
+	name := "TestDataCodec_BinaryTypes"
+	blob := []byte{0, 1, 2, 0xff}
+	now := time.Now().UTC().Round(time.Millisecond)
+	for _, codec := range []DataCodec{DataCodecMsgpack, DataCodecCbor} {
+		encoded, _ := EncodeData(codec, map[string]interface{}{"blob": blob, "time": now})
+		decoded, err := DecodeData(encoded)
+		if err != nil {
+			t.Fatalf("%s failed [%s]: %s", name, codec.Name(), err)
+		}
+		m := decoded.(map[string]interface{})
+		if !reflect.DeepEqual(m["blob"], blob) {
+			t.Fatalf("%s failed [%s]: expected %#v but received %#v", name, codec.Name(), blob, m["blob"])
+		}
+		if tm, ok := m["time"].(time.Time); !ok || !tm.Equal(now) {
+			t.Fatalf("%s failed [%s]: expected %#v but received %#v", name, codec.Name(), now, m["time"])
+		}
+	}
+}
+
+func TestDecodeData_Invalid(t *testing.T) {
+	name := "TestDecodeData_Invalid"
+	for _, invalid := range []interface{}{`{"a":1}`, "$c:msgpack", "$c:unknown:AAAA", []byte("$c:cbor:\xff"), 123} {
+		if _, err := DecodeData(invalid); err == nil {
+			t.Fatalf("%s failed: expected error for %#v", name, invalid)
+		}
+	}
+	if IsEncodedData(123) || IsEncodedData(`{"a":1}`) {
+		t.Fatalf("%s failed: unexpected encoded data", name)
+	}
+}
+
+func TestRegisterDataCodec(t *testing.T) {
+	name := "TestRegisterDataCodec"
+	if err := RegisterDataCodec(jsonDataCodecWithName("test-json")); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	encoded, _ := EncodeData(jsonDataCodecWithName("test-json"), []interface{}{"a"})
+	if decoded, err := DecodeData(encoded); err != nil || !reflect.DeepEqual(decoded, []interface{}{"a"}) {
+		t.Fatalf("%s failed: %#v / %s", name, decoded, err)
+	}
+	if err := RegisterDataCodec(jsonDataCodecWithName("a:b")); err == nil {
+		t.Fatalf("%s failed: expected error for invalid codec name", name)
+	}
+}
+
+type jsonDataCodecWithName string
+
+func (c jsonDataCodecWithName) Name() string { return string(c) }
+func (jsonDataCodecWithName) Marshal(data interface{}) ([]byte, error) {
+	return DataCodecJson.Marshal(data)
+}
+func (jsonDataCodecWithName) Unmarshal(encoded []byte) (interface{}, error) {
+	return DataCodecJson.Unmarshal(encoded)
+}
+
+func TestBoCodec_Binary(t *testing.T) {
+	name := "TestBoCodec_Binary"
+	kp, _ := NewStaticKeyProvider("k1", map[string][]byte{"k1": []byte("0123456789abcdef")})
+	fe := NewFieldEncryptor(kp).EncryptDataPath("secret", true).EncryptExtraAttr("email", true)
+	opts := []UboOpt{{TimestampRounding: TimestampRoundingSettingSecond}}
+	for _, codec := range []DataCodec{DataCodecMsgpack, DataCodecCbor} {
+		c := boCodec{fieldEncryptor: fe, dataCodec: codec}
+		ubo := NewUniversalBo("id", 1, opts...)
+		ubo.SetDataAttr("count", 12345)
+		ubo.SetDataAttr("secret", "s3cr3t")
+		ubo.SetExtraAttr("email", "user@domain.com")
+		gbo, err := c.toGenericBo(ubo)
+		if err != nil {
+			t.Fatalf("%s failed [%s]: %s", name, codec.Name(), err)
+		}
+		encoded, ok := gbo.GboGetAttrUnsafe(FieldData, nil).([]byte)
+		if !ok || !IsEncodedData(encoded) || bytes.Contains(encoded, []byte("s3cr3t")) {
+			t.Fatalf("%s failed [%s]: unexpected encoded data %#v", name, codec.Name(), encoded)
+		}
+		if email := gbo.GboGetAttrUnsafe("email", nil); !IsEncryptedValue(email) {
+			t.Fatalf("%s failed [%s]: extra attribute should be encrypted: %#v", name, codec.Name(), email)
+		}
+
+		// binary-encoded data is decoded regardless of the codec currently configured
+		bo, err := boCodec{fieldEncryptor: fe}.toUniversalBo(gbo, opts...)
+		if err != nil || bo == nil {
+			t.Fatalf("%s failed [%s]: %#v / %s", name, codec.Name(), bo, err)
+		}
+		if bo.IsChecksumMismatched() || bo.GetChecksum() != ubo.GetChecksum() || bo.GetDataJson() != ubo.GetDataJson() {
+			t.Fatalf("%s failed [%s]: expected %#v but received %#v", name, codec.Name(), ubo.GetDataJson(), bo.GetDataJson())
+		}
+		if v := bo.GetDataAttrUnsafe("count"); v != int64(12345) {
+			t.Fatalf("%s failed [%s]: integer type should be preserved, received %#v", name, codec.Name(), v)
+		}
+		if bo.GetDataAttrUnsafe("secret") != "s3cr3t" || bo.GetExtraAttr("email") != "user@domain.com" {
+			t.Fatalf("%s failed [%s]: attributes should be decrypted", name, codec.Name())
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package henge
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/btnguyen2k/consu/reddo"
+	"github.com/btnguyen2k/godal"
+	"github.com/klauspost/compress/zstd"
+)
+
+// CompressionAlgorithm specifies the algorithm used to compress BO's data.
+//
+// Available since v0.7.0
+type CompressionAlgorithm string
+
+const (
+	// CompressionGzip compresses BO's data with gzip.
+	CompressionGzip CompressionAlgorithm = "gzip"
+
+	// CompressionZstd compresses BO's data with Zstandard, which is generally faster and produces smaller output than gzip.
+	CompressionZstd CompressionAlgorithm = "zstd"
+
+	// DefaultCompressionThreshold is the default minimum size (in bytes) of BO's JSON-encoded data to be compressed.
+	DefaultCompressionThreshold = 4096
+)
+
+const compressedDataPrefix = "$z:"
+
+var (
+	zstdEncoder, zstdDecoder = func() (*zstd.Encoder, *zstd.Decoder) {
+		enc, _ := zstd.NewWriter(nil)
+		dec, _ := zstd.NewReader(nil)
+		return enc, dec
+	}()
+	gzipWriterPool = sync.Pool{New: func() interface{} { return gzip.NewWriter(nil) }}
+)
+
+// DataCompressor compresses BO's JSON-encoded data before being written to storage.
+//
+// Compressed data is stored as a string in the format "$z:<algorithm>:<base64(compressed-data)>". Such marker never
+// collides with a valid JSON document, hence UniversalDao implementations decompress data transparently when reading,
+// regardless of the DataCompressor currently configured.
+//
+// Available since v0.7.0
+type DataCompressor struct {
+	algorithm CompressionAlgorithm
+	threshold int
+}
+
+// NewDataCompressor creates a new DataCompressor instance.
+//   - Data whose JSON-encoded form is smaller than threshold bytes is left untouched
+//     (DefaultCompressionThreshold is used if threshold <= 0).
+//   - Data is also left untouched if compression does not make it smaller.
+//
+// Available since v0.7.0
+func NewDataCompressor(algorithm CompressionAlgorithm, threshold int) (*DataCompressor, error) {
+	if algorithm != CompressionGzip && algorithm != CompressionZstd {
+		return nil, fmt.Errorf("unsupported compression algorithm [%s]", algorithm)
+	}
+	if threshold <= 0 {
+		threshold = DefaultCompressionThreshold
+	}
+	return &DataCompressor{algorithm: algorithm, threshold: threshold}, nil
+}
+
+// GetAlgorithm returns the compression algorithm.
+func (dc *DataCompressor) GetAlgorithm() CompressionAlgorithm {
+	return dc.algorithm
+}
+
+// GetThreshold returns the minimum size (in bytes) of JSON-encoded data to be compressed.
+func (dc *DataCompressor) GetThreshold() int {
+	return dc.threshold
+}
+
+// Compress compresses the JSON-encoded data if it is large enough, otherwise returns it as-is.
+//
+// Available since v0.7.0
+func (dc *DataCompressor) Compress(dataJson string) (string, error) {
+	if len(dataJson) < dc.threshold || IsCompressedData(dataJson) {
+		return dataJson, nil
+	}
+	var compressed []byte
+	switch dc.algorithm {
+	case CompressionZstd:
+		compressed = zstdEncoder.EncodeAll([]byte(dataJson), nil)
+	default:
+		var buf bytes.Buffer
+		w := gzipWriterPool.Get().(*gzip.Writer)
+		defer gzipWriterPool.Put(w)
+		w.Reset(&buf)
+		if _, err := w.Write([]byte(dataJson)); err != nil {
+			return "", err
+		}
+		if err := w.Close(); err != nil {
+			return "", err
+		}
+		compressed = buf.Bytes()
+	}
+	result := compressedDataPrefix + string(dc.algorithm) + ":" + base64.StdEncoding.EncodeToString(compressed)
+	if len(result) >= len(dataJson) {
+		return dataJson, nil
+	}
+	return result, nil
+}
+
+// IsCompressedData returns true if the data is in the compressed format produced by DataCompressor.
+//
+// Available since v0.7.0
+func IsCompressedData(data string) bool {
+	return strings.HasPrefix(data, compressedDataPrefix)
+}
+
+// DecompressData decompresses data compressed by DataCompressor. Uncompressed data is returned as-is.
+//
+// Available since v0.7.0
+func DecompressData(data string) (string, error) {
+	if !IsCompressedData(data) {
+		return data, nil
+	}
+	tokens := strings.SplitN(data[len(compressedDataPrefix):], ":", 2)
+	if len(tokens) != 2 {
+		return "", fmt.Errorf("invalid compressed data format")
+	}
+	compressed, err := base64.StdEncoding.DecodeString(tokens[1])
+	if err != nil {
+		return "", err
+	}
+	var result []byte
+	switch CompressionAlgorithm(tokens[0]) {
+	case CompressionGzip:
+		r, err := gzip.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			return "", err
+		}
+		if result, err = io.ReadAll(r); err != nil {
+			return "", err
+		}
+	case CompressionZstd:
+		if result, err = zstdDecoder.DecodeAll(compressed, nil); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("unsupported compression algorithm [%s]", tokens[0])
+	}
+	return string(result), nil
+}
+
+// compressGbo compresses gbo's data in place.
+func (dc *DataCompressor) compressGbo(gbo godal.IGenericBo) error {
+	if dc == nil || gbo == nil {
+		return nil
+	}
+	dataJson, _ := gbo.GboGetAttrUnsafe(FieldData, reddo.TypeString).(string)
+	compressed, err := dc.Compress(dataJson)
+	if err == nil && compressed != dataJson {
+		gbo.GboSetAttr(FieldData, compressed)
+	}
+	return err
+}
+
+// toRowData returns value of gbo's FieldData to be stored in document-based storages: JSON-encoded data is stored as a
+// document, compressed data and binary-encoded data (see DataCodec) are stored as-is.
+func toRowData(gbo godal.IGenericBo) interface{} {
+	switch data := gbo.GboGetAttrUnsafe(FieldData, nil).(type) {
+	case string:
+		if IsCompressedData(data) {
+			return data
+		}
+	case []byte:
+		return data
+	}
+	v, _ := gbo.GboGetAttrUnmarshalJson(FieldData)
+	return v
+}
+
+// decompressGbo decompresses gbo's data in place, if compressed.
+func decompressGbo(gbo godal.IGenericBo) error {
+	if gbo == nil {
+		return nil
+	}
+	data, _ := gbo.GboGetAttrUnsafe(FieldData, reddo.TypeString).(string)
+	if !IsCompressedData(data) {
+		return nil
+	}
+	dataJson, err := DecompressData(data)
+	if err == nil {
+		gbo.GboSetAttr(FieldData, dataJson)
+	}
+	return err
+}
@@ -0,0 +1,86 @@
+package henge
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/btnguyen2k/consu/reddo"
+)
+
+func TestNewDataCompressor(t *testing.T) {
+	name := "TestNewDataCompressor"
+	if dc, err := NewDataCompressor(CompressionGzip, 0); err != nil || dc.GetThreshold() != DefaultCompressionThreshold || dc.GetAlgorithm() != CompressionGzip {
+		t.Fatalf("%s failed: %#v / %s", name, dc, err)
+	}
+	if _, err := NewDataCompressor("lz4", 0); err == nil {
+		t.Fatalf("%s failed: expected error for unsupported algorithm", name)
+	}
+}
+
+func TestDataCompressor_Compress(t *testing.T) {
+	name := "TestDataCompressor_Compress"
+	small := `{"name":"henge"}`
+	large := `{"items":[` + strings.Repeat(`{"name":"henge","value":12345},`, 200) + `{}]}`
+	for _, alg := range []CompressionAlgorithm{CompressionGzip, CompressionZstd} {
+		dc, _ := NewDataCompressor(alg, 1024)
+		if result, err := dc.Compress(small); err != nil || result != small {
+			t.Fatalf("%s failed: small data should be left untouched: %#v / %s", name, result, err)
+		}
+		compressed, err := dc.Compress(large)
+		if err != nil || !IsCompressedData(compressed) || !strings.HasPrefix(compressed, "$z:"+string(alg)+":") || len(compressed) >= len(large) {
+			t.Fatalf("%s failed: %#v / %s", name, compressed, err)
+		}
+		if again, _ := dc.Compress(compressed); again != compressed {
+			t.Fatalf("%s failed: compressed data should not be compressed again", name)
+		}
+		if decompressed, err := DecompressData(compressed); err != nil || decompressed != large {
+			t.Fatalf("%s failed: %#v / %s", name, decompressed, err)
+		}
+	}
+
+	// incompressible data is left untouched
+	dc, _ := NewDataCompressor(CompressionGzip, 1)
+	if result, _ := dc.Compress(`"x"`); result != `"x"` {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, `"x"`, result)
+	}
+	if result, err := DecompressData(small); err != nil || result != small {
+		t.Fatalf("%s failed: %#v / %s", name, result, err)
+	}
+	for _, invalid := range []string{"$z:gzip", "$z:lz4:AAAA", "$z:gzip:!!", "$z:zstd:AAAA"} {
+		if _, err := DecompressData(invalid); err == nil {
+			t.Fatalf("%s failed: expected error for %#v", name, invalid)
+		}
+	}
+}
+
+func TestDataCompressor_Gbo(t *testing.T) {
+	name := "TestDataCompressor_Gbo"
+	dc, _ := NewDataCompressor(CompressionZstd, 100)
+	ubo := NewUniversalBo("id", 1)
+	ubo.SetDataAttr("text", strings.Repeat("henge ", 100))
+	gbo := ubo.ToGenericBo()
+	if err := dc.compressGbo(gbo); err != nil || !IsCompressedData(gbo.GboGetAttrUnsafe(FieldData, reddo.TypeString).(string)) {
+		t.Fatalf("%s failed: data should be compressed (error: %s)", name, err)
+	}
+	if err := decompressGbo(gbo); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if loaded := NewUniversalBoFromGbo(gbo); !ContentEqual(ubo, loaded) || loaded.IsChecksumMismatched() {
+		t.Fatalf("%s failed: %#v", name, Diff(ubo, loaded))
+	}
+}
+
+func TestToRowData(t *testing.T) {
+	name := "TestToRowData"
+	dc, _ := NewDataCompressor(CompressionGzip, 100)
+	ubo := NewUniversalBo("id", 1)
+	ubo.SetDataAttr("text", strings.Repeat("henge ", 100))
+	gbo := ubo.ToGenericBo()
+	if data, ok := toRowData(gbo).(map[string]interface{}); !ok || data["text"] != ubo.GetDataAttrUnsafe("text") {
+		t.Fatalf("%s failed: JSON-encoded data should be stored as document: %#v", name, data)
+	}
+	dc.compressGbo(gbo)
+	if data, ok := toRowData(gbo).(string); !ok || !IsCompressedData(data) {
+		t.Fatalf("%s failed: compressed data should be stored as-is: %#v", name, data)
+	}
+}
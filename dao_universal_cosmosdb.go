@@ -1,7 +1,7 @@
 package henge
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
@@ -83,7 +83,7 @@ func (r *rowMapperCosmosdb) ToRow(storageId string, bo godal.IGenericBo) (interf
 		m[FieldTagVersion], _ = bo.GboGetAttr(FieldTagVersion, nil) // tag-version should be integer
 		m[FieldTimeCreated], _ = bo.GboGetTimeWithLayout(FieldTimeCreated, time.RFC3339)
 		m[FieldTimeUpdated], _ = bo.GboGetTimeWithLayout(FieldTimeUpdated, time.RFC3339)
-		m[FieldData], _ = bo.GboGetAttrUnmarshalJson(FieldData) // Note: FieldData must be JSON-encoded string!
+		m[FieldData] = toRowData(bo) // Note: FieldData must be JSON-encoded string (or compressed data since v0.7.0)!
 	}
 	return row, err
 }
@@ -104,7 +104,7 @@ func (r *rowMapperCosmosdb) ToBo(storageId string, row interface{}) (godal.IGene
 			} else if bytes, ok := data.([]byte); ok {
 				gbo.GboSetAttr(FieldData, string(bytes))
 			} else {
-				js, _ := json.Marshal(data)
+				js, _ := jsonCodecOrDefault(nil).Marshal(data)
 				gbo.GboSetAttr(FieldData, string(js))
 			}
 		}
@@ -134,6 +134,8 @@ type CosmosdbDaoSpec struct {
 // NewUniversalDaoCosmosdbSql is helper method to create UniversalDaoSql instance specific for Azure Cosmos DB.
 //   - txModeOnWrite: added for compatibility,not used.
 //
+// (since v0.7.0) The DAO accepts ids of at most MaxIdLengthCosmosdb characters, see SetMaxIdLength.
+//
 // Available: since v0.3.0
 func NewUniversalDaoCosmosdbSql(sqlc *prom.SqlConnect, tableName string, spec *CosmosdbDaoSpec) UniversalDao {
 	if spec == nil {
@@ -141,7 +143,7 @@ func NewUniversalDaoCosmosdbSql(sqlc *prom.SqlConnect, tableName string, spec *C
 	}
 	spec = &(*spec)
 	dao := &UniversalDaoCosmosdbSql{
-		UniversalDaoSql: &UniversalDaoSql{tableName: tableName},
+		UniversalDaoSql: &UniversalDaoSql{tableName: tableName, maxIdLength: MaxIdLengthCosmosdb},
 		pkName:          spec.PkName,
 		pkValue:         spec.PkValue,
 	}
@@ -181,6 +183,8 @@ func cosmosdbFilterGeneratorSql(_ string, input interface{}) godal.FilterOpt {
 
 // UniversalDaoCosmosdbSql is CosmosDB-based (using driver/sql interface) implementation of UniversalDao.
 //
+// Note: CosmosDB does not support binary values, hence binary data codecs (see SetDataCodec) must not be used.
+//
 // Available: since v0.3.2
 type UniversalDaoCosmosdbSql struct {
 	*UniversalDaoSql
@@ -200,31 +204,84 @@ func (dao *UniversalDaoCosmosdbSql) GetPkValue() string {
 var cosmosdbFields = []string{"_attachments", "_etag", "_rid", "_self", "_ts"}
 
 // ToUniversalBo transforms godal.IGenericBo to business object.
+//
+// (since v0.7.0) nil is returned if data cannot be decompressed or the encrypted attributes cannot be decrypted.
 func (dao *UniversalDaoCosmosdbSql) ToUniversalBo(gbo godal.IGenericBo) *UniversalBo {
+	bo, _ := dao.toUniversalBo(gbo)
+	return bo
+}
+
+// toUniversalBo transforms godal.IGenericBo to business object, decompressing data and decrypting the encrypted attributes.
+func (dao *UniversalDaoCosmosdbSql) toUniversalBo(gbo godal.IGenericBo) (*UniversalBo, error) {
 	if gbo != nil {
 		// remove CosmosDB's specific fields
 		for _, field := range cosmosdbFields {
 			gbo.GboSetAttr(field, nil)
 		}
 	}
-	return dao.UniversalDaoSql.ToUniversalBo(gbo)
+	return dao.UniversalDaoSql.toUniversalBo(gbo)
 }
 
 // Get implements UniversalDao.Get.
 func (dao *UniversalDaoCosmosdbSql) Get(id string) (*UniversalBo, error) {
+	return dao.GetContext(context.Background(), id)
+}
+
+// GetContext implements UniversalDaoContext.GetContext.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoCosmosdbSql) GetContext(ctx context.Context, id string) (*UniversalBo, error) {
+	bo, err := dao.get(ctx, id, dao.strictChecksum)
+	if err != nil {
+		return nil, err
+	}
+	if err := dao.afterLoad(ctx, bo); err != nil {
+		return nil, err
+	}
+	return bo, nil
+}
+
+// get loads a BO, returning ErrChecksumMismatch if strict is true and the BO's stored checksum does not match its content.
+func (dao *UniversalDaoCosmosdbSql) get(ctx context.Context, id string, strict bool) (*UniversalBo, error) {
 	filter := map[string]interface{}{CosmosdbColId: id}
 	if dao.pkName != "" && dao.pkValue != "" {
 		filter[dao.pkName] = dao.pkValue
 	}
-	gbo, err := dao.GdaoFetchOne(dao.tableName, godal.MakeFilter(filter))
+	gbo, err := dao.GdaoFetchOneWithTx(ctx, nil, dao.tableName, godal.MakeFilter(filter))
+	if err != nil {
+		return nil, err
+	}
+	bo, err := dao.toUniversalBo(gbo)
 	if err != nil {
 		return nil, err
 	}
-	return dao.ToUniversalBo(gbo), nil
+	if err := checkLoadedChecksum(bo, strict); err != nil {
+		return nil, err
+	}
+	return bo, nil
 }
 
 // GetN implements UniversalDao.GetN.
 func (dao *UniversalDaoCosmosdbSql) GetN(fromOffset, maxNumRows int, filter godal.FilterOpt, sorting *godal.SortingOpt) ([]*UniversalBo, error) {
+	return dao.GetNContext(context.Background(), fromOffset, maxNumRows, filter, sorting)
+}
+
+// GetNContext implements UniversalDaoContext.GetNContext.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoCosmosdbSql) GetNContext(ctx context.Context, fromOffset, maxNumRows int, filter godal.FilterOpt, sorting *godal.SortingOpt) ([]*UniversalBo, error) {
+	result, err := dao.getN(ctx, fromOffset, maxNumRows, filter, sorting, dao.strictChecksum)
+	if err != nil {
+		return nil, err
+	}
+	if err := dao.afterLoad(ctx, result...); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// getN loads BOs, returning ErrChecksumMismatch if strict is true and a loaded BO's stored checksum does not match its content.
+func (dao *UniversalDaoCosmosdbSql) getN(ctx context.Context, fromOffset, maxNumRows int, filter godal.FilterOpt, sorting *godal.SortingOpt, strict bool) ([]*UniversalBo, error) {
 	if sorting == nil {
 		sorting = dao.defaultSorting
 	}
@@ -237,13 +294,19 @@ func (dao *UniversalDaoCosmosdbSql) GetN(fromOffset, maxNumRows int, filter goda
 		tempFilter.Add(&godal.FilterOptFieldOpValue{FieldName: dao.pkName, Operator: godal.FilterOpEqual, Value: dao.pkValue})
 		filter = tempFilter
 	}
-	gboList, err := dao.GdaoFetchMany(dao.tableName, filter, sorting, fromOffset, maxNumRows)
+	gboList, err := dao.GdaoFetchManyWithTx(ctx, nil, dao.tableName, filter, sorting, fromOffset, maxNumRows)
 	if err != nil {
 		return nil, err
 	}
 	result := make([]*UniversalBo, 0)
 	for _, gbo := range gboList {
-		bo := dao.ToUniversalBo(gbo)
+		bo, err := dao.toUniversalBo(gbo)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkLoadedChecksum(bo, strict); err != nil {
+			return nil, err
+		}
 		result = append(result, bo)
 	}
 	return result, nil
@@ -251,15 +314,106 @@ func (dao *UniversalDaoCosmosdbSql) GetN(fromOffset, maxNumRows int, filter goda
 
 // GetAll implements UniversalDao.GetAll.
 func (dao *UniversalDaoCosmosdbSql) GetAll(filter godal.FilterOpt, sorting *godal.SortingOpt) ([]*UniversalBo, error) {
-	return dao.GetN(0, 0, filter, sorting)
+	return dao.GetAllContext(context.Background(), filter, sorting)
+}
+
+// GetAllContext implements UniversalDaoContext.GetAllContext.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoCosmosdbSql) GetAllContext(ctx context.Context, filter godal.FilterOpt, sorting *godal.SortingOpt) ([]*UniversalBo, error) {
+	return dao.GetNContext(ctx, 0, 0, filter, sorting)
+}
+
+// SetHistoryTable returns ErrHistoryUnsupported if tableName is not empty: Cosmos DB does not support transactions via
+// the SQL API, hence prior versions of BOs cannot be archived atomically with writes.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoCosmosdbSql) SetHistoryTable(tableName string) error {
+	if tableName != "" {
+		return ErrHistoryUnsupported
+	}
+	dao.UniversalDaoSql.SetHistoryTable(tableName)
+	return nil
+}
+
+// Update implements UniversalDao.Update.
+//
+// (since v0.7.0) If "skip unchanged writes" is enabled and the stored record has the same checksum as bo,
+// this function returns (false, nil) without writing, see UniversalBo.WasUnchanged. CosmosDB does not support
+// conditional updates via the SQL API, hence the stored record is read and compared before writing.
+func (dao *UniversalDaoCosmosdbSql) Update(bo *UniversalBo) (bool, error) {
+	return dao.UpdateContext(context.Background(), bo)
+}
+
+// UpdateContext implements UniversalDaoContext.UpdateContext.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoCosmosdbSql) UpdateContext(ctx context.Context, bo *UniversalBo) (bool, error) {
+	if err := dao.run(ctx, HookBeforeUpdate, bo); err != nil {
+		return false, err
+	}
+	stampAudit(bo, dao.principalFunc.principal(ctx), false)
+	if err := dao.dataSchemas.validate(bo, false); err != nil {
+		return false, err
+	}
+	dao.boCodec().prepareWrite(bo)
+	gbo, err := dao.toGenericBo(bo)
+	if err != nil {
+		return false, err
+	}
+	if dao.skipUnchangedWrites {
+		existing, err := dao.get(ctx, bo.GetId(), false)
+		if err != nil {
+			return false, err
+		}
+		if isUnchanged(dao.ToGenericBo(existing), gbo) && isUnchangedContent(existing, bo) {
+			return unchangedWrite(bo)
+		}
+	}
+	numRows, err := dao.GdaoUpdateWithTx(ctx, nil, dao.tableName, gbo)
+	ok, err := afterWrite(bo, numRows > 0, err)
+	return dao.after(ctx, HookAfterUpdate, bo, ok, err)
 }
 
 // Save implements UniversalDao.Save.
+//
+// (since v0.7.0) A BO with a blank id is assigned an id generated by the DAO's IdGenerator, see SetIdGenerator.
+//
+// (since v0.7.0) If "skip unchanged writes" is enabled and the stored record has the same checksum as bo,
+// this function returns (false, existing, nil) without writing, see UniversalBo.WasUnchanged.
 func (dao *UniversalDaoCosmosdbSql) Save(bo *UniversalBo) (bool, *UniversalBo, error) {
-	existing, err := dao.Get(bo.GetId())
+	return dao.SaveContext(context.Background(), bo)
+}
+
+// SaveContext implements UniversalDaoContext.SaveContext.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoCosmosdbSql) SaveContext(ctx context.Context, bo *UniversalBo) (bool, *UniversalBo, error) {
+	if err := prepareBoId(bo, dao.idGenerator, dao.maxIdLength, false); err != nil {
+		return false, nil, err
+	}
+	if err := dao.run(ctx, HookBeforeSave, bo); err != nil {
+		return false, nil, err
+	}
+	existing, err := dao.get(ctx, bo.GetId(), false)
 	if err != nil {
 		return false, nil, err
 	}
-	numRows, err := dao.GdaoSave(dao.tableName, dao.ToGenericBo(bo))
-	return numRows > 0, existing, err
+	stampAuditOnSave(bo, existing, dao.principalFunc.principal(ctx))
+	if err := dao.dataSchemas.validate(bo, existing == nil); err != nil {
+		return false, existing, err
+	}
+	dao.boCodec().prepareWrite(bo)
+	gbo, err := dao.toGenericBo(bo)
+	if err != nil {
+		return false, existing, err
+	}
+	if dao.skipUnchangedWrites && isUnchanged(dao.ToGenericBo(existing), gbo) && isUnchangedContent(existing, bo) {
+		ok, err := unchangedWrite(bo)
+		return ok, existing, err
+	}
+	numRows, err := dao.GdaoSaveWithTx(ctx, nil, dao.tableName, gbo)
+	ok, err := afterWrite(bo, numRows > 0, err)
+	ok, err = dao.after(ctx, HookAfterSave, bo, ok, err)
+	return ok, existing, err
 }
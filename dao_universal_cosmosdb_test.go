@@ -1,6 +1,7 @@
 package henge
 
 import (
+	"errors"
 	"fmt"
 	"math/rand"
 	"os"
@@ -947,3 +948,29 @@ func TestUniversalDaoCosmosdbSql_CreateUpdateGet_Checksum(t *testing.T) {
 		}
 	}
 }
+
+func TestUniversalDaoCosmosdbSql_SetHistoryTable(t *testing.T) {
+	testName := "TestUniversalDaoCosmosdbSql_SetHistoryTable"
+	// the connection is not used, the driver connects lazily
+	sqlc, err := NewCosmosdbConnection("AccountEndpoint=https://localhost:8081/;AccountKey=a2V5", "UTC", "gocosmos", 10000, nil)
+	if sqlc == nil {
+		t.Fatalf("%s failed: %s", testName, err)
+	}
+	dao := NewUniversalDaoCosmosdbSql(sqlc, "tbl_test", &CosmosdbDaoSpec{PkName: "pk"}).(*UniversalDaoCosmosdbSql)
+	if err := dao.SetHistoryTable("tbl_history"); !errors.Is(err, ErrHistoryUnsupported) || dao.GetHistoryTable() != "" {
+		t.Fatalf("%s failed: expected %#v but received %#v", testName, ErrHistoryUnsupported, err)
+	}
+	if err := dao.SetHistoryTable(""); err != nil {
+		t.Fatalf("%s failed: %s", testName, err)
+	}
+	var history UniversalDaoHistory = dao
+	if _, err := history.GetHistory("id", 0, 10); !errors.Is(err, ErrHistoryUnsupported) {
+		t.Fatalf("%s failed: expected %#v but received %#v", testName, ErrHistoryUnsupported, err)
+	}
+	if _, err := history.GetVersionAt("id", time.Now()); !errors.Is(err, ErrHistoryUnsupported) {
+		t.Fatalf("%s failed: expected %#v but received %#v", testName, ErrHistoryUnsupported, err)
+	}
+	if _, err := history.RestoreVersion("id", VersionRef{}); !errors.Is(err, ErrHistoryUnsupported) {
+		t.Fatalf("%s failed: expected %#v but received %#v", testName, ErrHistoryUnsupported, err)
+	}
+}
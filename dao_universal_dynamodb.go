@@ -1,7 +1,7 @@
 package henge
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
 	"strings"
@@ -158,7 +158,7 @@ func (r *rowMapperDynamodb) ToRow(tableName string, bo godal.IGenericBo) (interf
 		m[FieldTagVersion], _ = bo.GboGetAttr(FieldTagVersion, nil) // tag-version should be integer
 		m[FieldTimeCreated], _ = bo.GboGetTimeWithLayout(FieldTimeCreated, time.RFC3339)
 		m[FieldTimeUpdated], _ = bo.GboGetTimeWithLayout(FieldTimeUpdated, time.RFC3339)
-		m[FieldData], _ = bo.GboGetAttrUnmarshalJson(FieldData) // Note: FieldData must be JSON-encoded string!
+		m[FieldData] = toRowData(bo) // Note: FieldData must be JSON-encoded string (or compressed data since v0.7.0)!
 	}
 	return row, err
 }
@@ -174,7 +174,7 @@ func (r *rowMapperDynamodb) ToBo(tableName string, row interface{}) (godal.IGene
 			} else if bytes, ok := data.([]byte); ok {
 				gbo.GboSetAttr(FieldData, string(bytes))
 			} else {
-				js, _ := json.Marshal(data)
+				js, _ := jsonCodecOrDefault(nil).Marshal(data)
 				gbo.GboSetAttr(FieldData, string(js))
 			}
 		}
@@ -196,6 +196,9 @@ type DynamodbDaoSpec struct {
 //   - the table has default pk as { FieldId }. If pkPrefix is supplied, table pk becomes { pkPrefix, FieldId }.
 //   - static value for pkPrefix attribute can be specified via pkPrefixValue.
 //   - defaultUboOpts: (since v0.5.7) the default options to be used by the DAO when creating UniversalBo instances.
+//
+// (since v0.7.0) The DAO accepts ids of at most MaxIdLengthDynamodb bytes (MaxIdLengthDynamodbSortKey if pkPrefix is
+// supplied), see SetMaxIdLength.
 func NewUniversalDaoDynamodb(adc *prom.AwsDynamodbConnect, tableName string, spec *DynamodbDaoSpec, defaultUboOpts ...UboOpt) *UniversalDaoDynamodb {
 	if spec == nil {
 		spec = &DynamodbDaoSpec{}
@@ -210,6 +213,10 @@ func NewUniversalDaoDynamodb(adc *prom.AwsDynamodbConnect, tableName string, spe
 		uidxHf2:        checksum.Md5HashFunc,
 		gsiSortMapping: make(map[string]string),
 		defaultUboOpts: defaultUboOpts,
+		maxIdLength:    MaxIdLengthDynamodb,
+	}
+	if spec.PkPrefix != "" {
+		dao.maxIdLength = MaxIdLengthDynamodbSortKey
 	}
 	dao.GenericDaoDynamodb = dynamodb.NewGenericDaoDynamodb(adc, godal.NewAbstractGenericDao(dao))
 	dao.SetRowMapper(buildRowMapperDynamodb(tableName, spec.PkPrefix))
@@ -220,6 +227,7 @@ func NewUniversalDaoDynamodb(adc *prom.AwsDynamodbConnect, tableName string, spe
 // UniversalDaoDynamodb is AWS DynamoDB-based implementation of UniversalDao.
 type UniversalDaoDynamodb struct {
 	*dynamodb.GenericDaoDynamodb
+	DaoHooks                           // (since v0.7.0) lifecycle hooks, see DaoHooks
 	tableName        string            // name of database table to store business objects
 	pkPrefix         string            // (since v0.3.2) if pkPrefix is supplied, table has PK as { pkPrefix, FieldId }; otherwise { FieldId }
 	pkPrefixValue    string            // (since v0.3.2) static value for pkPrefix attribute
@@ -228,6 +236,21 @@ type UniversalDaoDynamodb struct {
 	uidxHf1, uidxHf2 checksum.HashFunc // hash functions used to calculate unique index hash
 	gsiSortMapping   map[string]string // (since v0.5.2) mapping {fieldName->gsiName}, used to lookup GSI if sorting is specified
 	defaultUboOpts   []UboOpt          // (since v0.5.7) default options used by the DAO to create UniversalBo instances
+
+	skipUnchangedWrites bool               // (since v0.7.0) if true, Update/Save do not write BOs whose checksum matches the stored one
+	strictChecksum      bool               // (since v0.7.0) if true, loading BOs whose stored checksum mismatches results in ErrChecksumMismatch
+	dataSchemas         dataSchemaRegistry // (since v0.7.0) schemas used to validate BO's data on write
+	fieldEncryptor      *FieldEncryptor    // (since v0.7.0) encrypts/decrypts sensitive attributes
+	dataCompressor      *DataCompressor    // (since v0.7.0) compresses large BO data
+	dataCodec           DataCodec          // (since v0.7.0) encodes BO data in binary form
+	clock               Clock              // (since v0.7.0) source of the current time for BO's timestamps, nil means SystemClock
+	csumAlgorithm       ChecksumAlgorithm  // (since v0.7.0) algorithm used to calculate checksums of loaded BOs, empty means UboOpt.ChecksumAlgorithm
+	csumCoverage        *ChecksumCoverage  // (since v0.7.0) parts of data and extra attributes covered by BO's checksum, nil means all
+	jsonCodec           JsonCodec          // (since v0.7.0) codec used to parse and encode BO data, nil means DefaultJsonCodec
+	idGenerator         IdGenerator        // (since v0.7.0) generates ids for BOs created with a blank id
+	maxIdLength         int                // (since v0.7.0) maximum length of BO's id, 0 means no limit
+	principalFunc       PrincipalFunc      // (since v0.7.0) extracts the principal filling in BO's audit fields
+	historyTable        string             // (since v0.7.0) table storing prior versions of BOs, empty means history mode is disabled
 }
 
 // Init should be called to initialize the DAO instance before use.
@@ -259,6 +282,244 @@ func (dao *UniversalDaoDynamodb) SetDefaultUboOpts(uboOpts []UboOpt) *UniversalD
 	return dao
 }
 
+// GetSkipUnchangedWrites returns true if Update/Save skip writing BOs whose checksum matches the stored one.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) GetSkipUnchangedWrites() bool {
+	return dao.skipUnchangedWrites
+}
+
+// SetSkipUnchangedWrites enables/disables skipping no-op writes.
+//
+// If enabled, Update/Save compare the BO's checksum with the stored one and return false without writing if they
+// match (see UniversalBo.WasUnchanged). Update uses the condition expression "csum <> :v" where possible, saving
+// write capacity units.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) SetSkipUnchangedWrites(value bool) *UniversalDaoDynamodb {
+	dao.skipUnchangedWrites = value
+	return dao
+}
+
+// GetStrictChecksum returns true if the DAO is in strict-load mode.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) GetStrictChecksum() bool {
+	return dao.strictChecksum
+}
+
+// SetStrictChecksum enables/disables strict-load mode.
+//
+// In strict-load mode, Get/GetN/GetAll return ErrChecksumMismatch if a loaded BO's stored checksum does not match its
+// content. Otherwise, mismatching BOs are loaded and flagged (see UniversalBo.IsChecksumMismatched).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) SetStrictChecksum(value bool) *UniversalDaoDynamodb {
+	dao.strictChecksum = value
+	return dao
+}
+
+// GetDataSchema returns the DataSchema used to validate data of BOs with the specified tag-version (nil if none).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) GetDataSchema(tagVersion uint64) *DataSchema {
+	return dao.dataSchemas.lookup(tagVersion)
+}
+
+// SetDataSchema sets the default DataSchema, used to validate data of BOs whose tag-version has no specific schema
+// (see SetDataSchemaForTagVersion). nil disables the default validation.
+//
+// If a schema is configured, Create/Update/Save validate BO's data against it and return a *DataValidationError if
+// the data does not conform. Default values declared in the schema are filled in when a new BO is written.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) SetDataSchema(schema *DataSchema) *UniversalDaoDynamodb {
+	dao.dataSchemas.defaultSchema = schema
+	return dao
+}
+
+// SetDataSchemaForTagVersion sets the DataSchema used to validate data of BOs with the specified tag-version.
+// nil removes the tag-version specific schema.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) SetDataSchemaForTagVersion(tagVersion uint64, schema *DataSchema) *UniversalDaoDynamodb {
+	dao.dataSchemas.setForTagVersion(tagVersion, schema)
+	return dao
+}
+
+// GetFieldEncryptor returns the FieldEncryptor used to encrypt/decrypt sensitive attributes (nil if none).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) GetFieldEncryptor() *FieldEncryptor {
+	return dao.fieldEncryptor
+}
+
+// SetFieldEncryptor sets the FieldEncryptor used to encrypt sensitive attributes in ToGenericBo and decrypt them in
+// ToUniversalBo. BO's checksum is calculated over plaintext values.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) SetFieldEncryptor(fe *FieldEncryptor) *UniversalDaoDynamodb {
+	dao.fieldEncryptor = fe
+	return dao
+}
+
+// GetDataCompressor returns the DataCompressor used to compress BO's data (nil if none).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) GetDataCompressor() *DataCompressor {
+	return dao.dataCompressor
+}
+
+// SetDataCompressor sets the DataCompressor used to compress large BO's data in ToGenericBo. nil disables compression.
+//
+// Compressed data is always decompressed transparently in ToUniversalBo, even if compression has been disabled since.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) SetDataCompressor(dc *DataCompressor) *UniversalDaoDynamodb {
+	dao.dataCompressor = dc
+	return dao
+}
+
+// GetDataCodec returns the DataCodec used to encode BO's data (nil means the default JSON format).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) GetDataCodec() DataCodec {
+	return dao.dataCodec
+}
+
+// SetDataCodec sets the DataCodec used to encode BO's data in ToGenericBo. nil or DataCodecJson means the default JSON format.
+//
+// Data encoded with a binary codec (e.g. DataCodecMsgpack or DataCodecCbor) is stored as []byte, hence the storage
+// must support binary values for the data field (e.g. BYTEA/BLOB column or DynamoDB's B type). Binary-encoded data is
+// neither compressed nor searchable by the storage. Data is always decoded transparently in ToUniversalBo, regardless
+// of the codec currently configured.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) SetDataCodec(codec DataCodec) *UniversalDaoDynamodb {
+	dao.dataCodec = codec
+	return dao
+}
+
+// GetClock returns the Clock used by the DAO for BO's timestamps (nil means SystemClock).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) GetClock() Clock {
+	return dao.clock
+}
+
+// SetClock sets the Clock used by the DAO for BO's timestamps: BOs loaded by the DAO use it (taking precedence over
+// UboOpt.Clock of the default UboOpts), and BOs written by the DAO that have no Clock of their own have their
+// last-updated timestamp bumped with it when their data changes.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) SetClock(clock Clock) *UniversalDaoDynamodb {
+	dao.clock = clock
+	return dao
+}
+
+// GetChecksumAlgorithm returns the ChecksumAlgorithm used by the DAO (empty means the one of the default UboOpts).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) GetChecksumAlgorithm() ChecksumAlgorithm {
+	return dao.csumAlgorithm
+}
+
+// SetChecksumAlgorithm sets the ChecksumAlgorithm used by the DAO: BOs loaded by the DAO use it, taking precedence over
+// UboOpt.ChecksumAlgorithm of the default UboOpts (see RecomputeChecksums to migrate stored checksums). An empty value
+// reverts to the default UboOpts.
+//
+// This function returns ErrUnsupportedChecksumAlgorithm, leaving the DAO unchanged, if alg is not supported.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) SetChecksumAlgorithm(alg ChecksumAlgorithm) error {
+	if alg != "" && !alg.IsSupported() {
+		return ErrUnsupportedChecksumAlgorithm
+	}
+	dao.csumAlgorithm = alg
+	return nil
+}
+
+// GetChecksumCoverage returns the ChecksumCoverage used by the DAO (nil means all data and extra attributes are covered).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) GetChecksumCoverage() *ChecksumCoverage {
+	return dao.csumCoverage
+}
+
+// SetChecksumCoverage sets the ChecksumCoverage used by the DAO: BOs loaded by the DAO use it (taking precedence over
+// UboOpt.ChecksumCoverage of the default UboOpts), and BOs written by the DAO that have no ChecksumCoverage of their own
+// adopt it.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) SetChecksumCoverage(cc *ChecksumCoverage) *UniversalDaoDynamodb {
+	dao.csumCoverage = cc
+	return dao
+}
+
+// GetJsonCodec returns the JsonCodec used by the DAO (nil means DefaultJsonCodec).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) GetJsonCodec() JsonCodec {
+	return dao.jsonCodec
+}
+
+// SetJsonCodec sets the JsonCodec used by the DAO to parse and encode data of the BOs it loads (taking precedence over
+// UboOpt.JsonCodec of the default UboOpts).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) SetJsonCodec(codec JsonCodec) *UniversalDaoDynamodb {
+	dao.jsonCodec = codec
+	return dao
+}
+
+// GetIdGenerator returns the IdGenerator used by the DAO (nil means none).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) GetIdGenerator() IdGenerator {
+	return dao.idGenerator
+}
+
+// SetIdGenerator sets the IdGenerator used by the DAO: Create and Save assign a generated id to BOs having a blank id.
+// Without IdGenerator, writing a BO with a blank id results in ErrEmptyId.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) SetIdGenerator(gen IdGenerator) *UniversalDaoDynamodb {
+	dao.idGenerator = gen
+	return dao
+}
+
+// GetMaxIdLength returns the maximum length (in bytes) of BO's id accepted by the DAO (0 means no limit).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) GetMaxIdLength() int {
+	return dao.maxIdLength
+}
+
+// SetMaxIdLength sets the maximum length (in bytes) of BO's id accepted by the DAO (0 means no limit): Create and
+// Save return ErrIdTooLong for BOs whose id is longer.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) SetMaxIdLength(value int) *UniversalDaoDynamodb {
+	dao.maxIdLength = value
+	return dao
+}
+
+// GetPrincipalFunc returns the PrincipalFunc used by the DAO to fill in BO's audit fields (nil means none).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) GetPrincipalFunc() PrincipalFunc {
+	return dao.principalFunc
+}
+
+// SetPrincipalFunc sets the PrincipalFunc used by the DAO to fill in BO's audit fields on writes. Audit fields are
+// stored as attributes of items, only when not empty.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) SetPrincipalFunc(fn PrincipalFunc) *UniversalDaoDynamodb {
+	dao.principalFunc = fn
+	return dao
+}
+
 // MapGsi associates a list of table fields (in order) with a GSI. The mappings are to be used for sorting.
 //
 // See function GetN for more information.
@@ -381,24 +642,60 @@ func (dao *UniversalDaoDynamodb) GdaoCreateFilter(_ string, bo godal.IGenericBo)
 }
 
 // ToUniversalBo transforms godal.IGenericBo to business object.
+//
+// (since v0.7.0) nil is returned if data cannot be decoded/decompressed or the encrypted attributes cannot be decrypted.
 func (dao *UniversalDaoDynamodb) ToUniversalBo(gbo godal.IGenericBo) *UniversalBo {
-	return NewUniversalBoFromGbo(gbo, dao.defaultUboOpts...)
+	bo, _ := dao.toUniversalBo(gbo)
+	return bo
+}
+
+// toUniversalBo transforms godal.IGenericBo to business object, decoding/decompressing data and decrypting the encrypted attributes.
+func (dao *UniversalDaoDynamodb) toUniversalBo(gbo godal.IGenericBo) (*UniversalBo, error) {
+	return dao.boCodec().toUniversalBo(gbo, dao.defaultUboOpts...)
 }
 
 // ToGenericBo transforms business object to godal.IGenericBo.
+//
+// (since v0.7.0) nil is returned if the attributes to be encrypted cannot be encrypted or data cannot be encoded/compressed.
 func (dao *UniversalDaoDynamodb) ToGenericBo(ubo *UniversalBo) godal.IGenericBo {
-	if ubo == nil {
-		return nil
-	}
-	return ubo.ToGenericBo()
+	gbo, _ := dao.toGenericBo(ubo)
+	return gbo
+}
+
+// toGenericBo transforms business object to godal.IGenericBo, encrypting the attributes to be encrypted and encoding/compressing data.
+func (dao *UniversalDaoDynamodb) toGenericBo(ubo *UniversalBo) (godal.IGenericBo, error) {
+	return dao.boCodec().toGenericBo(ubo)
+}
+
+// boCodec returns the settings used to convert BOs to/from godal.IGenericBo.
+func (dao *UniversalDaoDynamodb) boCodec() boCodec {
+	return boCodec{fieldEncryptor: dao.fieldEncryptor, dataCodec: dao.dataCodec, dataCompressor: dao.dataCompressor, clock: dao.clock, csumAlgorithm: dao.csumAlgorithm, csumCoverage: dao.csumCoverage, jsonCodec: dao.jsonCodec}
 }
 
 // Delete implements UniversalDao.Delete.
+//
+// (since v0.7.0) In history mode, the deleted version is archived, see SetHistoryTable.
 func (dao *UniversalDaoDynamodb) Delete(bo *UniversalBo) (bool, error) {
+	return dao.DeleteContext(context.Background(), bo)
+}
+
+// DeleteContext implements UniversalDaoContext.DeleteContext.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) DeleteContext(ctx context.Context, bo *UniversalBo) (bool, error) {
+	if err := dao.run(ctx, HookBeforeDelete, bo); err != nil {
+		return false, err
+	}
+	ok, err := dao.deleteBo(ctx, bo)
+	return dao.after(ctx, HookAfterDelete, bo, ok, err)
+}
+
+// deleteBo removes the BO from the main table, along with its unique index entries.
+func (dao *UniversalDaoDynamodb) deleteBo(ctx context.Context, bo *UniversalBo) (bool, error) {
 	gbo := dao.ToGenericBo(bo)
-	if dao.uidxAttrs == nil || len(dao.uidxAttrs) == 0 {
-		// go the easy way if there is no unique index
-		numRows, err := dao.GdaoDelete(dao.tableName, gbo)
+	if (dao.uidxAttrs == nil || len(dao.uidxAttrs) == 0) && dao.historyTable == "" {
+		// go the easy way if there is no unique index nor history to keep
+		numRows, err := dao.GdaoDeleteWithContext(ctx, dao.tableName, gbo)
 		return numRows > 0, err
 	}
 
@@ -428,6 +725,19 @@ func (dao *UniversalDaoDynamodb) Delete(bo *UniversalBo) (bool, error) {
 	txItem.Delete.ExpressionAttributeValues = conditionExp.Values()
 	txItems = append(txItems, txItem)
 
+	// (since v0.7.0) history mode: archive the deleted version
+	if dao.historyTable != "" {
+		prev, err := dao.GdaoFetchOneWithContext(ctx, dao.tableName, dao.GdaoCreateFilter(dao.tableName, gbo))
+		if err != nil || prev == nil {
+			return false, err
+		}
+		txItem, err := dao.buildTxArchive(ctx, OpDelete, bo.RoundTimestamp(clockOrDefault(dao.clock).Now()), prev)
+		if err != nil {
+			return false, err
+		}
+		txItems = append(txItems, txItem)
+	}
+
 	// step 2: delete record(s) from the uidx table
 	uidxValues := dao.BuildUidxValues(gbo)
 	for k, v := range uidxValues {
@@ -440,7 +750,7 @@ func (dao *UniversalDaoDynamodb) Delete(bo *UniversalBo) (bool, error) {
 	}
 
 	// wrap all steps inside a transaction
-	_, err = adc.ExecTxWriteItems(nil, &awsdynamodb.TransactWriteItemsInput{TransactItems: txItems})
+	_, err = adc.ExecTxWriteItems(ctx, &awsdynamodb.TransactWriteItemsInput{TransactItems: txItems})
 	if prom.IsAwsError(err, awsdynamodb.ErrCodeTransactionCanceledException) {
 		return false, nil
 	}
@@ -448,12 +758,41 @@ func (dao *UniversalDaoDynamodb) Delete(bo *UniversalBo) (bool, error) {
 }
 
 // Create implements UniversalDao.Create.
+//
+// (since v0.7.0) A BO with a blank id is assigned an id generated by the DAO's IdGenerator, see SetIdGenerator.
 func (dao *UniversalDaoDynamodb) Create(bo *UniversalBo) (bool, error) {
-	gbo := dao.ToGenericBo(bo)
+	return dao.CreateContext(context.Background(), bo)
+}
+
+// CreateContext implements UniversalDaoContext.CreateContext.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) CreateContext(ctx context.Context, bo *UniversalBo) (bool, error) {
+	if err := prepareBoId(bo, dao.idGenerator, dao.maxIdLength, true); err != nil {
+		return false, err
+	}
+	if err := dao.run(ctx, HookBeforeCreate, bo); err != nil {
+		return false, err
+	}
+	stampAudit(bo, dao.principalFunc.principal(ctx), true)
+	ok, err := dao.createBo(ctx, bo)
+	return dao.after(ctx, HookAfterCreate, bo, ok, err)
+}
+
+// createBo inserts the BO to the main table, along with its unique index entries.
+func (dao *UniversalDaoDynamodb) createBo(ctx context.Context, bo *UniversalBo) (bool, error) {
+	if err := dao.dataSchemas.validate(bo, true); err != nil {
+		return false, err
+	}
+	dao.boCodec().prepareWrite(bo)
+	gbo, err := dao.toGenericBo(bo)
+	if err != nil {
+		return false, err
+	}
 	if dao.uidxAttrs == nil || len(dao.uidxAttrs) == 0 {
 		// go the easy way if there is no unique index
-		numRows, err := dao.GdaoCreate(dao.tableName, gbo)
-		return numRows > 0, err
+		numRows, err := dao.GdaoCreateWithContext(ctx, dao.tableName, gbo)
+		return afterWrite(bo, numRows > 0, err)
 	}
 
 	pkAttrs := dao.GetRowMapper().ColumnsList(dao.tableName)
@@ -490,7 +829,7 @@ func (dao *UniversalDaoDynamodb) Create(bo *UniversalBo) (bool, error) {
 	}
 
 	// wrap all steps inside a transaction
-	_, err = adc.ExecTxWriteItems(nil, &awsdynamodb.TransactWriteItemsInput{TransactItems: txItems})
+	_, err = adc.ExecTxWriteItems(ctx, &awsdynamodb.TransactWriteItemsInput{TransactItems: txItems})
 	if awsErr, ok := err.(*awsdynamodb.TransactionCanceledException); ok {
 		for _, reason := range awsErr.CancellationReasons {
 			if *reason.Code == awsdynamodb.BatchStatementErrorCodeEnumConditionalCheckFailed {
@@ -498,17 +837,43 @@ func (dao *UniversalDaoDynamodb) Create(bo *UniversalBo) (bool, error) {
 			}
 		}
 	}
-	return true, err
+	return afterWrite(bo, true, err)
 }
 
 // Get implements UniversalDao.Get.
 func (dao *UniversalDaoDynamodb) Get(id string) (*UniversalBo, error) {
+	return dao.GetContext(context.Background(), id)
+}
+
+// GetContext implements UniversalDaoContext.GetContext.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) GetContext(ctx context.Context, id string) (*UniversalBo, error) {
+	bo, err := dao.get(ctx, id, dao.strictChecksum)
+	if err != nil {
+		return nil, err
+	}
+	if err := dao.afterLoad(ctx, bo); err != nil {
+		return nil, err
+	}
+	return bo, nil
+}
+
+// get loads a BO, returning ErrChecksumMismatch if strict is true and the BO's stored checksum does not match its content.
+func (dao *UniversalDaoDynamodb) get(ctx context.Context, id string, strict bool) (*UniversalBo, error) {
 	filterBo := NewUniversalBo(id, 0)
-	gbo, err := dao.GdaoFetchOne(dao.tableName, dao.GdaoCreateFilter(dao.tableName, dao.ToGenericBo(filterBo)))
+	gbo, err := dao.GdaoFetchOneWithContext(ctx, dao.tableName, dao.GdaoCreateFilter(dao.tableName, dao.ToGenericBo(filterBo)))
 	if err != nil {
 		return nil, err
 	}
-	return dao.ToUniversalBo(gbo), nil
+	bo, err := dao.toUniversalBo(gbo)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkLoadedChecksum(bo, strict); err != nil {
+		return nil, err
+	}
+	return bo, nil
 }
 
 // GetN implements UniversalDao.GetN.
@@ -521,6 +886,25 @@ func (dao *UniversalDaoDynamodb) Get(id string) (*UniversalBo, error) {
 //   - Map fields to GSI by calling function MapGsi.
 //   - Supply appropriate filter.
 func (dao *UniversalDaoDynamodb) GetN(fromOffset, maxNumRows int, filter godal.FilterOpt, sorting *godal.SortingOpt) ([]*UniversalBo, error) {
+	return dao.GetNContext(context.Background(), fromOffset, maxNumRows, filter, sorting)
+}
+
+// GetNContext implements UniversalDaoContext.GetNContext.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) GetNContext(ctx context.Context, fromOffset, maxNumRows int, filter godal.FilterOpt, sorting *godal.SortingOpt) ([]*UniversalBo, error) {
+	result, err := dao.getN(ctx, fromOffset, maxNumRows, filter, sorting, dao.strictChecksum)
+	if err != nil {
+		return nil, err
+	}
+	if err := dao.afterLoad(ctx, result...); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// getN loads BOs, returning ErrChecksumMismatch if strict is true and a loaded BO's stored checksum does not match its content.
+func (dao *UniversalDaoDynamodb) getN(ctx context.Context, fromOffset, maxNumRows int, filter godal.FilterOpt, sorting *godal.SortingOpt, strict bool) ([]*UniversalBo, error) {
 	if dao.pkPrefix != "" && dao.pkPrefixValue != "" {
 		/* multi-tenant: add tenant filtering */
 		tf := &godal.FilterOptAnd{}
@@ -546,13 +930,19 @@ func (dao *UniversalDaoDynamodb) GetN(fromOffset, maxNumRows int, filter godal.F
 			tableName = "!" + tableName
 		}
 	}
-	gboList, err := dao.GdaoFetchMany(tableName, filter, nil, fromOffset, maxNumRows)
+	gboList, err := dao.GdaoFetchManyWithContext(ctx, tableName, filter, nil, fromOffset, maxNumRows)
 	if err != nil {
 		return nil, err
 	}
 	result := make([]*UniversalBo, 0)
 	for _, gbo := range gboList {
-		bo := dao.ToUniversalBo(gbo)
+		bo, err := dao.toUniversalBo(gbo)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkLoadedChecksum(bo, strict); err != nil {
+			return nil, err
+		}
 		result = append(result, bo)
 	}
 	return result, nil
@@ -563,26 +953,118 @@ func (dao *UniversalDaoDynamodb) GetN(fromOffset, maxNumRows int, filter godal.F
 // Currently, AWS DynamoDB does not support custom sorting.
 // Since v0.5.2, UniversalDaoDynamodb allows limited sorting via GSI. See function GetN for more information.
 func (dao *UniversalDaoDynamodb) GetAll(filter godal.FilterOpt, sorting *godal.SortingOpt) ([]*UniversalBo, error) {
-	return dao.GetN(0, 0, filter, sorting)
+	return dao.GetAllContext(context.Background(), filter, sorting)
+}
+
+// GetAllContext implements UniversalDaoContext.GetAllContext.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) GetAllContext(ctx context.Context, filter godal.FilterOpt, sorting *godal.SortingOpt) ([]*UniversalBo, error) {
+	return dao.GetNContext(ctx, 0, 0, filter, sorting)
+}
+
+// updateIfChanged updates the stored item only if its checksum differs from the BO's one.
+//
+// If the BO's checksum does not cover all of its content, the stored content is compared too, see boCodec.isUnchanged.
+func (dao *UniversalDaoDynamodb) updateIfChanged(ctx context.Context, bo *UniversalBo, gbo godal.IGenericBo) (bool, error) {
+	if !bo.GetChecksumCoverage().coversAll() {
+		// conditional update is not sufficient: fallback to "read, compare then write"
+		existing, err := dao.GdaoFetchOneWithContext(ctx, dao.tableName, dao.GdaoCreateFilter(dao.tableName, gbo))
+		if err != nil {
+			return false, err
+		}
+		if dao.boCodec().isUnchanged(existing, gbo, bo) {
+			return unchangedWrite(bo)
+		}
+		numRows, err := dao.GdaoUpdateWithContext(ctx, dao.tableName, gbo)
+		return afterWrite(bo, numRows > 0, err)
+	}
+	pkAttrs := dao.GetRowMapper().ColumnsList(dao.tableName)
+	if pkAttrs == nil || len(pkAttrs) == 0 {
+		return false, fmt.Errorf("cannot find PK attribute list for table [%s]", dao.tableName)
+	}
+	keyFilter, err := toFilterMap(dao.GdaoCreateFilter(dao.tableName, gbo))
+	if err != nil {
+		return false, err
+	}
+	row, err := dao.GetRowMapper().ToRow(dao.tableName, gbo)
+	if err != nil {
+		return false, err
+	}
+	rowMap, ok := row.(map[string]interface{})
+	if !ok || keyFilter == nil {
+		return false, errors.New("row data must be a map")
+	}
+	// remove pk attributes from update list
+	for _, pk := range pkAttrs {
+		delete(rowMap, pk)
+	}
+	condition := prom.AwsDynamodbExistsAllBuilder(pkAttrs).
+		And(expression.Name(FieldChecksum).NotEqual(expression.Value(gbo.GboGetAttrUnsafe(FieldChecksum, reddo.TypeString))))
+	_, err = dao.GetAwsDynamodbConnect().UpdateItem(ctx, dao.tableName, keyFilter, &condition, nil, rowMap, nil, nil)
+	if prom.IsAwsError(err, awsdynamodb.ErrCodeConditionalCheckFailedException) {
+		// no item updated: either the item does not exist or it is unchanged
+		existing, err := dao.GdaoFetchOneWithContext(ctx, dao.tableName, dao.GdaoCreateFilter(dao.tableName, gbo))
+		if err != nil || existing == nil {
+			return false, err
+		}
+		return unchangedWrite(bo)
+	}
+	return afterWrite(bo, err == nil, err)
 }
 
 // Update implements UniversalDao.Update.
+//
+// (since v0.7.0) If "skip unchanged writes" is enabled and the stored item has the same checksum as bo,
+// this function returns (false, nil) without writing, see UniversalBo.WasUnchanged.
+//
+// (since v0.7.0) In history mode, the replaced version is archived, see SetHistoryTable.
 func (dao *UniversalDaoDynamodb) Update(bo *UniversalBo) (bool, error) {
-	gbo := dao.ToGenericBo(bo)
-	if dao.uidxAttrs == nil || len(dao.uidxAttrs) == 0 {
-		// go the easy way if there is no unique index
-		numRows, err := dao.GdaoUpdate(dao.tableName, gbo)
-		return numRows > 0, err
+	return dao.UpdateContext(context.Background(), bo)
+}
+
+// UpdateContext implements UniversalDaoContext.UpdateContext.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) UpdateContext(ctx context.Context, bo *UniversalBo) (bool, error) {
+	if err := dao.run(ctx, HookBeforeUpdate, bo); err != nil {
+		return false, err
+	}
+	stampAudit(bo, dao.principalFunc.principal(ctx), false)
+	ok, err := dao.updateBo(ctx, bo)
+	return dao.after(ctx, HookAfterUpdate, bo, ok, err)
+}
+
+// updateBo updates the BO in the main table, along with its unique index entries.
+func (dao *UniversalDaoDynamodb) updateBo(ctx context.Context, bo *UniversalBo) (bool, error) {
+	if err := dao.dataSchemas.validate(bo, false); err != nil {
+		return false, err
+	}
+	dao.boCodec().prepareWrite(bo)
+	gbo, err := dao.toGenericBo(bo)
+	if err != nil {
+		return false, err
+	}
+	if (dao.uidxAttrs == nil || len(dao.uidxAttrs) == 0) && dao.historyTable == "" {
+		// go the easy way if there is no unique index nor history to keep
+		if dao.skipUnchangedWrites {
+			return dao.updateIfChanged(ctx, bo, gbo)
+		}
+		numRows, err := dao.GdaoUpdateWithContext(ctx, dao.tableName, gbo)
+		return afterWrite(bo, numRows > 0, err)
 	}
 
 	// cancel update if there is no existing row to update
-	oldGbo, err := dao.GdaoFetchOne(dao.tableName, dao.GdaoCreateFilter(dao.tableName, dao.ToGenericBo(bo)))
+	oldGbo, err := dao.GdaoFetchOneWithContext(ctx, dao.tableName, dao.GdaoCreateFilter(dao.tableName, dao.ToGenericBo(bo)))
 	if err != nil {
 		return false, err
 	}
 	if oldGbo == nil {
 		return false, nil
 	}
+	if dao.skipUnchangedWrites && dao.boCodec().isUnchanged(oldGbo, gbo, bo) {
+		return unchangedWrite(bo)
+	}
 
 	pkAttrs := dao.GetRowMapper().ColumnsList(dao.tableName)
 	if pkAttrs == nil || len(pkAttrs) == 0 {
@@ -616,6 +1098,15 @@ func (dao *UniversalDaoDynamodb) Update(bo *UniversalBo) (bool, error) {
 	}
 	txItems = append(txItems, txItem)
 
+	// (since v0.7.0) history mode: archive the replaced version
+	if dao.historyTable != "" {
+		txItem, err := dao.buildTxArchive(ctx, OpUpdate, bo.RoundTimestamp(clockOrDefault(dao.clock).Now()), oldGbo)
+		if err != nil {
+			return false, err
+		}
+		txItems = append(txItems, txItem)
+	}
+
 	// step 2 & 3: remove existing records in the uidx table and insert updated ones
 	oldUidxValues := dao.BuildUidxValues(oldGbo)
 	uidxValues := dao.BuildUidxValues(gbo)
@@ -645,7 +1136,7 @@ func (dao *UniversalDaoDynamodb) Update(bo *UniversalBo) (bool, error) {
 	}
 
 	// wrap all steps inside a transaction
-	_, err = adc.ExecTxWriteItems(nil, &awsdynamodb.TransactWriteItemsInput{TransactItems: txItems})
+	_, err = adc.ExecTxWriteItems(ctx, &awsdynamodb.TransactWriteItemsInput{TransactItems: txItems})
 	if awsErr, ok := err.(*awsdynamodb.TransactionCanceledException); ok {
 		for _, reason := range awsErr.CancellationReasons {
 			if *reason.Code == awsdynamodb.BatchStatementErrorCodeEnumConditionalCheckFailed {
@@ -653,21 +1144,61 @@ func (dao *UniversalDaoDynamodb) Update(bo *UniversalBo) (bool, error) {
 			}
 		}
 	}
-	return true, err
+	return afterWrite(bo, true, err)
 }
 
 // Save implements UniversalDao.Save.
+//
+// (since v0.7.0) A BO with a blank id is assigned an id generated by the DAO's IdGenerator, see SetIdGenerator.
+//
+// (since v0.7.0) If "skip unchanged writes" is enabled and the stored item has the same checksum as bo,
+// this function returns (false, existing, nil) without writing, see UniversalBo.WasUnchanged.
+//
+// (since v0.7.0) In history mode, the replaced version is archived, see SetHistoryTable.
 func (dao *UniversalDaoDynamodb) Save(bo *UniversalBo) (bool, *UniversalBo, error) {
-	existing, err := dao.Get(bo.GetId())
+	return dao.SaveContext(context.Background(), bo)
+}
+
+// SaveContext implements UniversalDaoContext.SaveContext.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) SaveContext(ctx context.Context, bo *UniversalBo) (bool, *UniversalBo, error) {
+	if err := prepareBoId(bo, dao.idGenerator, dao.maxIdLength, true); err != nil {
+		return false, nil, err
+	}
+	if err := dao.run(ctx, HookBeforeSave, bo); err != nil {
+		return false, nil, err
+	}
+	ok, existing, err := dao.saveBo(ctx, bo, dao.principalFunc.principal(ctx))
+	ok, err = dao.after(ctx, HookAfterSave, bo, ok, err)
+	return ok, existing, err
+}
+
+// saveBo creates or updates the BO in the main table, along with its unique index entries.
+func (dao *UniversalDaoDynamodb) saveBo(ctx context.Context, bo *UniversalBo, principal string) (bool, *UniversalBo, error) {
+	existing, err := dao.get(ctx, bo.GetId(), false)
 	if err != nil {
 		return false, nil, err
 	}
+	stampAuditOnSave(bo, existing, principal)
+	if err := dao.dataSchemas.validate(bo, existing == nil); err != nil {
+		return false, existing, err
+	}
 
-	gbo := dao.ToGenericBo(bo)
-	if dao.uidxAttrs == nil || len(dao.uidxAttrs) == 0 {
-		// go the easy way if there is no unique index
-		numRows, err := dao.GdaoSave(dao.tableName, gbo)
-		return numRows > 0, existing, err
+	dao.boCodec().prepareWrite(bo)
+	gbo, err := dao.toGenericBo(bo)
+	if err != nil {
+		return false, existing, err
+	}
+	if dao.skipUnchangedWrites && isUnchanged(dao.ToGenericBo(existing), gbo) && isUnchangedContent(existing, bo) {
+		ok, err := unchangedWrite(bo)
+		return ok, existing, err
+	}
+	if (dao.uidxAttrs == nil || len(dao.uidxAttrs) == 0) && dao.historyTable == "" {
+		// go the easy way if there is no unique index nor history to keep
+		numRows, err := dao.GdaoSaveWithContext(ctx, dao.tableName, gbo)
+		ok, err := afterWrite(bo, numRows > 0, err)
+		return ok, existing, err
 	}
 
 	oldGbo := dao.ToGenericBo(existing)
@@ -698,6 +1229,21 @@ func (dao *UniversalDaoDynamodb) Save(bo *UniversalBo) (bool, *UniversalBo, erro
 	}
 	txItems = append(txItems, txItem)
 
+	// (since v0.7.0) history mode: archive the replaced version, if any
+	if dao.historyTable != "" {
+		prev, err := dao.GdaoFetchOneWithContext(ctx, dao.tableName, dao.GdaoCreateFilter(dao.tableName, gbo))
+		if err != nil {
+			return false, existing, err
+		}
+		if prev != nil {
+			txItem, err := dao.buildTxArchive(ctx, OpSave, bo.RoundTimestamp(clockOrDefault(dao.clock).Now()), prev)
+			if err != nil {
+				return false, existing, err
+			}
+			txItems = append(txItems, txItem)
+		}
+	}
+
 	// step 2 & 3: remove existing records in the uidx table and insert updated ones
 	oldUidxValues := dao.BuildUidxValues(oldGbo)
 	uidxValues := dao.BuildUidxValues(gbo)
@@ -727,7 +1273,7 @@ func (dao *UniversalDaoDynamodb) Save(bo *UniversalBo) (bool, *UniversalBo, erro
 	}
 
 	// wrap all steps inside a transaction
-	_, err = adc.ExecTxWriteItems(nil, &awsdynamodb.TransactWriteItemsInput{TransactItems: txItems})
+	_, err = adc.ExecTxWriteItems(ctx, &awsdynamodb.TransactWriteItemsInput{TransactItems: txItems})
 	if awsErr, ok := err.(*awsdynamodb.TransactionCanceledException); ok {
 		for _, reason := range awsErr.CancellationReasons {
 			if *reason.Code == awsdynamodb.BatchStatementErrorCodeEnumConditionalCheckFailed {
@@ -735,5 +1281,6 @@ func (dao *UniversalDaoDynamodb) Save(bo *UniversalBo) (bool, *UniversalBo, erro
 			}
 		}
 	}
-	return true, existing, err
+	ok, err = afterWrite(bo, true, err)
+	return ok, existing, err
 }
@@ -0,0 +1,166 @@
+package henge
+
+import (
+	"context"
+	"time"
+
+	awsdynamodb "github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/btnguyen2k/consu/reddo"
+	"github.com/btnguyen2k/godal"
+	prom "github.com/btnguyen2k/prom/dynamodb"
+)
+
+// InitDynamodbHistoryTable initializes a DynamoDB table to store prior versions of henge business objects, see
+// UniversalDaoDynamodb.SetHistoryTable.
+//   - The table is created with PK as { HistoryColId (partition key, string), HistoryColSeq (sort key, number) }.
+//   - Other than the table, no local index or global index is created.
+//
+// Available since v0.7.0
+func InitDynamodbHistoryTable(adc *prom.AwsDynamodbConnect, tableName string, rcu, wcu int64) error {
+	attrDefs := []prom.AwsDynamodbNameAndType{
+		{Name: HistoryColId, Type: prom.AwsAttrTypeString},
+		{Name: HistoryColSeq, Type: prom.AwsAttrTypeNumber},
+	}
+	pkDefs := []prom.AwsDynamodbNameAndType{
+		{Name: HistoryColId, Type: prom.AwsKeyTypePartition},
+		{Name: HistoryColSeq, Type: prom.AwsKeyTypeSort},
+	}
+	err := adc.CreateTable(nil, tableName, rcu, wcu, attrDefs, pkDefs)
+	if err = prom.AwsIgnoreErrorIfMatched(err, awsdynamodb.ErrCodeTableAlreadyExistsException); err != nil {
+		if err = prom.AwsIgnoreErrorIfMatched(err, awsdynamodb.ErrCodeResourceInUseException); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetHistoryTable returns name of the table storing prior versions of BOs, empty if history mode is disabled.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) GetHistoryTable() string {
+	return dao.historyTable
+}
+
+// SetHistoryTable enables history mode (see UniversalDaoHistory), storing prior versions of BOs in the specified table
+// (see InitDynamodbHistoryTable). An empty table name disables history mode, which is the default.
+//
+// In history mode, Update, Save and Delete archive the version being replaced in the same transaction as the write.
+// If the table's PK is { pkPrefix, FieldId }, history entries are keyed by "<pkPrefix value>:<id>".
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) SetHistoryTable(tableName string) *UniversalDaoDynamodb {
+	dao.historyTable = tableName
+	return dao
+}
+
+// historyId returns the key of the history entries of a stored item.
+func (dao *UniversalDaoDynamodb) historyId(gbo godal.IGenericBo) string {
+	id, _ := gbo.GboGetAttrUnsafe(FieldId, reddo.TypeString).(string)
+	if dao.pkPrefix == "" {
+		return id
+	}
+	prefix, _ := gbo.GboGetAttrUnsafe(dao.pkPrefix, reddo.TypeString).(string)
+	if prefix == "" {
+		prefix = dao.pkPrefixValue
+	}
+	return prefix + ":" + id
+}
+
+// buildTxArchive builds the transaction item that archives the stored version prev to the history table.
+func (dao *UniversalDaoDynamodb) buildTxArchive(ctx context.Context, op DaoOp, t time.Time, prev godal.IGenericBo) (*awsdynamodb.TransactWriteItem, error) {
+	hid := dao.historyId(prev)
+	last, err := dao.GdaoFetchManyWithContext(ctx, "!@"+dao.historyTable, godal.MakeFilter(map[string]interface{}{HistoryColId: hid}), nil, 0, 1)
+	if err != nil {
+		return nil, err
+	}
+	row, err := dao.GetRowMapper().ToRow(dao.tableName, prev)
+	if err != nil {
+		return nil, err
+	}
+	rec := newHistoryRecord(hid, lastHistorySeq(last, HistoryColSeq)+1, op, t, row)
+	return dao.GetAwsDynamodbConnect().BuildTxPutIfNotExist(dao.historyTable, rec, []string{HistoryColId, HistoryColSeq})
+}
+
+// fetchHistory loads the history entries of a BO, most recent first.
+func (dao *UniversalDaoDynamodb) fetchHistory(id string, fromOffset, maxNumRows int) ([]*HistoryEntry, error) {
+	hid := dao.historyId(dao.ToGenericBo(NewUniversalBo(id, 0)))
+	recs, err := dao.GdaoFetchMany("!@"+dao.historyTable, godal.MakeFilter(map[string]interface{}{HistoryColId: hid}), nil, fromOffset, maxNumRows)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*HistoryEntry, 0, len(recs))
+	for _, rec := range recs {
+		seq, op, t, row := parseHistoryRecord(rec)
+		gbo, err := dao.GetRowMapper().ToBo(dao.tableName, row)
+		if err != nil {
+			return nil, err
+		}
+		bo, err := dao.toUniversalBo(gbo)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &HistoryEntry{Seq: seq, Op: op, TimeArchived: t, Bo: bo})
+	}
+	return result, nil
+}
+
+// GetHistory implements UniversalDaoHistory.GetHistory.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) GetHistory(id string, fromOffset, maxNumRows int) ([]*HistoryEntry, error) {
+	if dao.historyTable == "" {
+		return nil, ErrHistoryDisabled
+	}
+	entries, err := dao.fetchHistory(id, fromOffset, maxNumRows)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if err := dao.afterLoad(context.Background(), entry.Bo); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// GetVersionAt implements UniversalDaoHistory.GetVersionAt.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) GetVersionAt(id string, t time.Time) (*UniversalBo, error) {
+	if dao.historyTable == "" {
+		return nil, ErrHistoryDisabled
+	}
+	current, err := dao.get(context.Background(), id, false)
+	if err != nil {
+		return nil, err
+	}
+	bo, err := versionAt(current, func(fromOffset, maxNumRows int) ([]*HistoryEntry, error) {
+		return dao.fetchHistory(id, fromOffset, maxNumRows)
+	}, t)
+	if err != nil || bo == nil {
+		return nil, err
+	}
+	if err := dao.afterLoad(context.Background(), bo); err != nil {
+		return nil, err
+	}
+	return bo, nil
+}
+
+// RestoreVersion implements UniversalDaoHistory.RestoreVersion.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) RestoreVersion(id string, ref VersionRef) (*UniversalBo, error) {
+	return dao.RestoreVersionContext(context.Background(), id, ref)
+}
+
+// RestoreVersionContext implements UniversalDaoHistory.RestoreVersionContext.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoDynamodb) RestoreVersionContext(ctx context.Context, id string, ref VersionRef) (*UniversalBo, error) {
+	if dao.historyTable == "" {
+		return nil, ErrHistoryDisabled
+	}
+	return restoreVersion(ctx, dao, dao.clock, func(fromOffset, maxNumRows int) ([]*HistoryEntry, error) {
+		return dao.fetchHistory(id, fromOffset, maxNumRows)
+	}, ref)
+}
@@ -1,13 +1,16 @@
 package henge
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"time"
 
 	"github.com/btnguyen2k/consu/reddo"
 	"github.com/btnguyen2k/godal"
 	"github.com/btnguyen2k/godal/mongo"
 	prom "github.com/btnguyen2k/prom/mongo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongodrv "go.mongodb.org/mongo-driver/mongo"
 )
 
 // InitMongoCollection initializes a MongoDB collection to store henge business objects.
@@ -37,7 +40,7 @@ func (r *rowMapperMongo) ToRow(storageId string, bo godal.IGenericBo) (interface
 		m[FieldTagVersion], _ = bo.GboGetAttr(FieldTagVersion, nil) // tag-version should be integer
 		m[FieldTimeCreated], _ = bo.GboGetTimeWithLayout(FieldTimeCreated, time.RFC3339)
 		m[FieldTimeUpdated], _ = bo.GboGetTimeWithLayout(FieldTimeUpdated, time.RFC3339)
-		m[FieldData], _ = bo.GboGetAttrUnmarshalJson(FieldData) // Note: FieldData must be JSON-encoded string!
+		m[FieldData] = toRowData(bo) // Note: FieldData must be JSON-encoded string (or compressed data since v0.7.0)!
 	}
 	return row, err
 }
@@ -57,8 +60,11 @@ func (r *rowMapperMongo) ToBo(storageId string, row interface{}) (godal.IGeneric
 				gbo.GboSetAttr(FieldData, str)
 			} else if bytes, ok := data.([]byte); ok {
 				gbo.GboSetAttr(FieldData, string(bytes))
+			} else if bin, ok := data.(primitive.Binary); ok {
+				// (since v0.7.0) binary-encoded data
+				gbo.GboSetAttr(FieldData, bin.Data)
 			} else {
-				js, _ := json.Marshal(data)
+				js, _ := jsonCodecOrDefault(nil).Marshal(data)
 				gbo.GboSetAttr(FieldData, string(js))
 			}
 		}
@@ -93,8 +99,25 @@ const (
 // UniversalDaoMongo is MongoDB-based implementation of UniversalDao.
 type UniversalDaoMongo struct {
 	*mongo.GenericDaoMongo
+	DaoHooks                // (since v0.7.0) lifecycle hooks, see DaoHooks
 	collectionName string   // name of the MongoDB collection to store business objects
 	defaultUboOpts []UboOpt // (since v0.5.7) default options used by the DAO to create UniversalBo instances
+
+	skipUnchangedWrites bool               // (since v0.7.0) if true, Update/Save do not write BOs whose checksum matches the stored one
+	strictChecksum      bool               // (since v0.7.0) if true, loading BOs whose stored checksum mismatches results in ErrChecksumMismatch
+	dataSchemas         dataSchemaRegistry // (since v0.7.0) schemas used to validate BO's data on write
+	fieldEncryptor      *FieldEncryptor    // (since v0.7.0) encrypts/decrypts sensitive attributes
+	dataCompressor      *DataCompressor    // (since v0.7.0) compresses large BO data
+	dataCodec           DataCodec          // (since v0.7.0) encodes BO data in binary form
+	clock               Clock              // (since v0.7.0) source of the current time for BO's timestamps, nil means SystemClock
+	csumAlgorithm       ChecksumAlgorithm  // (since v0.7.0) algorithm used to calculate checksums of loaded BOs, empty means UboOpt.ChecksumAlgorithm
+	csumCoverage        *ChecksumCoverage  // (since v0.7.0) parts of data and extra attributes covered by BO's checksum, nil means all
+	jsonCodec           JsonCodec          // (since v0.7.0) codec used to parse and encode BO data, nil means DefaultJsonCodec
+	idGenerator         IdGenerator        // (since v0.7.0) generates ids for BOs created with a blank id
+	maxIdLength         int                // (since v0.7.0) maximum length of BO's id, 0 means no limit
+	principalFunc       PrincipalFunc      // (since v0.7.0) extracts the principal filling in BO's audit fields
+	historyCollection   string             // (since v0.7.0) collection storing prior versions of BOs, empty means history mode is disabled
+	outboxCollection    string             // (since v0.7.0) collection storing ChangeEvents to be relayed, empty means outbox mode is disabled
 }
 
 // Init should be called to initialize the DAO instance before use.
@@ -126,60 +149,428 @@ func (dao *UniversalDaoMongo) SetDefaultUboOpts(uboOpts []UboOpt) *UniversalDaoM
 	return dao
 }
 
+// GetSkipUnchangedWrites returns true if Update/Save skip writing BOs whose checksum matches the stored one.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) GetSkipUnchangedWrites() bool {
+	return dao.skipUnchangedWrites
+}
+
+// SetSkipUnchangedWrites enables/disables skipping no-op writes.
+//
+// If enabled, Update/Save compare the BO's checksum with the stored one and return false without writing if they
+// match (see UniversalBo.WasUnchanged). Update performs a conditional replace with filter {csum: {$ne: <checksum>}}.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) SetSkipUnchangedWrites(value bool) *UniversalDaoMongo {
+	dao.skipUnchangedWrites = value
+	return dao
+}
+
+// GetStrictChecksum returns true if the DAO is in strict-load mode.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) GetStrictChecksum() bool {
+	return dao.strictChecksum
+}
+
+// SetStrictChecksum enables/disables strict-load mode.
+//
+// In strict-load mode, Get/GetN/GetAll return ErrChecksumMismatch if a loaded BO's stored checksum does not match its
+// content. Otherwise, mismatching BOs are loaded and flagged (see UniversalBo.IsChecksumMismatched).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) SetStrictChecksum(value bool) *UniversalDaoMongo {
+	dao.strictChecksum = value
+	return dao
+}
+
+// GetDataSchema returns the DataSchema used to validate data of BOs with the specified tag-version (nil if none).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) GetDataSchema(tagVersion uint64) *DataSchema {
+	return dao.dataSchemas.lookup(tagVersion)
+}
+
+// SetDataSchema sets the default DataSchema, used to validate data of BOs whose tag-version has no specific schema
+// (see SetDataSchemaForTagVersion). nil disables the default validation.
+//
+// If a schema is configured, Create/Update/Save validate BO's data against it and return a *DataValidationError if
+// the data does not conform. Default values declared in the schema are filled in when a new BO is written.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) SetDataSchema(schema *DataSchema) *UniversalDaoMongo {
+	dao.dataSchemas.defaultSchema = schema
+	return dao
+}
+
+// SetDataSchemaForTagVersion sets the DataSchema used to validate data of BOs with the specified tag-version.
+// nil removes the tag-version specific schema.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) SetDataSchemaForTagVersion(tagVersion uint64, schema *DataSchema) *UniversalDaoMongo {
+	dao.dataSchemas.setForTagVersion(tagVersion, schema)
+	return dao
+}
+
+// GetFieldEncryptor returns the FieldEncryptor used to encrypt/decrypt sensitive attributes (nil if none).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) GetFieldEncryptor() *FieldEncryptor {
+	return dao.fieldEncryptor
+}
+
+// SetFieldEncryptor sets the FieldEncryptor used to encrypt sensitive attributes in ToGenericBo and decrypt them in
+// ToUniversalBo. BO's checksum is calculated over plaintext values.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) SetFieldEncryptor(fe *FieldEncryptor) *UniversalDaoMongo {
+	dao.fieldEncryptor = fe
+	return dao
+}
+
+// GetDataCompressor returns the DataCompressor used to compress BO's data (nil if none).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) GetDataCompressor() *DataCompressor {
+	return dao.dataCompressor
+}
+
+// SetDataCompressor sets the DataCompressor used to compress large BO's data in ToGenericBo. nil disables compression.
+//
+// Compressed data is always decompressed transparently in ToUniversalBo, even if compression has been disabled since.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) SetDataCompressor(dc *DataCompressor) *UniversalDaoMongo {
+	dao.dataCompressor = dc
+	return dao
+}
+
+// GetDataCodec returns the DataCodec used to encode BO's data (nil means the default JSON format).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) GetDataCodec() DataCodec {
+	return dao.dataCodec
+}
+
+// SetDataCodec sets the DataCodec used to encode BO's data in ToGenericBo. nil or DataCodecJson means the default JSON format.
+//
+// Data encoded with a binary codec (e.g. DataCodecMsgpack or DataCodecCbor) is stored as []byte, hence the storage
+// must support binary values for the data field (e.g. BYTEA/BLOB column or DynamoDB's B type). Binary-encoded data is
+// neither compressed nor searchable by the storage. Data is always decoded transparently in ToUniversalBo, regardless
+// of the codec currently configured.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) SetDataCodec(codec DataCodec) *UniversalDaoMongo {
+	dao.dataCodec = codec
+	return dao
+}
+
+// GetClock returns the Clock used by the DAO for BO's timestamps (nil means SystemClock).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) GetClock() Clock {
+	return dao.clock
+}
+
+// SetClock sets the Clock used by the DAO for BO's timestamps: BOs loaded by the DAO use it (taking precedence over
+// UboOpt.Clock of the default UboOpts), and BOs written by the DAO that have no Clock of their own have their
+// last-updated timestamp bumped with it when their data changes.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) SetClock(clock Clock) *UniversalDaoMongo {
+	dao.clock = clock
+	return dao
+}
+
+// GetChecksumAlgorithm returns the ChecksumAlgorithm used by the DAO (empty means the one of the default UboOpts).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) GetChecksumAlgorithm() ChecksumAlgorithm {
+	return dao.csumAlgorithm
+}
+
+// SetChecksumAlgorithm sets the ChecksumAlgorithm used by the DAO: BOs loaded by the DAO use it, taking precedence over
+// UboOpt.ChecksumAlgorithm of the default UboOpts (see RecomputeChecksums to migrate stored checksums). An empty value
+// reverts to the default UboOpts.
+//
+// This function returns ErrUnsupportedChecksumAlgorithm, leaving the DAO unchanged, if alg is not supported.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) SetChecksumAlgorithm(alg ChecksumAlgorithm) error {
+	if alg != "" && !alg.IsSupported() {
+		return ErrUnsupportedChecksumAlgorithm
+	}
+	dao.csumAlgorithm = alg
+	return nil
+}
+
+// GetChecksumCoverage returns the ChecksumCoverage used by the DAO (nil means all data and extra attributes are covered).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) GetChecksumCoverage() *ChecksumCoverage {
+	return dao.csumCoverage
+}
+
+// SetChecksumCoverage sets the ChecksumCoverage used by the DAO: BOs loaded by the DAO use it (taking precedence over
+// UboOpt.ChecksumCoverage of the default UboOpts), and BOs written by the DAO that have no ChecksumCoverage of their own
+// adopt it.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) SetChecksumCoverage(cc *ChecksumCoverage) *UniversalDaoMongo {
+	dao.csumCoverage = cc
+	return dao
+}
+
+// GetJsonCodec returns the JsonCodec used by the DAO (nil means DefaultJsonCodec).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) GetJsonCodec() JsonCodec {
+	return dao.jsonCodec
+}
+
+// SetJsonCodec sets the JsonCodec used by the DAO to parse and encode data of the BOs it loads (taking precedence over
+// UboOpt.JsonCodec of the default UboOpts).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) SetJsonCodec(codec JsonCodec) *UniversalDaoMongo {
+	dao.jsonCodec = codec
+	return dao
+}
+
+// GetIdGenerator returns the IdGenerator used by the DAO (nil means none).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) GetIdGenerator() IdGenerator {
+	return dao.idGenerator
+}
+
+// SetIdGenerator sets the IdGenerator used by the DAO: Create and Save assign a generated id to BOs having a blank id.
+// Without IdGenerator, writing a BO with a blank id results in ErrEmptyId.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) SetIdGenerator(gen IdGenerator) *UniversalDaoMongo {
+	dao.idGenerator = gen
+	return dao
+}
+
+// GetMaxIdLength returns the maximum length (in characters) of BO's id accepted by the DAO (0 means no limit).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) GetMaxIdLength() int {
+	return dao.maxIdLength
+}
+
+// SetMaxIdLength sets the maximum length (in characters) of BO's id accepted by the DAO (0 means no limit): Create and
+// Save return ErrIdTooLong for BOs whose id is longer.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) SetMaxIdLength(value int) *UniversalDaoMongo {
+	dao.maxIdLength = value
+	return dao
+}
+
+// GetPrincipalFunc returns the PrincipalFunc used by the DAO to fill in BO's audit fields (nil means none).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) GetPrincipalFunc() PrincipalFunc {
+	return dao.principalFunc
+}
+
+// SetPrincipalFunc sets the PrincipalFunc used by the DAO to fill in BO's audit fields on writes. Audit fields are
+// stored as attributes of documents, only when not empty.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) SetPrincipalFunc(fn PrincipalFunc) *UniversalDaoMongo {
+	dao.principalFunc = fn
+	return dao
+}
+
 // GdaoCreateFilter implements IGenericDao.GdaoCreateFilter.
 func (dao *UniversalDaoMongo) GdaoCreateFilter(_ string, bo godal.IGenericBo) godal.FilterOpt {
 	return godal.MakeFilter(map[string]interface{}{MongoColId: bo.GboGetAttrUnsafe(FieldId, reddo.TypeString)})
 }
 
-// ToUniversalBo implements UniversalDao.ToUniversalBo.
+// ToUniversalBo transforms godal.IGenericBo to business object.
+//
+// (since v0.7.0) nil is returned if data cannot be decoded/decompressed or the encrypted attributes cannot be decrypted.
 func (dao *UniversalDaoMongo) ToUniversalBo(gbo godal.IGenericBo) *UniversalBo {
-	return NewUniversalBoFromGbo(gbo, dao.defaultUboOpts...)
+	bo, _ := dao.toUniversalBo(gbo)
+	return bo
+}
+
+// toUniversalBo transforms godal.IGenericBo to business object, decoding/decompressing data and decrypting the encrypted attributes.
+func (dao *UniversalDaoMongo) toUniversalBo(gbo godal.IGenericBo) (*UniversalBo, error) {
+	return dao.boCodec().toUniversalBo(gbo, dao.defaultUboOpts...)
 }
 
-// ToGenericBo implements UniversalDao.ToGenericBo.
+// ToGenericBo transforms business object to godal.IGenericBo.
+//
+// (since v0.7.0) nil is returned if the attributes to be encrypted cannot be encrypted or data cannot be encoded/compressed.
 func (dao *UniversalDaoMongo) ToGenericBo(ubo *UniversalBo) godal.IGenericBo {
-	if ubo == nil {
-		return nil
-	}
-	return ubo.ToGenericBo()
+	gbo, _ := dao.toGenericBo(ubo)
+	return gbo
+}
+
+// toGenericBo transforms business object to godal.IGenericBo, encrypting the attributes to be encrypted and encoding/compressing data.
+func (dao *UniversalDaoMongo) toGenericBo(ubo *UniversalBo) (godal.IGenericBo, error) {
+	return dao.boCodec().toGenericBo(ubo)
+}
+
+// boCodec returns the settings used to convert BOs to/from godal.IGenericBo.
+func (dao *UniversalDaoMongo) boCodec() boCodec {
+	return boCodec{fieldEncryptor: dao.fieldEncryptor, dataCodec: dao.dataCodec, dataCompressor: dao.dataCompressor, clock: dao.clock, csumAlgorithm: dao.csumAlgorithm, csumCoverage: dao.csumCoverage, jsonCodec: dao.jsonCodec}
 }
 
 // Delete implements UniversalDao.Delete.
+//
+// (since v0.7.0) In history mode, the deleted version is archived, see SetHistoryCollection.
+//
+// (since v0.7.0) In outbox mode, a ChangeEvent is recorded along with the write, see SetOutboxCollection.
 func (dao *UniversalDaoMongo) Delete(bo *UniversalBo) (bool, error) {
-	numRows, err := dao.GdaoDelete(dao.collectionName, dao.ToGenericBo(bo))
-	return numRows > 0, err
+	return dao.DeleteContext(context.Background(), bo)
+}
+
+// DeleteContext implements UniversalDaoContext.DeleteContext.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) DeleteContext(ctx context.Context, bo *UniversalBo) (bool, error) {
+	if err := dao.run(ctx, HookBeforeDelete, bo); err != nil {
+		return false, err
+	}
+	gbo := dao.ToGenericBo(bo)
+	var numRows int
+	var err error
+	if dao.historyCollection != "" || dao.outboxCollection != "" {
+		numRows, _, err = dao.writeTx(ctx, OpDelete, bo, gbo, func(ctx context.Context) (int, error) {
+			return dao.GdaoDeleteWithContext(ctx, dao.collectionName, gbo)
+		})
+	} else {
+		numRows, err = dao.GdaoDeleteWithContext(ctx, dao.collectionName, gbo)
+	}
+	return dao.after(ctx, HookAfterDelete, bo, numRows > 0, err)
 }
 
 // Create implements UniversalDao.Create.
+//
+// (since v0.7.0) A BO with a blank id is assigned an id generated by the DAO's IdGenerator, see SetIdGenerator.
+//
+// (since v0.7.0) In outbox mode, a ChangeEvent is recorded along with the write, see SetOutboxCollection.
 func (dao *UniversalDaoMongo) Create(bo *UniversalBo) (bool, error) {
-	numRows, err := dao.GdaoCreate(dao.collectionName, dao.ToGenericBo(bo))
-	return numRows > 0, err
+	return dao.CreateContext(context.Background(), bo)
+}
+
+// CreateContext implements UniversalDaoContext.CreateContext.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) CreateContext(ctx context.Context, bo *UniversalBo) (bool, error) {
+	if err := prepareBoId(bo, dao.idGenerator, dao.maxIdLength, false); err != nil {
+		return false, err
+	}
+	if err := dao.run(ctx, HookBeforeCreate, bo); err != nil {
+		return false, err
+	}
+	stampAudit(bo, dao.principalFunc.principal(ctx), true)
+	if err := dao.dataSchemas.validate(bo, true); err != nil {
+		return false, err
+	}
+	dao.boCodec().prepareWrite(bo)
+	gbo, err := dao.toGenericBo(bo)
+	if err != nil {
+		return false, err
+	}
+	var numRows int
+	if dao.outboxCollection != "" {
+		numRows, _, err = dao.writeTx(ctx, OpCreate, bo, gbo, func(ctx context.Context) (int, error) {
+			return dao.GdaoCreateWithContext(ctx, dao.collectionName, gbo)
+		})
+	} else {
+		numRows, err = dao.GdaoCreateWithContext(ctx, dao.collectionName, gbo)
+	}
+	ok, err := afterWrite(bo, numRows > 0, err)
+	return dao.after(ctx, HookAfterCreate, bo, ok, err)
 }
 
 // Get implements UniversalDao.Get.
 func (dao *UniversalDaoMongo) Get(id string) (*UniversalBo, error) {
+	return dao.GetContext(context.Background(), id)
+}
+
+// GetContext implements UniversalDaoContext.GetContext.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) GetContext(ctx context.Context, id string) (*UniversalBo, error) {
+	bo, err := dao.get(ctx, id, dao.strictChecksum)
+	if err != nil {
+		return nil, err
+	}
+	if err := dao.afterLoad(ctx, bo); err != nil {
+		return nil, err
+	}
+	return bo, nil
+}
+
+// get loads a BO, returning ErrChecksumMismatch if strict is true and the BO's stored checksum does not match its content.
+func (dao *UniversalDaoMongo) get(ctx context.Context, id string, strict bool) (*UniversalBo, error) {
 	filterBo := NewUniversalBo(id, 0)
 	filter := dao.GdaoCreateFilter(dao.collectionName, filterBo.ToGenericBo())
-	gbo, err := dao.GdaoFetchOne(dao.collectionName, filter)
+	gbo, err := dao.GdaoFetchOneWithContext(ctx, dao.collectionName, filter)
 	if err != nil {
 		return nil, err
 	}
-	return dao.ToUniversalBo(gbo), nil
+	bo, err := dao.toUniversalBo(gbo)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkLoadedChecksum(bo, strict); err != nil {
+		return nil, err
+	}
+	return bo, nil
 }
 
 // GetN implements UniversalDao.GetN.
 func (dao *UniversalDaoMongo) GetN(fromOffset, maxNumRows int, filter godal.FilterOpt, sorting *godal.SortingOpt) ([]*UniversalBo, error) {
+	return dao.GetNContext(context.Background(), fromOffset, maxNumRows, filter, sorting)
+}
+
+// GetNContext implements UniversalDaoContext.GetNContext.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) GetNContext(ctx context.Context, fromOffset, maxNumRows int, filter godal.FilterOpt, sorting *godal.SortingOpt) ([]*UniversalBo, error) {
+	result, err := dao.getN(ctx, fromOffset, maxNumRows, filter, sorting, dao.strictChecksum)
+	if err != nil {
+		return nil, err
+	}
+	if err := dao.afterLoad(ctx, result...); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// scanSorting returns the sorting by id used to scan BOs.
+func (dao *UniversalDaoMongo) scanSorting() *godal.SortingOpt {
+	return (&godal.SortingField{FieldName: MongoColId}).ToSortingOpt()
+}
+
+// getN loads BOs, returning ErrChecksumMismatch if strict is true and a loaded BO's stored checksum does not match its content.
+func (dao *UniversalDaoMongo) getN(ctx context.Context, fromOffset, maxNumRows int, filter godal.FilterOpt, sorting *godal.SortingOpt, strict bool) ([]*UniversalBo, error) {
 	if sorting == nil {
 		// default sorting: ascending by "id" column
 		sorting = (&godal.SortingField{FieldName: MongoColId}).ToSortingOpt()
 	}
-	gboList, err := dao.GdaoFetchMany(dao.collectionName, filter, sorting, fromOffset, maxNumRows)
+	gboList, err := dao.GdaoFetchManyWithContext(ctx, dao.collectionName, filter, sorting, fromOffset, maxNumRows)
 	if err != nil {
 		return nil, err
 	}
 	result := make([]*UniversalBo, 0)
 	for _, gbo := range gboList {
-		bo := dao.ToUniversalBo(gbo)
+		bo, err := dao.toUniversalBo(gbo)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkLoadedChecksum(bo, strict); err != nil {
+			return nil, err
+		}
 		result = append(result, bo)
 	}
 	return result, nil
@@ -187,21 +578,157 @@ func (dao *UniversalDaoMongo) GetN(fromOffset, maxNumRows int, filter godal.Filt
 
 // GetAll implements UniversalDao.GetAll.
 func (dao *UniversalDaoMongo) GetAll(filter godal.FilterOpt, sorting *godal.SortingOpt) ([]*UniversalBo, error) {
-	return dao.GetN(0, 0, filter, sorting)
+	return dao.GetAllContext(context.Background(), filter, sorting)
+}
+
+// GetAllContext implements UniversalDaoContext.GetAllContext.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) GetAllContext(ctx context.Context, filter godal.FilterOpt, sorting *godal.SortingOpt) ([]*UniversalBo, error) {
+	return dao.GetNContext(ctx, 0, 0, filter, sorting)
+}
+
+// updateIfChanged replaces the stored document only if its checksum differs from the BO's one.
+//
+// If the BO's checksum does not cover all of its content, the stored content is compared too, see boCodec.isUnchanged.
+func (dao *UniversalDaoMongo) updateIfChanged(ctx context.Context, bo *UniversalBo, gbo godal.IGenericBo) (bool, error) {
+	idFilter := dao.GdaoCreateFilter(dao.collectionName, gbo)
+	if !bo.GetChecksumCoverage().coversAll() {
+		// conditional update is not sufficient: fallback to "read, compare then write"
+		existing, err := dao.GdaoFetchOneWithContext(ctx, dao.collectionName, idFilter)
+		if err != nil {
+			return false, err
+		}
+		if dao.boCodec().isUnchanged(existing, gbo, bo) {
+			return unchangedWrite(bo)
+		}
+		numRows, err := dao.GdaoUpdateWithContext(ctx, dao.collectionName, gbo)
+		return afterWrite(bo, numRows > 0, err)
+	}
+	doc, err := dao.GetRowMapper().ToRow(dao.collectionName, gbo)
+	if err != nil {
+		return false, err
+	}
+	csumFilter := &godal.FilterOptFieldOpValue{FieldName: FieldChecksum, Operator: godal.FilterOpNotEqual, Value: gbo.GboGetAttrUnsafe(FieldChecksum, reddo.TypeString)}
+	result := dao.MongoUpdateOne(ctx, dao.collectionName, (&godal.FilterOptAnd{}).Add(idFilter).Add(csumFilter), doc)
+	if result == nil {
+		return false, errors.New("cannot build filter for conditional update")
+	}
+	if _, err = result.DecodeBytes(); err == mongodrv.ErrNoDocuments {
+		// no document replaced: either the document does not exist or it is unchanged
+		existing, err := dao.GdaoFetchOneWithContext(ctx, dao.collectionName, idFilter)
+		if err != nil || existing == nil {
+			return false, err
+		}
+		return unchangedWrite(bo)
+	} else if mongodrv.IsDuplicateKeyError(err) {
+		return false, godal.ErrGdaoDuplicatedEntry
+	}
+	return afterWrite(bo, err == nil, err)
 }
 
 // Update implements UniversalDao.Update.
+//
+// (since v0.7.0) If "skip unchanged writes" is enabled and the stored document has the same checksum as bo,
+// this function returns (false, nil) without writing, see UniversalBo.WasUnchanged.
+//
+// (since v0.7.0) In history mode, the replaced version is archived, see SetHistoryCollection.
+//
+// (since v0.7.0) In outbox mode, a ChangeEvent is recorded along with the write, see SetOutboxCollection.
 func (dao *UniversalDaoMongo) Update(bo *UniversalBo) (bool, error) {
-	numRows, err := dao.GdaoUpdate(dao.collectionName, dao.ToGenericBo(bo))
-	return numRows > 0, err
+	return dao.UpdateContext(context.Background(), bo)
+}
+
+// UpdateContext implements UniversalDaoContext.UpdateContext.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) UpdateContext(ctx context.Context, bo *UniversalBo) (bool, error) {
+	if err := dao.run(ctx, HookBeforeUpdate, bo); err != nil {
+		return false, err
+	}
+	stampAudit(bo, dao.principalFunc.principal(ctx), false)
+	if err := dao.dataSchemas.validate(bo, false); err != nil {
+		return false, err
+	}
+	dao.boCodec().prepareWrite(bo)
+	gbo, err := dao.toGenericBo(bo)
+	if err != nil {
+		return false, err
+	}
+	var ok bool
+	if dao.historyCollection != "" || dao.outboxCollection != "" {
+		numRows, unchanged, e := dao.writeTx(ctx, OpUpdate, bo, gbo, func(ctx context.Context) (int, error) {
+			return dao.GdaoUpdateWithContext(ctx, dao.collectionName, gbo)
+		})
+		if unchanged {
+			ok, err = unchangedWrite(bo)
+		} else {
+			ok, err = afterWrite(bo, numRows > 0, e)
+		}
+	} else if dao.skipUnchangedWrites {
+		ok, err = dao.updateIfChanged(ctx, bo, gbo)
+	} else {
+		numRows, e := dao.GdaoUpdateWithContext(ctx, dao.collectionName, gbo)
+		ok, err = afterWrite(bo, numRows > 0, e)
+	}
+	return dao.after(ctx, HookAfterUpdate, bo, ok, err)
 }
 
 // Save implements UniversalDao.Save.
+//
+// (since v0.7.0) A BO with a blank id is assigned an id generated by the DAO's IdGenerator, see SetIdGenerator.
+//
+// (since v0.7.0) If "skip unchanged writes" is enabled and the stored document has the same checksum as bo,
+// this function returns (false, existing, nil) without writing, see UniversalBo.WasUnchanged.
+//
+// (since v0.7.0) In history mode, the replaced version is archived, see SetHistoryCollection.
+//
+// (since v0.7.0) In outbox mode, a ChangeEvent is recorded along with the write, see SetOutboxCollection.
 func (dao *UniversalDaoMongo) Save(bo *UniversalBo) (bool, *UniversalBo, error) {
-	existing, err := dao.Get(bo.GetId())
+	return dao.SaveContext(context.Background(), bo)
+}
+
+// SaveContext implements UniversalDaoContext.SaveContext.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) SaveContext(ctx context.Context, bo *UniversalBo) (bool, *UniversalBo, error) {
+	if err := prepareBoId(bo, dao.idGenerator, dao.maxIdLength, false); err != nil {
+		return false, nil, err
+	}
+	if err := dao.run(ctx, HookBeforeSave, bo); err != nil {
+		return false, nil, err
+	}
+	existing, err := dao.get(ctx, bo.GetId(), false)
 	if err != nil {
 		return false, nil, err
 	}
-	numRows, err := dao.GdaoSave(dao.collectionName, dao.ToGenericBo(bo))
-	return numRows > 0, existing, err
+	stampAuditOnSave(bo, existing, dao.principalFunc.principal(ctx))
+	if err := dao.dataSchemas.validate(bo, existing == nil); err != nil {
+		return false, existing, err
+	}
+	dao.boCodec().prepareWrite(bo)
+	gbo, err := dao.toGenericBo(bo)
+	if err != nil {
+		return false, existing, err
+	}
+	if dao.skipUnchangedWrites && isUnchanged(dao.ToGenericBo(existing), gbo) && isUnchangedContent(existing, bo) {
+		ok, err := unchangedWrite(bo)
+		return ok, existing, err
+	}
+	var numRows int
+	if dao.historyCollection != "" || dao.outboxCollection != "" {
+		var unchanged bool
+		numRows, unchanged, err = dao.writeTx(ctx, OpSave, bo, gbo, func(ctx context.Context) (int, error) {
+			return dao.GdaoSaveWithContext(ctx, dao.collectionName, gbo)
+		})
+		if unchanged {
+			ok, err := unchangedWrite(bo)
+			return ok, existing, err
+		}
+	} else {
+		numRows, err = dao.GdaoSaveWithContext(ctx, dao.collectionName, gbo)
+	}
+	ok, err := afterWrite(bo, numRows > 0, err)
+	ok, err = dao.after(ctx, HookAfterSave, bo, ok, err)
+	return ok, existing, err
 }
@@ -0,0 +1,200 @@
+package henge
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/btnguyen2k/consu/reddo"
+	"github.com/btnguyen2k/godal"
+	prom "github.com/btnguyen2k/prom/mongo"
+	"go.mongodb.org/mongo-driver/bson"
+	mongodrv "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// InitMongoHistoryCollection initializes a MongoDB collection to store prior versions of henge business objects, see
+// UniversalDaoMongo.SetHistoryCollection.
+//   - This function creates the specified collection with default settings.
+//   - A unique index on {HistoryColId: 1, HistoryColSeq: -1} is also created.
+//
+// Available since v0.7.0
+func InitMongoHistoryCollection(mc *prom.MongoConnect, collectionName string) error {
+	if err := mc.CreateCollection(collectionName); err != nil {
+		return err
+	}
+	index := mongodrv.IndexModel{
+		Keys:    bson.D{{Key: HistoryColId, Value: 1}, {Key: HistoryColSeq, Value: -1}},
+		Options: options.Index().SetName("uidx_" + HistoryColId + "_" + HistoryColSeq).SetUnique(true),
+	}
+	_, err := mc.CreateCollectionIndexes(collectionName, []interface{}{index})
+	return err
+}
+
+// GetHistoryCollection returns name of the collection storing prior versions of BOs, empty if history mode is disabled.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) GetHistoryCollection() string {
+	return dao.historyCollection
+}
+
+// SetHistoryCollection enables history mode (see UniversalDaoHistory), storing prior versions of BOs in the specified
+// collection (see InitMongoHistoryCollection). An empty collection name disables history mode, which is the default.
+//
+// In history mode, Update, Save and Delete archive the version being replaced before writing. The archive and the
+// write are done in the same transaction if transaction mode is enabled on writes (see NewUniversalDaoMongo).
+// Concurrent writes to the same BO may conflict on the history entry's sequence number, in which case one of them
+// fails with godal.ErrGdaoDuplicatedEntry.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) SetHistoryCollection(collectionName string) *UniversalDaoMongo {
+	dao.historyCollection = collectionName
+	return dao
+}
+
+// writeTx writes bo using the write function, archiving the stored version in history mode (see SetHistoryCollection)
+// and recording the change in outbox mode (see SetOutboxCollection). All is done in the same transaction if
+// transaction mode is enabled on writes.
+// unchanged is true if nothing was written because "skip unchanged writes" is enabled and bo is unchanged.
+func (dao *UniversalDaoMongo) writeTx(ctx context.Context, op DaoOp, bo *UniversalBo, gbo godal.IGenericBo,
+	write func(ctx context.Context) (int, error)) (numRows int, unchanged bool, err error) {
+	fn := func(ctx context.Context) error {
+		var prev godal.IGenericBo
+		if op != OpCreate {
+			var err error
+			if prev, err = dao.GdaoFetchOneWithContext(ctx, dao.collectionName, dao.GdaoCreateFilter(dao.collectionName, gbo)); err != nil {
+				return err
+			}
+			if op != OpDelete && dao.skipUnchangedWrites && dao.boCodec().isUnchanged(prev, gbo, bo) {
+				unchanged = true
+				return nil
+			}
+			if prev != nil && dao.historyCollection != "" {
+				if err := dao.archive(ctx, op, bo.RoundTimestamp(clockOrDefault(dao.clock).Now()), prev); err != nil {
+					return err
+				}
+			}
+		}
+		var err error
+		if numRows, err = write(ctx); err != nil || numRows == 0 || dao.outboxCollection == "" {
+			return err
+		}
+		return dao.writeOutbox(ctx, op, bo, prev)
+	}
+	if dao.GetTxModeOnWrite() {
+		err = dao.WrapTransaction(ctx, func(sctx mongodrv.SessionContext) error {
+			return fn(sctx)
+		})
+	} else {
+		err = fn(ctx)
+	}
+	return numRows, unchanged, err
+}
+
+// archive inserts the stored version prev to the history collection.
+func (dao *UniversalDaoMongo) archive(ctx context.Context, op DaoOp, t time.Time, prev godal.IGenericBo) error {
+	id, _ := prev.GboGetAttrUnsafe(FieldId, reddo.TypeString).(string)
+	last, err := dao.GdaoFetchManyWithContext(ctx, dao.historyCollection, godal.MakeFilter(map[string]interface{}{HistoryColId: id}),
+		(&godal.SortingField{FieldName: HistoryColSeq, Descending: true}).ToSortingOpt(), 0, 1)
+	if err != nil {
+		return err
+	}
+	row, err := dao.GetRowMapper().ToRow(dao.collectionName, prev)
+	if err != nil {
+		return err
+	}
+	seq := lastHistorySeq(last, HistoryColSeq) + 1
+	doc := newHistoryRecord(id, seq, op, t, row)
+	doc[MongoColId] = fmt.Sprintf("%s:%d", id, seq)
+	if _, err := dao.MongoInsertOne(ctx, dao.historyCollection, doc); err != nil {
+		if mongodrv.IsDuplicateKeyError(err) {
+			return godal.ErrGdaoDuplicatedEntry
+		}
+		return err
+	}
+	return nil
+}
+
+// fetchHistory loads the history entries of a BO, most recent first.
+func (dao *UniversalDaoMongo) fetchHistory(id string, fromOffset, maxNumRows int) ([]*HistoryEntry, error) {
+	recs, err := dao.GdaoFetchMany(dao.historyCollection, godal.MakeFilter(map[string]interface{}{HistoryColId: id}),
+		(&godal.SortingField{FieldName: HistoryColSeq, Descending: true}).ToSortingOpt(), fromOffset, maxNumRows)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*HistoryEntry, 0, len(recs))
+	for _, rec := range recs {
+		seq, op, t, row := parseHistoryRecord(rec)
+		gbo, err := dao.GetRowMapper().ToBo(dao.collectionName, row)
+		if err != nil {
+			return nil, err
+		}
+		bo, err := dao.toUniversalBo(gbo)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &HistoryEntry{Seq: seq, Op: op, TimeArchived: t, Bo: bo})
+	}
+	return result, nil
+}
+
+// GetHistory implements UniversalDaoHistory.GetHistory.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) GetHistory(id string, fromOffset, maxNumRows int) ([]*HistoryEntry, error) {
+	if dao.historyCollection == "" {
+		return nil, ErrHistoryDisabled
+	}
+	entries, err := dao.fetchHistory(id, fromOffset, maxNumRows)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if err := dao.afterLoad(context.Background(), entry.Bo); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// GetVersionAt implements UniversalDaoHistory.GetVersionAt.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) GetVersionAt(id string, t time.Time) (*UniversalBo, error) {
+	if dao.historyCollection == "" {
+		return nil, ErrHistoryDisabled
+	}
+	current, err := dao.get(context.Background(), id, false)
+	if err != nil {
+		return nil, err
+	}
+	bo, err := versionAt(current, func(fromOffset, maxNumRows int) ([]*HistoryEntry, error) {
+		return dao.fetchHistory(id, fromOffset, maxNumRows)
+	}, t)
+	if err != nil || bo == nil {
+		return nil, err
+	}
+	if err := dao.afterLoad(context.Background(), bo); err != nil {
+		return nil, err
+	}
+	return bo, nil
+}
+
+// RestoreVersion implements UniversalDaoHistory.RestoreVersion.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) RestoreVersion(id string, ref VersionRef) (*UniversalBo, error) {
+	return dao.RestoreVersionContext(context.Background(), id, ref)
+}
+
+// RestoreVersionContext implements UniversalDaoHistory.RestoreVersionContext.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) RestoreVersionContext(ctx context.Context, id string, ref VersionRef) (*UniversalBo, error) {
+	if dao.historyCollection == "" {
+		return nil, ErrHistoryDisabled
+	}
+	return restoreVersion(ctx, dao, dao.clock, func(fromOffset, maxNumRows int) ([]*HistoryEntry, error) {
+		return dao.fetchHistory(id, fromOffset, maxNumRows)
+	}, ref)
+}
@@ -0,0 +1,155 @@
+package henge
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/btnguyen2k/consu/reddo"
+	"github.com/btnguyen2k/godal"
+	prom "github.com/btnguyen2k/prom/mongo"
+	"go.mongodb.org/mongo-driver/bson"
+	mongodrv "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// InitMongoOutboxCollection initializes a MongoDB collection to store ChangeEvents to be relayed, see
+// UniversalDaoMongo.SetOutboxCollection.
+//   - This function creates the specified collection with default settings.
+//   - A unique index on {OutboxColId: 1, OutboxColSeq: -1} and an index on {OutboxColTime: 1, OutboxColId: 1, OutboxColSeq: 1}
+//     are also created.
+//
+// Available since v0.7.0
+func InitMongoOutboxCollection(mc *prom.MongoConnect, collectionName string) error {
+	if err := mc.CreateCollection(collectionName); err != nil {
+		return err
+	}
+	indexes := []interface{}{
+		mongodrv.IndexModel{
+			Keys:    bson.D{{Key: OutboxColId, Value: 1}, {Key: OutboxColSeq, Value: -1}},
+			Options: options.Index().SetName("uidx_" + OutboxColId + "_" + OutboxColSeq).SetUnique(true),
+		},
+		mongodrv.IndexModel{
+			Keys:    bson.D{{Key: OutboxColTime, Value: 1}, {Key: OutboxColId, Value: 1}, {Key: OutboxColSeq, Value: 1}},
+			Options: options.Index().SetName("idx_" + OutboxColTime),
+		},
+	}
+	_, err := mc.CreateCollectionIndexes(collectionName, indexes)
+	return err
+}
+
+// GetOutboxCollection returns name of the collection storing ChangeEvents to be relayed, empty if outbox mode is disabled.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) GetOutboxCollection() string {
+	return dao.outboxCollection
+}
+
+// SetOutboxCollection enables outbox mode, recording a ChangeEvent in the specified collection (see
+// InitMongoOutboxCollection) for each Create, Update, Save or Delete that actually writes. An empty collection name
+// disables outbox mode, which is the default.
+//
+// The event is recorded in the same transaction as the write if transaction mode is enabled on writes (see
+// NewUniversalDaoMongo); otherwise it is recorded right after the write, and is lost if the process stops in between.
+// Recorded events are delivered to an EventSink by an OutboxRelay, the DAO being the relay's Outbox. Before and After
+// of recorded events are stored in JSON format, with the attributes configured in the DAO's FieldEncryptor encrypted.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) SetOutboxCollection(collectionName string) *UniversalDaoMongo {
+	dao.outboxCollection = collectionName
+	return dao
+}
+
+// writeOutbox records the change made to bo in the outbox collection, prev being the replaced version as stored.
+func (dao *UniversalDaoMongo) writeOutbox(ctx context.Context, op DaoOp, bo *UniversalBo, prev godal.IGenericBo) error {
+	id := bo.GetId()
+	last, err := dao.GdaoFetchManyWithContext(ctx, dao.outboxCollection, godal.MakeFilter(map[string]interface{}{OutboxColId: id}),
+		(&godal.SortingField{FieldName: OutboxColSeq, Descending: true}).ToSortingOpt(), 0, 1)
+	if err != nil {
+		return err
+	}
+	var lastTime time.Time
+	if len(last) > 0 && last[0] != nil {
+		lastTime, _ = last[0].GboGetTimeWithLayout(OutboxColTime, time.RFC3339Nano)
+	}
+	// MongoDB stores timestamps at millisecond precision
+	now := clockOrDefault(dao.clock).Now().Truncate(time.Millisecond)
+	seq, t := nextOutboxSeqAndTime(last, OutboxColSeq, lastTime, now)
+	doc := map[string]interface{}{
+		MongoColId:    fmt.Sprintf("%s:%d", id, seq),
+		OutboxColId:   id,
+		OutboxColSeq:  seq,
+		OutboxColOp:   string(op),
+		OutboxColTime: t,
+	}
+	codec := dao.boCodec()
+	if prev != nil {
+		before, err := dao.toUniversalBo(prev)
+		if err != nil {
+			return err
+		}
+		if doc[OutboxColBefore], err = codec.sealBo(before); err != nil {
+			return err
+		}
+	}
+	if op != OpDelete {
+		if doc[OutboxColAfter], err = codec.sealBo(bo); err != nil {
+			return err
+		}
+	}
+	if _, err := dao.MongoInsertOne(ctx, dao.outboxCollection, doc); err != nil {
+		if mongodrv.IsDuplicateKeyError(err) {
+			return godal.ErrGdaoDuplicatedEntry
+		}
+		return err
+	}
+	return nil
+}
+
+// FetchOutbox implements Outbox.FetchOutbox.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) FetchOutbox(ctx context.Context, maxEvents int) ([]*OutboxEvent, error) {
+	if dao.outboxCollection == "" {
+		return nil, ErrOutboxDisabled
+	}
+	sorting := (&godal.SortingOpt{}).Add(&godal.SortingField{FieldName: OutboxColTime},
+		&godal.SortingField{FieldName: OutboxColId}, &godal.SortingField{FieldName: OutboxColSeq})
+	recs, err := dao.GdaoFetchManyWithContext(ctx, dao.outboxCollection, nil, sorting, 0, maxEvents)
+	if err != nil {
+		return nil, err
+	}
+	codec := dao.boCodec()
+	result := make([]*OutboxEvent, 0, len(recs))
+	for _, rec := range recs {
+		id, _ := rec.GboGetAttrUnsafe(OutboxColId, reddo.TypeString).(string)
+		seq, _ := rec.GboGetAttrUnsafe(OutboxColSeq, reddo.TypeUint).(uint64)
+		op, _ := rec.GboGetAttrUnsafe(OutboxColOp, reddo.TypeString).(string)
+		t, _ := rec.GboGetTimeWithLayout(OutboxColTime, time.RFC3339Nano)
+		ev := &ChangeEvent{Op: DaoOp(op), Id: id, Timestamp: t}
+		for col, target := range map[string]**UniversalBo{OutboxColBefore: &ev.Before, OutboxColAfter: &ev.After} {
+			bo, err := codec.openBo(gboGetString(rec, col), dao.defaultUboOpts...)
+			if err != nil {
+				return nil, err
+			}
+			if err := dao.afterLoad(ctx, bo); err != nil {
+				return nil, err
+			}
+			*target = bo
+		}
+		result = append(result, &OutboxEvent{ChangeEvent: ev, Seq: seq})
+	}
+	return result, nil
+}
+
+// AckOutbox implements Outbox.AckOutbox.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoMongo) AckOutbox(ctx context.Context, ev *OutboxEvent) error {
+	if dao.outboxCollection == "" {
+		return ErrOutboxDisabled
+	}
+	filter := godal.MakeFilter(map[string]interface{}{OutboxColId: ev.Id, OutboxColSeq: ev.Seq})
+	_, err := dao.GdaoDeleteManyWithContext(ctx, dao.outboxCollection, filter)
+	return err
+}
@@ -14,24 +14,32 @@ func NewMssqlConnection(url, timezone, driver string, defaultTimeoutMs int, pool
 
 // InitMssqlTable initializes a database table to store henge business objects.
 //   - Table is created as the following: { SqlColId: "NVARCHAR(64)", SqlColData: "NTEXT",
-//     SqlColChecksum: "NVARCHAR(32)", SqlColTimeCreated: "DATETIMEOFFSET", SqlColTimeUpdated: "DATETIMEOFFSET",
+//     SqlColChecksum: "NVARCHAR(80)", SqlColTimeCreated: "DATETIMEOFFSET", SqlColTimeUpdated: "DATETIMEOFFSET",
 //     SqlColTagVersion: "BIGINT" }, plus additional column defined by extraCols parameter.
 //   - This function returns error if the table already existed.
 //   - SqlColId is table's primary key.
 //   - extraCols (nillable) is a map of {col-name:col-type} and is supplied so that table columns other than
 //     core columns are also created.
 //   - extraCols can also be used to override data type of core columns.
+//   - (since v0.7.0) SqlColChecksum is 80-character long to store algorithm-prefixed checksums (see ChecksumAlgorithm);
+//     existing tables created with a 32-character long column should be altered before switching to a non-MD5 algorithm.
+//   - (since v0.7.0) to store data encoded with a binary DataCodec (see UniversalDaoSql.SetDataCodec), SqlColData should be
+//     overridden with a binary type (e.g. "VARBINARY(MAX)") via extraCols.
+//   - (since v0.7.0) audit columns SqlColCreatedBy and SqlColUpdatedBy ("NVARCHAR(255)") are also created, they are used only if
+//     enabled on the DAO (see UniversalDaoSql.SetAuditColumns).
 //   - Other than the database table, no index is created.
 func InitMssqlTable(sqlc *prom.SqlConnect, tableName string, extraCols map[string]string) error {
 	colDef := map[string]string{
 		SqlColId:          "NVARCHAR(64)",
 		SqlColData:        "NTEXT",
-		SqlColChecksum:    "NVARCHAR(32)",
+		SqlColChecksum:    "NVARCHAR(80)",
 		SqlColTimeCreated: "DATETIMEOFFSET",
 		SqlColTimeUpdated: "DATETIMEOFFSET",
 		SqlColTagVersion:  "BIGINT",
+		SqlColCreatedBy:   "NVARCHAR(255)",
+		SqlColUpdatedBy:   "NVARCHAR(255)",
 	}
-	colNames := sqlColumnNames
+	colNames := append(append([]string{}, sqlColumnNames...), sqlAuditColumnNames...)
 	for k, v := range extraCols {
 		colDef[k] = v
 		colNames = append(colNames, k)
@@ -39,3 +47,57 @@ func InitMssqlTable(sqlc *prom.SqlConnect, tableName string, extraCols map[strin
 	pk := []string{SqlColId}
 	return CreateTableSql(sqlc, tableName, false, colDef, colNames, pk)
 }
+
+// InitMssqlHistoryTable initializes a database table to store prior versions of henge business objects, see
+// UniversalDaoSql.SetHistoryTable.
+//   - Table is created with the core columns of InitMssqlTable (SqlColData: "NTEXT"), plus
+//     { SqlColHistorySeq: "BIGINT", SqlColHistoryOp: "NVARCHAR(16)", SqlColHistoryTime: "DATETIMEOFFSET", SqlColHistoryExtras: "NTEXT" }.
+//   - (SqlColId, SqlColHistorySeq) is table's primary key.
+//   - colTypes (nillable) is a map of {col-name:col-type} used to override data type of columns, e.g. SqlColData
+//     should match the type of the same column in the main table. Extra columns of the main table are stored in
+//     column SqlColHistoryExtras.
+//
+// Available since v0.7.0
+func InitMssqlHistoryTable(sqlc *prom.SqlConnect, tableName string, colTypes map[string]string) error {
+	colDef := map[string]string{
+		SqlColId:            "NVARCHAR(64)",
+		SqlColData:          "NTEXT",
+		SqlColChecksum:      "NVARCHAR(80)",
+		SqlColTimeCreated:   "DATETIMEOFFSET",
+		SqlColTimeUpdated:   "DATETIMEOFFSET",
+		SqlColTagVersion:    "BIGINT",
+		SqlColCreatedBy:     "NVARCHAR(255)",
+		SqlColUpdatedBy:     "NVARCHAR(255)",
+		SqlColHistorySeq:    "BIGINT",
+		SqlColHistoryOp:     "NVARCHAR(16)",
+		SqlColHistoryTime:   "DATETIMEOFFSET",
+		SqlColHistoryExtras: "NTEXT",
+	}
+	for k, v := range colTypes {
+		colDef[k] = v
+	}
+	return initSqlHistoryTable(sqlc, tableName, false, colDef)
+}
+
+// InitMssqlOutboxTable initializes a database table to store ChangeEvents to be relayed, see UniversalDaoSql.SetOutboxTable.
+//   - Table is created with columns { SqlColId: "NVARCHAR(64)", SqlColOutboxSeq: "BIGINT",
+//     SqlColOutboxOp: "NVARCHAR(16)", SqlColOutboxTime: "DATETIMEOFFSET", SqlColOutboxBefore: "NTEXT", SqlColOutboxAfter: "NTEXT" }.
+//   - (SqlColId, SqlColOutboxSeq) is table's primary key.
+//   - colTypes (nillable) is a map of {col-name:col-type} used to override data type of columns, e.g. SqlColId
+//     should match the type of the same column in the main table.
+//
+// Available since v0.7.0
+func InitMssqlOutboxTable(sqlc *prom.SqlConnect, tableName string, colTypes map[string]string) error {
+	colDef := map[string]string{
+		SqlColId:           "NVARCHAR(64)",
+		SqlColOutboxSeq:    "BIGINT",
+		SqlColOutboxOp:     "NVARCHAR(16)",
+		SqlColOutboxTime:   "DATETIMEOFFSET",
+		SqlColOutboxBefore: "NTEXT",
+		SqlColOutboxAfter:  "NTEXT",
+	}
+	for k, v := range colTypes {
+		colDef[k] = v
+	}
+	return initSqlOutboxTable(sqlc, tableName, false, colDef)
+}
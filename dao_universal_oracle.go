@@ -14,23 +14,31 @@ func NewOracleConnection(url, timezone, driver string, defaultTimeoutMs int, poo
 
 // InitOracleTable initializes a database table to store henge business objects.
 //   - Table is created "if not exists" as the following: { SqlColId: "NVARCHAR2(64)", SqlColData: "CLOB",
-//     SqlColChecksum: "NVARCHAR2(32)", SqlColTimeCreated: "TIMESTAMP WITH TIME ZONE", SqlColTimeUpdated: "TIMESTAMP WITH TIME ZONE",
+//     SqlColChecksum: "NVARCHAR2(80)", SqlColTimeCreated: "TIMESTAMP WITH TIME ZONE", SqlColTimeUpdated: "TIMESTAMP WITH TIME ZONE",
 //     SqlColTagVersion: "INT" }, plus additional column defined by extraCols parameter.
 //   - SqlColId is table's primary key.
 //   - extraCols (nillable) is a map of {col-name:col-type} and is supplied so that table columns other than
 //     core columns are also created.
 //   - extraCols can also be used to override data type of core columns.
+//   - (since v0.7.0) SqlColChecksum is 80-character long to store algorithm-prefixed checksums (see ChecksumAlgorithm);
+//     existing tables created with a 32-character long column should be altered before switching to a non-MD5 algorithm.
+//   - (since v0.7.0) to store data encoded with a binary DataCodec (see UniversalDaoSql.SetDataCodec), SqlColData should be
+//     overridden with a binary type (e.g. "BLOB") via extraCols.
+//   - (since v0.7.0) audit columns SqlColCreatedBy and SqlColUpdatedBy ("NVARCHAR2(255)") are also created, they are used only if
+//     enabled on the DAO (see UniversalDaoSql.SetAuditColumns).
 //   - Other than the database table, no index is created.
 func InitOracleTable(sqlc *prom.SqlConnect, tableName string, extraCols map[string]string) error {
 	colDef := map[string]string{
 		SqlColId:          "NVARCHAR2(64)",
 		SqlColData:        "CLOB",
-		SqlColChecksum:    "NVARCHAR2(32)",
+		SqlColChecksum:    "NVARCHAR2(80)",
 		SqlColTimeCreated: "TIMESTAMP WITH TIME ZONE",
 		SqlColTimeUpdated: "TIMESTAMP WITH TIME ZONE",
 		SqlColTagVersion:  "INT",
+		SqlColCreatedBy:   "NVARCHAR2(255)",
+		SqlColUpdatedBy:   "NVARCHAR2(255)",
 	}
-	colNames := sqlColumnNames
+	colNames := append(append([]string{}, sqlColumnNames...), sqlAuditColumnNames...)
 	for k, v := range extraCols {
 		colDef[k] = v
 		colNames = append(colNames, k)
@@ -38,3 +46,57 @@ func InitOracleTable(sqlc *prom.SqlConnect, tableName string, extraCols map[stri
 	pk := []string{SqlColId}
 	return CreateTableSql(sqlc, tableName, false, colDef, colNames, pk)
 }
+
+// InitOracleHistoryTable initializes a database table to store prior versions of henge business objects, see
+// UniversalDaoSql.SetHistoryTable.
+//   - Table is created with the core columns of InitOracleTable (SqlColData: "CLOB"), plus
+//     { SqlColHistorySeq: "INT", SqlColHistoryOp: "NVARCHAR2(16)", SqlColHistoryTime: "TIMESTAMP WITH TIME ZONE", SqlColHistoryExtras: "CLOB" }.
+//   - (SqlColId, SqlColHistorySeq) is table's primary key.
+//   - colTypes (nillable) is a map of {col-name:col-type} used to override data type of columns, e.g. SqlColData
+//     should match the type of the same column in the main table. Extra columns of the main table are stored in
+//     column SqlColHistoryExtras.
+//
+// Available since v0.7.0
+func InitOracleHistoryTable(sqlc *prom.SqlConnect, tableName string, colTypes map[string]string) error {
+	colDef := map[string]string{
+		SqlColId:            "NVARCHAR2(64)",
+		SqlColData:          "CLOB",
+		SqlColChecksum:      "NVARCHAR2(80)",
+		SqlColTimeCreated:   "TIMESTAMP WITH TIME ZONE",
+		SqlColTimeUpdated:   "TIMESTAMP WITH TIME ZONE",
+		SqlColTagVersion:    "INT",
+		SqlColCreatedBy:     "NVARCHAR2(255)",
+		SqlColUpdatedBy:     "NVARCHAR2(255)",
+		SqlColHistorySeq:    "INT",
+		SqlColHistoryOp:     "NVARCHAR2(16)",
+		SqlColHistoryTime:   "TIMESTAMP WITH TIME ZONE",
+		SqlColHistoryExtras: "CLOB",
+	}
+	for k, v := range colTypes {
+		colDef[k] = v
+	}
+	return initSqlHistoryTable(sqlc, tableName, false, colDef)
+}
+
+// InitOracleOutboxTable initializes a database table to store ChangeEvents to be relayed, see UniversalDaoSql.SetOutboxTable.
+//   - Table is created with columns { SqlColId: "NVARCHAR2(64)", SqlColOutboxSeq: "INT",
+//     SqlColOutboxOp: "NVARCHAR2(16)", SqlColOutboxTime: "TIMESTAMP WITH TIME ZONE", SqlColOutboxBefore: "CLOB", SqlColOutboxAfter: "CLOB" }.
+//   - (SqlColId, SqlColOutboxSeq) is table's primary key.
+//   - colTypes (nillable) is a map of {col-name:col-type} used to override data type of columns, e.g. SqlColId
+//     should match the type of the same column in the main table.
+//
+// Available since v0.7.0
+func InitOracleOutboxTable(sqlc *prom.SqlConnect, tableName string, colTypes map[string]string) error {
+	colDef := map[string]string{
+		SqlColId:           "NVARCHAR2(64)",
+		SqlColOutboxSeq:    "INT",
+		SqlColOutboxOp:     "NVARCHAR2(16)",
+		SqlColOutboxTime:   "TIMESTAMP WITH TIME ZONE",
+		SqlColOutboxBefore: "CLOB",
+		SqlColOutboxAfter:  "CLOB",
+	}
+	for k, v := range colTypes {
+		colDef[k] = v
+	}
+	return initSqlOutboxTable(sqlc, tableName, false, colDef)
+}
@@ -13,24 +13,34 @@ func NewPgsqlConnection(url, timezone, driver string, defaultTimeoutMs int, pool
 }
 
 // InitPgsqlTable initializes a database table to store henge business objects.
-//   - Table is created "if not exists" as the following: { SqlColId: "VARCHAR(64)", SqlColData: "JSONB", SqlColChecksum: "VARCHAR(32)",
+//   - Table is created "if not exists" as the following: { SqlColId: "VARCHAR(64)", SqlColData: "JSONB", SqlColChecksum: "VARCHAR(80)",
 //     SqlColTimeCreated: "TIMESTAMP WITH TIME ZONE", SqlColTimeUpdated: "TIMESTAMP WITH TIME ZONE",
 //     SqlColTagVersion: "BIGINT" }, plus additional column defined by extraCols parameter.
 //   - SqlColId is table's primary key.
 //   - extraCols (nillable) is a map of {col-name:col-type} and is supplied so that table columns other than
 //     core columns are also created.
 //   - extraCols can also be used to override data type of core columns.
+//   - (since v0.7.0) SqlColChecksum is 80-character long to store algorithm-prefixed checksums (see ChecksumAlgorithm);
+//     existing tables created with a 32-character long column should be altered before switching to a non-MD5 algorithm.
+//   - (since v0.7.0) to store data encoded with a binary DataCodec (see UniversalDaoSql.SetDataCodec), SqlColData should be
+//     overridden with a binary type (e.g. "BYTEA") via extraCols.
+//   - (since v0.7.0) to store compressed data (see UniversalDaoSql.SetDataCompressor), SqlColData should be overridden
+//     with type "TEXT" via extraCols.
+//   - (since v0.7.0) audit columns SqlColCreatedBy and SqlColUpdatedBy ("VARCHAR(255)") are also created, they are used only if
+//     enabled on the DAO (see UniversalDaoSql.SetAuditColumns).
 //   - Other than the database table, no index is created.
 func InitPgsqlTable(sqlc *prom.SqlConnect, tableName string, extraCols map[string]string) error {
 	colDef := map[string]string{
 		SqlColId:          "VARCHAR(64)",
 		SqlColData:        "JSONB",
-		SqlColChecksum:    "VARCHAR(32)",
+		SqlColChecksum:    "VARCHAR(80)",
 		SqlColTimeCreated: "TIMESTAMP WITH TIME ZONE",
 		SqlColTimeUpdated: "TIMESTAMP WITH TIME ZONE",
 		SqlColTagVersion:  "BIGINT",
+		SqlColCreatedBy:   "VARCHAR(255)",
+		SqlColUpdatedBy:   "VARCHAR(255)",
 	}
-	colNames := sqlColumnNames
+	colNames := append(append([]string{}, sqlColumnNames...), sqlAuditColumnNames...)
 	for k, v := range extraCols {
 		colDef[k] = v
 		colNames = append(colNames, k)
@@ -38,3 +48,57 @@ func InitPgsqlTable(sqlc *prom.SqlConnect, tableName string, extraCols map[strin
 	pk := []string{SqlColId}
 	return CreateTableSql(sqlc, tableName, true, colDef, colNames, pk)
 }
+
+// InitPgsqlHistoryTable initializes a database table to store prior versions of henge business objects, see
+// UniversalDaoSql.SetHistoryTable.
+//   - Table is created "if not exists" with the core columns of InitPgsqlTable (SqlColData: "JSONB"), plus
+//     { SqlColHistorySeq: "BIGINT", SqlColHistoryOp: "VARCHAR(16)", SqlColHistoryTime: "TIMESTAMP WITH TIME ZONE", SqlColHistoryExtras: "JSONB" }.
+//   - (SqlColId, SqlColHistorySeq) is table's primary key.
+//   - colTypes (nillable) is a map of {col-name:col-type} used to override data type of columns, e.g. SqlColData
+//     should match the type of the same column in the main table. Extra columns of the main table are stored in
+//     column SqlColHistoryExtras.
+//
+// Available since v0.7.0
+func InitPgsqlHistoryTable(sqlc *prom.SqlConnect, tableName string, colTypes map[string]string) error {
+	colDef := map[string]string{
+		SqlColId:            "VARCHAR(64)",
+		SqlColData:          "JSONB",
+		SqlColChecksum:      "VARCHAR(80)",
+		SqlColTimeCreated:   "TIMESTAMP WITH TIME ZONE",
+		SqlColTimeUpdated:   "TIMESTAMP WITH TIME ZONE",
+		SqlColTagVersion:    "BIGINT",
+		SqlColCreatedBy:     "VARCHAR(255)",
+		SqlColUpdatedBy:     "VARCHAR(255)",
+		SqlColHistorySeq:    "BIGINT",
+		SqlColHistoryOp:     "VARCHAR(16)",
+		SqlColHistoryTime:   "TIMESTAMP WITH TIME ZONE",
+		SqlColHistoryExtras: "JSONB",
+	}
+	for k, v := range colTypes {
+		colDef[k] = v
+	}
+	return initSqlHistoryTable(sqlc, tableName, true, colDef)
+}
+
+// InitPgsqlOutboxTable initializes a database table to store ChangeEvents to be relayed, see UniversalDaoSql.SetOutboxTable.
+//   - Table is created "if not exists" with columns { SqlColId: "VARCHAR(64)", SqlColOutboxSeq: "BIGINT",
+//     SqlColOutboxOp: "VARCHAR(16)", SqlColOutboxTime: "TIMESTAMP WITH TIME ZONE", SqlColOutboxBefore: "TEXT", SqlColOutboxAfter: "TEXT" }.
+//   - (SqlColId, SqlColOutboxSeq) is table's primary key.
+//   - colTypes (nillable) is a map of {col-name:col-type} used to override data type of columns, e.g. SqlColId
+//     should match the type of the same column in the main table.
+//
+// Available since v0.7.0
+func InitPgsqlOutboxTable(sqlc *prom.SqlConnect, tableName string, colTypes map[string]string) error {
+	colDef := map[string]string{
+		SqlColId:           "VARCHAR(64)",
+		SqlColOutboxSeq:    "BIGINT",
+		SqlColOutboxOp:     "VARCHAR(16)",
+		SqlColOutboxTime:   "TIMESTAMP WITH TIME ZONE",
+		SqlColOutboxBefore: "TEXT",
+		SqlColOutboxAfter:  "TEXT",
+	}
+	for k, v := range colTypes {
+		colDef[k] = v
+	}
+	return initSqlOutboxTable(sqlc, tableName, true, colDef)
+}
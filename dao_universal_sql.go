@@ -1,6 +1,9 @@
 package henge
 
 import (
+	"context"
+	gosql "database/sql"
+	"reflect"
 	"time"
 
 	"github.com/btnguyen2k/consu/reddo"
@@ -38,11 +41,51 @@ func buildRowMapperSql(tableName string, extraColNameToFieldMappings map[string]
 		myMapColNameToField[col] = field
 		myMapFieldToColName[field] = col
 	}
-	return &sql.GenericRowMapperSql{
+	return &rowMapperSql{IRowMapper: &sql.GenericRowMapperSql{
 		NameTransformation:          sql.NameTransfLowerCase,
 		GboFieldToColNameTranslator: map[string]map[string]interface{}{tableName: myMapFieldToColName},
 		ColNameToGboFieldTranslator: map[string]map[string]interface{}{tableName: myMapColNameToField},
 		ColumnsListMap:              map[string][]string{tableName: myCols},
+	}}
+}
+
+// rowMapperSql is an implementation of godal.IRowMapper specific for RDBMS/SQL.
+type rowMapperSql struct {
+	godal.IRowMapper
+	auditColumns bool // (since v0.7.0) if true, audit fields are stored in columns SqlColCreatedBy and SqlColUpdatedBy
+}
+
+// ToRow implements godal.IRowMapper.ToRow.
+func (r *rowMapperSql) ToRow(tableName string, bo godal.IGenericBo) (interface{}, error) {
+	row, err := r.IRowMapper.ToRow(tableName, bo)
+	if m, ok := row.(map[string]interface{}); err == nil && ok && m != nil {
+		if data, ok := bo.GboGetAttrUnsafe(FieldData, nil).([]byte); ok {
+			// binary-encoded data (see DataCodec) is stored as-is
+			m[SqlColData] = data
+		}
+		if !r.auditColumns {
+			// the table might not have audit columns
+			delete(m, SqlColCreatedBy)
+			delete(m, SqlColUpdatedBy)
+		}
+	}
+	return row, err
+}
+
+// setAuditColumns enables/disables reading and writing audit columns.
+func (r *rowMapperSql) setAuditColumns(tableName string, enabled bool) {
+	r.auditColumns = enabled
+	if mapper, ok := r.IRowMapper.(*sql.GenericRowMapperSql); ok {
+		cols := make([]string, 0, len(mapper.ColumnsListMap[tableName])+len(sqlAuditColumnNames))
+		for _, col := range mapper.ColumnsListMap[tableName] {
+			if !containsString(sqlAuditColumnNames, col) {
+				cols = append(cols, col)
+			}
+		}
+		if enabled {
+			cols = append(cols, sqlAuditColumnNames...)
+		}
+		mapper.ColumnsListMap[tableName] = cols
 	}
 }
 
@@ -52,6 +95,10 @@ func buildRowMapperSql(tableName string, extraColNameToFieldMappings map[string]
 //       It can be done either in transaction (txModeOnWrite=true) or non-transaction (txModeOnWrite=false) mode.
 //       Recommended setting is "txModeOnWrite=true".
 //   - defaultUboOpts: (since v0.5.7) the default options to be used by the DAO when creating UniversalBo instances.
+//
+// (since v0.7.0) The DAO does not limit the length of BO's id by default, as existing tables may have id columns of any
+// size. For tables created by the Init*Table helpers, SetMaxIdLength(MaxIdLengthSql) has long ids rejected before
+// reaching the database.
 func NewUniversalDaoSql(sqlc *prom.SqlConnect, tableName string, txModeOnWrite bool,
 	extraColNameToFieldMappings map[string]string, defaultUboOpts ...UboOpt) UniversalDao {
 	dao := &UniversalDaoSql{
@@ -80,10 +127,15 @@ const (
 	SqlColTimeUpdated = "ztupdated"
 	// SqlColTagVersion is name of table column to store BO's "tag-version" - a value that can be used for compatibility check or data migration.
 	SqlColTagVersion = "ztversion"
+	// SqlColCreatedBy is name of table column to store the principal who created the BO (since v0.7.0).
+	SqlColCreatedBy = "zcby"
+	// SqlColUpdatedBy is name of table column to store the principal who last modified the BO (since v0.7.0).
+	SqlColUpdatedBy = "zuby"
 )
 
 var (
 	sqlColumnNames       = []string{SqlColId, SqlColData, SqlColTagVersion, SqlColChecksum, SqlColTimeCreated, SqlColTimeUpdated}
+	sqlAuditColumnNames  = []string{SqlColCreatedBy, SqlColUpdatedBy}
 	sqlMapFieldToColName = map[string]interface{}{
 		FieldId:          SqlColId,
 		FieldData:        SqlColData,
@@ -91,6 +143,8 @@ var (
 		FieldChecksum:    SqlColChecksum,
 		FieldTimeCreated: SqlColTimeCreated,
 		FieldTimeUpdated: SqlColTimeUpdated,
+		FieldCreatedBy:   SqlColCreatedBy,
+		FieldUpdatedBy:   SqlColUpdatedBy,
 	}
 	sqlMapColNameToField = map[string]interface{}{
 		SqlColId:          FieldId,
@@ -99,6 +153,8 @@ var (
 		SqlColChecksum:    FieldChecksum,
 		SqlColTimeCreated: FieldTimeCreated,
 		SqlColTimeUpdated: FieldTimeUpdated,
+		SqlColCreatedBy:   FieldCreatedBy,
+		SqlColUpdatedBy:   FieldUpdatedBy,
 	}
 )
 
@@ -131,10 +187,26 @@ func defaultFilterGeneratorSql(_ string, input interface{}) godal.FilterOpt {
 // UniversalDaoSql is SQL-based implementation of UniversalDao.
 type UniversalDaoSql struct {
 	sql.IGenericDaoSql
+	DaoHooks               // (since v0.7.0) lifecycle hooks, see DaoHooks
 	tableName              string
 	funcFilterGeneratorSql FuncFilterGeneratorSql
 	defaultSorting         *godal.SortingOpt
-	defaultUboOpts         []UboOpt // (since v0.5.7) default options used by the DAO to create UniversalBo instances
+	defaultUboOpts         []UboOpt           // (since v0.5.7) default options used by the DAO to create UniversalBo instances
+	skipUnchangedWrites    bool               // (since v0.7.0) if true, Update/Save do not write BOs whose checksum matches the stored one
+	strictChecksum         bool               // (since v0.7.0) if true, loading BOs whose stored checksum mismatches results in ErrChecksumMismatch
+	dataSchemas            dataSchemaRegistry // (since v0.7.0) schemas used to validate BO's data on write
+	fieldEncryptor         *FieldEncryptor    // (since v0.7.0) encrypts/decrypts sensitive attributes
+	dataCompressor         *DataCompressor    // (since v0.7.0) compresses large BO data
+	dataCodec              DataCodec          // (since v0.7.0) encodes BO data in binary form
+	clock                  Clock              // (since v0.7.0) source of the current time for BO's timestamps, nil means SystemClock
+	csumAlgorithm          ChecksumAlgorithm  // (since v0.7.0) algorithm used to calculate checksums of loaded BOs, empty means UboOpt.ChecksumAlgorithm
+	csumCoverage           *ChecksumCoverage  // (since v0.7.0) parts of data and extra attributes covered by BO's checksum, nil means all
+	jsonCodec              JsonCodec          // (since v0.7.0) codec used to parse and encode BO data, nil means DefaultJsonCodec
+	idGenerator            IdGenerator        // (since v0.7.0) generates ids for BOs created with a blank id
+	maxIdLength            int                // (since v0.7.0) maximum length of BO's id, 0 means no limit
+	principalFunc          PrincipalFunc      // (since v0.7.0) extracts the principal filling in BO's audit fields
+	historyTable           string             // (since v0.7.0) table storing prior versions of BOs, empty means history mode is disabled
+	outboxTable            string             // (since v0.7.0) table storing ChangeEvents to be relayed, empty means outbox mode is disabled
 }
 
 // Init should be called to initialize the DAO instance before use.
@@ -213,6 +285,267 @@ func (dao *UniversalDaoSql) SetDefaultSorting(defaultSorting *godal.SortingOpt)
 	return dao
 }
 
+// GetSkipUnchangedWrites returns true if Update/Save skip writing BOs whose checksum matches the stored one.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) GetSkipUnchangedWrites() bool {
+	return dao.skipUnchangedWrites
+}
+
+// SetSkipUnchangedWrites enables/disables skipping no-op writes.
+//
+// If enabled, Update/Save compare the BO's checksum with the stored one and return false without writing if they
+// match (see UniversalBo.WasUnchanged). Update performs a conditional "UPDATE ... WHERE zchecksum<>?" where supported.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) SetSkipUnchangedWrites(value bool) *UniversalDaoSql {
+	dao.skipUnchangedWrites = value
+	return dao
+}
+
+// GetStrictChecksum returns true if the DAO is in strict-load mode.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) GetStrictChecksum() bool {
+	return dao.strictChecksum
+}
+
+// SetStrictChecksum enables/disables strict-load mode.
+//
+// In strict-load mode, Get/GetN/GetAll return ErrChecksumMismatch if a loaded BO's stored checksum does not match its
+// content. Otherwise, mismatching BOs are loaded and flagged (see UniversalBo.IsChecksumMismatched).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) SetStrictChecksum(value bool) *UniversalDaoSql {
+	dao.strictChecksum = value
+	return dao
+}
+
+// GetDataSchema returns the DataSchema used to validate data of BOs with the specified tag-version (nil if none).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) GetDataSchema(tagVersion uint64) *DataSchema {
+	return dao.dataSchemas.lookup(tagVersion)
+}
+
+// SetDataSchema sets the default DataSchema, used to validate data of BOs whose tag-version has no specific schema
+// (see SetDataSchemaForTagVersion). nil disables the default validation.
+//
+// If a schema is configured, Create/Update/Save validate BO's data against it and return a *DataValidationError if
+// the data does not conform. Default values declared in the schema are filled in when a new BO is written.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) SetDataSchema(schema *DataSchema) *UniversalDaoSql {
+	dao.dataSchemas.defaultSchema = schema
+	return dao
+}
+
+// SetDataSchemaForTagVersion sets the DataSchema used to validate data of BOs with the specified tag-version.
+// nil removes the tag-version specific schema.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) SetDataSchemaForTagVersion(tagVersion uint64, schema *DataSchema) *UniversalDaoSql {
+	dao.dataSchemas.setForTagVersion(tagVersion, schema)
+	return dao
+}
+
+// GetFieldEncryptor returns the FieldEncryptor used to encrypt/decrypt sensitive attributes (nil if none).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) GetFieldEncryptor() *FieldEncryptor {
+	return dao.fieldEncryptor
+}
+
+// SetFieldEncryptor sets the FieldEncryptor used to encrypt sensitive attributes in ToGenericBo and decrypt them in
+// ToUniversalBo. BO's checksum is calculated over plaintext values.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) SetFieldEncryptor(fe *FieldEncryptor) *UniversalDaoSql {
+	dao.fieldEncryptor = fe
+	return dao
+}
+
+// GetDataCompressor returns the DataCompressor used to compress BO's data (nil if none).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) GetDataCompressor() *DataCompressor {
+	return dao.dataCompressor
+}
+
+// SetDataCompressor sets the DataCompressor used to compress large BO's data in ToGenericBo. nil disables compression.
+//
+// Compressed data is always decompressed transparently in ToUniversalBo, even if compression has been disabled since.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) SetDataCompressor(dc *DataCompressor) *UniversalDaoSql {
+	dao.dataCompressor = dc
+	return dao
+}
+
+// GetDataCodec returns the DataCodec used to encode BO's data (nil means the default JSON format).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) GetDataCodec() DataCodec {
+	return dao.dataCodec
+}
+
+// SetDataCodec sets the DataCodec used to encode BO's data in ToGenericBo. nil or DataCodecJson means the default JSON format.
+//
+// Data encoded with a binary codec (e.g. DataCodecMsgpack or DataCodecCbor) is stored as []byte, hence the storage
+// must support binary values for the data field (e.g. BYTEA/BLOB column or DynamoDB's B type). Binary-encoded data is
+// neither compressed nor searchable by the storage. Data is always decoded transparently in ToUniversalBo, regardless
+// of the codec currently configured.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) SetDataCodec(codec DataCodec) *UniversalDaoSql {
+	dao.dataCodec = codec
+	return dao
+}
+
+// GetClock returns the Clock used by the DAO for BO's timestamps (nil means SystemClock).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) GetClock() Clock {
+	return dao.clock
+}
+
+// SetClock sets the Clock used by the DAO for BO's timestamps: BOs loaded by the DAO use it (taking precedence over
+// UboOpt.Clock of the default UboOpts), and BOs written by the DAO that have no Clock of their own have their
+// last-updated timestamp bumped with it when their data changes.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) SetClock(clock Clock) *UniversalDaoSql {
+	dao.clock = clock
+	return dao
+}
+
+// GetChecksumAlgorithm returns the ChecksumAlgorithm used by the DAO (empty means the one of the default UboOpts).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) GetChecksumAlgorithm() ChecksumAlgorithm {
+	return dao.csumAlgorithm
+}
+
+// SetChecksumAlgorithm sets the ChecksumAlgorithm used by the DAO: BOs loaded by the DAO use it, taking precedence over
+// UboOpt.ChecksumAlgorithm of the default UboOpts (see RecomputeChecksums to migrate stored checksums). An empty value
+// reverts to the default UboOpts.
+//
+// This function returns ErrUnsupportedChecksumAlgorithm, leaving the DAO unchanged, if alg is not supported.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) SetChecksumAlgorithm(alg ChecksumAlgorithm) error {
+	if alg != "" && !alg.IsSupported() {
+		return ErrUnsupportedChecksumAlgorithm
+	}
+	dao.csumAlgorithm = alg
+	return nil
+}
+
+// GetChecksumCoverage returns the ChecksumCoverage used by the DAO (nil means all data and extra attributes are covered).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) GetChecksumCoverage() *ChecksumCoverage {
+	return dao.csumCoverage
+}
+
+// SetChecksumCoverage sets the ChecksumCoverage used by the DAO: BOs loaded by the DAO use it (taking precedence over
+// UboOpt.ChecksumCoverage of the default UboOpts), and BOs written by the DAO that have no ChecksumCoverage of their own
+// adopt it.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) SetChecksumCoverage(cc *ChecksumCoverage) *UniversalDaoSql {
+	dao.csumCoverage = cc
+	return dao
+}
+
+// GetJsonCodec returns the JsonCodec used by the DAO (nil means DefaultJsonCodec).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) GetJsonCodec() JsonCodec {
+	return dao.jsonCodec
+}
+
+// SetJsonCodec sets the JsonCodec used by the DAO to parse and encode data of the BOs it loads (taking precedence over
+// UboOpt.JsonCodec of the default UboOpts).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) SetJsonCodec(codec JsonCodec) *UniversalDaoSql {
+	dao.jsonCodec = codec
+	return dao
+}
+
+// GetIdGenerator returns the IdGenerator used by the DAO (nil means none).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) GetIdGenerator() IdGenerator {
+	return dao.idGenerator
+}
+
+// SetIdGenerator sets the IdGenerator used by the DAO: Create and Save assign a generated id to BOs having a blank id.
+// Without IdGenerator, writing a BO with a blank id results in ErrEmptyId.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) SetIdGenerator(gen IdGenerator) *UniversalDaoSql {
+	dao.idGenerator = gen
+	return dao
+}
+
+// GetMaxIdLength returns the maximum length (in characters) of BO's id accepted by the DAO (0 means no limit).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) GetMaxIdLength() int {
+	return dao.maxIdLength
+}
+
+// SetMaxIdLength sets the maximum length (in characters) of BO's id accepted by the DAO (0 means no limit): Create and
+// Save return ErrIdTooLong for BOs whose id is longer.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) SetMaxIdLength(value int) *UniversalDaoSql {
+	dao.maxIdLength = value
+	return dao
+}
+
+// GetPrincipalFunc returns the PrincipalFunc used by the DAO to fill in BO's audit fields (nil means none).
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) GetPrincipalFunc() PrincipalFunc {
+	return dao.principalFunc
+}
+
+// SetPrincipalFunc sets the PrincipalFunc used by the DAO to fill in BO's audit fields on writes. Audit fields are
+// stored only if audit columns are enabled, see SetAuditColumns.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) SetPrincipalFunc(fn PrincipalFunc) *UniversalDaoSql {
+	dao.principalFunc = fn
+	return dao
+}
+
+// GetAuditColumns returns true if BO's audit fields are stored in columns SqlColCreatedBy and SqlColUpdatedBy.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) GetAuditColumns() bool {
+	if r, ok := dao.GetRowMapper().(*rowMapperSql); ok {
+		return r.auditColumns
+	}
+	return false
+}
+
+// SetAuditColumns enables/disables storing BO's audit fields in columns SqlColCreatedBy and SqlColUpdatedBy. It is
+// disabled by default, as tables created before v0.7.0 do not have audit columns; tables created by the Init*Table
+// helpers since v0.7.0 do. Existing tables can be altered to add the audit columns before enabling this setting.
+//
+// This setting has no effect on UniversalDaoCosmosdbSql, which stores audit fields as document attributes.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) SetAuditColumns(enabled bool) *UniversalDaoSql {
+	if r, ok := dao.GetRowMapper().(*rowMapperSql); ok {
+		r.setAuditColumns(dao.tableName, enabled)
+	}
+	return dao
+}
+
 // GdaoCreateFilter implements IGenericDao.GdaoCreateFilter.
 func (dao *UniversalDaoSql) GdaoCreateFilter(tableName string, bo godal.IGenericBo) godal.FilterOpt {
 	if dao.funcFilterGeneratorSql == nil {
@@ -222,53 +555,184 @@ func (dao *UniversalDaoSql) GdaoCreateFilter(tableName string, bo godal.IGeneric
 }
 
 // ToUniversalBo transforms godal.IGenericBo to business object.
+//
+// (since v0.7.0) nil is returned if data cannot be decoded/decompressed or the encrypted attributes cannot be decrypted.
 func (dao *UniversalDaoSql) ToUniversalBo(gbo godal.IGenericBo) *UniversalBo {
-	return NewUniversalBoFromGbo(gbo, dao.defaultUboOpts...)
+	bo, _ := dao.toUniversalBo(gbo)
+	return bo
+}
+
+// toUniversalBo transforms godal.IGenericBo to business object, decoding/decompressing data and decrypting the encrypted attributes.
+func (dao *UniversalDaoSql) toUniversalBo(gbo godal.IGenericBo) (*UniversalBo, error) {
+	return dao.boCodec().toUniversalBo(gbo, dao.defaultUboOpts...)
 }
 
 // ToGenericBo transforms business object to godal.IGenericBo.
+//
+// (since v0.7.0) nil is returned if the attributes to be encrypted cannot be encrypted or data cannot be encoded/compressed.
 func (dao *UniversalDaoSql) ToGenericBo(ubo *UniversalBo) godal.IGenericBo {
-	if ubo == nil {
-		return nil
-	}
-	return ubo.ToGenericBo()
+	gbo, _ := dao.toGenericBo(ubo)
+	return gbo
+}
+
+// toGenericBo transforms business object to godal.IGenericBo, encrypting the attributes to be encrypted and encoding/compressing data.
+func (dao *UniversalDaoSql) toGenericBo(ubo *UniversalBo) (godal.IGenericBo, error) {
+	return dao.boCodec().toGenericBo(ubo)
+}
+
+// boCodec returns the settings used to convert BOs to/from godal.IGenericBo.
+func (dao *UniversalDaoSql) boCodec() boCodec {
+	return boCodec{fieldEncryptor: dao.fieldEncryptor, dataCodec: dao.dataCodec, dataCompressor: dao.dataCompressor, clock: dao.clock, csumAlgorithm: dao.csumAlgorithm, csumCoverage: dao.csumCoverage, jsonCodec: dao.jsonCodec}
 }
 
 // Delete implements UniversalDao.Delete.
+//
+// (since v0.7.0) In history mode, the deleted version is archived, see SetHistoryTable.
+//
+// (since v0.7.0) In outbox mode, a ChangeEvent is recorded along with the write, see SetOutboxTable.
 func (dao *UniversalDaoSql) Delete(bo *UniversalBo) (bool, error) {
-	numRows, err := dao.GdaoDelete(dao.tableName, dao.ToGenericBo(bo))
-	return numRows > 0, err
+	return dao.DeleteContext(context.Background(), bo)
+}
+
+// DeleteContext implements UniversalDaoContext.DeleteContext.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) DeleteContext(ctx context.Context, bo *UniversalBo) (bool, error) {
+	if err := dao.run(ctx, HookBeforeDelete, bo); err != nil {
+		return false, err
+	}
+	gbo := dao.ToGenericBo(bo)
+	var numRows int
+	var err error
+	if dao.historyEnabled() || dao.outboxEnabled() {
+		numRows, _, err = dao.writeTx(ctx, OpDelete, bo, gbo, func(ctx context.Context, tx *gosql.Tx) (int, error) {
+			return dao.GdaoDeleteWithTx(ctx, tx, dao.tableName, gbo)
+		})
+	} else {
+		numRows, err = dao.GdaoDeleteWithTx(ctx, nil, dao.tableName, gbo)
+	}
+	return dao.after(ctx, HookAfterDelete, bo, numRows > 0, err)
 }
 
 // Create implements UniversalDao.Create.
+//
+// (since v0.7.0) A BO with a blank id is assigned an id generated by the DAO's IdGenerator, see SetIdGenerator.
+//
+// (since v0.7.0) In outbox mode, a ChangeEvent is recorded along with the write, see SetOutboxTable.
 func (dao *UniversalDaoSql) Create(bo *UniversalBo) (bool, error) {
-	numRows, err := dao.GdaoCreate(dao.tableName, dao.ToGenericBo(bo))
-	return numRows > 0, err
+	return dao.CreateContext(context.Background(), bo)
+}
+
+// CreateContext implements UniversalDaoContext.CreateContext.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) CreateContext(ctx context.Context, bo *UniversalBo) (bool, error) {
+	if err := prepareBoId(bo, dao.idGenerator, dao.maxIdLength, false); err != nil {
+		return false, err
+	}
+	if err := dao.run(ctx, HookBeforeCreate, bo); err != nil {
+		return false, err
+	}
+	stampAudit(bo, dao.principalFunc.principal(ctx), true)
+	if err := dao.dataSchemas.validate(bo, true); err != nil {
+		return false, err
+	}
+	dao.boCodec().prepareWrite(bo)
+	gbo, err := dao.toGenericBo(bo)
+	if err != nil {
+		return false, err
+	}
+	var numRows int
+	if dao.outboxEnabled() {
+		numRows, _, err = dao.writeTx(ctx, OpCreate, bo, gbo, func(ctx context.Context, tx *gosql.Tx) (int, error) {
+			return dao.GdaoCreateWithTx(ctx, tx, dao.tableName, gbo)
+		})
+	} else {
+		numRows, err = dao.GdaoCreateWithTx(ctx, nil, dao.tableName, gbo)
+	}
+	ok, err := afterWrite(bo, numRows > 0, err)
+	return dao.after(ctx, HookAfterCreate, bo, ok, err)
 }
 
 // Get implements UniversalDao.Get.
 func (dao *UniversalDaoSql) Get(id string) (*UniversalBo, error) {
+	return dao.GetContext(context.Background(), id)
+}
+
+// GetContext implements UniversalDaoContext.GetContext.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) GetContext(ctx context.Context, id string) (*UniversalBo, error) {
+	bo, err := dao.get(ctx, id, dao.strictChecksum)
+	if err != nil {
+		return nil, err
+	}
+	if err := dao.afterLoad(ctx, bo); err != nil {
+		return nil, err
+	}
+	return bo, nil
+}
+
+// get loads a BO, returning ErrChecksumMismatch if strict is true and the BO's stored checksum does not match its content.
+func (dao *UniversalDaoSql) get(ctx context.Context, id string, strict bool) (*UniversalBo, error) {
 	filterBo := &UniversalBo{id: id, _dirty: false}
 	filterGbo := dao.ToGenericBo(filterBo)
-	gbo, err := dao.GdaoFetchOne(dao.tableName, dao.GdaoCreateFilter(dao.tableName, filterGbo))
+	gbo, err := dao.GdaoFetchOneWithTx(ctx, nil, dao.tableName, dao.GdaoCreateFilter(dao.tableName, filterGbo))
 	if err != nil {
 		return nil, err
 	}
-	return dao.ToUniversalBo(gbo), nil
+	bo, err := dao.toUniversalBo(gbo)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkLoadedChecksum(bo, strict); err != nil {
+		return nil, err
+	}
+	return bo, nil
 }
 
 // GetN implements UniversalDao.GetN.
 func (dao *UniversalDaoSql) GetN(fromOffset, maxNumRows int, filter godal.FilterOpt, sorting *godal.SortingOpt) ([]*UniversalBo, error) {
+	return dao.GetNContext(context.Background(), fromOffset, maxNumRows, filter, sorting)
+}
+
+// GetNContext implements UniversalDaoContext.GetNContext.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) GetNContext(ctx context.Context, fromOffset, maxNumRows int, filter godal.FilterOpt, sorting *godal.SortingOpt) ([]*UniversalBo, error) {
+	result, err := dao.getN(ctx, fromOffset, maxNumRows, filter, sorting, dao.strictChecksum)
+	if err != nil {
+		return nil, err
+	}
+	if err := dao.afterLoad(ctx, result...); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// scanSorting returns the sorting by id used to scan BOs, overriding the default sorting.
+func (dao *UniversalDaoSql) scanSorting() *godal.SortingOpt {
+	return (&godal.SortingField{FieldName: FieldId}).ToSortingOpt()
+}
+
+// getN loads BOs, returning ErrChecksumMismatch if strict is true and a loaded BO's stored checksum does not match its content.
+func (dao *UniversalDaoSql) getN(ctx context.Context, fromOffset, maxNumRows int, filter godal.FilterOpt, sorting *godal.SortingOpt, strict bool) ([]*UniversalBo, error) {
 	if sorting == nil {
 		sorting = dao.defaultSorting
 	}
-	gboList, err := dao.GdaoFetchMany(dao.tableName, filter, sorting, fromOffset, maxNumRows)
+	gboList, err := dao.GdaoFetchManyWithTx(ctx, nil, dao.tableName, filter, sorting, fromOffset, maxNumRows)
 	if err != nil {
 		return nil, err
 	}
 	result := make([]*UniversalBo, 0)
 	for _, gbo := range gboList {
-		bo := dao.ToUniversalBo(gbo)
+		bo, err := dao.toUniversalBo(gbo)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkLoadedChecksum(bo, strict); err != nil {
+			return nil, err
+		}
 		result = append(result, bo)
 	}
 	return result, nil
@@ -276,21 +740,195 @@ func (dao *UniversalDaoSql) GetN(fromOffset, maxNumRows int, filter godal.Filter
 
 // GetAll implements UniversalDao.GetAll.
 func (dao *UniversalDaoSql) GetAll(filter godal.FilterOpt, sorting *godal.SortingOpt) ([]*UniversalBo, error) {
-	return dao.GetN(0, 0, filter, sorting)
+	return dao.GetAllContext(context.Background(), filter, sorting)
+}
+
+// GetAllContext implements UniversalDaoContext.GetAllContext.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) GetAllContext(ctx context.Context, filter godal.FilterOpt, sorting *godal.SortingOpt) ([]*UniversalBo, error) {
+	return dao.GetNContext(ctx, 0, 0, filter, sorting)
+}
+
+// sqlConditionalUpdater is implemented by godal's SQL-based generic DAOs that can execute UPDATE statements with custom filters.
+type sqlConditionalUpdater interface {
+	BuildFilter(tableName string, filter godal.FilterOpt) (sql.IFilter, error)
+	SqlUpdate(ctx context.Context, tx *gosql.Tx, table string, colsAndVals map[string]interface{}, filter sql.IFilter) (gosql.Result, error)
+	IsErrorDuplicatedEntry(err error) bool
+}
+
+// updateIfChanged updates the stored record only if its checksum differs from the BO's one.
+//
+// If the BO's checksum does not cover all of its content, the stored content is compared too, see boCodec.isUnchanged.
+func (dao *UniversalDaoSql) updateIfChanged(ctx context.Context, bo *UniversalBo, gbo godal.IGenericBo) (bool, error) {
+	idFilter := dao.GdaoCreateFilter(dao.tableName, gbo)
+	updater, ok := dao.IGenericDaoSql.(sqlConditionalUpdater)
+	if !ok || !bo.GetChecksumCoverage().coversAll() {
+		// conditional update is not supported or not sufficient: fallback to "read, compare then write"
+		existing, err := dao.GdaoFetchOneWithTx(ctx, nil, dao.tableName, idFilter)
+		if err != nil {
+			return false, err
+		}
+		if dao.boCodec().isUnchanged(existing, gbo, bo) {
+			return unchangedWrite(bo)
+		}
+		numRows, err := dao.GdaoUpdateWithTx(ctx, nil, dao.tableName, gbo)
+		return afterWrite(bo, numRows > 0, err)
+	}
+
+	csumFilter := &godal.FilterOptFieldOpValue{FieldName: FieldChecksum, Operator: godal.FilterOpNotEqual, Value: gbo.GboGetAttrUnsafe(FieldChecksum, reddo.TypeString)}
+	filter, err := updater.BuildFilter(dao.tableName, (&godal.FilterOptAnd{}).Add(idFilter).Add(csumFilter))
+	if err != nil {
+		return false, err
+	}
+	row, err := dao.GetRowMapper().ToRow(dao.tableName, gbo)
+	if err != nil {
+		return false, err
+	}
+	colsAndVals, err := reddo.ToMap(row, reflect.TypeOf(map[string]interface{}{}))
+	if err != nil {
+		return false, err
+	}
+	result, err := updater.SqlUpdate(ctx, nil, dao.tableName, colsAndVals.(map[string]interface{}), filter)
+	if err != nil {
+		if updater.IsErrorDuplicatedEntry(err) {
+			return false, godal.ErrGdaoDuplicatedEntry
+		}
+		return false, err
+	}
+	numRows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if numRows == 0 {
+		// no row updated: either the record does not exist or it is unchanged
+		existing, err := dao.GdaoFetchOneWithTx(ctx, nil, dao.tableName, idFilter)
+		if err != nil {
+			return false, err
+		}
+		if existing != nil {
+			return unchangedWrite(bo)
+		}
+	}
+	return afterWrite(bo, numRows > 0, nil)
 }
 
 // Update implements UniversalDao.Update.
+//
+// (since v0.7.0) If "skip unchanged writes" is enabled and the stored record has the same checksum as bo,
+// this function returns (false, nil) without writing, see UniversalBo.WasUnchanged.
+//
+// (since v0.7.0) In history mode, the replaced version is archived, see SetHistoryTable.
+//
+// (since v0.7.0) In outbox mode, a ChangeEvent is recorded along with the write, see SetOutboxTable.
 func (dao *UniversalDaoSql) Update(bo *UniversalBo) (bool, error) {
-	numRows, err := dao.GdaoUpdate(dao.tableName, dao.ToGenericBo(bo))
-	return numRows > 0, err
+	return dao.UpdateContext(context.Background(), bo)
+}
+
+// UpdateContext implements UniversalDaoContext.UpdateContext.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) UpdateContext(ctx context.Context, bo *UniversalBo) (bool, error) {
+	if err := dao.run(ctx, HookBeforeUpdate, bo); err != nil {
+		return false, err
+	}
+	stampAudit(bo, dao.principalFunc.principal(ctx), false)
+	if err := dao.dataSchemas.validate(bo, false); err != nil {
+		return false, err
+	}
+	dao.boCodec().prepareWrite(bo)
+	gbo, err := dao.toGenericBo(bo)
+	if err != nil {
+		return false, err
+	}
+	var ok bool
+	if dao.historyEnabled() || dao.outboxEnabled() {
+		numRows, unchanged, e := dao.writeTx(ctx, OpUpdate, bo, gbo, func(ctx context.Context, tx *gosql.Tx) (int, error) {
+			return dao.GdaoUpdateWithTx(ctx, tx, dao.tableName, gbo)
+		})
+		if unchanged {
+			ok, err = unchangedWrite(bo)
+		} else {
+			ok, err = afterWrite(bo, numRows > 0, e)
+		}
+	} else if dao.skipUnchangedWrites {
+		ok, err = dao.updateIfChanged(ctx, bo, gbo)
+	} else {
+		numRows, e := dao.GdaoUpdateWithTx(ctx, nil, dao.tableName, gbo)
+		ok, err = afterWrite(bo, numRows > 0, e)
+	}
+	return dao.after(ctx, HookAfterUpdate, bo, ok, err)
+}
+
+// gdaoSave saves gbo to the table within ctx, wrapped in a transaction if transaction mode is enabled on write operations.
+func (dao *UniversalDaoSql) gdaoSave(ctx context.Context, gbo godal.IGenericBo) (int, error) {
+	if !dao.GetTxModeOnWrite() {
+		return dao.GdaoSaveWithTx(ctx, nil, dao.tableName, gbo)
+	}
+	var numRows int
+	err := dao.WrapTransaction(ctx, func(ctx context.Context, tx *gosql.Tx) error {
+		var e error
+		numRows, e = dao.GdaoSaveWithTx(ctx, tx, dao.tableName, gbo)
+		return e
+	})
+	return numRows, err
 }
 
 // Save implements UniversalDao.Save.
+//
+// (since v0.7.0) A BO with a blank id is assigned an id generated by the DAO's IdGenerator, see SetIdGenerator.
+//
+// (since v0.7.0) If "skip unchanged writes" is enabled and the stored record has the same checksum as bo,
+// this function returns (false, existing, nil) without writing, see UniversalBo.WasUnchanged.
+//
+// (since v0.7.0) In history mode, the replaced version is archived, see SetHistoryTable.
+//
+// (since v0.7.0) In outbox mode, a ChangeEvent is recorded along with the write, see SetOutboxTable.
 func (dao *UniversalDaoSql) Save(bo *UniversalBo) (bool, *UniversalBo, error) {
-	existing, err := dao.Get(bo.GetId())
+	return dao.SaveContext(context.Background(), bo)
+}
+
+// SaveContext implements UniversalDaoContext.SaveContext.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) SaveContext(ctx context.Context, bo *UniversalBo) (bool, *UniversalBo, error) {
+	if err := prepareBoId(bo, dao.idGenerator, dao.maxIdLength, false); err != nil {
+		return false, nil, err
+	}
+	if err := dao.run(ctx, HookBeforeSave, bo); err != nil {
+		return false, nil, err
+	}
+	existing, err := dao.get(ctx, bo.GetId(), false)
 	if err != nil {
 		return false, nil, err
 	}
-	numRows, err := dao.GdaoSave(dao.tableName, dao.ToGenericBo(bo))
-	return numRows > 0, existing, err
+	stampAuditOnSave(bo, existing, dao.principalFunc.principal(ctx))
+	if err := dao.dataSchemas.validate(bo, existing == nil); err != nil {
+		return false, existing, err
+	}
+	dao.boCodec().prepareWrite(bo)
+	gbo, err := dao.toGenericBo(bo)
+	if err != nil {
+		return false, existing, err
+	}
+	if dao.skipUnchangedWrites && isUnchanged(dao.ToGenericBo(existing), gbo) && isUnchangedContent(existing, bo) {
+		ok, err := unchangedWrite(bo)
+		return ok, existing, err
+	}
+	var numRows int
+	if dao.historyEnabled() || dao.outboxEnabled() {
+		var unchanged bool
+		numRows, unchanged, err = dao.writeTx(ctx, OpSave, bo, gbo, func(ctx context.Context, tx *gosql.Tx) (int, error) {
+			return dao.GdaoSaveWithTx(ctx, tx, dao.tableName, gbo)
+		})
+		if unchanged {
+			ok, err := unchangedWrite(bo)
+			return ok, existing, err
+		}
+	} else {
+		numRows, err = dao.gdaoSave(ctx, gbo)
+	}
+	ok, err := afterWrite(bo, numRows > 0, err)
+	ok, err = dao.after(ctx, HookAfterSave, bo, ok, err)
+	return ok, existing, err
 }
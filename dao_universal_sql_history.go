@@ -0,0 +1,279 @@
+package henge
+
+import (
+	"context"
+	gosql "database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/btnguyen2k/consu/reddo"
+	"github.com/btnguyen2k/godal"
+	prom "github.com/btnguyen2k/prom/sql"
+)
+
+const (
+	// SqlColHistorySeq is name of history table column to store the sequence number of the history entry (since v0.7.0).
+	SqlColHistorySeq = "zhseq"
+	// SqlColHistoryOp is name of history table column to store the operation that replaced the version (since v0.7.0).
+	SqlColHistoryOp = "zhop"
+	// SqlColHistoryTime is name of history table column to store the time the version was archived (since v0.7.0).
+	SqlColHistoryTime = "zhtime"
+	// SqlColHistoryExtras is name of history table column to store the version's extra columns in JSON format (since v0.7.0).
+	SqlColHistoryExtras = "zhextras"
+)
+
+var (
+	// sqlHistoryBoColumnNames are columns of the main table copied as-is to the history table; other columns are
+	// stored in column SqlColHistoryExtras.
+	sqlHistoryBoColumnNames = append(append([]string{}, sqlColumnNames...), sqlAuditColumnNames...)
+	sqlHistoryColumnNames   = append(append([]string{}, sqlHistoryBoColumnNames...), SqlColHistorySeq, SqlColHistoryOp, SqlColHistoryTime, SqlColHistoryExtras)
+)
+
+// sqlInserter is implemented by godal's SQL-based generic DAOs that can execute INSERT statements on arbitrary tables.
+type sqlInserter interface {
+	SqlInsert(ctx context.Context, tx *gosql.Tx, table string, colsAndVals map[string]interface{}) (gosql.Result, error)
+}
+
+// GetHistoryTable returns name of the table storing prior versions of BOs, empty if history mode is disabled.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) GetHistoryTable() string {
+	return dao.historyTable
+}
+
+// SetHistoryTable enables history mode (see UniversalDaoHistory), storing prior versions of BOs in the specified table
+// (see InitSqliteHistoryTable, InitMysqlHistoryTable, InitPgsqlHistoryTable, InitMssqlHistoryTable and
+// InitOracleHistoryTable). An empty table name disables history mode, which is the default.
+//
+// In history mode, Update, Save and Delete archive the version being replaced in the same transaction as the write.
+// Concurrent writes to the same BO may conflict on the history entry's sequence number, in which case one of them
+// fails with godal.ErrGdaoDuplicatedEntry and nothing is written.
+//
+// History mode is not supported by UniversalDaoCosmosdbSql, see UniversalDaoCosmosdbSql.SetHistoryTable.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) SetHistoryTable(tableName string) *UniversalDaoSql {
+	dao.historyTable = tableName
+	return dao
+}
+
+// historyEnabled returns true if the DAO is in history mode.
+func (dao *UniversalDaoSql) historyEnabled() bool {
+	return dao.historyTable != "" && dao.GetSqlFlavor() != prom.FlavorCosmosDb
+}
+
+// checkHistory returns ErrHistoryUnsupported if the DAO does not support history mode, ErrHistoryDisabled if history
+// mode is not enabled.
+func (dao *UniversalDaoSql) checkHistory() error {
+	if dao.GetSqlFlavor() == prom.FlavorCosmosDb {
+		return ErrHistoryUnsupported
+	}
+	if !dao.historyEnabled() {
+		return ErrHistoryDisabled
+	}
+	return nil
+}
+
+// withTx runs fn in a transaction, which is committed if fn succeeds and rolled back otherwise.
+func (dao *UniversalDaoSql) withTx(ctx context.Context, fn func(ctx context.Context, tx *gosql.Tx) error) error {
+	tx, err := dao.StartTx(ctx)
+	if err != nil {
+		return err
+	}
+	if err := fn(ctx, tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// writeTx writes bo using the write function in one transaction, archiving the stored version in history mode (see
+// SetHistoryTable) and recording the change in outbox mode (see SetOutboxTable).
+// unchanged is true if nothing was written because "skip unchanged writes" is enabled and bo is unchanged.
+func (dao *UniversalDaoSql) writeTx(ctx context.Context, op DaoOp, bo *UniversalBo, gbo godal.IGenericBo,
+	write func(ctx context.Context, tx *gosql.Tx) (int, error)) (numRows int, unchanged bool, err error) {
+	err = dao.withTx(ctx, func(ctx context.Context, tx *gosql.Tx) error {
+		var prev godal.IGenericBo
+		if op != OpCreate {
+			var err error
+			if prev, err = dao.GdaoFetchOneWithTx(ctx, tx, dao.tableName, dao.GdaoCreateFilter(dao.tableName, gbo)); err != nil {
+				return err
+			}
+			if op != OpDelete && dao.skipUnchangedWrites && dao.boCodec().isUnchanged(prev, gbo, bo) {
+				unchanged = true
+				return nil
+			}
+			if prev != nil && dao.historyEnabled() {
+				if err := dao.archive(ctx, tx, op, bo.RoundTimestamp(clockOrDefault(dao.clock).Now()), prev); err != nil {
+					return err
+				}
+			}
+		}
+		var err error
+		if numRows, err = write(ctx, tx); err != nil || numRows == 0 || !dao.outboxEnabled() {
+			return err
+		}
+		return dao.writeOutbox(ctx, tx, op, bo, prev)
+	})
+	return numRows, unchanged, err
+}
+
+// archive inserts the stored version prev to the history table.
+func (dao *UniversalDaoSql) archive(ctx context.Context, tx *gosql.Tx, op DaoOp, t time.Time, prev godal.IGenericBo) error {
+	id := prev.GboGetAttrUnsafe(FieldId, reddo.TypeString)
+	last, err := dao.GdaoFetchManyWithTx(ctx, tx, dao.historyTable, godal.MakeFilter(map[string]interface{}{SqlColId: id}),
+		(&godal.SortingField{FieldName: SqlColHistorySeq, Descending: true}).ToSortingOpt(), 0, 1)
+	if err != nil {
+		return err
+	}
+	row, err := dao.GetRowMapper().ToRow(dao.tableName, prev)
+	if err != nil {
+		return err
+	}
+	colsAndVals := map[string]interface{}{
+		SqlColHistorySeq:  lastHistorySeq(last, SqlColHistorySeq) + 1,
+		SqlColHistoryOp:   string(op),
+		SqlColHistoryTime: t,
+	}
+	extras := make(map[string]interface{})
+	if m, ok := row.(map[string]interface{}); ok {
+		for col, v := range m {
+			if containsString(sqlHistoryBoColumnNames, col) {
+				colsAndVals[col] = v
+			} else {
+				extras[col] = v
+			}
+		}
+	}
+	if len(extras) > 0 {
+		js, err := json.Marshal(extras)
+		if err != nil {
+			return err
+		}
+		colsAndVals[SqlColHistoryExtras] = string(js)
+	}
+	inserter, ok := dao.IGenericDaoSql.(sqlInserter)
+	if !ok {
+		return ErrHistoryDisabled
+	}
+	if _, err := inserter.SqlInsert(ctx, tx, dao.historyTable, colsAndVals); err != nil {
+		if dao.IsErrorDuplicatedEntry(err) {
+			return godal.ErrGdaoDuplicatedEntry
+		}
+		return err
+	}
+	return nil
+}
+
+// fetchHistory loads the history entries of a BO, most recent first.
+func (dao *UniversalDaoSql) fetchHistory(id string, fromOffset, maxNumRows int) ([]*HistoryEntry, error) {
+	recs, err := dao.GdaoFetchMany(dao.historyTable, godal.MakeFilter(map[string]interface{}{SqlColId: id}),
+		(&godal.SortingField{FieldName: SqlColHistorySeq, Descending: true}).ToSortingOpt(), fromOffset, maxNumRows)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*HistoryEntry, 0, len(recs))
+	for _, rec := range recs {
+		entry, err := dao.toHistoryEntry(rec)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, entry)
+	}
+	return result, nil
+}
+
+// toHistoryEntry transforms a row of the history table to HistoryEntry.
+func (dao *UniversalDaoSql) toHistoryEntry(rec godal.IGenericBo) (*HistoryEntry, error) {
+	row := make(map[string]interface{})
+	if js := gboGetString(rec, SqlColHistoryExtras); js != "" {
+		if err := json.Unmarshal([]byte(js), &row); err != nil {
+			return nil, err
+		}
+	}
+	for _, col := range sqlHistoryBoColumnNames {
+		if v := rec.GboGetAttrUnsafe(col, nil); v != nil {
+			row[col] = v
+		}
+	}
+	gbo, err := dao.GetRowMapper().ToBo(dao.tableName, row)
+	if err != nil {
+		return nil, err
+	}
+	bo, err := dao.toUniversalBo(gbo)
+	if err != nil {
+		return nil, err
+	}
+	seq, _ := rec.GboGetAttrUnsafe(SqlColHistorySeq, reddo.TypeUint).(uint64)
+	op, _ := rec.GboGetAttrUnsafe(SqlColHistoryOp, reddo.TypeString).(string)
+	t, _ := rec.GboGetTimeWithLayout(SqlColHistoryTime, _extractTimeLayout(dao.defaultUboOpts...))
+	return &HistoryEntry{Seq: seq, Op: DaoOp(op), TimeArchived: t, Bo: bo}, nil
+}
+
+// GetHistory implements UniversalDaoHistory.GetHistory.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) GetHistory(id string, fromOffset, maxNumRows int) ([]*HistoryEntry, error) {
+	if err := dao.checkHistory(); err != nil {
+		return nil, err
+	}
+	entries, err := dao.fetchHistory(id, fromOffset, maxNumRows)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if err := dao.afterLoad(context.Background(), entry.Bo); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// GetVersionAt implements UniversalDaoHistory.GetVersionAt.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) GetVersionAt(id string, t time.Time) (*UniversalBo, error) {
+	if err := dao.checkHistory(); err != nil {
+		return nil, err
+	}
+	current, err := dao.get(context.Background(), id, false)
+	if err != nil {
+		return nil, err
+	}
+	bo, err := versionAt(current, func(fromOffset, maxNumRows int) ([]*HistoryEntry, error) {
+		return dao.fetchHistory(id, fromOffset, maxNumRows)
+	}, t)
+	if err != nil || bo == nil {
+		return nil, err
+	}
+	if err := dao.afterLoad(context.Background(), bo); err != nil {
+		return nil, err
+	}
+	return bo, nil
+}
+
+// RestoreVersion implements UniversalDaoHistory.RestoreVersion.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) RestoreVersion(id string, ref VersionRef) (*UniversalBo, error) {
+	return dao.RestoreVersionContext(context.Background(), id, ref)
+}
+
+// RestoreVersionContext implements UniversalDaoHistory.RestoreVersionContext.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) RestoreVersionContext(ctx context.Context, id string, ref VersionRef) (*UniversalBo, error) {
+	if err := dao.checkHistory(); err != nil {
+		return nil, err
+	}
+	return restoreVersion(ctx, dao, dao.clock, func(fromOffset, maxNumRows int) ([]*HistoryEntry, error) {
+		return dao.fetchHistory(id, fromOffset, maxNumRows)
+	}, ref)
+}
+
+// initSqlHistoryTable creates a history table with the specified column definitions, see SetHistoryTable.
+func initSqlHistoryTable(sqlc *prom.SqlConnect, tableName string, ifNotExist bool, colDef map[string]string) error {
+	colNames := append([]string{}, sqlHistoryColumnNames...)
+	pk := []string{SqlColId, SqlColHistorySeq}
+	return CreateTableSql(sqlc, tableName, ifNotExist, colDef, colNames, pk)
+}
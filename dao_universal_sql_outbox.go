@@ -0,0 +1,158 @@
+package henge
+
+import (
+	"context"
+	gosql "database/sql"
+	"time"
+
+	"github.com/btnguyen2k/consu/reddo"
+	"github.com/btnguyen2k/godal"
+	prom "github.com/btnguyen2k/prom/sql"
+)
+
+const (
+	// SqlColOutboxSeq is name of outbox table column to store the event's sequence number among the pending events of the BO (since v0.7.0).
+	SqlColOutboxSeq = "zoseq"
+	// SqlColOutboxOp is name of outbox table column to store the operation (since v0.7.0).
+	SqlColOutboxOp = "zoop"
+	// SqlColOutboxTime is name of outbox table column to store the event's timestamp (since v0.7.0).
+	SqlColOutboxTime = "zotime"
+	// SqlColOutboxBefore is name of outbox table column to store the BO before the change, in JSON format (since v0.7.0).
+	SqlColOutboxBefore = "zobefore"
+	// SqlColOutboxAfter is name of outbox table column to store the BO after the change, in JSON format (since v0.7.0).
+	SqlColOutboxAfter = "zoafter"
+)
+
+var sqlOutboxColumnNames = []string{SqlColId, SqlColOutboxSeq, SqlColOutboxOp, SqlColOutboxTime, SqlColOutboxBefore, SqlColOutboxAfter}
+
+// GetOutboxTable returns name of the table storing ChangeEvents to be relayed, empty if outbox mode is disabled.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) GetOutboxTable() string {
+	return dao.outboxTable
+}
+
+// SetOutboxTable enables outbox mode, recording a ChangeEvent in the specified table (see InitSqliteOutboxTable,
+// InitMysqlOutboxTable, InitPgsqlOutboxTable, InitMssqlOutboxTable and InitOracleOutboxTable) for each Create, Update,
+// Save or Delete that actually writes. An empty table name disables outbox mode, which is the default.
+//
+// In outbox mode, the event is recorded in the same transaction as the write: it is recorded if and only if the write
+// is committed. Recorded events are delivered to an EventSink by an OutboxRelay, the DAO being the relay's Outbox.
+// Before and After of recorded events are stored in JSON format, with the attributes configured in the DAO's
+// FieldEncryptor encrypted.
+//
+// Outbox mode is not supported by UniversalDaoCosmosdbSql.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) SetOutboxTable(tableName string) *UniversalDaoSql {
+	dao.outboxTable = tableName
+	return dao
+}
+
+// outboxEnabled returns true if the DAO is in outbox mode.
+func (dao *UniversalDaoSql) outboxEnabled() bool {
+	return dao.outboxTable != "" && dao.GetSqlFlavor() != prom.FlavorCosmosDb
+}
+
+// writeOutbox records the change made to bo in the outbox table, prev being the replaced version as stored.
+func (dao *UniversalDaoSql) writeOutbox(ctx context.Context, tx *gosql.Tx, op DaoOp, bo *UniversalBo, prev godal.IGenericBo) error {
+	id := bo.GetId()
+	last, err := dao.GdaoFetchManyWithTx(ctx, tx, dao.outboxTable, godal.MakeFilter(map[string]interface{}{SqlColId: id}),
+		(&godal.SortingField{FieldName: SqlColOutboxSeq, Descending: true}).ToSortingOpt(), 0, 1)
+	if err != nil {
+		return err
+	}
+	layout := _extractTimeLayout(dao.defaultUboOpts...)
+	var lastTime time.Time
+	if len(last) > 0 && last[0] != nil {
+		lastTime, _ = last[0].GboGetTimeWithLayout(SqlColOutboxTime, layout)
+	}
+	seq, t := nextOutboxSeqAndTime(last, SqlColOutboxSeq, lastTime, bo.RoundTimestamp(clockOrDefault(dao.clock).Now()))
+	colsAndVals := map[string]interface{}{
+		SqlColId:         id,
+		SqlColOutboxSeq:  seq,
+		SqlColOutboxOp:   string(op),
+		SqlColOutboxTime: t,
+	}
+	codec := dao.boCodec()
+	if prev != nil {
+		before, err := dao.toUniversalBo(prev)
+		if err != nil {
+			return err
+		}
+		if colsAndVals[SqlColOutboxBefore], err = codec.sealBo(before); err != nil {
+			return err
+		}
+	}
+	if op != OpDelete {
+		if colsAndVals[SqlColOutboxAfter], err = codec.sealBo(bo); err != nil {
+			return err
+		}
+	}
+	inserter, ok := dao.IGenericDaoSql.(sqlInserter)
+	if !ok {
+		return ErrOutboxDisabled
+	}
+	if _, err := inserter.SqlInsert(ctx, tx, dao.outboxTable, colsAndVals); err != nil {
+		if dao.IsErrorDuplicatedEntry(err) {
+			return godal.ErrGdaoDuplicatedEntry
+		}
+		return err
+	}
+	return nil
+}
+
+// FetchOutbox implements Outbox.FetchOutbox.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) FetchOutbox(ctx context.Context, maxEvents int) ([]*OutboxEvent, error) {
+	if !dao.outboxEnabled() {
+		return nil, ErrOutboxDisabled
+	}
+	sorting := (&godal.SortingOpt{}).Add(&godal.SortingField{FieldName: SqlColOutboxTime},
+		&godal.SortingField{FieldName: SqlColId}, &godal.SortingField{FieldName: SqlColOutboxSeq})
+	recs, err := dao.GdaoFetchManyWithTx(ctx, nil, dao.outboxTable, nil, sorting, 0, maxEvents)
+	if err != nil {
+		return nil, err
+	}
+	codec := dao.boCodec()
+	layout := _extractTimeLayout(dao.defaultUboOpts...)
+	result := make([]*OutboxEvent, 0, len(recs))
+	for _, rec := range recs {
+		id, _ := rec.GboGetAttrUnsafe(SqlColId, reddo.TypeString).(string)
+		seq, _ := rec.GboGetAttrUnsafe(SqlColOutboxSeq, reddo.TypeUint).(uint64)
+		op, _ := rec.GboGetAttrUnsafe(SqlColOutboxOp, reddo.TypeString).(string)
+		t, _ := rec.GboGetTimeWithLayout(SqlColOutboxTime, layout)
+		ev := &ChangeEvent{Op: DaoOp(op), Id: id, Timestamp: t}
+		for col, target := range map[string]**UniversalBo{SqlColOutboxBefore: &ev.Before, SqlColOutboxAfter: &ev.After} {
+			bo, err := codec.openBo(gboGetString(rec, col), dao.defaultUboOpts...)
+			if err != nil {
+				return nil, err
+			}
+			if err := dao.afterLoad(ctx, bo); err != nil {
+				return nil, err
+			}
+			*target = bo
+		}
+		result = append(result, &OutboxEvent{ChangeEvent: ev, Seq: seq})
+	}
+	return result, nil
+}
+
+// AckOutbox implements Outbox.AckOutbox.
+//
+// Available since v0.7.0
+func (dao *UniversalDaoSql) AckOutbox(ctx context.Context, ev *OutboxEvent) error {
+	if !dao.outboxEnabled() {
+		return ErrOutboxDisabled
+	}
+	filter := godal.MakeFilter(map[string]interface{}{SqlColId: ev.Id, SqlColOutboxSeq: ev.Seq})
+	_, err := dao.GdaoDeleteManyWithTx(ctx, nil, dao.outboxTable, filter)
+	return err
+}
+
+// initSqlOutboxTable creates an outbox table with the specified column definitions, see SetOutboxTable.
+func initSqlOutboxTable(sqlc *prom.SqlConnect, tableName string, ifNotExist bool, colDef map[string]string) error {
+	pk := []string{SqlColId, SqlColOutboxSeq}
+	return CreateTableSql(sqlc, tableName, ifNotExist, colDef, sqlOutboxColumnNames, pk)
+}
@@ -1,7 +1,10 @@
 package henge
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"math/rand"
 	"reflect"
 	"strconv"
@@ -1103,3 +1106,1139 @@ func TestUniversalDaoSql_CreateUpdateGet_Checksum(t *testing.T) {
 		})
 	}
 }
+
+func TestUniversalDaoSql_ChangeTracking(t *testing.T) {
+	testName := "TestUniversalDaoSql_ChangeTracking"
+	for _, subtest := range testSqlList {
+		t.Run(subtest, func(t *testing.T) {
+			setupFunc := testSqlSetupFuncMap[subtest]
+			teardownFunc := testSqlTeardownFuncMap[subtest]
+			teardownTest := setupTest(t, testName, setupFunc, teardownFunc)
+			defer teardownTest(t)
+			if testDao == nil {
+				t.Skip("skipped.")
+			}
+
+			ubo := NewUniversalBo("id", 1357)
+			ubo.SetDataAttr("testName.first", "Thanh")
+			ubo.SetDataAttr("testName.last", "Nguyen")
+			ubo.SetExtraAttr("email", "myname@mydomain.com")
+			if _, err := testDao.Create(ubo); err != nil {
+				t.Fatalf("%s failed: %s", testName, err)
+			}
+			if v := ubo.ChangedPaths(); len(v) != 0 {
+				t.Fatalf("%s failed: expected no change after Create but received %#v", testName, v)
+			}
+
+			bo, err := testDao.Get("id")
+			if err != nil || bo == nil {
+				t.Fatalf("%s failed: %#v / %s", testName, bo, err)
+			}
+			if bo.HasChanges() {
+				t.Fatalf("%s failed: expected no change after Get", testName)
+			}
+			bo.SetDataAttr("testName.first", "Thanh2")
+			bo.SetExtraAttr("email", "thanh@mydomain.com")
+			if v, expected := bo.ChangedPaths(), []string{"testName.first"}; !reflect.DeepEqual(v, expected) {
+				t.Fatalf("%s failed: expected %#v but received %#v", testName, expected, v)
+			}
+			if v, expected := bo.ChangedExtraAttrs(), []string{"email"}; !reflect.DeepEqual(v, expected) {
+				t.Fatalf("%s failed: expected %#v but received %#v", testName, expected, v)
+			}
+			if v, _ := bo.OriginalValue("testName.first"); v != "Thanh" {
+				t.Fatalf("%s failed: expected %#v but received %#v", testName, "Thanh", v)
+			}
+			if _, err := testDao.Update(bo); err != nil {
+				t.Fatalf("%s failed: %s", testName, err)
+			}
+			if bo.HasChanges() {
+				t.Fatalf("%s failed: expected no change after Update", testName)
+			}
+		})
+	}
+}
+
+func TestUniversalDaoSql_SkipUnchangedWrites(t *testing.T) {
+	testName := "TestUniversalDaoSql_SkipUnchangedWrites"
+	for _, subtest := range testSqlList {
+		t.Run(subtest, func(t *testing.T) {
+			setupFunc := testSqlSetupFuncMap[subtest]
+			teardownFunc := testSqlTeardownFuncMap[subtest]
+			teardownTest := setupTest(t, testName, setupFunc, teardownFunc)
+			defer teardownTest(t)
+			if testDao == nil {
+				t.Skip("skipped.")
+			}
+			testDao.(*UniversalDaoSql).SetSkipUnchangedWrites(true)
+
+			ubo := NewUniversalBo("id", 1357)
+			ubo.SetDataAttr("testName.first", "Thanh")
+			ubo.SetExtraAttr("email", "myname@mydomain.com")
+			if _, err := testDao.Create(ubo); err != nil {
+				t.Fatalf("%s failed: %s", testName, err)
+			}
+
+			bo, _ := testDao.Get("id")
+			if ok, err := testDao.Update(bo); ok || err != nil || !bo.WasUnchanged() {
+				t.Fatalf("%s failed: expected unchanged %#v/%#v but received %#v/%#v", testName, false, nil, ok, err)
+			}
+			if ok, existing, err := testDao.Save(bo); ok || err != nil || existing == nil || !bo.WasUnchanged() {
+				t.Fatalf("%s failed: expected unchanged %#v/%#v but received %#v/%#v", testName, false, nil, ok, err)
+			}
+
+			bo.SetDataAttr("testName.first", "Thanh2")
+			if ok, err := testDao.Update(bo); !ok || err != nil || bo.WasUnchanged() {
+				t.Fatalf("%s failed: expected %#v/%#v but received %#v/%#v", testName, true, nil, ok, err)
+			}
+			if bo, _ := testDao.Get("id"); bo.GetDataAttrAsUnsafe("testName.first", reddo.TypeString) != "Thanh2" {
+				t.Fatalf("%s failed: expected %#v but received %#v", testName, "Thanh2", bo.GetDataAttrUnsafe("testName.first"))
+			}
+			bo.SetDataAttr("testName.first", "Thanh3")
+			if ok, _, err := testDao.Save(bo); !ok || err != nil {
+				t.Fatalf("%s failed: expected %#v/%#v but received %#v/%#v", testName, true, nil, ok, err)
+			}
+
+			notExist := NewUniversalBo("not-exist", 1357)
+			if ok, err := testDao.Update(notExist); ok || err != nil || notExist.WasUnchanged() {
+				t.Fatalf("%s failed: expected %#v/%#v but received %#v/%#v", testName, false, nil, ok, err)
+			}
+
+			// the caller's context is honored by conditional updates
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			bo.SetDataAttr("testName.first", "Thanh4")
+			if ok, err := testDao.(*UniversalDaoSql).UpdateContext(ctx, bo); ok || !errors.Is(err, context.Canceled) {
+				t.Fatalf("%s failed: expected %#v/%#v but received %#v/%#v", testName, false, context.Canceled, ok, err)
+			}
+		})
+	}
+}
+
+func TestUniversalDaoSql_SkipUnchangedWrites_ChecksumCoverage(t *testing.T) {
+	testName := "TestUniversalDaoSql_SkipUnchangedWrites_ChecksumCoverage"
+	for _, subtest := range testSqlList {
+		t.Run(subtest, func(t *testing.T) {
+			setupFunc := testSqlSetupFuncMap[subtest]
+			teardownFunc := testSqlTeardownFuncMap[subtest]
+			teardownTest := setupTest(t, testName, setupFunc, teardownFunc)
+			defer teardownTest(t)
+			if testDao == nil {
+				t.Skip("skipped.")
+			}
+			testDao.(*UniversalDaoSql).SetSkipUnchangedWrites(true).
+				SetChecksumCoverage(&ChecksumCoverage{ExcludeDataPaths: []string{"visits"}, ExcludeExtraKeys: []string{"email"}})
+
+			ubo := NewUniversalBo("id", 1357)
+			ubo.SetDataAttr("testName.first", "Thanh")
+			ubo.SetDataAttr("visits", 1)
+			ubo.SetExtraAttr("email", "myname@mydomain.com")
+			if _, err := testDao.Create(ubo); err != nil {
+				t.Fatalf("%s failed: %s", testName, err)
+			}
+
+			bo, _ := testDao.Get("id")
+			if ok, err := testDao.Update(bo); ok || err != nil || !bo.WasUnchanged() {
+				t.Fatalf("%s failed: expected unchanged %#v/%#v but received %#v/%#v", testName, false, nil, ok, err)
+			}
+
+			// changes to attributes not covered by the checksum must still be written
+			bo.SetExtraAttr("email", "another@mydomain.com")
+			if ok, err := testDao.Update(bo); !ok || err != nil {
+				t.Fatalf("%s failed: expected %#v/%#v but received %#v/%#v", testName, true, nil, ok, err)
+			}
+			if bo, _ := testDao.Get("id"); bo.GetExtraAttrAsUnsafe("email", reddo.TypeString) != "another@mydomain.com" {
+				t.Fatalf("%s failed: expected %#v but received %#v", testName, "another@mydomain.com", bo.GetExtraAttr("email"))
+			}
+			bo.SetDataAttr("visits", 2)
+			if ok, _, err := testDao.Save(bo); !ok || err != nil {
+				t.Fatalf("%s failed: expected %#v/%#v but received %#v/%#v", testName, true, nil, ok, err)
+			}
+			loaded, _ := testDao.Get("id")
+			if v := loaded.GetDataAttrAsUnsafe("visits", reddo.TypeInt); v != int64(2) {
+				t.Fatalf("%s failed: expected %#v but received %#v", testName, int64(2), v)
+			}
+			if ok, _, err := testDao.Save(loaded); ok || err != nil || !loaded.WasUnchanged() {
+				t.Fatalf("%s failed: expected unchanged %#v/%#v but received %#v/%#v", testName, false, nil, ok, err)
+			}
+		})
+	}
+}
+
+func TestUniversalDaoSql_RecomputeChecksums(t *testing.T) {
+	testName := "TestUniversalDaoSql_RecomputeChecksums"
+	for _, subtest := range testSqlList {
+		t.Run(subtest, func(t *testing.T) {
+			setupFunc := testSqlSetupFuncMap[subtest]
+			teardownFunc := testSqlTeardownFuncMap[subtest]
+			teardownTest := setupTest(t, testName, setupFunc, teardownFunc)
+			defer teardownTest(t)
+			if testDao == nil {
+				t.Skip("skipped.")
+			}
+
+			numItems := 11
+			for i := 0; i < numItems; i++ {
+				ubo := NewUniversalBo(strconv.Itoa(i), 1357)
+				ubo.SetDataAttr("index", i)
+				ubo.SetExtraAttr("email", strconv.Itoa(i)+"@mydomain.com")
+				if _, err := testDao.Create(ubo); err != nil {
+					t.Fatalf("%s failed: %s", testName, err)
+				}
+			}
+
+			dao := testDao.(*UniversalDaoSql)
+			dao.SetDefaultUboOpts(append(dao.GetDefaultUboOpts(), UboOpt{ChecksumAlgorithm: ChecksumSha256}))
+			if numUpdated, err := RecomputeChecksums(dao, nil, 3); err != nil || numUpdated != numItems {
+				t.Fatalf("%s failed: expected %#v but received %#v (error: %s)", testName, numItems, numUpdated, err)
+			}
+			if numUpdated, err := RecomputeChecksums(dao, nil, 3); err != nil || numUpdated != 0 {
+				t.Fatalf("%s failed: expected %#v but received %#v (error: %s)", testName, 0, numUpdated, err)
+			}
+
+			gboList, _ := dao.GdaoFetchMany(dao.tableName, nil, nil, 0, 0)
+			for _, gbo := range gboList {
+				if csum := gbo.GboGetAttrUnsafe(FieldChecksum, reddo.TypeString).(string); !strings.HasPrefix(csum, "sha256:") {
+					t.Fatalf("%s failed: expected sha256 checksum but received %#v", testName, csum)
+				}
+			}
+			bo, _ := dao.Get("1")
+			if bo == nil || bo.GetDataAttrUnsafe("index") != 1.0 || bo.GetChecksumAlgorithm() != ChecksumSha256 {
+				t.Fatalf("%s failed: unexpected BO %#v", testName, bo)
+			}
+
+			// the DAO's algorithm takes precedence over the default UboOpts, unsupported ones are rejected
+			if err := dao.SetChecksumAlgorithm("sha-256"); !errors.Is(err, ErrUnsupportedChecksumAlgorithm) || dao.GetChecksumAlgorithm() != "" {
+				t.Fatalf("%s failed: expected %#v but received %#v", testName, ErrUnsupportedChecksumAlgorithm, err)
+			}
+			if err := dao.SetChecksumAlgorithm(ChecksumBlake2b256); err != nil {
+				t.Fatalf("%s failed: %s", testName, err)
+			}
+			if numUpdated, err := RecomputeChecksums(dao, nil, 3); err != nil || numUpdated != numItems {
+				t.Fatalf("%s failed: expected %#v but received %#v (error: %s)", testName, numItems, numUpdated, err)
+			}
+			if bo, _ := dao.Get("1"); bo == nil || bo.GetChecksumAlgorithm() != ChecksumBlake2b256 || bo.IsChecksumMismatched() {
+				t.Fatalf("%s failed: unexpected BO %#v", testName, bo)
+			}
+		})
+	}
+}
+
+func TestUniversalDaoSql_StrictChecksumAndVerifyAll(t *testing.T) {
+	testName := "TestUniversalDaoSql_StrictChecksumAndVerifyAll"
+	for _, subtest := range testSqlList {
+		t.Run(subtest, func(t *testing.T) {
+			setupFunc := testSqlSetupFuncMap[subtest]
+			teardownFunc := testSqlTeardownFuncMap[subtest]
+			teardownTest := setupTest(t, testName, setupFunc, teardownFunc)
+			defer teardownTest(t)
+			if testDao == nil {
+				t.Skip("skipped.")
+			}
+
+			dao := testDao.(*UniversalDaoSql)
+			for i := 0; i < 5; i++ {
+				ubo := NewUniversalBo(strconv.Itoa(i), 1357)
+				ubo.SetDataAttr("index", i)
+				ubo.SetExtraAttr("email", strconv.Itoa(i)+"@mydomain.com")
+				if _, err := dao.Create(ubo); err != nil {
+					t.Fatalf("%s failed: %s", testName, err)
+				}
+			}
+			if mismatches, err := VerifyAll(dao, false); err != nil || len(mismatches) != 0 {
+				t.Fatalf("%s failed: expected no mismatch but received %#v (error: %s)", testName, mismatches, err)
+			}
+
+			// tamper with the stored row, bypassing checksum calculation
+			gbo, _ := dao.GdaoFetchOne(dao.tableName, godal.MakeFilter(map[string]interface{}{SqlColId: "1"}))
+			gbo.GboSetAttr(FieldData, `{"index":100}`)
+			if _, err := dao.GdaoUpdate(dao.tableName, gbo); err != nil {
+				t.Fatalf("%s failed: %s", testName, err)
+			}
+
+			if bo, err := dao.Get("1"); err != nil || bo == nil || !bo.IsChecksumMismatched() {
+				t.Fatalf("%s failed: expected BO to be flagged (error: %s)", testName, err)
+			}
+			dao.SetStrictChecksum(true)
+			if _, err := dao.Get("1"); !errors.Is(err, ErrChecksumMismatch) {
+				t.Fatalf("%s failed: expected %#v but received %#v", testName, ErrChecksumMismatch, err)
+			}
+			if _, err := dao.GetAll(nil, nil); !errors.Is(err, ErrChecksumMismatch) {
+				t.Fatalf("%s failed: expected %#v but received %#v", testName, ErrChecksumMismatch, err)
+			}
+			if bo, err := dao.Get("2"); err != nil || bo == nil {
+				t.Fatalf("%s failed: %#v / %s", testName, bo, err)
+			}
+
+			mismatches, err := VerifyAll(dao, false)
+			if err != nil || len(mismatches) != 1 || mismatches[0].Id != "1" || mismatches[0].Repaired {
+				t.Fatalf("%s failed: unexpected result %#v (error: %s)", testName, mismatches, err)
+			}
+			mismatches, err = VerifyAll(dao, true)
+			if err != nil || len(mismatches) != 1 || mismatches[0].Id != "1" || !mismatches[0].Repaired {
+				t.Fatalf("%s failed: unexpected result %#v (error: %s)", testName, mismatches, err)
+			}
+			if mismatches, err = VerifyAll(dao, false); err != nil || len(mismatches) != 0 {
+				t.Fatalf("%s failed: expected no mismatch but received %#v (error: %s)", testName, mismatches, err)
+			}
+			if bo, err := dao.Get("1"); err != nil || bo == nil || bo.GetDataAttrUnsafe("index") != 100.0 {
+				t.Fatalf("%s failed: %#v / %s", testName, bo, err)
+			}
+		})
+	}
+}
+
+func TestUniversalDaoSql_VerifyAllChained(t *testing.T) {
+	testName := "TestUniversalDaoSql_VerifyAllChained"
+	for _, subtest := range testSqlList {
+		t.Run(subtest, func(t *testing.T) {
+			setupFunc := testSqlSetupFuncMap[subtest]
+			teardownFunc := testSqlTeardownFuncMap[subtest]
+			teardownTest := setupTest(t, testName, setupFunc, teardownFunc)
+			defer teardownTest(t)
+			if testDao == nil {
+				t.Skip("skipped.")
+			}
+
+			dao := testDao.(*UniversalDaoSql)
+			for i := 0; i < 5; i++ {
+				ubo := NewUniversalBo(strconv.Itoa(i), 1357)
+				ubo.SetDataAttr("index", i)
+				if _, err := dao.Create(ubo); err != nil {
+					t.Fatalf("%s failed: %s", testName, err)
+				}
+			}
+			for _, id := range []string{"1", "3"} {
+				gbo, _ := dao.GdaoFetchOne(dao.tableName, godal.MakeFilter(map[string]interface{}{SqlColId: id}))
+				gbo.GboSetAttr(FieldData, `{"index":100}`)
+				if _, err := dao.GdaoUpdate(dao.tableName, gbo); err != nil {
+					t.Fatalf("%s failed: %s", testName, err)
+				}
+			}
+			// scanning sorts BOs by id regardless of the default sorting
+			dao.SetStrictChecksum(true).SetDefaultSorting((&godal.SortingField{FieldName: FieldId, Descending: true}).ToSortingOpt())
+			numLoads := 0
+			chained := Chain(dao, func(ctx context.Context, call *DaoCall, next Invoker) error {
+				if call.Op == OpGetN {
+					numLoads++
+				}
+				return next(ctx, call)
+			})
+
+			mismatches, err := VerifyAll(chained, false)
+			if err != nil || len(mismatches) != 2 || mismatches[0].Id != "1" || mismatches[1].Id != "3" {
+				t.Fatalf("%s failed: unexpected result %#v (error: %s)", testName, mismatches, err)
+			}
+			if numLoads == 0 {
+				t.Fatalf("%s failed: BOs should be loaded through the interceptors", testName)
+			}
+			if _, err := chained.GetN(0, 0, nil, nil); !errors.Is(err, ErrChecksumMismatch) {
+				t.Fatalf("%s failed: expected %#v but received %#v", testName, ErrChecksumMismatch, err)
+			}
+		})
+	}
+}
+
+func TestUniversalDaoSql_ScanBosRewrite(t *testing.T) {
+	testName := "TestUniversalDaoSql_ScanBosRewrite"
+	for _, subtest := range testSqlList {
+		t.Run(subtest, func(t *testing.T) {
+			setupFunc := testSqlSetupFuncMap[subtest]
+			teardownFunc := testSqlTeardownFuncMap[subtest]
+			teardownTest := setupTest(t, testName, setupFunc, teardownFunc)
+			defer teardownTest(t)
+			if testDao == nil {
+				t.Skip("skipped.")
+			}
+
+			dao := testDao.(*UniversalDaoSql)
+			now := time.Now().Truncate(time.Second)
+			for i := 0; i < 5; i++ {
+				ubo := NewUniversalBo(strconv.Itoa(i), 1357)
+				ubo.SetDataAttr("index", i)
+				ubo.SetTimeUpdated(now.Add(time.Duration(i) * time.Minute))
+				if _, err := dao.Create(ubo); err != nil {
+					t.Fatalf("%s failed: %s", testName, err)
+				}
+			}
+			// rewritten BOs move to the end of the default sorting, scanning must not be affected
+			dao.SetDefaultSorting((&godal.SortingField{FieldName: FieldTimeUpdated}).ToSortingOpt())
+			visited := make(map[string]int)
+			err := scanBos(dao, nil, 2, func(bo *UniversalBo) error {
+				visited[bo.GetId()]++
+				bo.SetTimeUpdated(now.Add(time.Hour + time.Duration(len(visited))*time.Minute))
+				_, err := dao.Update(bo)
+				return err
+			})
+			if err != nil {
+				t.Fatalf("%s failed: %s", testName, err)
+			}
+			for i := 0; i < 5; i++ {
+				if visited[strconv.Itoa(i)] != 1 {
+					t.Fatalf("%s failed: expected each BO to be visited once but received %#v", testName, visited)
+				}
+			}
+		})
+	}
+}
+
+func TestUniversalDaoSql_DataSchema(t *testing.T) {
+	testName := "TestUniversalDaoSql_DataSchema"
+	for _, subtest := range testSqlList {
+		t.Run(subtest, func(t *testing.T) {
+			setupFunc := testSqlSetupFuncMap[subtest]
+			teardownFunc := testSqlTeardownFuncMap[subtest]
+			teardownTest := setupTest(t, testName, setupFunc, teardownFunc)
+			defer teardownTest(t)
+			if testDao == nil {
+				t.Skip("skipped.")
+			}
+
+			schema, _ := NewDataSchema([]byte(`{"type":"object","required":["name"],"properties":{"name":{"type":"string"},"active":{"type":"boolean","default":true}}}`))
+			dao := testDao.(*UniversalDaoSql)
+			dao.SetDataSchemaForTagVersion(1, schema)
+
+			var verr *DataValidationError
+			ubo := NewUniversalBo("1", 1)
+			ubo.SetDataAttr("name", 1)
+			if _, err := dao.Create(ubo); !errors.As(err, &verr) || verr.Violations[0].Path != "name" {
+				t.Fatalf("%s failed: unexpected error %#v", testName, err)
+			}
+			if bo, _ := dao.Get("1"); bo != nil {
+				t.Fatalf("%s failed: invalid BO should not be stored", testName)
+			}
+
+			ubo.SetDataAttr("name", "henge")
+			if ok, err := dao.Create(ubo); !ok || err != nil {
+				t.Fatalf("%s failed: %#v / %s", testName, ok, err)
+			}
+			if bo, _ := dao.Get("1"); bo == nil || bo.GetDataAttrUnsafe("active") != true {
+				t.Fatalf("%s failed: default value should be filled", testName)
+			}
+
+			ubo.SetDataJson(`{"active":false}`)
+			if _, err := dao.Update(ubo); !errors.As(err, &verr) {
+				t.Fatalf("%s failed: unexpected error %#v", testName, err)
+			}
+			if _, _, err := dao.Save(ubo); !errors.As(err, &verr) {
+				t.Fatalf("%s failed: unexpected error %#v", testName, err)
+			}
+
+			// BOs of other tag-versions are not validated
+			ubo = NewUniversalBo("2", 2)
+			if ok, err := dao.Create(ubo); !ok || err != nil {
+				t.Fatalf("%s failed: %#v / %s", testName, ok, err)
+			}
+			ubo = NewUniversalBo("3", 1)
+			ubo.SetDataAttr("name", "henge")
+			if ok, _, err := dao.Save(ubo); !ok || err != nil || ubo.GetDataAttrUnsafe("active") != true {
+				t.Fatalf("%s failed: %#v / %s", testName, ok, err)
+			}
+		})
+	}
+}
+
+func TestUniversalDaoSql_FieldEncryption(t *testing.T) {
+	testName := "TestUniversalDaoSql_FieldEncryption"
+	for _, subtest := range testSqlList {
+		t.Run(subtest, func(t *testing.T) {
+			setupFunc := testSqlSetupFuncMap[subtest]
+			teardownFunc := testSqlTeardownFuncMap[subtest]
+			teardownTest := setupTest(t, testName, setupFunc, teardownFunc)
+			defer teardownTest(t)
+			if testDao == nil {
+				t.Skip("skipped.")
+			}
+
+			kp, _ := NewStaticKeyProvider("k1", map[string][]byte{"k1": []byte("0123456789abcdef0123456789abcdef")})
+			fe := NewFieldEncryptor(kp).EncryptDataPath("phone", false).EncryptExtraAttr("email", true)
+			dao := testDao.(*UniversalDaoSql)
+			dao.SetFieldEncryptor(fe).SetStrictChecksum(true)
+
+			for i := 0; i < 3; i++ {
+				ubo := NewUniversalBo(strconv.Itoa(i), 1)
+				ubo.SetDataAttr("name", "user"+strconv.Itoa(i))
+				ubo.SetDataAttr("phone", "012345678"+strconv.Itoa(i))
+				ubo.SetExtraAttr("email", strconv.Itoa(i)+"@mydomain.com")
+				if ok, err := dao.Create(ubo); !ok || err != nil {
+					t.Fatalf("%s failed: %#v / %s", testName, ok, err)
+				}
+			}
+
+			gbo, _ := dao.GdaoFetchOne(dao.tableName, godal.MakeFilter(map[string]interface{}{SqlColId: "1"}))
+			dataJson := gbo.GboGetAttrUnsafe(FieldData, reddo.TypeString).(string)
+			if strings.Contains(dataJson, "0123456781") || !strings.Contains(dataJson, "user1") {
+				t.Fatalf("%s failed: unexpected stored data %s", testName, dataJson)
+			}
+			if email := gbo.GboGetAttrUnsafe("email", reddo.TypeString); !IsEncryptedValue(email) {
+				t.Fatalf("%s failed: stored email should be encrypted: %#v", testName, email)
+			}
+
+			bo, err := dao.Get("1")
+			if err != nil || bo == nil || bo.GetDataAttrUnsafe("phone") != "0123456781" || bo.GetExtraAttr("email") != "1@mydomain.com" {
+				t.Fatalf("%s failed: %#v / %s", testName, bo, err)
+			}
+
+			// equality search on deterministically encrypted attribute
+			encEmail, _ := fe.EncryptExtraAttrValue("email", "2@mydomain.com")
+			boList, err := dao.GetAll(godal.MakeFilter(map[string]interface{}{"col_email": encEmail}), nil)
+			if err != nil || len(boList) != 1 || boList[0].GetId() != "2" {
+				t.Fatalf("%s failed: %#v / %s", testName, boList, err)
+			}
+
+			bo.SetDataAttr("phone", "999")
+			if ok, err := dao.Update(bo); !ok || err != nil {
+				t.Fatalf("%s failed: %#v / %s", testName, ok, err)
+			}
+			if bo, err = dao.Get("1"); err != nil || bo.GetDataAttrUnsafe("phone") != "999" {
+				t.Fatalf("%s failed: %#v / %s", testName, bo, err)
+			}
+
+			dao.SetFieldEncryptor(nil)
+			if bo, err = dao.Get("1"); err == nil || !errors.Is(err, ErrChecksumMismatch) {
+				t.Fatalf("%s failed: encrypted BO should not be verified without decryption (error: %s)", testName, err)
+			}
+		})
+	}
+}
+
+func TestUniversalDaoSql_DataCompression(t *testing.T) {
+	testName := "TestUniversalDaoSql_DataCompression"
+	for _, subtest := range testSqlList {
+		t.Run(subtest, func(t *testing.T) {
+			setupFunc := testSqlSetupFuncMap[subtest]
+			teardownFunc := testSqlTeardownFuncMap[subtest]
+			teardownTest := setupTest(t, testName, setupFunc, teardownFunc)
+			defer teardownTest(t)
+			if testDao == nil {
+				t.Skip("skipped.")
+			}
+
+			dc, _ := NewDataCompressor(CompressionGzip, 1024)
+			kp, _ := NewStaticKeyProvider("k1", map[string][]byte{"k1": []byte("0123456789abcdef")})
+			dao := testDao.(*UniversalDaoSql)
+			dao.SetDataCompressor(dc).SetFieldEncryptor(NewFieldEncryptor(kp).EncryptDataPath("secret", false)).SetStrictChecksum(true)
+
+			small := NewUniversalBo("small", 1)
+			small.SetDataAttr("name", "henge")
+			large := NewUniversalBo("large", 1)
+			large.SetDataAttr("text", strings.Repeat("henge ", 1000))
+			large.SetDataAttr("secret", "s3cr3t")
+			for _, ubo := range []*UniversalBo{small, large} {
+				if ok, err := dao.Create(ubo); !ok || err != nil {
+					t.Fatalf("%s failed: %#v / %s", testName, ok, err)
+				}
+			}
+			for id, compressed := range map[string]bool{"small": false, "large": true} {
+				gbo, _ := dao.GdaoFetchOne(dao.tableName, godal.MakeFilter(map[string]interface{}{SqlColId: id}))
+				if data := gbo.GboGetAttrUnsafe(FieldData, reddo.TypeString).(string); IsCompressedData(data) != compressed {
+					t.Fatalf("%s failed: compressed data of BO [%s] expected %#v", testName, id, compressed)
+				}
+			}
+
+			// compressed data is read transparently even if compression is disabled
+			dao.SetDataCompressor(nil)
+			bo, err := dao.Get("large")
+			if err != nil || bo == nil || bo.GetDataJson() != large.GetDataJson() || bo.GetDataAttrUnsafe("secret") != "s3cr3t" {
+				t.Fatalf("%s failed: unexpected BO data (error: %s)", testName, err)
+			}
+		})
+	}
+}
+
+func TestUniversalDaoSql_DataCodec(t *testing.T) {
+	testName := "TestUniversalDaoSql_DataCodec"
+	for _, subtest := range testSqlList {
+		if subtest != "sqlite" {
+			// other databases require a binary data column
+			continue
+		}
+		t.Run(subtest, func(t *testing.T) {
+			setupFunc := testSqlSetupFuncMap[subtest]
+			teardownFunc := testSqlTeardownFuncMap[subtest]
+			teardownTest := setupTest(t, testName, setupFunc, teardownFunc)
+			defer teardownTest(t)
+			if testDao == nil {
+				t.Skip("skipped.")
+			}
+
+			kp, _ := NewStaticKeyProvider("k1", map[string][]byte{"k1": []byte("0123456789abcdef")})
+			dao := testDao.(*UniversalDaoSql)
+			dao.SetFieldEncryptor(NewFieldEncryptor(kp).EncryptDataPath("secret", false)).SetStrictChecksum(true)
+			for _, codec := range []DataCodec{DataCodecMsgpack, DataCodecCbor} {
+				dao.SetDataCodec(codec)
+				ubo := NewUniversalBo(codec.Name(), 1)
+				ubo.SetDataAttr("count", 1<<60)
+				ubo.SetDataAttr("secret", "s3cr3t")
+				if ok, err := dao.Create(ubo); !ok || err != nil {
+					t.Fatalf("%s failed [%s]: %#v / %s", testName, codec.Name(), ok, err)
+				}
+				gbo, _ := dao.GdaoFetchOne(dao.tableName, godal.MakeFilter(map[string]interface{}{SqlColId: codec.Name()}))
+				if data := gbo.GboGetAttrUnsafe(FieldData, nil); !IsEncodedData(data) {
+					t.Fatalf("%s failed [%s]: data should be binary-encoded, received %#v", testName, codec.Name(), data)
+				}
+
+				// encoded data is read transparently even if the codec has been switched back to JSON
+				dao.SetDataCodec(nil)
+				bo, err := dao.Get(codec.Name())
+				if err != nil || bo == nil || bo.GetDataJson() != ubo.GetDataJson() || bo.GetDataAttrUnsafe("secret") != "s3cr3t" {
+					t.Fatalf("%s failed [%s]: unexpected BO data %#v (error: %s)", testName, codec.Name(), bo, err)
+				}
+				if v := bo.GetDataAttrUnsafe("count"); v != int64(1<<60) {
+					t.Fatalf("%s failed [%s]: expected %#v but received %#v", testName, codec.Name(), int64(1<<60), v)
+				}
+			}
+		})
+	}
+}
+
+func TestUniversalDaoSql_Clock(t *testing.T) {
+	testName := "TestUniversalDaoSql_Clock"
+	for _, subtest := range testSqlList {
+		t.Run(subtest, func(t *testing.T) {
+			setupFunc := testSqlSetupFuncMap[subtest]
+			teardownFunc := testSqlTeardownFuncMap[subtest]
+			teardownTest := setupTest(t, testName, setupFunc, teardownFunc)
+			defer teardownTest(t)
+			if testDao == nil {
+				t.Skip("skipped.")
+			}
+
+			now := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+			clock := NewFixedClock(now)
+			dao := testDao.(*UniversalDaoSql)
+			dao.SetClock(clock)
+			// BO has no clock of its own: DAO's clock is used to bump its last-updated timestamp when written
+			ubo := NewUniversalBo("id", 1, dao.GetDefaultUboOpts()...)
+			ubo.SetDataAttr("a", 1)
+			if ok, err := dao.Create(ubo); !ok || err != nil {
+				t.Fatalf("%s failed: %#v / %s", testName, ok, err)
+			}
+			bo, err := dao.Get("id")
+			if err != nil || bo == nil || bo.GetTimeUpdated().Unix() != now.Unix() {
+				t.Fatalf("%s failed: expected %#v but received %#v (error: %s)", testName, now, bo, err)
+			}
+
+			clock.Advance(time.Hour)
+			bo.SetDataAttr("a", 2)
+			if ok, err := dao.Update(bo); !ok || err != nil {
+				t.Fatalf("%s failed: %#v / %s", testName, ok, err)
+			}
+			bo, err = dao.Get("id")
+			if err != nil || bo == nil || bo.GetTimeUpdated().Unix() != now.Add(time.Hour).Unix() {
+				t.Fatalf("%s failed: expected %#v but received %#v (error: %s)", testName, now.Add(time.Hour), bo, err)
+			}
+		})
+	}
+}
+
+func TestUniversalDaoSql_ClockTimestampRounding(t *testing.T) {
+	testName := "TestUniversalDaoSql_ClockTimestampRounding"
+	for _, subtest := range testSqlList {
+		t.Run(subtest, func(t *testing.T) {
+			setupFunc := testSqlSetupFuncMap[subtest]
+			teardownFunc := testSqlTeardownFuncMap[subtest]
+			teardownTest := setupTest(t, testName, setupFunc, teardownFunc)
+			defer teardownTest(t)
+			if testDao == nil {
+				t.Skip("skipped.")
+			}
+
+			now := time.Date(2021, 1, 2, 3, 4, 5, 678000000, time.UTC)
+			dao := testDao.(*UniversalDaoSql)
+			dao.SetDefaultUboOpts([]UboOpt{{TimestampRounding: TimestampRoundingSettingSecond}})
+			dao.SetClock(NewFixedClock(now))
+			ubo := NewUniversalBo("id", 1, dao.GetDefaultUboOpts()...)
+			ubo.SetDataAttr("a", 1)
+			if ok, err := dao.Create(ubo); !ok || err != nil {
+				t.Fatalf("%s failed: %#v / %s", testName, ok, err)
+			}
+			// DAO's clock must not override the timestamp rounding of its default options
+			bo, err := dao.Get("id")
+			if err != nil || bo == nil || bo.GetTimestampRounding() != TimestampRoundingSettingSecond {
+				t.Fatalf("%s failed: expected %#v but received %#v (error: %s)", testName, TimestampRoundingSettingSecond, bo, err)
+			}
+			if v := bo.GetTimeCreated(); v.Nanosecond() != 0 {
+				t.Fatalf("%s failed: expected timestamp rounded to second but received %#v", testName, v)
+			}
+		})
+	}
+}
+
+func TestUniversalDaoSql_IdGenerator(t *testing.T) {
+	testName := "TestUniversalDaoSql_IdGenerator"
+	for _, subtest := range testSqlList {
+		t.Run(subtest, func(t *testing.T) {
+			setupFunc := testSqlSetupFuncMap[subtest]
+			teardownFunc := testSqlTeardownFuncMap[subtest]
+			teardownTest := setupTest(t, testName, setupFunc, teardownFunc)
+			defer teardownTest(t)
+			if testDao == nil {
+				t.Skip("skipped.")
+			}
+
+			dao := testDao.(*UniversalDaoSql)
+			if dao.GetMaxIdLength() != 0 {
+				t.Fatalf("%s failed: expected %#v but received %#v", testName, 0, dao.GetMaxIdLength())
+			}
+			ubo := NewUniversalBo("  ", 1, dao.GetDefaultUboOpts()...)
+			if _, err := dao.Create(ubo); !errors.Is(err, ErrEmptyId) {
+				t.Fatalf("%s failed: expected %#v but received %#v", testName, ErrEmptyId, err)
+			}
+			ubo.SetId(strings.Repeat("x", MaxIdLengthSql+1))
+			if _, err := dao.SetMaxIdLength(MaxIdLengthSql).Create(ubo); !errors.Is(err, ErrIdTooLong) {
+				t.Fatalf("%s failed: expected %#v but received %#v", testName, ErrIdTooLong, err)
+			}
+
+			dao.SetIdGenerator(IdGeneratorUlid)
+			ubo.SetId("")
+			ubo.SetDataAttr("a", 1)
+			if ok, err := dao.Create(ubo); !ok || err != nil {
+				t.Fatalf("%s failed: %#v / %s", testName, ok, err)
+			}
+			if id := ubo.GetId(); len(id) != 26 {
+				t.Fatalf("%s failed: expected a generated ULID but received %#v", testName, id)
+			}
+			if bo, err := dao.Get(ubo.GetId()); err != nil || bo == nil {
+				t.Fatalf("%s failed: %#v / %s", testName, bo, err)
+			}
+		})
+	}
+}
+
+func TestUniversalDaoSql_Hooks(t *testing.T) {
+	testName := "TestUniversalDaoSql_Hooks"
+	for _, subtest := range testSqlList {
+		t.Run(subtest, func(t *testing.T) {
+			setupFunc := testSqlSetupFuncMap[subtest]
+			teardownFunc := testSqlTeardownFuncMap[subtest]
+			teardownTest := setupTest(t, testName, setupFunc, teardownFunc)
+			defer teardownTest(t)
+			if testDao == nil {
+				t.Skip("skipped.")
+			}
+
+			dao := testDao.(*UniversalDaoSql)
+			var events []string
+			record := func(event string) HookFunc {
+				return func(ctx context.Context, bo *UniversalBo) error {
+					events = append(events, event)
+					return nil
+				}
+			}
+			errDenied := errors.New("denied")
+			dao.OnBeforeCreate(func(ctx context.Context, bo *UniversalBo) error {
+				email, _ := bo.GetDataAttrAsUnsafe("email", reddo.TypeString).(string)
+				if email == "" {
+					return errDenied
+				}
+				return bo.SetDataAttr("email", strings.ToLower(email))
+			}).OnAfterCreate(func(ctx context.Context, bo *UniversalBo) error {
+				if bo.GetChecksum() == "" || bo.IsDirty() {
+					t.Fatalf("%s failed: after-hooks should see the persisted BO", testName)
+				}
+				return nil
+			}).OnAfterCreate(record("AfterCreate")).OnBeforeUpdate(record("BeforeUpdate")).OnAfterUpdate(record("AfterUpdate")).
+				OnBeforeSave(record("BeforeSave")).OnAfterSave(record("AfterSave")).OnBeforeDelete(record("BeforeDelete")).
+				OnAfterDelete(record("AfterDelete")).OnAfterLoad(record("AfterLoad"))
+
+			ubo := NewUniversalBo("id", 1, dao.GetDefaultUboOpts()...)
+			if _, err := dao.CreateContext(context.Background(), ubo); err != errDenied {
+				t.Fatalf("%s failed: expected %#v but received %#v", testName, errDenied, err)
+			}
+			if bo, err := dao.Get("id"); err != nil || bo != nil {
+				t.Fatalf("%s failed: BO should not be created if a before-hook fails (%s)", testName, err)
+			}
+			ubo.SetDataAttr("email", "Me@Example.COM")
+			if ok, err := dao.Create(ubo); !ok || err != nil {
+				t.Fatalf("%s failed: %#v / %s", testName, ok, err)
+			}
+			bo, err := dao.Get("id")
+			if err != nil || bo == nil || bo.GetDataAttrAsUnsafe("email", reddo.TypeString) != "me@example.com" {
+				t.Fatalf("%s failed: before-hooks should be able to modify the BO (%#v / %s)", testName, bo, err)
+			}
+			bo.SetDataAttr("name", "me")
+			dao.Update(bo)
+			dao.Save(bo)
+			dao.GetAll(nil, nil)
+			dao.Delete(bo)
+			expected := []string{"AfterCreate", "AfterLoad", "BeforeUpdate", "AfterUpdate", "BeforeSave", "AfterSave", "AfterLoad", "BeforeDelete", "AfterDelete"}
+			if !reflect.DeepEqual(events, expected) {
+				t.Fatalf("%s failed: expected %#v but received %#v", testName, expected, events)
+			}
+
+			// after-hooks are not invoked if nothing was written
+			events = nil
+			if ok, err := dao.Update(bo); ok || err != nil {
+				t.Fatalf("%s failed: %#v / %s", testName, ok, err)
+			}
+			if expected := []string{"BeforeUpdate"}; !reflect.DeepEqual(events, expected) {
+				t.Fatalf("%s failed: expected %#v but received %#v", testName, expected, events)
+			}
+		})
+	}
+}
+
+func TestUniversalDaoSql_ContextCancelled(t *testing.T) {
+	testName := "TestUniversalDaoSql_ContextCancelled"
+	for _, subtest := range testSqlList {
+		t.Run(subtest, func(t *testing.T) {
+			setupFunc := testSqlSetupFuncMap[subtest]
+			teardownFunc := testSqlTeardownFuncMap[subtest]
+			teardownTest := setupTest(t, testName, setupFunc, teardownFunc)
+			defer teardownTest(t)
+			if testDao == nil {
+				t.Skip("skipped.")
+			}
+
+			dao := testDao.(*UniversalDaoSql)
+			ubo := NewUniversalBo("id", 1, dao.GetDefaultUboOpts()...)
+			if ok, err := dao.Create(ubo); !ok || err != nil {
+				t.Fatalf("%s failed: %#v / %s", testName, ok, err)
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			if _, err := dao.CreateContext(ctx, NewUniversalBo("id2", 1, dao.GetDefaultUboOpts()...)); !errors.Is(err, context.Canceled) {
+				t.Fatalf("%s failed [CreateContext]: expected %#v but received %#v", testName, context.Canceled, err)
+			}
+			if _, err := dao.GetContext(ctx, "id"); !errors.Is(err, context.Canceled) {
+				t.Fatalf("%s failed [GetContext]: expected %#v but received %#v", testName, context.Canceled, err)
+			}
+			if _, err := dao.GetAllContext(ctx, nil, nil); !errors.Is(err, context.Canceled) {
+				t.Fatalf("%s failed [GetAllContext]: expected %#v but received %#v", testName, context.Canceled, err)
+			}
+			ubo.SetDataAttr("a", 1)
+			if _, err := dao.UpdateContext(ctx, ubo); !errors.Is(err, context.Canceled) {
+				t.Fatalf("%s failed [UpdateContext]: expected %#v but received %#v", testName, context.Canceled, err)
+			}
+			if _, _, err := dao.SaveContext(ctx, ubo); !errors.Is(err, context.Canceled) {
+				t.Fatalf("%s failed [SaveContext]: expected %#v but received %#v", testName, context.Canceled, err)
+			}
+			if _, err := dao.DeleteContext(ctx, ubo); !errors.Is(err, context.Canceled) {
+				t.Fatalf("%s failed [DeleteContext]: expected %#v but received %#v", testName, context.Canceled, err)
+			}
+			if bo, err := dao.Get("id"); err != nil || bo == nil || bo.GetDataAttrUnsafe("a") != nil {
+				t.Fatalf("%s failed: BO should not be modified by cancelled operations (%#v / %s)", testName, bo, err)
+			}
+		})
+	}
+}
+
+func TestUniversalDaoSql_AuditFields(t *testing.T) {
+	testName := "TestUniversalDaoSql_AuditFields"
+	for _, subtest := range testSqlList {
+		t.Run(subtest, func(t *testing.T) {
+			setupFunc := testSqlSetupFuncMap[subtest]
+			teardownFunc := testSqlTeardownFuncMap[subtest]
+			teardownTest := setupTest(t, testName, setupFunc, teardownFunc)
+			defer teardownTest(t)
+			if testDao == nil {
+				t.Skip("skipped.")
+			}
+
+			dao := testDao.(*UniversalDaoSql)
+			dao.SetPrincipalFunc(PrincipalFromContext)
+
+			// audit columns are disabled by default
+			ubo := NewUniversalBo("id0", 1, dao.GetDefaultUboOpts()...)
+			if ok, err := dao.CreateContext(ContextWithPrincipal(context.Background(), "alice"), ubo); !ok || err != nil {
+				t.Fatalf("%s failed: %#v / %s", testName, ok, err)
+			}
+			if bo, err := dao.Get("id0"); err != nil || bo == nil || bo.GetCreatedBy() != "" {
+				t.Fatalf("%s failed: audit fields should not be stored if audit columns are disabled (%#v / %s)", testName, bo, err)
+			}
+
+			dao.SetAuditColumns(true)
+			if !dao.GetAuditColumns() {
+				t.Fatalf("%s failed: audit columns should be enabled", testName)
+			}
+			ubo = NewUniversalBo("id", 1, dao.GetDefaultUboOpts()...)
+			ubo.SetDataAttr("name", "me")
+			if ok, err := dao.CreateContext(ContextWithPrincipal(context.Background(), "alice"), ubo); !ok || err != nil {
+				t.Fatalf("%s failed: %#v / %s", testName, ok, err)
+			}
+			bo, err := dao.Get("id")
+			if err != nil || bo == nil || bo.GetCreatedBy() != "alice" || bo.GetUpdatedBy() != "alice" {
+				t.Fatalf("%s failed: expected alice/alice but received %#v (%s)", testName, bo, err)
+			}
+
+			bo.SetDataAttr("name", "you")
+			if ok, err := dao.UpdateContext(ContextWithPrincipal(context.Background(), "bob"), bo); !ok || err != nil {
+				t.Fatalf("%s failed: %#v / %s", testName, ok, err)
+			}
+			if bo, _ = dao.Get("id"); bo.GetCreatedBy() != "alice" || bo.GetUpdatedBy() != "bob" {
+				t.Fatalf("%s failed: expected alice/bob but received %#v/%#v", testName, bo.GetCreatedBy(), bo.GetUpdatedBy())
+			}
+
+			// save keeps created-by of the existing BO
+			ubo = NewUniversalBo("id", 2, dao.GetDefaultUboOpts()...)
+			if ok, existing, err := dao.SaveContext(ContextWithPrincipal(context.Background(), "carol"), ubo); !ok || err != nil || existing == nil {
+				t.Fatalf("%s failed: %#v / %#v / %s", testName, ok, existing, err)
+			}
+			if bo, _ = dao.Get("id"); bo.GetCreatedBy() != "alice" || bo.GetUpdatedBy() != "carol" {
+				t.Fatalf("%s failed: expected alice/carol but received %#v/%#v", testName, bo.GetCreatedBy(), bo.GetUpdatedBy())
+			}
+
+			// no principal: audit fields are left untouched
+			bo.SetDataAttr("name", "them")
+			if ok, err := dao.Update(bo); !ok || err != nil {
+				t.Fatalf("%s failed: %#v / %s", testName, ok, err)
+			}
+			if bo, _ = dao.Get("id"); bo.GetCreatedBy() != "alice" || bo.GetUpdatedBy() != "carol" {
+				t.Fatalf("%s failed: expected alice/carol but received %#v/%#v", testName, bo.GetCreatedBy(), bo.GetUpdatedBy())
+			}
+
+			dao.SetAuditColumns(false)
+			if dao.GetAuditColumns() {
+				t.Fatalf("%s failed: audit columns should be disabled", testName)
+			}
+			if bo, _ = dao.Get("id"); bo.GetCreatedBy() != "" {
+				t.Fatalf("%s failed: audit columns should not be read if disabled", testName)
+			}
+		})
+	}
+}
+
+var testSqlInitHistoryTableFuncMap = map[string]func(sqlc *prom.SqlConnect, tableName string, colTypes map[string]string) error{
+	"mssql":  InitMssqlHistoryTable,
+	"mysql":  InitMysqlHistoryTable,
+	"oracle": InitOracleHistoryTable,
+	"pgsql":  InitPgsqlHistoryTable,
+	"sqlite": InitSqliteHistoryTable,
+}
+
+func TestUniversalDaoSql_History(t *testing.T) {
+	testName := "TestUniversalDaoSql_History"
+	for _, subtest := range testSqlList {
+		t.Run(subtest, func(t *testing.T) {
+			setupFunc := testSqlSetupFuncMap[subtest]
+			teardownFunc := testSqlTeardownFuncMap[subtest]
+			teardownTest := setupTest(t, testName, setupFunc, teardownFunc)
+			defer teardownTest(t)
+			if testDao == nil {
+				t.Skip("skipped.")
+			}
+
+			historyTable := testTable + "_h"
+			testSqlc.GetDB().Exec(fmt.Sprintf("DROP TABLE %s", historyTable))
+			if err := testSqlInitHistoryTableFuncMap[subtest](testSqlc, historyTable, nil); err != nil {
+				t.Fatalf("%s failed: %s", testName, err)
+			}
+			defer testSqlc.GetDB().Exec(fmt.Sprintf("DROP TABLE %s", historyTable))
+
+			dao := testDao.(*UniversalDaoSql)
+			if _, err := dao.GetHistory("id", 0, 0); err != ErrHistoryDisabled {
+				t.Fatalf("%s failed: expected %#v but received %#v", testName, ErrHistoryDisabled, err)
+			}
+			clock := NewFixedClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+			dao.SetHistoryTable(historyTable).SetClock(clock)
+			if dao.GetHistoryTable() != historyTable {
+				t.Fatalf("%s failed: expected %#v but received %#v", testName, historyTable, dao.GetHistoryTable())
+			}
+
+			ubo := NewUniversalBo("id", 1, dao.GetDefaultUboOpts()...)
+			ubo.SetDataAttr("name", "v1")
+			ubo.SetExtraAttr("email", "v1@domain.com")
+			if ok, err := dao.Create(ubo); !ok || err != nil {
+				t.Fatalf("%s failed: %#v / %s", testName, ok, err)
+			}
+			v1Checksum := ubo.GetChecksum()
+			t1 := clock.Now()
+
+			clock.Advance(time.Hour)
+			ubo.SetDataAttr("name", "v2")
+			ubo.SetExtraAttr("email", "v2@domain.com")
+			if ok, err := dao.Update(ubo); !ok || err != nil {
+				t.Fatalf("%s failed: %#v / %s", testName, ok, err)
+			}
+			clock.Advance(time.Hour)
+			v3 := NewUniversalBo("id", 3, dao.GetDefaultUboOpts()...)
+			v3.SetDataAttr("name", "v3")
+			if ok, _, err := dao.Save(v3); !ok || err != nil {
+				t.Fatalf("%s failed: %#v / %s", testName, ok, err)
+			}
+
+			history, err := dao.GetHistory("id", 0, 0)
+			if err != nil || len(history) != 2 {
+				t.Fatalf("%s failed: expected 2 history entries but received %#v (%s)", testName, history, err)
+			}
+			if history[0].Seq != 2 || history[0].Op != OpSave || history[1].Seq != 1 || history[1].Op != OpUpdate {
+				t.Fatalf("%s failed: unexpected history entries %#v / %#v", testName, history[0], history[1])
+			}
+			if v1 := history[1].Bo; v1.GetChecksum() != v1Checksum || v1.GetDataAttrAsUnsafe("name", reddo.TypeString) != "v1" || v1.GetExtraAttr("email") != "v1@domain.com" {
+				t.Fatalf("%s failed: unexpected archived version %#v", testName, v1)
+			}
+			if history, err := dao.GetHistory("id", 1, 10); err != nil || len(history) != 1 || history[0].Seq != 1 {
+				t.Fatalf("%s failed: unexpected paged history %#v (%s)", testName, history, err)
+			}
+
+			if bo, err := dao.GetVersionAt("id", t1.Add(30*time.Minute)); err != nil || bo == nil || bo.GetDataAttrAsUnsafe("name", reddo.TypeString) != "v1" {
+				t.Fatalf("%s failed: expected v1 but received %#v (%s)", testName, bo, err)
+			}
+			if bo, err := dao.GetVersionAt("id", clock.Now()); err != nil || bo == nil || bo.GetTagVersion() != 3 {
+				t.Fatalf("%s failed: expected v3 but received %#v (%s)", testName, bo, err)
+			}
+			if bo, err := dao.GetVersionAt("id", t1.Add(-time.Minute)); err != nil || bo != nil {
+				t.Fatalf("%s failed: expected nil but received %#v (%s)", testName, bo, err)
+			}
+
+			clock.Advance(time.Hour)
+			bo, err := dao.RestoreVersion("id", VersionChecksum(v1Checksum))
+			if err != nil || bo == nil || bo.GetDataAttrAsUnsafe("name", reddo.TypeString) != "v1" {
+				t.Fatalf("%s failed: expected v1 but received %#v (%s)", testName, bo, err)
+			}
+			if bo, _ = dao.Get("id"); bo.GetChecksum() != v1Checksum || !bo.GetTimeUpdated().Equal(clock.Now()) {
+				t.Fatalf("%s failed: v1 should be restored at %s, received %#v", testName, clock.Now(), bo)
+			}
+			if bo, err := dao.RestoreVersion("id", VersionSeq(10)); err != nil || bo != nil {
+				t.Fatalf("%s failed: expected nil but received %#v (%s)", testName, bo, err)
+			}
+
+			if ok, err := dao.Delete(bo); !ok || err != nil {
+				t.Fatalf("%s failed: %#v / %s", testName, ok, err)
+			}
+			history, _ = dao.GetHistory("id", 0, 0)
+			if len(history) != 4 || history[0].Op != OpDelete || history[0].Bo.GetChecksum() != v1Checksum {
+				t.Fatalf("%s failed: the deleted version should be archived, received %#v", testName, history)
+			}
+			if bo, err := dao.GetVersionAt("id", clock.Now().Add(time.Minute)); err != nil || bo != nil {
+				t.Fatalf("%s failed: expected nil but received %#v (%s)", testName, bo, err)
+			}
+		})
+	}
+}
+
+var testSqlInitOutboxTableFuncMap = map[string]func(sqlc *prom.SqlConnect, tableName string, colTypes map[string]string) error{
+	"mssql":  InitMssqlOutboxTable,
+	"mysql":  InitMysqlOutboxTable,
+	"oracle": InitOracleOutboxTable,
+	"pgsql":  InitPgsqlOutboxTable,
+	"sqlite": InitSqliteOutboxTable,
+}
+
+func TestUniversalDaoSql_Outbox(t *testing.T) {
+	testName := "TestUniversalDaoSql_Outbox"
+	for _, subtest := range testSqlList {
+		t.Run(subtest, func(t *testing.T) {
+			setupFunc := testSqlSetupFuncMap[subtest]
+			teardownFunc := testSqlTeardownFuncMap[subtest]
+			teardownTest := setupTest(t, testName, setupFunc, teardownFunc)
+			defer teardownTest(t)
+			if testDao == nil {
+				t.Skip("skipped.")
+			}
+
+			outboxTable := testTable + "_o"
+			testSqlc.GetDB().Exec(fmt.Sprintf("DROP TABLE %s", outboxTable))
+			if err := testSqlInitOutboxTableFuncMap[subtest](testSqlc, outboxTable, nil); err != nil {
+				t.Fatalf("%s failed: %s", testName, err)
+			}
+			defer testSqlc.GetDB().Exec(fmt.Sprintf("DROP TABLE %s", outboxTable))
+
+			ctx := context.Background()
+			dao := testDao.(*UniversalDaoSql)
+			if _, err := dao.FetchOutbox(ctx, 10); err != ErrOutboxDisabled {
+				t.Fatalf("%s failed: expected %#v but received %#v", testName, ErrOutboxDisabled, err)
+			}
+			clock := NewFixedClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+			dao.SetOutboxTable(outboxTable).SetClock(clock)
+			if dao.GetOutboxTable() != outboxTable {
+				t.Fatalf("%s failed: expected %#v but received %#v", testName, outboxTable, dao.GetOutboxTable())
+			}
+
+			ubo := NewUniversalBo("id1", 1, dao.GetDefaultUboOpts()...)
+			ubo.SetDataAttr("name", "v1")
+			if ok, err := dao.Create(ubo); !ok || err != nil {
+				t.Fatalf("%s failed: %#v / %s", testName, ok, err)
+			}
+			clock.Advance(time.Hour)
+			ubo.SetDataAttr("name", "v2")
+			if ok, err := dao.Update(ubo); !ok || err != nil {
+				t.Fatalf("%s failed: %#v / %s", testName, ok, err)
+			}
+			clock.Advance(time.Hour)
+			if ok, err := dao.Create(NewUniversalBo("id2", 1, dao.GetDefaultUboOpts()...)); !ok || err != nil {
+				t.Fatalf("%s failed: %#v / %s", testName, ok, err)
+			}
+			// failed writes do not record events
+			if ok, err := dao.Create(NewUniversalBo("id2", 1, dao.GetDefaultUboOpts()...)); ok || err == nil {
+				t.Fatalf("%s failed: expected duplicated entry but received %#v / %s", testName, ok, err)
+			}
+			if ok, err := dao.Update(NewUniversalBo("id3", 1, dao.GetDefaultUboOpts()...)); ok || err != nil {
+				t.Fatalf("%s failed: %#v / %s", testName, ok, err)
+			}
+			clock.Advance(time.Hour)
+			ubo.SetDataAttr("name", "v3")
+			if ok, _, err := dao.Save(ubo); !ok || err != nil {
+				t.Fatalf("%s failed: %#v / %s", testName, ok, err)
+			}
+			clock.Advance(time.Hour)
+			if ok, err := dao.Delete(ubo); !ok || err != nil {
+				t.Fatalf("%s failed: %#v / %s", testName, ok, err)
+			}
+
+			events, err := dao.FetchOutbox(ctx, 10)
+			if err != nil || len(events) != 5 {
+				t.Fatalf("%s failed: expected 5 events but received %#v (%s)", testName, events, err)
+			}
+			expected := []struct {
+				op     DaoOp
+				id     string
+				seq    uint64
+				before interface{}
+				after  interface{}
+			}{
+				{OpCreate, "id1", 1, nil, "v1"},
+				{OpUpdate, "id1", 2, "v1", "v2"},
+				{OpCreate, "id2", 1, nil, nil},
+				{OpSave, "id1", 3, "v2", "v3"},
+				{OpDelete, "id1", 4, "v3", nil},
+			}
+			nameOf := func(bo *UniversalBo) interface{} {
+				if bo == nil {
+					return nil
+				}
+				return bo.GetDataAttrAsUnsafe("name", nil)
+			}
+			for i, e := range expected {
+				ev := events[i]
+				if ev.Op != e.op || ev.Id != e.id || ev.Seq != e.seq || nameOf(ev.Before) != e.before || nameOf(ev.After) != e.after {
+					t.Fatalf("%s failed: expected %#v but received %#v / %#v / %#v", testName, e, ev.ChangeEvent, ev.Before, ev.After)
+				}
+			}
+			if events[2].After == nil || events[2].After.GetId() != "id2" || events[1].After.GetChecksum() == events[1].Before.GetChecksum() {
+				t.Fatalf("%s failed: unexpected event %#v", testName, events[2].ChangeEvent)
+			}
+
+			var delivered []*ChangeEvent
+			errSink := errors.New("sink failure")
+			relay := NewOutboxRelay(dao, EventSinkFunc(func(ctx context.Context, ev *ChangeEvent) error {
+				if len(delivered) == 3 {
+					return errSink
+				}
+				delivered = append(delivered, ev)
+				return nil
+			})).SetBatchSize(2)
+			if n, err := relay.Drain(ctx); err != errSink || n != 3 {
+				t.Fatalf("%s failed: expected %#v / %#v but received %#v / %#v", testName, 3, errSink, n, err)
+			}
+			if events, _ := dao.FetchOutbox(ctx, 10); len(events) != 2 || events[0].Op != OpSave {
+				t.Fatalf("%s failed: expected 2 pending events but received %#v", testName, events)
+			}
+			relay = NewOutboxRelay(dao, EventSinkFunc(func(ctx context.Context, ev *ChangeEvent) error {
+				delivered = append(delivered, ev)
+				return nil
+			}))
+			if n, err := relay.Drain(ctx); err != nil || n != 2 {
+				t.Fatalf("%s failed: expected %#v but received %#v (%s)", testName, 2, n, err)
+			}
+			for i, e := range expected {
+				if delivered[i].Op != e.op || delivered[i].Id != e.id {
+					t.Fatalf("%s failed: expected %#v but received %#v", testName, e, delivered[i])
+				}
+			}
+			if events, _ := dao.FetchOutbox(ctx, 10); len(events) != 0 {
+				t.Fatalf("%s failed: expected empty outbox but received %#v", testName, events)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package henge
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/btnguyen2k/consu/semita"
+)
+
+// isJsonPointer returns true if the data path is a JSON Pointer (RFC 6901), i.e. it starts with a "/".
+// Other paths are in semita's dot/bracket syntax (e.g. "a.b[0].c").
+func isJsonPointer(path string) bool {
+	return strings.HasPrefix(path, "/")
+}
+
+// getDataValue returns the value located at 'path' (in semita syntax or JSON Pointer) of the data tree.
+// Similar to semita, nil is returned (without error) if the path does not exist.
+func getDataValue(root *interface{}, path string) (interface{}, error) {
+	if !isJsonPointer(path) {
+		return semita.NewSemita(root).GetValue(path)
+	}
+	tokens, err := parseJsonPointer(path)
+	if err != nil {
+		return nil, err
+	}
+	node := *root
+	for _, token := range tokens {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			node = n[token]
+		case []interface{}:
+			index, err := parseJsonArrayIndex(token, math.MaxInt, false)
+			if err != nil {
+				return nil, err
+			}
+			if index >= len(n) {
+				return nil, nil
+			}
+			node = n[index]
+		case nil:
+			return nil, nil
+		default:
+			return nil, fmt.Errorf("cannot get data at path [%s]: parent of [%s] is neither object nor array", path, token)
+		}
+	}
+	return node, nil
+}
+
+// setDataValue sets the value located at 'path' (in semita syntax or JSON Pointer) of the data tree.
+//
+// With JSON Pointer, missing intermediate nodes are created as objects; array elements can be set by index, or
+// appended with index "-" (or the array's length).
+func setDataValue(root *interface{}, path string, value interface{}) error {
+	if !isJsonPointer(path) {
+		return semita.NewSemita(root).SetValue(path, value)
+	}
+	tokens, err := parseJsonPointer(path)
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		return fmt.Errorf("cannot set data at path [%s]", path)
+	}
+	newRoot, err := jsonTreeSet(*root, tokens, value)
+	if err != nil {
+		return fmt.Errorf("cannot set data at path [%s]: %s", path, err)
+	}
+	*root = newRoot
+	return nil
+}
+
+// jsonTreeSet sets the value located at the path specified by tokens, creating missing intermediate objects, and
+// returns the (possibly new) root of the tree.
+func jsonTreeSet(node interface{}, tokens []string, value interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	switch n := node.(type) {
+	case nil:
+		return jsonTreeSet(make(map[string]interface{}), tokens, value)
+	case map[string]interface{}:
+		child, err := jsonTreeSet(n[tokens[0]], tokens[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		n[tokens[0]] = child
+		return n, nil
+	case []interface{}:
+		index, err := parseJsonArrayIndex(tokens[0], len(n), true)
+		if err != nil {
+			return nil, err
+		}
+		if index == len(n) {
+			n = append(n, nil)
+		}
+		child, err := jsonTreeSet(n[index], tokens[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		n[index] = child
+		return n, nil
+	}
+	return nil, fmt.Errorf("parent of [%s] is neither object nor array", tokens[0])
+}
+
+// dataPathTokens splits a data path (in semita syntax or JSON Pointer) into tokens, e.g. "a.b[0].c" and "/a/b/0/c"
+// both result in ["a", "b", "0", "c"].
+func dataPathTokens(path string) ([]string, error) {
+	if isJsonPointer(path) {
+		return parseJsonPointer(path)
+	}
+	tokens := semita.SplitPath(path)
+	for i, token := range tokens {
+		if strings.HasPrefix(token, "[") && strings.HasSuffix(token, "]") {
+			tokens[i] = token[1 : len(token)-1]
+		}
+	}
+	return tokens, nil
+}
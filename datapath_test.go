@@ -0,0 +1,88 @@
+package henge
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/btnguyen2k/consu/reddo"
+)
+
+func TestUniversalBo_GetDataAttrByPointer(t *testing.T) {
+	name := "TestUniversalBo_GetDataAttrByPointer"
+	ubo := NewUniversalBo("id", 1)
+	ubo.SetDataJson(`{"hosts":{"example.com":{"port":8080}},"a/b":{"c~d":"escaped"},"list":[1,{"x":"y"}]}`)
+	testCases := map[string]interface{}{
+		"/hosts/example.com/port": 8080.0,
+		"/a~1b/c~0d":              "escaped",
+		"/list/1/x":               "y",
+		"/list/5":                 nil,
+		"/hosts/example.org/port": nil,
+	}
+	for pointer, expected := range testCases {
+		if v, err := ubo.GetDataAttrByPointer(pointer); err != nil || v != expected {
+			t.Fatalf("%s failed for [%s]: expected %#v but received %#v (error: %s)", name, pointer, expected, v, err)
+		}
+	}
+	if v := ubo.GetDataAttrAsUnsafe("/hosts/example.com/port", reddo.TypeInt); v != int64(8080) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, int64(8080), v)
+	}
+	if v, err := ubo.GetDataAttrByPointer(""); err != nil || !reflect.DeepEqual(v, ubo._data) {
+		t.Fatalf("%s failed: expected whole data but received %#v (error: %s)", name, v, err)
+	}
+	for _, invalid := range []string{"hosts", "/list/x", "/list/01", "/a~1b/c~0d/x"} {
+		if v, err := ubo.GetDataAttrByPointer(invalid); err == nil {
+			t.Fatalf("%s failed: expected error for [%s] but received %#v", name, invalid, v)
+		}
+	}
+}
+
+func TestUniversalBo_SetDataAttrByPointer(t *testing.T) {
+	name := "TestUniversalBo_SetDataAttrByPointer"
+	ubo := NewUniversalBo("id", 1)
+	if err := ubo.SetDataAttrByPointer("/hosts/example.com/port", 8080); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if err := ubo.SetDataAttr("/hosts/example.com/tags", []interface{}{}); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	for _, pointer := range []string{"/hosts/example.com/tags/-", "/hosts/example.com/tags/1"} {
+		if err := ubo.SetDataAttr(pointer, "tag"); err != nil {
+			t.Fatalf("%s failed for [%s]: %s", name, pointer, err)
+		}
+	}
+	ubo.SetDataAttr("/a~1b", "escaped")
+	expected := `{"a/b":"escaped","hosts":{"example.com":{"port":8080,"tags":["tag","tag"]}}}`
+	if js := ubo.Sync().GetDataJson(); js != expected {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, expected, js)
+	}
+	if v := ubo.GetDataAttrUnsafe("hosts.example"); v != nil {
+		t.Fatalf("%s failed: key containing dot should not be reachable via semita syntax, received %#v", name, v)
+	}
+	for _, invalid := range []string{"hosts", "/hosts/example.com/tags/5", "/hosts/example.com/port/x"} {
+		if err := ubo.SetDataAttrByPointer(invalid, 1); err == nil {
+			t.Fatalf("%s failed: expected error for [%s]", name, invalid)
+		}
+	}
+}
+
+func TestFieldEncryptor_JsonPointer(t *testing.T) {
+	name := "TestFieldEncryptor_JsonPointer"
+	kp, _ := NewStaticKeyProvider("k1", map[string][]byte{"k1": []byte("0123456789abcdef")})
+	fe := NewFieldEncryptor(kp).EncryptDataPath("/hosts/example.com/password", false)
+	ubo := NewUniversalBo("id", 1)
+	ubo.SetDataAttr("/hosts/example.com/password", "s3cr3t")
+	gbo := ubo.ToGenericBo()
+	if err := fe.encryptGbo(gbo); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if data := gbo.GboGetAttrUnsafe(FieldData, reddo.TypeString).(string); strings.Contains(data, "s3cr3t") {
+		t.Fatalf("%s failed: attribute should be encrypted: %s", name, data)
+	}
+	if err := fe.decryptGbo(gbo); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if bo := NewUniversalBoFromGbo(gbo); bo.GetDataAttrUnsafe("/hosts/example.com/password") != "s3cr3t" {
+		t.Fatalf("%s failed: attribute should be decrypted", name)
+	}
+}
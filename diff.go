@@ -0,0 +1,187 @@
+package henge
+
+import (
+	"strings"
+	"sync"
+)
+
+// ChangeType specifies type of a Change.
+//
+// Available since v0.7.0
+type ChangeType string
+
+const (
+	// ChangeTypeAdded indicates that the attribute does not exist in the old BO but exists in the new one.
+	ChangeTypeAdded ChangeType = "added"
+
+	// ChangeTypeRemoved indicates that the attribute exists in the old BO but not in the new one.
+	ChangeTypeRemoved ChangeType = "removed"
+
+	// ChangeTypeChanged indicates that the attribute exists in both BOs but with different values.
+	ChangeTypeChanged ChangeType = "changed"
+)
+
+// Change captures a difference between two UniversalBo instances.
+//
+// Available since v0.7.0
+type Change struct {
+	Type ChangeType // type of the change
+
+	// Field is the top level field where the change is, one of FieldId, FieldTagVersion, FieldData or FieldExtras.
+	Field string
+
+	// Path is location of the changed attribute, in the same syntax as of UniversalBo.GetDataAttr (e.g. "a.b[0].c").
+	// For FieldExtras, the first element of the path is the extra attribute's key. Path is empty for FieldId and FieldTagVersion.
+	Path string
+
+	OldValue interface{} // value in the old BO, nil if Type is ChangeTypeAdded
+	NewValue interface{} // value in the new BO, nil if Type is ChangeTypeRemoved
+}
+
+// uboContent is a JSON-normalized snapshot of the BO's attributes that are taken into account when comparing BOs.
+//
+// (since v0.7.0) Data is kept in its JSON-encoded form and decoded on first access (see getData).
+type uboContent struct {
+	id         string
+	tagVersion uint64
+	dataJson   string
+	useNumber  bool
+	jsonCodec  JsonCodec
+	dataOnce   sync.Once
+	data       interface{}
+	extras     interface{}
+}
+
+// getData returns the snapshot of data, decoded from its JSON-encoded form on first access.
+// If data cannot be decoded, its raw form is returned instead.
+func (c *uboContent) getData() interface{} {
+	c.dataOnce.Do(func() {
+		if c.dataJson != "" {
+			if err := unmarshalJsonData(c.jsonCodec, []byte(c.dataJson), c.useNumber, &c.data); err != nil {
+				c.data = c.dataJson
+			}
+		}
+	})
+	return c.data
+}
+
+// sameData returns true if data of both snapshots is the same, comparing the JSON-encoded forms first so that data
+// does not need to be decoded if it has not been changed.
+func (c *uboContent) sameData(other *uboContent) bool {
+	if c.dataJson != "" && c.dataJson == other.dataJson {
+		return true
+	}
+	return jsonValuesEqual(c.getData(), other.getData())
+}
+
+// _content takes a uboContent snapshot of the BO; nil BO results in an empty snapshot.
+func (ubo *UniversalBo) _content() *uboContent {
+	if ubo == nil {
+		return &uboContent{extras: map[string]interface{}{}}
+	}
+	ubo._lock.RLock()
+	defer ubo._lock.RUnlock()
+	return ubo._takeContent()
+}
+
+// _takeContent takes a uboContent snapshot of the BO.
+// If an attribute cannot be normalized, its raw form is used instead.
+//
+// Caller is responsible for locking the BO.
+func (ubo *UniversalBo) _takeContent() *uboContent {
+	content := &uboContent{id: ubo.id, tagVersion: ubo.tagVersion, useNumber: ubo._useNumber, jsonCodec: ubo._jsonCodec, extras: map[string]interface{}{}}
+	if js, err := ubo._currentDataJson(); err != nil {
+		content.data = ubo.dataJson
+	} else {
+		content.dataJson = string(js)
+	}
+	if len(ubo._extraAttrs) > 0 {
+		js, err := ubo._json().Marshal(ubo._extraAttrs)
+		if err == nil {
+			err = ubo._json().Unmarshal(js, &content.extras)
+		}
+		if err != nil {
+			content.extras = cloneMap(ubo._extraAttrs)
+		}
+	}
+	return content
+}
+
+// toSemitaPath converts a list of reference tokens to path in semita syntax (e.g. "a.b[0].c"),
+// using the data tree root to determine if a token is an object key or an array index.
+func toSemitaPath(root interface{}, tokens []string) string {
+	var sb strings.Builder
+	node := root
+	for _, token := range tokens {
+		switch n := node.(type) {
+		case []interface{}:
+			sb.WriteString("[" + token + "]")
+			index, err := parseJsonArrayIndex(token, len(n), false)
+			if err != nil {
+				return sb.String()
+			}
+			node = n[index]
+		default:
+			if sb.Len() > 0 {
+				sb.WriteString(".")
+			}
+			sb.WriteString(token)
+			if m, ok := n.(map[string]interface{}); ok {
+				node = m[token]
+			} else {
+				node = nil
+			}
+		}
+	}
+	return sb.String()
+}
+
+func diffContentTree(field string, a, b interface{}) []Change {
+	changes := make([]Change, 0)
+	diffJsonTrees([]string{}, a, b, func(op string, tokens []string, oldValue, newValue interface{}) {
+		change := Change{Field: field, OldValue: oldValue, NewValue: newValue}
+		switch op {
+		case jsonDiffOpAdd:
+			change.Type, change.Path = ChangeTypeAdded, toSemitaPath(b, tokens)
+		case jsonDiffOpRemove:
+			change.Type, change.Path = ChangeTypeRemoved, toSemitaPath(a, tokens)
+		default:
+			change.Type, change.Path = ChangeTypeChanged, toSemitaPath(a, tokens)
+		}
+		changes = append(changes, change)
+	})
+	return changes
+}
+
+// Diff compares two UniversalBo instances and returns the list of differences, from a to b.
+//   - BO's id and tag-version are compared as a whole.
+//   - User-defined data and extra attributes are compared attribute by attribute, down to leaf values.
+//   - Timestamps and checksum are not taken into account.
+//   - Values are normalized to JSON-compatible types before comparing, hence an int and a float with the same value
+//     are considered equal. Values reported in Change are also normalized.
+//
+// A nil BO is treated as an empty one.
+//
+// Available since v0.7.0
+func Diff(a, b *UniversalBo) []Change {
+	contentA, contentB := a._content(), b._content()
+	changes := make([]Change, 0)
+	if contentA.id != contentB.id {
+		changes = append(changes, Change{Type: ChangeTypeChanged, Field: FieldId, OldValue: contentA.id, NewValue: contentB.id})
+	}
+	if contentA.tagVersion != contentB.tagVersion {
+		changes = append(changes, Change{Type: ChangeTypeChanged, Field: FieldTagVersion, OldValue: contentA.tagVersion, NewValue: contentB.tagVersion})
+	}
+	changes = append(changes, diffContentTree(FieldData, contentA.getData(), contentB.getData())...)
+	changes = append(changes, diffContentTree(FieldExtras, contentA.extras, contentB.extras)...)
+	return changes
+}
+
+// ContentEqual returns true if the two UniversalBo instances have the same content, that is Diff(a, b) returns no change.
+//
+// Timestamps, checksum and formatting differences (e.g. JSON key order, 1 vs 1.0) are ignored.
+//
+// Available since v0.7.0
+func ContentEqual(a, b *UniversalBo) bool {
+	return len(Diff(a, b)) == 0
+}
@@ -0,0 +1,96 @@
+package henge
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	name := "TestDiff"
+	a := NewUniversalBo("id", 1)
+	a.SetDataJson(`{"name":"henge","tags":["a","b","c"],"obj":{"x":1,"y":true}}`)
+	a.SetExtraAttr("email", "a@example.com")
+	a.SetExtraAttr("age", 18)
+
+	b := a.Clone()
+	b.SetTagVersion(2)
+	b.SetDataAttr("name", "HENGE")
+	b.SetDataAttr("tags[3]", "d")
+	b.SetDataAttr("obj.z", "new")
+	b.SetExtraAttr("age", 19)
+	b.SetExtraAttr("active", true)
+	b.SetTimeUpdated(time.Now().Add(time.Hour))
+
+	expected := []Change{
+		{Type: ChangeTypeChanged, Field: FieldTagVersion, OldValue: uint64(1), NewValue: uint64(2)},
+		{Type: ChangeTypeChanged, Field: FieldData, Path: "name", OldValue: "henge", NewValue: "HENGE"},
+		{Type: ChangeTypeAdded, Field: FieldData, Path: "obj.z", NewValue: "new"},
+		{Type: ChangeTypeAdded, Field: FieldData, Path: "tags[3]", NewValue: "d"},
+		{Type: ChangeTypeAdded, Field: FieldExtras, Path: "active", NewValue: true},
+		{Type: ChangeTypeChanged, Field: FieldExtras, Path: "age", OldValue: 18.0, NewValue: 19.0},
+	}
+	if changes := Diff(a, b); !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, expected, changes)
+	}
+
+	expected = []Change{
+		{Type: ChangeTypeChanged, Field: FieldTagVersion, OldValue: uint64(2), NewValue: uint64(1)},
+		{Type: ChangeTypeChanged, Field: FieldData, Path: "name", OldValue: "HENGE", NewValue: "henge"},
+		{Type: ChangeTypeRemoved, Field: FieldData, Path: "obj.z", OldValue: "new"},
+		{Type: ChangeTypeRemoved, Field: FieldData, Path: "tags[3]", OldValue: "d"},
+		{Type: ChangeTypeRemoved, Field: FieldExtras, Path: "active", OldValue: true},
+		{Type: ChangeTypeChanged, Field: FieldExtras, Path: "age", OldValue: 19.0, NewValue: 18.0},
+	}
+	if changes := Diff(b, a); !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, expected, changes)
+	}
+
+	if changes := Diff(a, a.Clone()); len(changes) != 0 {
+		t.Fatalf("%s failed: expected no change but received %#v", name, changes)
+	}
+}
+
+func TestDiff_nil(t *testing.T) {
+	name := "TestDiff_nil"
+	if changes := Diff(nil, nil); len(changes) != 0 {
+		t.Fatalf("%s failed: expected no change but received %#v", name, changes)
+	}
+	a := NewUniversalBo("id", 0)
+	a.SetDataJson(`{"a":1}`)
+	expected := []Change{
+		{Type: ChangeTypeChanged, Field: FieldId, OldValue: "", NewValue: "id"},
+		{Type: ChangeTypeChanged, Field: FieldData, Path: "", OldValue: nil, NewValue: map[string]interface{}{"a": 1.0}},
+	}
+	if changes := Diff(nil, a); !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, expected, changes)
+	}
+}
+
+func TestContentEqual(t *testing.T) {
+	name := "TestContentEqual"
+	a := NewUniversalBo("id", 0)
+	a.SetDataJson(`{"a":1,"b":[1.0,"x"]}`)
+	a.SetExtraAttr("n", 2)
+
+	b := NewUniversalBo("id", 0)
+	b.SetDataJson(`{ "b" : [1, "x"], "a" : 1.0 }`)
+	b.SetExtraAttr("n", 2.0)
+	b.SetTimeUpdated(time.Now().Add(time.Hour))
+	if !ContentEqual(a, b) {
+		t.Fatalf("%s failed: expected content equal, changes: %#v", name, Diff(a, b))
+	}
+
+	b.SetDataAttr("b[1]", "y")
+	if ContentEqual(a, b) {
+		t.Fatalf("%s failed: expected content not equal", name)
+	}
+
+	// numbers are compared by value, regardless of UboOpt.UseNumber
+	c := NewUniversalBo("id", 0, UboOpt{UseNumber: true})
+	c.SetDataJson(`{"a":1.0,"b":[1e0,"x"]}`)
+	c.SetExtraAttr("n", 2)
+	if !ContentEqual(a, c) || !ContentEqual(c, a) {
+		t.Fatalf("%s failed: expected content equal, changes: %#v", name, Diff(a, c))
+	}
+}
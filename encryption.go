@@ -0,0 +1,350 @@
+package henge
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/btnguyen2k/consu/reddo"
+	"github.com/btnguyen2k/godal"
+)
+
+// KeyProvider supplies encryption keys to FieldEncryptor.
+//
+// Each key is identified by an id, which is stored along with the encrypted values so that keys can be rotated:
+// new values are encrypted with the current key, existing values are decrypted with the key they were encrypted with.
+//
+// Available since v0.7.0
+type KeyProvider interface {
+	// CurrentKey returns the id and the key used to encrypt new values.
+	CurrentKey() (keyId string, key []byte, err error)
+
+	// GetKey returns the key identified by keyId, used to decrypt values.
+	GetKey(keyId string) ([]byte, error)
+}
+
+// StaticKeyProvider is a KeyProvider backed by a static set of keys.
+//
+// Available since v0.7.0
+type StaticKeyProvider struct {
+	currentKeyId string
+	keys         map[string][]byte
+}
+
+// NewStaticKeyProvider creates a new StaticKeyProvider instance.
+//   - keys is a map of {key-id: key}, each key must be 16, 24 or 32 bytes long (AES-128, AES-192 or AES-256).
+//   - currentKeyId is id of the key used to encrypt new values, it must exist in keys.
+//   - key ids must not contain the character ':'.
+//
+// Available since v0.7.0
+func NewStaticKeyProvider(currentKeyId string, keys map[string][]byte) (*StaticKeyProvider, error) {
+	for keyId, key := range keys {
+		if keyId == "" || strings.Contains(keyId, ":") {
+			return nil, fmt.Errorf("invalid key id [%s]", keyId)
+		}
+		if _, err := aes.NewCipher(key); err != nil {
+			return nil, fmt.Errorf("invalid key [%s]: %s", keyId, err)
+		}
+	}
+	if _, ok := keys[currentKeyId]; !ok {
+		return nil, fmt.Errorf("key [%s] not found", currentKeyId)
+	}
+	kp := &StaticKeyProvider{currentKeyId: currentKeyId, keys: make(map[string][]byte, len(keys))}
+	for keyId, key := range keys {
+		kp.keys[keyId] = append([]byte{}, key...)
+	}
+	return kp, nil
+}
+
+// CurrentKey implements KeyProvider.CurrentKey.
+func (kp *StaticKeyProvider) CurrentKey() (string, []byte, error) {
+	return kp.currentKeyId, kp.keys[kp.currentKeyId], nil
+}
+
+// GetKey implements KeyProvider.GetKey.
+func (kp *StaticKeyProvider) GetKey(keyId string) ([]byte, error) {
+	if key, ok := kp.keys[keyId]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("key [%s] not found", keyId)
+}
+
+const (
+	encryptedValuePrefix = "$enc:"
+	encModeRandom        = "r"
+	encModeDeterministic = "d"
+)
+
+// FieldEncryptor encrypts/decrypts selected data attributes and extra attributes of BOs with AES-GCM.
+//
+// Values are JSON-encoded before being encrypted, and stored as strings in the format
+// "$enc:<mode>:<key-id>:<base64(nonce+ciphertext)>".
+//
+// Encrypted attributes are bound to their path (data attributes) or key (extra attributes): the ciphertext of an
+// attribute cannot be copied to another attribute without failing decryption.
+//
+// In deterministic mode, the nonce is derived from the attribute and the plaintext with a key derived from the
+// encryption key, hence the same value of the same attribute always results in the same ciphertext (under the same key),
+// which allows equality search on encrypted fields (see EncryptDataPathValue and EncryptExtraAttrValue) at the cost of
+// revealing which records share the same value. Attributes used in filters or unique indexes must be encrypted in
+// deterministic mode.
+//
+// Available since v0.7.0
+type FieldEncryptor struct {
+	keyProvider KeyProvider
+	dataPaths   map[string]bool // {path: deterministic}
+	extraAttrs  map[string]bool // {key: deterministic}
+}
+
+// NewFieldEncryptor creates a new FieldEncryptor instance.
+//
+// Available since v0.7.0
+func NewFieldEncryptor(keyProvider KeyProvider) *FieldEncryptor {
+	return &FieldEncryptor{keyProvider: keyProvider, dataPaths: make(map[string]bool), extraAttrs: make(map[string]bool)}
+}
+
+// EncryptDataPath marks the data attribute at 'path' (in the same syntax as of UniversalBo.GetDataAttr) as encrypted.
+//
+// Available since v0.7.0
+func (fe *FieldEncryptor) EncryptDataPath(path string, deterministic bool) *FieldEncryptor {
+	fe.dataPaths[path] = deterministic
+	return fe
+}
+
+// EncryptExtraAttr marks the extra attribute specified by 'key' as encrypted.
+//
+// Available since v0.7.0
+func (fe *FieldEncryptor) EncryptExtraAttr(key string, deterministic bool) *FieldEncryptor {
+	fe.extraAttrs[key] = deterministic
+	return fe
+}
+
+// IsEncryptedValue returns true if the value is in the encrypted format produced by FieldEncryptor.
+//
+// Available since v0.7.0
+func IsEncryptedValue(value interface{}) bool {
+	s, ok := value.(string)
+	return ok && strings.HasPrefix(s, encryptedValuePrefix)
+}
+
+// EncryptValue encrypts a value with the current key. The encrypted value is not bound to any attribute.
+//
+// Available since v0.7.0
+func (fe *FieldEncryptor) EncryptValue(value interface{}, deterministic bool) (string, error) {
+	return fe.seal(value, deterministic, nil)
+}
+
+// EncryptDataPathValue encrypts a value of the data attribute at 'path', configured by EncryptDataPath.
+//
+// Values of attributes encrypted in deterministic mode can be used to build filters for equality search.
+//
+// Available since v0.7.0
+func (fe *FieldEncryptor) EncryptDataPathValue(path string, value interface{}) (string, error) {
+	deterministic, ok := fe.dataPaths[path]
+	if !ok {
+		return "", fmt.Errorf("data attribute [%s] is not encrypted", path)
+	}
+	return fe.seal(value, deterministic, attrAad(FieldData, path))
+}
+
+// EncryptExtraAttrValue encrypts a value of the extra attribute specified by 'key', configured by EncryptExtraAttr.
+//
+// Values of attributes encrypted in deterministic mode can be used to build filters for equality search.
+//
+// Available since v0.7.0
+func (fe *FieldEncryptor) EncryptExtraAttrValue(key string, value interface{}) (string, error) {
+	deterministic, ok := fe.extraAttrs[key]
+	if !ok {
+		return "", fmt.Errorf("extra attribute [%s] is not encrypted", key)
+	}
+	return fe.seal(value, deterministic, attrAad(FieldExtras, key))
+}
+
+// attrAad returns the additional authenticated data binding an encrypted value to the attribute 'name' of 'field'.
+func attrAad(field, name string) []byte {
+	return []byte(field + ":" + name)
+}
+
+// nonceKeyInfo is used to derive, from an encryption key, the key used to derive nonces in deterministic mode.
+const nonceKeyInfo = "henge:field-encryptor:nonce"
+
+// seal encrypts a value with the current key, aad being the additional authenticated data (nil for none).
+func (fe *FieldEncryptor) seal(value interface{}, deterministic bool, aad []byte) (string, error) {
+	plaintext, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	keyId, key, err := fe.keyProvider.CurrentKey()
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAead(key)
+	if err != nil {
+		return "", err
+	}
+	mode, nonce := encModeRandom, make([]byte, aead.NonceSize())
+	if deterministic {
+		mode = encModeDeterministic
+		kdf := hmac.New(sha256.New, key)
+		kdf.Write([]byte(nonceKeyInfo))
+		mac := hmac.New(sha256.New, kdf.Sum(nil))
+		mac.Write(aad)
+		mac.Write([]byte{0})
+		mac.Write(plaintext)
+		copy(nonce, mac.Sum(nil))
+	} else if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+	ciphertext := aead.Seal(nonce, nonce, plaintext, aad)
+	return encryptedValuePrefix + mode + ":" + keyId + ":" + base64.RawURLEncoding.EncodeToString(ciphertext), nil
+}
+
+// DecryptValue decrypts a value encrypted by EncryptValue.
+//
+// Available since v0.7.0
+func (fe *FieldEncryptor) DecryptValue(encrypted string) (interface{}, error) {
+	return fe.open(encrypted, nil, false)
+}
+
+// open decrypts a value encrypted by seal with the same additional authenticated data. Numbers are decoded as
+// json.Number if useNumber is true, float64 otherwise.
+func (fe *FieldEncryptor) open(encrypted string, aad []byte, useNumber bool) (interface{}, error) {
+	if !IsEncryptedValue(encrypted) {
+		return nil, errors.New("value is not encrypted")
+	}
+	tokens := strings.SplitN(encrypted[len(encryptedValuePrefix):], ":", 3)
+	if len(tokens) != 3 || (tokens[0] != encModeRandom && tokens[0] != encModeDeterministic) {
+		return nil, errors.New("invalid encrypted value format")
+	}
+	ciphertext, err := base64.RawURLEncoding.DecodeString(tokens[2])
+	if err != nil {
+		return nil, err
+	}
+	key, err := fe.keyProvider.GetKey(tokens[1])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAead(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("invalid encrypted value format")
+	}
+	plaintext, err := aead.Open(nil, ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():], aad)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	err = unmarshalJsonData(JsonCodecStd, plaintext, useNumber, &value)
+	return value, err
+}
+
+func newAead(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// attrTransformer transforms the value of an encrypted attribute, aad binding the value to the attribute.
+type attrTransformer func(value interface{}, deterministic bool, aad []byte) (interface{}, error)
+
+// transformData applies the transformation function, in place, to the configured attributes of the data tree.
+// Attributes that do not exist or are nil are left untouched.
+func (fe *FieldEncryptor) transformData(data interface{}, f attrTransformer) error {
+	if fe == nil || data == nil {
+		return nil
+	}
+	for path, deterministic := range fe.dataPaths {
+		if v, err := getDataValue(&data, path); err == nil && v != nil {
+			if v, err = f(v, deterministic, attrAad(FieldData, path)); err != nil {
+				return fmt.Errorf("data attribute [%s]: %s", path, err)
+			}
+			if err = setDataValue(&data, path, v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// transformExtras applies the transformation function to the configured extra attributes of gbo.
+// Attributes that do not exist or are nil are left untouched.
+func (fe *FieldEncryptor) transformExtras(gbo godal.IGenericBo, f attrTransformer) error {
+	if fe == nil || gbo == nil {
+		return nil
+	}
+	for key, deterministic := range fe.extraAttrs {
+		if v, err := gbo.GboGetAttr(key, nil); err == nil && v != nil {
+			if v, err = f(v, deterministic, attrAad(FieldExtras, key)); err != nil {
+				return fmt.Errorf("extra attribute [%s]: %s", key, err)
+			}
+			gbo.GboSetAttr(key, v)
+		}
+	}
+	return nil
+}
+
+// transformGbo applies the transformation function to the configured data attributes and extra attributes of gbo,
+// whose data is in JSON format.
+func (fe *FieldEncryptor) transformGbo(gbo godal.IGenericBo, f attrTransformer) error {
+	if fe == nil || gbo == nil {
+		return nil
+	}
+	if len(fe.dataPaths) > 0 {
+		dataJson, _ := gbo.GboGetAttrUnsafe(FieldData, reddo.TypeString).(string)
+		var data interface{}
+		if dataJson != "" {
+			if err := unmarshalJsonData(nil, []byte(dataJson), true, &data); err != nil {
+				return err
+			}
+		}
+		if data != nil {
+			if err := fe.transformData(data, f); err != nil {
+				return err
+			}
+			js, err := jsonCodecOrDefault(nil).Marshal(data)
+			if err != nil {
+				return err
+			}
+			gbo.GboSetAttr(FieldData, string(js))
+		}
+	}
+	return fe.transformExtras(gbo, f)
+}
+
+// encryptAttr encrypts an attribute value. Values are always encrypted, even if they look already encrypted, so that
+// plaintext values in the encrypted format are loaded back as-is.
+func (fe *FieldEncryptor) encryptAttr(value interface{}, deterministic bool, aad []byte) (interface{}, error) {
+	return fe.seal(value, deterministic, aad)
+}
+
+// decryptAttr decrypts an attribute value. Decrypted values are re-encoded to JSON along with the rest of the BO, hence
+// numbers are decoded as json.Number to keep their precision whether or not the BO decodes numbers as json.Number (see
+// UboOpt.UseNumber). Values that are not encrypted (e.g. written before encryption was enabled) are left untouched.
+func (fe *FieldEncryptor) decryptAttr(value interface{}, _ bool, aad []byte) (interface{}, error) {
+	if IsEncryptedValue(value) {
+		return fe.open(value.(string), aad, true)
+	}
+	return value, nil
+}
+
+// encryptGbo encrypts the configured attributes of gbo in place.
+func (fe *FieldEncryptor) encryptGbo(gbo godal.IGenericBo) error {
+	return fe.transformGbo(gbo, fe.encryptAttr)
+}
+
+// decryptGbo decrypts the configured attributes of gbo in place. Values that are not encrypted (e.g. written before
+// encryption was enabled) are left untouched.
+func (fe *FieldEncryptor) decryptGbo(gbo godal.IGenericBo) error {
+	return fe.transformGbo(gbo, fe.decryptAttr)
+}
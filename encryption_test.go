@@ -0,0 +1,187 @@
+package henge
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/btnguyen2k/consu/reddo"
+)
+
+var (
+	testEncKey1 = []byte("0123456789abcdef0123456789abcdef")
+	testEncKey2 = []byte("fedcba9876543210")
+)
+
+func TestNewStaticKeyProvider(t *testing.T) {
+	name := "TestNewStaticKeyProvider"
+	if _, err := NewStaticKeyProvider("k1", map[string][]byte{"k1": testEncKey1, "k2": testEncKey2}); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if _, err := NewStaticKeyProvider("k3", map[string][]byte{"k1": testEncKey1}); err == nil {
+		t.Fatalf("%s failed: expected error for missing current key", name)
+	}
+	if _, err := NewStaticKeyProvider("k1", map[string][]byte{"k1": []byte("short")}); err == nil {
+		t.Fatalf("%s failed: expected error for invalid key", name)
+	}
+	if _, err := NewStaticKeyProvider("k:1", map[string][]byte{"k:1": testEncKey1}); err == nil {
+		t.Fatalf("%s failed: expected error for invalid key id", name)
+	}
+}
+
+func TestFieldEncryptor_EncryptValue(t *testing.T) {
+	name := "TestFieldEncryptor_EncryptValue"
+	kp, _ := NewStaticKeyProvider("k1", map[string][]byte{"k1": testEncKey1})
+	fe := NewFieldEncryptor(kp)
+	for _, value := range []interface{}{"a@example.com", 12.5, true, map[string]interface{}{"x": []interface{}{1.0, "y"}}} {
+		for _, deterministic := range []bool{false, true} {
+			enc1, err := fe.EncryptValue(value, deterministic)
+			if err != nil || !IsEncryptedValue(enc1) || !strings.HasPrefix(enc1, "$enc:") {
+				t.Fatalf("%s failed: %#v / %s", name, enc1, err)
+			}
+			enc2, _ := fe.EncryptValue(value, deterministic)
+			if (enc1 == enc2) != deterministic {
+				t.Fatalf("%s failed: deterministic %#v, but received %#v and %#v", name, deterministic, enc1, enc2)
+			}
+			if dec, err := fe.DecryptValue(enc1); err != nil || !reflect.DeepEqual(dec, value) {
+				t.Fatalf("%s failed: expected %#v but received %#v (error: %s)", name, value, dec, err)
+			}
+		}
+	}
+
+	enc, _ := fe.EncryptValue("secret", false)
+	tampered := enc[:len(enc)-2] + "AA"
+	if tampered == enc {
+		tampered = enc[:len(enc)-2] + "BB"
+	}
+	if _, err := fe.DecryptValue(tampered); err == nil {
+		t.Fatalf("%s failed: expected error for tampered value", name)
+	}
+	if _, err := fe.DecryptValue("secret"); err == nil {
+		t.Fatalf("%s failed: expected error for unencrypted value", name)
+	}
+
+	fe.EncryptDataPath("a", true).EncryptExtraAttr("b", true)
+	encA, _ := fe.EncryptDataPathValue("a", "secret")
+	encB, _ := fe.EncryptExtraAttrValue("b", "secret")
+	if !IsEncryptedValue(encA) || !IsEncryptedValue(encB) || encA == encB || encA == enc {
+		t.Fatalf("%s failed: values should be bound to their attributes: %#v / %#v", name, encA, encB)
+	}
+	if v, _ := fe.EncryptDataPathValue("a", "secret"); v != encA {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, encA, v)
+	}
+	if _, err := fe.DecryptValue(encA); err == nil {
+		t.Fatalf("%s failed: expected error for value bound to an attribute", name)
+	}
+	if _, err := fe.EncryptExtraAttrValue("c", "secret"); err == nil {
+		t.Fatalf("%s failed: expected error for unencrypted attribute", name)
+	}
+}
+
+func TestFieldEncryptor_KeyRotation(t *testing.T) {
+	name := "TestFieldEncryptor_KeyRotation"
+	kp1, _ := NewStaticKeyProvider("k1", map[string][]byte{"k1": testEncKey1})
+	enc, _ := NewFieldEncryptor(kp1).EncryptValue("secret", true)
+
+	kp2, _ := NewStaticKeyProvider("k2", map[string][]byte{"k1": testEncKey1, "k2": testEncKey2})
+	fe := NewFieldEncryptor(kp2)
+	if dec, err := fe.DecryptValue(enc); err != nil || dec != "secret" {
+		t.Fatalf("%s failed: %#v / %s", name, dec, err)
+	}
+	if enc2, _ := fe.EncryptValue("secret", true); enc2 == enc || !strings.Contains(enc2, ":k2:") {
+		t.Fatalf("%s failed: value should be encrypted with the new key: %#v", name, enc2)
+	}
+
+	kp3, _ := NewStaticKeyProvider("k2", map[string][]byte{"k2": testEncKey2})
+	if _, err := NewFieldEncryptor(kp3).DecryptValue(enc); err == nil {
+		t.Fatalf("%s failed: expected error for unknown key", name)
+	}
+}
+
+func TestFieldEncryptor_Gbo(t *testing.T) {
+	name := "TestFieldEncryptor_Gbo"
+	kp, _ := NewStaticKeyProvider("k1", map[string][]byte{"k1": testEncKey1})
+	fe := NewFieldEncryptor(kp).EncryptDataPath("contact.phone", false).EncryptDataPath("missing", false).EncryptExtraAttr("email", true)
+
+	ubo := NewUniversalBo("id", 1)
+	ubo.SetDataJson(`{"name":"henge","contact":{"phone":"0123","fax":"456"}}`)
+	ubo.SetExtraAttr("email", "a@example.com")
+	gbo := ubo.ToGenericBo()
+	if err := fe.encryptGbo(gbo); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	dataJson := gbo.GboGetAttrUnsafe(FieldData, reddo.TypeString).(string)
+	if strings.Contains(dataJson, "0123") || !strings.Contains(dataJson, "456") || strings.Contains(dataJson, "missing") {
+		t.Fatalf("%s failed: unexpected encrypted data %s", name, dataJson)
+	}
+	if email := gbo.GboGetAttrUnsafe("email", nil); !IsEncryptedValue(email) {
+		t.Fatalf("%s failed: extra attribute should be encrypted: %#v", name, email)
+	}
+	if csum := gbo.GboGetAttrUnsafe(FieldChecksum, reddo.TypeString); csum != ubo.GetChecksum() {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, ubo.GetChecksum(), csum)
+	}
+
+	// encrypted values are bound to their attributes
+	swapped := ubo.ToGenericBo()
+	swapped.GboSetAttr("email", gbo.GboGetAttrUnsafe("email", nil))
+	if err := fe.encryptGbo(swapped); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	swapped.GboSetAttr(FieldData, dataJson)
+	if err := fe.decryptGbo(swapped); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if v := swapped.GboGetAttrUnsafe("email", nil); v != gbo.GboGetAttrUnsafe("email", nil) {
+		t.Fatalf("%s failed: plaintext in encrypted format should be loaded as-is but received %#v", name, v)
+	}
+	moved := ubo.ToGenericBo()
+	encPhone, _ := fe.EncryptDataPathValue("contact.phone", "a@example.com")
+	moved.GboSetAttr("email", encPhone)
+	if err := fe.decryptGbo(moved); err == nil {
+		t.Fatalf("%s failed: expected error for value encrypted for another attribute", name)
+	}
+
+	if err := fe.decryptGbo(gbo); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	loaded := NewUniversalBoFromGbo(gbo)
+	if !ContentEqual(ubo, loaded) || loaded.IsChecksumMismatched() {
+		t.Fatalf("%s failed: %#v", name, Diff(ubo, loaded))
+	}
+
+	// unencrypted values are loaded as-is
+	gbo = ubo.ToGenericBo()
+	if err := fe.decryptGbo(gbo); err != nil || !ContentEqual(ubo, NewUniversalBoFromGbo(gbo)) {
+		t.Fatalf("%s failed: unencrypted values should be left untouched (error: %s)", name, err)
+	}
+}
+
+func TestFieldEncryptor_UseNumber(t *testing.T) {
+	name := "TestFieldEncryptor_UseNumber"
+	kp, _ := NewStaticKeyProvider("k1", map[string][]byte{"k1": testEncKey1})
+	fe := NewFieldEncryptor(kp).EncryptDataPath("n", false)
+	// MD5 checksum depends on the Go types of numbers, which differ between binary codecs
+	opts := []UboOpt{{UseNumber: true, ChecksumAlgorithm: ChecksumSha256}}
+	// 2^53+1 cannot be represented as float64
+	const big = "9007199254740993"
+	for _, codec := range []DataCodec{nil, DataCodecMsgpack, DataCodecCbor} {
+		c := boCodec{fieldEncryptor: fe, dataCodec: codec}
+		ubo := NewUniversalBo("id", 1, opts...)
+		ubo.SetDataJson(`{"n":` + big + `,"m":` + big + `}`)
+		gbo, err := c.toGenericBo(ubo)
+		if err != nil {
+			t.Fatalf("%s failed [%T]: %s", name, codec, err)
+		}
+		bo, err := c.toUniversalBo(gbo, opts...)
+		if err != nil || bo == nil {
+			t.Fatalf("%s failed [%T]: %#v / %s", name, codec, bo, err)
+		}
+		if v := bo.GetDataAttrUnsafe("n"); v != json.Number(big) {
+			t.Fatalf("%s failed [%T]: expected %s but received %#v", name, codec, big, v)
+		}
+		if bo.IsChecksumMismatched() || bo.GetChecksum() != ubo.GetChecksum() {
+			t.Fatalf("%s failed [%T]: expected checksum %#v but received %#v", name, codec, ubo.GetChecksum(), bo.GetChecksum())
+		}
+	}
+}
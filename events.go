@@ -0,0 +1,82 @@
+package henge
+
+import (
+	"context"
+	"time"
+)
+
+// ChangeEvent describes a change made to a BO by a DAO write.
+//
+//   - Create: Before is nil, After is the created BO.
+//   - Update and Save: Before is the replaced version (nil if Save created the BO), After is the written BO.
+//   - Delete: Before is the deleted version, After is nil.
+//
+// Available since v0.7.0
+type ChangeEvent struct {
+	Op        DaoOp        // the operation: OpCreate, OpUpdate, OpSave or OpDelete
+	Id        string       // id of the changed BO
+	Before    *UniversalBo // the BO before the change, nil if it did not exist
+	After     *UniversalBo // the BO after the change, nil if it was deleted
+	Timestamp time.Time    // when the change was made
+}
+
+// EventSink receives ChangeEvents emitted by DAOs, e.g. to publish them to a message broker.
+//
+// Available since v0.7.0
+type EventSink interface {
+	// Emit delivers an event. An error means the event was not delivered; depending on how events are emitted (see
+	// EmitEvents and OutboxRelay), it may be delivered again later.
+	Emit(ctx context.Context, ev *ChangeEvent) error
+}
+
+// EventSinkFunc adapts a function to EventSink.
+//
+// Available since v0.7.0
+type EventSinkFunc func(ctx context.Context, ev *ChangeEvent) error
+
+// Emit implements EventSink.Emit.
+func (fn EventSinkFunc) Emit(ctx context.Context, ev *ChangeEvent) error {
+	return fn(ctx, ev)
+}
+
+// EmitEvents returns an Interceptor (see Chain) that emits a ChangeEvent to sink after each successful Create, Update,
+// Save or Delete. Before an Update or a Delete, the stored version of the BO is loaded through the rest of the chain
+// (as a Get operation) to fill ChangeEvent.Before. Events are timestamped with BO's clock or, if BO has none, the
+// wrapped DAO's one (see UniversalDaoSql.SetClock).
+//
+// Events are emitted directly, outside of the write: an event is lost if the process stops between the write and the
+// emission, and events of concurrent writes to the same BO may be emitted out of order. If the sink fails, the
+// operation returns its results along with the sink's error. For at-least-once delivery with events ordered per BO,
+// use the outbox mode of the DAO (see UniversalDaoSql.SetOutboxTable and UniversalDaoMongo.SetOutboxCollection)
+// along with OutboxRelay instead.
+//
+// Available since v0.7.0
+func EmitEvents(sink EventSink) Interceptor {
+	return func(ctx context.Context, call *DaoCall, next Invoker) error {
+		var before *UniversalBo
+		switch call.Op {
+		case OpCreate, OpSave:
+		case OpUpdate, OpDelete:
+			if call.Bo != nil {
+				get := &DaoCall{Op: OpGet, Id: call.Bo.GetId()}
+				if err := next(ctx, get); err != nil {
+					return err
+				}
+				before = get.Result
+			}
+		default:
+			return next(ctx, call)
+		}
+		if err := next(ctx, call); err != nil || !call.Ok {
+			return err
+		}
+		ev := &ChangeEvent{Op: call.Op, Id: call.Bo.GetId(), Before: before, Timestamp: call.Bo._clockWithDefault(call.clock).Now()}
+		if call.Op == OpSave {
+			ev.Before = call.Result
+		}
+		if call.Op != OpDelete {
+			ev.After = call.Bo.Clone()
+		}
+		return sink.Emit(ctx, ev)
+	}
+}
@@ -0,0 +1,101 @@
+package henge
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestEmitEvents(t *testing.T) {
+	name := "TestEmitEvents"
+	var events []*ChangeEvent
+	sink := EventSinkFunc(func(ctx context.Context, ev *ChangeEvent) error {
+		events = append(events, ev)
+		return nil
+	})
+	dao := Chain(&memDao{bos: map[string]*UniversalBo{}}, EmitEvents(sink))
+
+	bo := NewUniversalBo("id", 1)
+	bo.SetDataAttr("name", "v1")
+	if ok, err := dao.Create(bo); !ok || err != nil {
+		t.Fatalf("%s failed: %#v / %s", name, ok, err)
+	}
+	if len(events) != 1 || events[0].Op != OpCreate || events[0].Id != "id" || events[0].Before != nil || events[0].After == nil {
+		t.Fatalf("%s failed: unexpected events %#v", name, events)
+	}
+	if ok, err := dao.Create(bo); ok || err == nil || len(events) != 1 {
+		t.Fatalf("%s failed: expected no event for failed create but received %#v", name, events)
+	}
+
+	bo.SetDataAttr("name", "v2")
+	if ok, err := dao.Update(bo); !ok || err != nil {
+		t.Fatalf("%s failed: %#v / %s", name, ok, err)
+	}
+	ev := events[1]
+	if ev.Op != OpUpdate || ev.Before == nil || ev.Before.GetDataAttrAsUnsafe("name", nil) != "v1" || ev.After.GetDataAttrAsUnsafe("name", nil) != "v2" {
+		t.Fatalf("%s failed: unexpected event %#v", name, ev)
+	}
+
+	bo.SetDataAttr("name", "v3")
+	if ok, _, err := dao.Save(bo); !ok || err != nil {
+		t.Fatalf("%s failed: %#v / %s", name, ok, err)
+	}
+	ev = events[2]
+	if ev.Op != OpSave || ev.Before == nil || ev.Before.GetDataAttrAsUnsafe("name", nil) != "v2" || ev.After.GetDataAttrAsUnsafe("name", nil) != "v3" {
+		t.Fatalf("%s failed: unexpected event %#v", name, ev)
+	}
+
+	if ok, err := dao.Delete(bo); !ok || err != nil {
+		t.Fatalf("%s failed: %#v / %s", name, ok, err)
+	}
+	ev = events[3]
+	if ev.Op != OpDelete || ev.Before == nil || ev.Before.GetDataAttrAsUnsafe("name", nil) != "v3" || ev.After != nil {
+		t.Fatalf("%s failed: unexpected event %#v", name, ev)
+	}
+	if ok, err := dao.Delete(bo); ok || err != nil || len(events) != 4 {
+		t.Fatalf("%s failed: expected no event for no-op delete but received %#v", name, events)
+	}
+	if _, err := dao.Get("id"); err != nil || len(events) != 4 {
+		t.Fatalf("%s failed: expected no event for get but received %#v", name, events)
+	}
+
+	errSink := errors.New("sink failure")
+	dao = Chain(&memDao{bos: map[string]*UniversalBo{}}, EmitEvents(EventSinkFunc(func(ctx context.Context, ev *ChangeEvent) error {
+		return errSink
+	})))
+	if ok, err := dao.Create(NewUniversalBo("id", 1)); !ok || err != errSink {
+		t.Fatalf("%s failed: expected %#v / %#v but received %#v / %#v", name, true, errSink, ok, err)
+	}
+}
+
+type clockedMemDao struct {
+	*memDao
+	clock Clock
+}
+
+func (dao *clockedMemDao) GetClock() Clock { return dao.clock }
+
+func TestEmitEvents_clock(t *testing.T) {
+	name := "TestEmitEvents_clock"
+	var events []*ChangeEvent
+	sink := EventSinkFunc(func(ctx context.Context, ev *ChangeEvent) error {
+		events = append(events, ev)
+		return nil
+	})
+	daoTime := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	boTime := daoTime.Add(time.Hour)
+	inner := &clockedMemDao{memDao: &memDao{bos: map[string]*UniversalBo{}}, clock: NewFixedClock(daoTime)}
+	// the wrapped DAO's clock is also reachable through nested chains
+	dao := Chain(Chain(inner), EmitEvents(sink))
+
+	if ok, err := dao.Create(NewUniversalBo("1", 1)); !ok || err != nil {
+		t.Fatalf("%s failed: %#v / %s", name, ok, err)
+	}
+	if ok, err := dao.Create(NewUniversalBo("2", 1, UboOpt{Clock: NewFixedClock(boTime)})); !ok || err != nil {
+		t.Fatalf("%s failed: %#v / %s", name, ok, err)
+	}
+	if len(events) != 2 || !events[0].Timestamp.Equal(daoTime) || !events[1].Timestamp.Equal(boTime) {
+		t.Fatalf("%s failed: expected timestamps %s / %s but received %#v", name, daoTime, boTime, events)
+	}
+}
@@ -4,15 +4,15 @@
 package henge
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/btnguyen2k/consu/checksum"
 	"github.com/btnguyen2k/consu/reddo"
 	"github.com/btnguyen2k/consu/semita"
 	"github.com/btnguyen2k/godal"
@@ -81,6 +81,11 @@ func cloneSlice(src []interface{}) []interface{} {
 type UboOpt struct {
 	TimeLayout        string
 	TimestampRounding TimestampRoundingSetting
+	ChecksumAlgorithm ChecksumAlgorithm // (since v0.7.0) algorithm used to calculate BO's checksum, unsupported algorithms are ignored
+	UseNumber         bool              // (since v0.7.0) decodes numbers in BO's data as json.Number, preserving their precision and original text
+	Clock             Clock             // (since v0.7.0) source of the current time used for BO's timestamps, nil means SystemClock
+	ChecksumCoverage  *ChecksumCoverage // (since v0.7.0) parts of data and extra attributes covered by BO's checksum, nil means all
+	JsonCodec         JsonCodec         // (since v0.7.0) codec used to parse and encode BO's data, nil means DefaultJsonCodec
 }
 
 func _extractTimeLayout(opts ...UboOpt) string {
@@ -92,6 +97,15 @@ func _extractTimeLayout(opts ...UboOpt) string {
 	return DefaultTimeLayout
 }
 
+func _extractUseNumber(opts ...UboOpt) bool {
+	for _, opt := range opts {
+		if opt.UseNumber {
+			return true
+		}
+	}
+	return false
+}
+
 func _extractTimestampRounding(opts ...UboOpt) TimestampRoundingSetting {
 	for _, opt := range opts {
 		if opt.TimestampRounding >= TimestampRoundingSettingNone && opt.TimestampRounding <= TimestampRoundingSettingSecond {
@@ -104,8 +118,11 @@ func _extractTimestampRounding(opts ...UboOpt) TimestampRoundingSetting {
 // NewUniversalBo is helper function to create a new UniversalBo instance.
 //
 // Note: id will be space-trimmed.
+//
+// (since v0.7.0) BO's timestamps are taken from the Clock specified by UboOpt.Clock, or SystemClock if none.
 func NewUniversalBo(id string, tagVersion uint64, opts ...UboOpt) *UniversalBo {
-	now := time.Now()
+	clock := _extractClock(opts...)
+	now := clockOrDefault(clock).Now()
 	bo := &UniversalBo{
 		id:                 strings.TrimSpace(id),
 		timeCreated:        now,
@@ -114,7 +131,15 @@ func NewUniversalBo(id string, tagVersion uint64, opts ...UboOpt) *UniversalBo {
 		_dirty:             true,
 		_extraAttrs:        make(map[string]interface{}),
 		_timestampRounding: _extractTimestampRounding(opts...),
+		_checksumAlgorithm: _extractChecksumAlgorithm(opts...),
+		_useNumber:         _extractUseNumber(opts...),
+		_clock:             clock,
+		_checksumCoverage:  _extractChecksumCoverage(opts...),
+		_jsonCodec:         _extractJsonCodec(opts...),
+		_dataChanged:       true,
 	}
+	bo._dataParsed.Store(true)
+	bo._original = bo._takeContent()
 	return bo.Sync(UboSyncOpts{UpdateTimestampIfChecksumChange: true})
 }
 
@@ -134,21 +159,36 @@ func NewUniversalBoFromGbo(gbo godal.IGenericBo, opts ...UboOpt) *UniversalBo {
 	_timestampRounding := _extractTimestampRounding(opts...)
 	tcreated, _ := gbo.GboGetTimeWithLayout(FieldTimeCreated, _timeLayout)
 	tupdated, _ := gbo.GboGetTimeWithLayout(FieldTimeUpdated, _timeLayout)
+	createdBy, _ := gbo.GboGetAttrUnsafe(FieldCreatedBy, reddo.TypeString).(string)
+	updatedBy, _ := gbo.GboGetAttrUnsafe(FieldUpdatedBy, reddo.TypeString).(string)
+	storedChecksum := gbo.GboGetAttrUnsafe(FieldChecksum, reddo.TypeString).(string)
+	_checksumAlgorithm := _extractChecksumAlgorithm(opts...)
+	if _checksumAlgorithm == "" {
+		_checksumAlgorithm = supportedChecksumAlgorithmOf(storedChecksum)
+	}
 	bo := &UniversalBo{
 		id:                 gbo.GboGetAttrUnsafe(FieldId, reddo.TypeString).(string),
-		dataJson:           gbo.GboGetAttrUnsafe(FieldData, reddo.TypeString).(string),
-		checksum:           gbo.GboGetAttrUnsafe(FieldChecksum, reddo.TypeString).(string),
+		checksum:           storedChecksum,
 		timeCreated:        tcreated,
 		timeUpdated:        tupdated,
+		createdBy:          createdBy,
+		updatedBy:          updatedBy,
 		tagVersion:         gbo.GboGetAttrUnsafe(FieldTagVersion, reddo.TypeUint).(uint64),
 		_extraAttrs:        extraAttrs,
-		_dirty:             true,
 		_timestampRounding: _timestampRounding,
+		_checksumAlgorithm: _checksumAlgorithm,
+		_storedChecksum:    storedChecksum,
+		_useNumber:         _extractUseNumber(opts...),
+		_clock:             _extractClock(opts...),
+		_checksumCoverage:  _extractChecksumCoverage(opts...),
+		_jsonCodec:         _extractJsonCodec(opts...),
 	}
-	if err := bo._parseDataJson(dataInitNone); err != nil {
+	if err := bo._loadDataJson(gbo.GboGetAttrUnsafe(FieldData, reddo.TypeString).(string)); err != nil {
 		return nil
 	}
-	return bo._sync()
+	bo._original = bo._takeContent()
+	bo._syncLoaded(storedChecksum)
+	return bo
 }
 
 const (
@@ -170,6 +210,16 @@ const (
 	// FieldTimeUpdated is a top level field: BO's last-updated timestamp.
 	FieldTimeUpdated = "tupd"
 
+	// FieldCreatedBy is an optional top level field: the principal who created the BO.
+	//
+	// Available since v0.7.0
+	FieldCreatedBy = "cby"
+
+	// FieldUpdatedBy is an optional top level field: the principal who last modified the BO.
+	//
+	// Available since v0.7.0
+	FieldUpdatedBy = "uby"
+
 	// FieldExtras is an internally used field.
 	FieldExtras = "_ext"
 )
@@ -209,7 +259,7 @@ const (
 )
 
 var (
-	topLevelFieldList = []string{FieldId, FieldData, FieldChecksum, FieldTagVersion, FieldTimeCreated, FieldTimeUpdated}
+	topLevelFieldList = []string{FieldId, FieldData, FieldChecksum, FieldTagVersion, FieldTimeCreated, FieldTimeUpdated, FieldCreatedBy, FieldUpdatedBy}
 )
 
 // UboSyncOpts specifies behaviors of UniversalBo.Sync function.
@@ -229,6 +279,8 @@ type UniversalBo struct {
 	checksum    string    `json:"csum"` // bo's checksum (should not take update-time into account)
 	timeCreated time.Time `json:"tcre"` // bo's creation timestamp
 	timeUpdated time.Time `json:"tupd"` // bo's last-updated timestamp
+	createdBy   string    `json:"cby"`  // (since v0.7.0) principal who created the bo
+	updatedBy   string    `json:"uby"`  // (since v0.7.0) principal who last modified the bo
 
 	/* computed attributes */
 	_data  interface{}    `json:"-"` // deserialized form of data-json
@@ -239,6 +291,22 @@ type UniversalBo struct {
 	_lock              sync.RWMutex
 	_dirty             bool
 	_timestampRounding TimestampRoundingSetting
+	_original          *uboContent       // snapshot of bo's content when it was loaded or last persisted, used for change tracking
+	_checksumAlgorithm ChecksumAlgorithm // algorithm used to calculate bo's checksum, empty means DefaultChecksumAlgorithm
+	_storedChecksum    string            // checksum as loaded from storage, before being recalculated
+	_checksumMismatch  bool              // true if the stored checksum does not match BO's content when loaded
+	_pendingChecksum   *pendingChecksum  // pending verification of the stored checksum, see _resolveChecksum
+	_useNumber         bool              // decodes numbers in data as json.Number
+	_clock             Clock             // source of the current time, nil means SystemClock
+	_checksumCoverage  *ChecksumCoverage // parts of data and extra attributes covered by checksum, nil means all
+	_jsonCodec         JsonCodec         // codec used to parse and encode data, nil means DefaultJsonCodec
+	_dataParsed        atomic.Bool       // true if _data holds the parsed (or since modified) form of dataJson
+	_parseLock         sync.Mutex        // serializes lazy parsing of data by concurrent readers
+	_dataPristine      bool              // true if _data has not been modified since being parsed from dataJson
+	_dataChanged       bool              // true if _data has been modified since dataJson was last encoded
+	_dataRaw           bool              // true if dataJson is kept as loaded from storage, not necessarily in canonical form
+	_dataShared        atomic.Bool       // true if _data is shared with clones and must be copied before being modified
+	_unchangedWrite    bool              // true if the last write of bo was skipped because it was unchanged
 }
 
 // FuncPreUboToMap is used by UniversalBo.ToMap to export UniversalBo's attributes to a map[string]interface{}.
@@ -252,9 +320,11 @@ type FuncPostUboToMap func(map[string]interface{}) map[string]interface{}
 // This function exports the input UniversalBo as-is to a map with following fields:
 // { FieldId (string), FieldData (string), FieldTagVersion (uint64), FieldChecksum (string),
 // FieldTimeCreated (time.Time), FieldTimeUpdated (time.Time), FieldExtras (map[string]interface{}) }
+//
+// (since v0.7.0) FieldCreatedBy and FieldUpdatedBy (string) are also exported if not empty.
 var DefaultFuncPreUboToMap FuncPreUboToMap = func(_ubo *UniversalBo) map[string]interface{} {
 	ubo := _ubo.Clone()
-	return map[string]interface{}{
+	m := map[string]interface{}{
 		FieldId:          ubo.id,
 		FieldData:        ubo.dataJson,
 		FieldTagVersion:  ubo.tagVersion,
@@ -263,10 +333,19 @@ var DefaultFuncPreUboToMap FuncPreUboToMap = func(_ubo *UniversalBo) map[string]
 		FieldTimeUpdated: ubo.timeUpdated,
 		FieldExtras:      cloneMap(ubo._extraAttrs),
 	}
+	if ubo.createdBy != "" {
+		m[FieldCreatedBy] = ubo.createdBy
+	}
+	if ubo.updatedBy != "" {
+		m[FieldUpdatedBy] = ubo.updatedBy
+	}
+	return m
 }
 
 // ToGenericBo exports the BO data to a godal.IGenericBo.
 //   - the exported godal.IGenericBo is populated with fields FieldId, FieldData, FieldChecksum, FieldTimeCreated, FieldTimeUpdated and FieldTagVersion.
+//   - (since v0.7.0) fields FieldCreatedBy and FieldUpdatedBy are populated only if not empty, so that storages without
+//     audit columns are not affected.
 //
 // Available since v0.4.1
 func (ubo *UniversalBo) ToGenericBo() godal.IGenericBo {
@@ -278,6 +357,12 @@ func (ubo *UniversalBo) ToGenericBo() godal.IGenericBo {
 	gbo.GboSetAttr(FieldTimeCreated, clone.timeCreated)
 	gbo.GboSetAttr(FieldTimeUpdated, clone.timeUpdated)
 	gbo.GboSetAttr(FieldTagVersion, clone.tagVersion)
+	if clone.createdBy != "" {
+		gbo.GboSetAttr(FieldCreatedBy, clone.createdBy)
+	}
+	if clone.updatedBy != "" {
+		gbo.GboSetAttr(FieldUpdatedBy, clone.updatedBy)
+	}
 	for k, v := range clone._extraAttrs {
 		gbo.GboSetAttr(k, v)
 	}
@@ -299,31 +384,24 @@ func (ubo *UniversalBo) ToMap(preFunc FuncPreUboToMap, postFunc FuncPostUboToMap
 }
 
 // MarshalJSON implements json.encode.Marshaler.MarshalJSON.
+//
+// (since v0.7.0) The BO is no longer synced by this function: pending changes are exported as if the BO had been synced,
+// but the BO itself is not modified. See UboJsonEncoder for a configurable JSON representation.
 func (ubo *UniversalBo) MarshalJSON() ([]byte, error) {
-	ubo.Sync(UboSyncOpts{UpdateTimestampIfChecksumChange: true})
-	ubo._lock.RLock()
-	defer ubo._lock.RUnlock()
-	m := map[string]interface{}{
-		FieldId:          ubo.id,
-		FieldData:        ubo.dataJson,
-		FieldTagVersion:  ubo.tagVersion,
-		FieldChecksum:    ubo.checksum,
-		FieldTimeCreated: ubo.timeCreated.Format(DefaultTimeLayout),
-		FieldTimeUpdated: ubo.timeUpdated.Format(DefaultTimeLayout),
-		FieldExtras:      cloneMap(ubo._extraAttrs),
-	}
-	return json.Marshal(m)
+	return UboJsonEncoder{}.Marshal(ubo)
 }
 
 // UnmarshalJSON implements json.decode.Unmarshaler.UnmarshalJSON.
+//
+// (since v0.7.0) Data is accepted both as a JSON-encoded string and as a nested JSON value, and timestamps in the formats
+// accepted by UboJsonDecoder.
 func (ubo *UniversalBo) UnmarshalJSON(data []byte) error {
-	var m map[string]interface{}
-	err := json.Unmarshal(data, &m)
+	m, err := decodeJsonObject(ubo._json(), data)
 	if err == nil {
 		m[FieldId], err = reddo.ToString(m[FieldId])
 	}
 	if err == nil {
-		m[FieldData], err = reddo.ToString(m[FieldData])
+		m[FieldData], err = decodeJsonDataField(ubo._json(), m[FieldData])
 	}
 	if err == nil {
 		m[FieldTagVersion], err = reddo.ToUint(m[FieldTagVersion])
@@ -332,13 +410,19 @@ func (ubo *UniversalBo) UnmarshalJSON(data []byte) error {
 		m[FieldChecksum], err = reddo.ToString(m[FieldChecksum])
 	}
 	if err == nil {
-		m[FieldTimeCreated], err = reddo.ToTimeWithLayout(m[FieldTimeCreated], time.RFC3339Nano)
+		m[FieldTimeCreated], err = parseJsonTime(m[FieldTimeCreated], nil)
+	}
+	if err == nil {
+		m[FieldTimeUpdated], err = parseJsonTime(m[FieldTimeUpdated], nil)
+	}
+	if err == nil {
+		m[FieldCreatedBy], err = reddo.ToString(m[FieldCreatedBy])
 	}
 	if err == nil {
-		m[FieldTimeUpdated], err = reddo.ToTimeWithLayout(m[FieldTimeUpdated], time.RFC3339Nano)
+		m[FieldUpdatedBy], err = reddo.ToString(m[FieldUpdatedBy])
 	}
 	if err == nil {
-		m[FieldExtras], err = reddo.ToMap(m[FieldExtras], reflect.TypeOf(map[string]interface{}{}))
+		m[FieldExtras], err = reddo.ToMap(jsonNumbersToNative(m[FieldExtras]), reflect.TypeOf(map[string]interface{}{}))
 	}
 	if err != nil {
 		return err
@@ -351,11 +435,18 @@ func (ubo *UniversalBo) UnmarshalJSON(data []byte) error {
 	ubo.checksum = m[FieldChecksum].(string)
 	ubo.timeCreated = m[FieldTimeCreated].(time.Time)
 	ubo.timeUpdated = m[FieldTimeUpdated].(time.Time)
+	ubo.createdBy = m[FieldCreatedBy].(string)
+	ubo.updatedBy = m[FieldUpdatedBy].(string)
+	if ubo._checksumAlgorithm == "" {
+		ubo._checksumAlgorithm = supportedChecksumAlgorithmOf(ubo.checksum)
+	}
 	ubo._extraAttrs = make(map[string]interface{})
 	if m[FieldExtras] != nil {
 		ubo._extraAttrs = m[FieldExtras].(map[string]interface{})
 	}
+	ubo._pendingChecksum = nil
 	ubo._setDataJson(m[FieldData].(string))
+	ubo._original = ubo._takeContent()
 	ubo._sync(UboSyncOpts{UpdateTimestampIfChecksumChange: true})
 	return nil
 }
@@ -376,6 +467,15 @@ func (ubo *UniversalBo) SetId(value string) *UniversalBo {
 
 // GetDataJson returns bo's user-defined attributes in JSON format.
 func (ubo *UniversalBo) GetDataJson() string {
+	ubo._lock.RLock()
+	if !ubo._dataRaw {
+		defer ubo._lock.RUnlock()
+		return ubo.dataJson
+	}
+	ubo._lock.RUnlock()
+	ubo._lock.Lock()
+	defer ubo._lock.Unlock()
+	ubo._canonicalizeDataJson()
 	return ubo.dataJson
 }
 
@@ -393,7 +493,7 @@ var (
 )
 
 func (ubo *UniversalBo) _parseDataJson(dataInit dataInitType) error {
-	err := json.Unmarshal([]byte(ubo.dataJson), &ubo._data)
+	err := unmarshalJsonData(ubo._jsonCodec, []byte(ubo.dataJson), ubo._useNumber, &ubo._data)
 	if err != nil || ubo._data == nil {
 		if dataInit == dataInitMap {
 			ubo._data = make(map[string]interface{})
@@ -410,12 +510,43 @@ func (ubo *UniversalBo) _parseDataJson(dataInit dataInitType) error {
 	} else {
 		ubo._sdata = nil
 	}
+	ubo._dataPristine = err == nil
+	ubo._dataShared.Store(false)
+	ubo._dataParsed.Store(true)
 	return err
 }
 
+// _cloneData returns a deep copy of bo's data tree, normalized to JSON-compatible types
+// (map[string]interface{}, []interface{}, float64 or json.Number, string, bool and nil).
+//
+// Caller is responsible for locking the BO.
+func (ubo *UniversalBo) _cloneData() (interface{}, error) {
+	js, err := ubo._currentDataJson()
+	if err != nil {
+		return nil, err
+	}
+	var data interface{}
+	err = unmarshalJsonData(ubo._jsonCodec, js, ubo._useNumber, &data)
+	return data, err
+}
+
+// _setData replaces bo's data tree as a whole.
+//
+// Caller is responsible for locking the BO.
+func (ubo *UniversalBo) _setData(data interface{}) error {
+	js, err := ubo._json().Marshal(data)
+	if err != nil {
+		return err
+	}
+	ubo._setDataJson(string(js))
+	return nil
+}
+
 func (ubo *UniversalBo) _setDataJson(value string) *UniversalBo {
 	ubo.dataJson = strings.TrimSpace(value)
 	ubo._parseDataJson(dataInitNone)
+	// the parsed data tree is kept as pristine, only dataJson needs to be re-encoded in canonical form
+	ubo._dataChanged, ubo._dataRaw = true, false
 	ubo._dirty = true
 	return ubo
 }
@@ -443,7 +574,10 @@ func (ubo *UniversalBo) SetTagVersion(value uint64) *UniversalBo {
 
 // GetChecksum returns value of bo's 'checksum' field.
 func (ubo *UniversalBo) GetChecksum() string {
-	return ubo.checksum
+	ubo._lock.RLock()
+	defer ubo._lock.RUnlock()
+	csum, _ := ubo._checksumState()
+	return csum
 }
 
 // RoundTimestamp rounds the input time according to bo's timestamp-rounding setting and returns the result.
@@ -503,6 +637,48 @@ func (ubo *UniversalBo) SetTimeUpdated(value time.Time) *UniversalBo {
 	return ubo
 }
 
+// GetCreatedBy returns value of bo's 'created-by' field, the principal who created the BO.
+//
+// Available since v0.7.0
+func (ubo *UniversalBo) GetCreatedBy() string {
+	ubo._lock.RLock()
+	defer ubo._lock.RUnlock()
+	return ubo.createdBy
+}
+
+// SetCreatedBy sets value of bo's 'created-by' field.
+//
+// Audit fields are not covered by BO's checksum. They are usually filled in by the DAO, see PrincipalFunc.
+//
+// Available since v0.7.0
+func (ubo *UniversalBo) SetCreatedBy(value string) *UniversalBo {
+	ubo._lock.Lock()
+	defer ubo._lock.Unlock()
+	ubo.createdBy = strings.TrimSpace(value)
+	return ubo
+}
+
+// GetUpdatedBy returns value of bo's 'updated-by' field, the principal who last modified the BO.
+//
+// Available since v0.7.0
+func (ubo *UniversalBo) GetUpdatedBy() string {
+	ubo._lock.RLock()
+	defer ubo._lock.RUnlock()
+	return ubo.updatedBy
+}
+
+// SetUpdatedBy sets value of bo's 'updated-by' field.
+//
+// Audit fields are not covered by BO's checksum. They are usually filled in by the DAO, see PrincipalFunc.
+//
+// Available since v0.7.0
+func (ubo *UniversalBo) SetUpdatedBy(value string) *UniversalBo {
+	ubo._lock.Lock()
+	defer ubo._lock.Unlock()
+	ubo.updatedBy = strings.TrimSpace(value)
+	return ubo
+}
+
 // IsDirty returns 'true' if bo's data has been modified.
 func (ubo *UniversalBo) IsDirty() bool {
 	return ubo._dirty
@@ -536,17 +712,30 @@ func (ubo *UniversalBo) GetDataAttrAsTimeWithLayoutUnsafe(path, layout string) t
 	return t
 }
 
+// _initSdata parses data if needed and, if data is null, initializes it as an empty map (or as an empty slice if path
+// ends with "[").
 func (ubo *UniversalBo) _initSdata(path string) {
+	ubo._ensureData()
 	if ubo._sdata == nil {
-		dataInit := dataInitMap
 		if strings.HasSuffix(path, "[") {
-			dataInit = dataInitSlice
+			ubo._data = make([]interface{}, 0)
+		} else {
+			ubo._data = make(map[string]interface{})
 		}
-		ubo._parseDataJson(dataInit)
+		ubo._sdata = semita.NewSemita(&ubo._data)
+		ubo._dataPristine, ubo._dataChanged = false, true
 	}
 }
 
 // GetDataAttrAs returns value, converted to the specified type, of a data attribute located at 'path'.
+//
+// (since v0.7.0) 'path' can also be a JSON Pointer (RFC 6901), e.g. "/hosts/example.com/port", which is useful to
+// access keys containing dots or brackets. Paths starting with "/" are treated as JSON Pointers.
+//
+// (since v0.7.0) Numbers decoded as json.Number (see UboOpt.UseNumber) are converted without going through float64,
+// hence precisely to int64/uint64 as well as to *big.Int (TypeBigInt) and *big.Float (TypeBigFloat).
+//
+// (since v0.7.0) If the BO's data is shared with clones (see Clone), objects and arrays are returned as copies.
 func (ubo *UniversalBo) GetDataAttrAs(path string, typ reflect.Type) (interface{}, error) {
 	ubo._lock.RLock()
 	defer ubo._lock.RUnlock()
@@ -554,28 +743,77 @@ func (ubo *UniversalBo) GetDataAttrAs(path string, typ reflect.Type) (interface{
 	if ubo._sdata == nil {
 		return nil, errors.New("cannot get data at path [" + path + "]")
 	}
-	return ubo._sdata.GetValueOfType(path, typ)
+	v, err := getDataValue(&ubo._data, path)
+	if v == nil || err != nil {
+		return v, err
+	}
+	v = ubo._detachValue(v)
+	if n, ok := v.(json.Number); ok && typ != nil {
+		return convertJsonNumber(n, typ)
+	}
+	return reddo.Convert(v, typ)
+}
+
+// GetDataAttrByPointer returns value of a data attribute located at the JSON Pointer (RFC 6901), e.g.
+// "/hosts/example.com/port". The empty pointer "" references the whole data.
+//
+// Available since v0.7.0
+func (ubo *UniversalBo) GetDataAttrByPointer(pointer string) (interface{}, error) {
+	if pointer == "" {
+		ubo._lock.RLock()
+		defer ubo._lock.RUnlock()
+		ubo._ensureData()
+		return cloneDataTree(ubo._data), nil
+	}
+	if !isJsonPointer(pointer) {
+		return nil, errors.New("invalid JSON pointer [" + pointer + "]")
+	}
+	return ubo.GetDataAttr(pointer)
+}
+
+// SetDataAttrByPointer sets value of a data attribute located at the JSON Pointer (RFC 6901), e.g.
+// "/hosts/example.com/port". See SetDataAttr.
+//
+// Available since v0.7.0
+func (ubo *UniversalBo) SetDataAttrByPointer(pointer string, value interface{}) error {
+	if !isJsonPointer(pointer) {
+		return errors.New("invalid JSON pointer [" + pointer + "]")
+	}
+	return ubo.SetDataAttr(pointer, value)
 }
 
 // SetDataAttr sets value of a data attribute located at 'path'.
 //
 // - If value is a time.Time (or *time.Time), the time value is rounded according to the BO's timestamp-rounding setting.
 // - (since v0.5.5) Furthermore, the time value is converted to string (using layout DefaultTimeLayout) before storing.
+//
+// (since v0.7.0) 'path' can also be a JSON Pointer (RFC 6901), see GetDataAttrAs. Missing intermediate nodes are
+// created as objects, array elements can be appended with index "-".
 func (ubo *UniversalBo) SetDataAttr(path string, value interface{}) error {
 	ubo._lock.Lock()
 	defer ubo._lock.Unlock()
-	ubo._dirty = true
+	ubo._unshareData()
+	ubo._markDataChanged()
 	ubo._initSdata(path)
 	if ubo._sdata == nil {
 		return errors.New("cannot set data at path [" + path + "]")
 	}
+	value = ubo._normalizeDataValue(value)
+	if isJsonPointer(path) {
+		return setDataValue(&ubo._data, path, value)
+	}
+	return ubo._sdata.SetValue(path, value)
+}
+
+// _normalizeDataValue normalizes a value before being stored in bo's data: time values are rounded and converted to string.
+func (ubo *UniversalBo) _normalizeDataValue(value interface{}) interface{} {
 	switch value.(type) {
 	case time.Time:
-		value = ubo.NormalizeTimestampForStoring(value.(time.Time), DefaultTimeLayout)
+		return ubo.NormalizeTimestampForStoring(value.(time.Time), DefaultTimeLayout)
 	case *time.Time:
-		value = ubo.NormalizeTimestampForStoring(*value.(*time.Time), DefaultTimeLayout)
+		return ubo.NormalizeTimestampForStoring(*value.(*time.Time), DefaultTimeLayout)
 	}
-	return ubo._sdata.SetValue(path, value)
+	return value
 }
 
 // GetExtraAttrs returns the 'extra-attrs' map.
@@ -654,24 +892,27 @@ func _requireTimeUpdatedSyncIfChecksumChange(opts ...UboSyncOpts) bool {
 }
 
 func (ubo *UniversalBo) _sync(opts ...UboSyncOpts) *UniversalBo {
+	return ubo._syncWithClock(ubo._clock, opts...)
+}
+
+// _syncWithClock syncs data the same way as _sync, taking the current time from the specified clock.
+func (ubo *UniversalBo) _syncWithClock(clock Clock, opts ...UboSyncOpts) *UniversalBo {
 	if ubo._dirty {
+		ubo._resolveChecksum()
 		ubo.timeCreated = ubo.RoundTimestamp(ubo.timeCreated)
 		ubo.timeUpdated = ubo.RoundTimestamp(ubo.timeUpdated)
 		oldChecksum := ubo.checksum
-		csumMap := map[string]interface{}{
-			"id":          ubo.id,
-			"app_version": ubo.tagVersion,
-			"t_created":   ubo.timeCreated.In(time.UTC).Format(DefaultTimeLayout),
-			"data":        ubo._data,
-			"extra":       ubo._extraAttrs,
+		if ubo._dataChanged || ubo.dataJson == "" {
+			// (since v0.7.0) data is re-encoded only if it has been changed
+			js, _ := ubo._currentDataJson()
+			ubo.dataJson = string(js)
+			ubo._dataChanged, ubo._dataRaw = false, false
 		}
-		ubo.checksum = fmt.Sprintf("%x", checksum.Md5Checksum(csumMap))
+		ubo.checksum = ubo._calcChecksum(ubo._checksumAlgorithm, ubo._checksumInput(ubo._checksumAlgorithm))
 		if _requireTimeUpdatedSync(opts...) ||
 			(_requireTimeUpdatedSyncIfChecksumChange(opts...) && oldChecksum != ubo.checksum) {
-			ubo.timeUpdated = ubo.RoundTimestamp(time.Now())
+			ubo.timeUpdated = ubo.RoundTimestamp(clockOrDefault(clock).Now())
 		}
-		js, _ := json.Marshal(ubo._data)
-		ubo.dataJson = string(js)
 		ubo._dirty = false
 	}
 	return ubo
@@ -685,6 +926,9 @@ func (ubo *UniversalBo) Sync(opts ...UboSyncOpts) *UniversalBo {
 }
 
 // Clone creates a cloned copy of the business object.
+//
+// (since v0.7.0) The clone shares data with the original BO until either of them modifies it, hence cloning is cheap
+// even for large documents.
 func (ubo *UniversalBo) Clone() *UniversalBo {
 	ubo._lock.RLock()
 	defer ubo._lock.RUnlock()
@@ -696,13 +940,31 @@ func (ubo *UniversalBo) Clone() *UniversalBo {
 		checksum:           ubo.checksum,
 		timeCreated:        ubo.timeCreated,
 		timeUpdated:        ubo.timeUpdated,
-		_data:              nil,
-		_sdata:             nil,
+		createdBy:          ubo.createdBy,
+		updatedBy:          ubo.updatedBy,
 		_extraAttrs:        cloneMap(ubo._extraAttrs),
 		_dirty:             false,
 		_timestampRounding: ubo._timestampRounding,
+		_original:          ubo._original,
+		_checksumAlgorithm: ubo._checksumAlgorithm,
+		_storedChecksum:    ubo._storedChecksum,
+		_checksumMismatch:  ubo._checksumMismatch,
+		_pendingChecksum:   ubo._pendingChecksum,
+		_useNumber:         ubo._useNumber,
+		_clock:             ubo._clock,
+		_checksumCoverage:  ubo._checksumCoverage,
+		_jsonCodec:         ubo._jsonCodec,
+		_dataRaw:           ubo._dataRaw,
+	}
+	if ubo._dataParsed.Load() && ubo._dataPristine {
+		clone._data, clone._dataPristine = ubo._data, true
+		if clone._data != nil {
+			clone._sdata = semita.NewSemita(&clone._data)
+		}
+		clone._dataParsed.Store(true)
+		clone._dataShared.Store(true)
+		ubo._dataShared.Store(true)
 	}
-	clone._parseDataJson(dataInitNone)
 	return clone
 }
 
@@ -741,3 +1003,84 @@ type UniversalDao interface {
 	// This function returns the existing record along with value true if number of inserted/updated record is non-zero.
 	Save(bo *UniversalBo) (bool, *UniversalBo, error)
 }
+
+// UniversalDaoContext is a UniversalDao whose operations also accept a context.Context. The context is passed to the
+// DAO's lifecycle hooks (see DaoHooks); functions without context use context.Background().
+//
+// All UniversalDao implementations of this package implement UniversalDaoContext.
+//
+// Available since v0.7.0
+type UniversalDaoContext interface {
+	UniversalDao
+
+	// DeleteContext is similar to Delete, with context.
+	DeleteContext(ctx context.Context, bo *UniversalBo) (bool, error)
+
+	// CreateContext is similar to Create, with context.
+	CreateContext(ctx context.Context, bo *UniversalBo) (bool, error)
+
+	// GetContext is similar to Get, with context.
+	GetContext(ctx context.Context, id string) (*UniversalBo, error)
+
+	// GetNContext is similar to GetN, with context.
+	GetNContext(ctx context.Context, fromOffset, maxNumRows int, filter godal.FilterOpt, sorting *godal.SortingOpt) ([]*UniversalBo, error)
+
+	// GetAllContext is similar to GetAll, with context.
+	GetAllContext(ctx context.Context, filter godal.FilterOpt, sorting *godal.SortingOpt) ([]*UniversalBo, error)
+
+	// UpdateContext is similar to Update, with context.
+	UpdateContext(ctx context.Context, bo *UniversalBo) (bool, error)
+
+	// SaveContext is similar to Save, with context.
+	SaveContext(ctx context.Context, bo *UniversalBo) (bool, *UniversalBo, error)
+}
+
+// isUnchanged returns true if the stored record exists and has the same checksum as the business object to be written.
+func isUnchanged(existing godal.IGenericBo, gbo godal.IGenericBo) bool {
+	if existing == nil || gbo == nil {
+		return false
+	}
+	csum := gbo.GboGetAttrUnsafe(FieldChecksum, reddo.TypeString)
+	return csum != "" && existing.GboGetAttrUnsafe(FieldChecksum, reddo.TypeString) == csum
+}
+
+// isUnchangedContent returns true if bo's checksum covers all of its content, or if the stored BO has the same content
+// as bo. Extra attributes with nil value are treated as missing, as storages might return them for unset attributes.
+func isUnchangedContent(stored, bo *UniversalBo) bool {
+	if bo.GetChecksumCoverage().coversAll() {
+		return true
+	}
+	if stored == nil {
+		return false
+	}
+	a, b := stored._content(), bo._content()
+	if a.id != b.id || a.tagVersion != b.tagVersion || !a.sameData(b) {
+		return false
+	}
+	return jsonValuesEqual(nonNilValues(a.extras), nonNilValues(b.extras))
+}
+
+// nonNilValues returns a copy of the map without nil values; non-map input is returned as-is.
+func nonNilValues(v interface{}) interface{} {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+	result := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if v != nil {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// unchangedWrite is called when a write is skipped because the business object is unchanged.
+// The business object is in sync with storage, hence its change tracking is reset; the skipped write is reported
+// by UniversalBo.WasUnchanged, not as an error.
+func unchangedWrite(bo *UniversalBo) (bool, error) {
+	if bo != nil {
+		bo.ResetChangeTracking()._markPersisted()._setUnchangedWrite(true)
+	}
+	return false, nil
+}
@@ -312,7 +312,7 @@ func TestUniversalBo_ToMap(t *testing.T) {
 	for i, roundingOpt := range roundingOptList {
 		t.Run(fmt.Sprintf("%v", roundingOpt), func(t *testing.T) {
 			now := time.Now()
-			ubo := NewUniversalBo(_id, _tagVersion, UboOpt{TimestampRounding: roundingOpt})
+			ubo := NewUniversalBo(_id, _tagVersion, UboOpt{TimestampRounding: roundingOpt, Clock: NewFixedClock(now)})
 			ubo.SetDataAttr("key", "value")
 			ubo.SetExtraAttr("str", vStr)
 			ubo.SetExtraAttr("int", vInt)
@@ -515,7 +515,8 @@ func TestUniversalBo_json(t *testing.T) {
 
 				t.Fatalf("%s failed [data]: expected\n%#v\nbut received\n%#v", testName, ubo1._data, ubo2._data)
 			}
-			if ubo1.checksum != ubo2.checksum {
+			// MarshalJSON does not sync the BO (since v0.7.0)
+			if ubo1.Sync(); ubo1.checksum != ubo2.checksum {
 				t.Fatalf("%s failed [checksum]: expected %#v but received %#v", testName, ubo1.checksum, ubo2.checksum)
 			}
 		})
@@ -560,17 +561,19 @@ func TestUniversalBo_SetTagVersion(t *testing.T) {
 
 func TestUniversalBo_GetTimeCreated_rounding(t *testing.T) {
 	name := "TestUniversalBo_GetTimeCreated_rounding"
+	now := time.Date(2021, 1, 2, 3, 4, 5, 123456789, time.UTC)
 	roundingOptList := []TimestampRoundingSetting{TimestampRoundingSettingNone, TimestampRoundingSettingNanosecond, TimestampRoundingSettingMicrosecond, TimestampRoundingSettingMillisecond, TimestampRoundingSettingSecond}
-	expectedDeltaLimit1List := []int{0, -0, -999, -999_999, -999_999_999}
-	expectedDeltaLimit2List := []int{999, 999, 999, 999_999, 999_999}
+	expectedList := []time.Time{now, now, now.Add(-789 + 1000), now.Add(-456789), now.Add(-123456789)}
 	for i, roundingOpt := range roundingOptList {
 		t.Run(fmt.Sprintf("%v", roundingOpt), func(t *testing.T) {
-			now := time.Now()
 			_id := "id"
 			_tagVersion := uint64(1357)
-			ubo := NewUniversalBo(_id, _tagVersion, UboOpt{TimestampRounding: roundingOpt})
-			if d := ubo.GetTimeCreated().Nanosecond() - now.Nanosecond(); d > expectedDeltaLimit2List[i] || d < expectedDeltaLimit1List[i] {
-				t.Fatalf("%s failed: expected delta between {%v - %v} but received %v", name, expectedDeltaLimit1List[i], expectedDeltaLimit2List[i], d)
+			ubo := NewUniversalBo(_id, _tagVersion, UboOpt{TimestampRounding: roundingOpt, Clock: NewFixedClock(now)})
+			if v := ubo.GetTimeCreated(); !v.Equal(expectedList[i]) {
+				t.Fatalf("%s failed: expected %v but received %v", name, expectedList[i], v)
+			}
+			if v := ubo.GetTimeUpdated(); !v.Equal(expectedList[i]) {
+				t.Fatalf("%s failed: expected %v but received %v", name, expectedList[i], v)
 			}
 		})
 	}
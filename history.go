@@ -0,0 +1,228 @@
+package henge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/btnguyen2k/consu/reddo"
+	"github.com/btnguyen2k/godal"
+)
+
+// HistoryEntry is a prior version of a BO, archived by a DAO in history mode when the BO was updated, saved or deleted.
+//
+// Available since v0.7.0
+type HistoryEntry struct {
+	Seq          uint64       // sequence number of the entry, starting from 1 and increasing with each archived version of the BO
+	Op           DaoOp        // the operation that replaced (or deleted) the version: OpUpdate, OpSave or OpDelete
+	TimeArchived time.Time    // when the version was replaced (or deleted)
+	Bo           *UniversalBo // the version as it was stored
+}
+
+// VersionRef identifies a version of a BO in its history, either by the sequence number of its HistoryEntry or by its
+// checksum. Use VersionSeq or VersionChecksum to create VersionRef instances.
+//
+// Available since v0.7.0
+type VersionRef struct {
+	Seq      uint64 // sequence number of the history entry, used if Checksum is empty
+	Checksum string // checksum of the version; if several history entries match, the most recent one is used
+}
+
+// VersionSeq returns a VersionRef identifying a version by the sequence number of its history entry.
+//
+// Available since v0.7.0
+func VersionSeq(seq uint64) VersionRef {
+	return VersionRef{Seq: seq}
+}
+
+// VersionChecksum returns a VersionRef identifying a version by its checksum.
+//
+// Available since v0.7.0
+func VersionChecksum(checksum string) VersionRef {
+	return VersionRef{Checksum: checksum}
+}
+
+// String implements fmt.Stringer.
+func (r VersionRef) String() string {
+	if r.Checksum != "" {
+		return "checksum:" + r.Checksum
+	}
+	return fmt.Sprintf("seq:%d", r.Seq)
+}
+
+// matches returns true if the history entry is the referenced version.
+func (r VersionRef) matches(entry *HistoryEntry) bool {
+	if r.Checksum != "" {
+		return entry.Bo != nil && entry.Bo.GetChecksum() == r.Checksum
+	}
+	return entry.Seq == r.Seq
+}
+
+// UniversalDaoHistory is implemented by UniversalDao implementations that can keep prior versions of BOs.
+//
+// In history mode, each Update, Save or Delete that actually writes archives the version being replaced (or deleted),
+// as stored, to a companion history storage. Creates are not archived as there is no prior version; writes skipped
+// because the BO is unchanged (see UniversalBo.WasUnchanged) are not archived either.
+//
+// Available since v0.7.0
+type UniversalDaoHistory interface {
+	// GetHistory returns the archived versions of a BO, most recent first. maxNumRows <= 0 means no limit.
+	GetHistory(id string, fromOffset, maxNumRows int) ([]*HistoryEntry, error)
+
+	// GetVersionAt returns the version of a BO that was current at the specified time, nil if the BO did not exist at
+	// that time. Versions are placed in time by their last-updated timestamp (see UniversalBo.GetTimeUpdated) and the
+	// time they were archived.
+	GetVersionAt(id string, t time.Time) (*UniversalBo, error)
+
+	// RestoreVersion saves an archived version of a BO as its current version, archiving the current one. The restored
+	// version is returned as persisted (with a new last-updated timestamp), nil if the referenced version does not exist.
+	// If "skip unchanged writes" is enabled and the referenced version is the current one, nothing is written and the
+	// version is returned as-is, with UniversalBo.WasUnchanged reporting true.
+	RestoreVersion(id string, ref VersionRef) (*UniversalBo, error)
+
+	// RestoreVersionContext is RestoreVersion with a context, passed to the DAO's hooks (see DaoHooks) and PrincipalFunc.
+	RestoreVersionContext(ctx context.Context, id string, ref VersionRef) (*UniversalBo, error)
+}
+
+// ErrHistoryDisabled is returned by UniversalDaoHistory's functions if history mode is not enabled on the DAO.
+//
+// Available since v0.7.0
+var ErrHistoryDisabled = errors.New("history mode is not enabled")
+
+// ErrHistoryUnsupported is returned when enabling history mode, and by UniversalDaoHistory's functions, on DAOs that do
+// not support history mode (UniversalDaoCosmosdbSql).
+//
+// Available since v0.7.0
+var ErrHistoryUnsupported = errors.New("history mode is not supported by the DAO")
+
+const (
+	// HistoryColId is name of the history record's attribute to store the BO's id (MongoDB and AWS DynamoDB).
+	//
+	// Available since v0.7.0
+	HistoryColId = "hid"
+
+	// HistoryColSeq is name of the history record's attribute to store the entry's sequence number (MongoDB and AWS DynamoDB).
+	//
+	// Available since v0.7.0
+	HistoryColSeq = "hseq"
+
+	// HistoryColOp is name of the history record's attribute to store the operation that replaced the version (MongoDB and AWS DynamoDB).
+	//
+	// Available since v0.7.0
+	HistoryColOp = "hop"
+
+	// HistoryColTime is name of the history record's attribute to store the time the version was archived (MongoDB and AWS DynamoDB).
+	//
+	// Available since v0.7.0
+	HistoryColTime = "htime"
+
+	// HistoryColBo is name of the history record's attribute to store the archived version as stored (MongoDB and AWS DynamoDB).
+	//
+	// Available since v0.7.0
+	HistoryColBo = "hbo"
+)
+
+// historyPageSize is the number of history entries fetched at a time when looking up a version.
+const historyPageSize = 100
+
+// historyFetcher fetches archived versions of a BO, most recent first.
+type historyFetcher func(fromOffset, maxNumRows int) ([]*HistoryEntry, error)
+
+// newHistoryRecord builds a history record storing row, the archived version as stored, in a nested attribute.
+func newHistoryRecord(hid string, seq uint64, op DaoOp, t time.Time, row interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		HistoryColId:   hid,
+		HistoryColSeq:  seq,
+		HistoryColOp:   string(op),
+		HistoryColTime: t,
+		HistoryColBo:   row,
+	}
+}
+
+// parseHistoryRecord extracts the sequence number, operation, archive time and archived row from a history record
+// built by newHistoryRecord.
+func parseHistoryRecord(rec godal.IGenericBo) (seq uint64, op DaoOp, t time.Time, row interface{}) {
+	seq, _ = rec.GboGetAttrUnsafe(HistoryColSeq, reddo.TypeUint).(uint64)
+	opStr, _ := rec.GboGetAttrUnsafe(HistoryColOp, reddo.TypeString).(string)
+	t, _ = rec.GboGetTimeWithLayout(HistoryColTime, time.RFC3339Nano)
+	return seq, DaoOp(opStr), t, rec.GboGetAttrUnsafe(HistoryColBo, nil)
+}
+
+// lastHistorySeq returns the sequence number of the most recent history entry of a record, 0 if none.
+func lastHistorySeq(last []godal.IGenericBo, seqField string) uint64 {
+	if len(last) == 0 || last[0] == nil {
+		return 0
+	}
+	seq, _ := last[0].GboGetAttrUnsafe(seqField, reddo.TypeUint).(uint64)
+	return seq
+}
+
+// findVersion looks up the referenced version in the history, nil if not found.
+func findVersion(fetch historyFetcher, ref VersionRef) (*HistoryEntry, error) {
+	for offset := 0; ; offset += historyPageSize {
+		entries, err := fetch(offset, historyPageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if ref.matches(entry) {
+				return entry, nil
+			}
+			if ref.Checksum == "" && entry.Seq < ref.Seq {
+				// entries are sorted by sequence number, descending
+				return nil, nil
+			}
+		}
+		if len(entries) < historyPageSize {
+			return nil, nil
+		}
+	}
+}
+
+// versionAt returns the version of a BO that was current at time t, given its current version (nil if it does not
+// exist) and its history.
+func versionAt(current *UniversalBo, fetch historyFetcher, t time.Time) (*UniversalBo, error) {
+	if current != nil && !current.GetTimeUpdated().After(t) {
+		return current, nil
+	}
+	for offset := 0; ; offset += historyPageSize {
+		entries, err := fetch(offset, historyPageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.Bo == nil || entry.Bo.GetTimeUpdated().After(t) {
+				continue
+			}
+			if entry.TimeArchived.After(t) {
+				return entry.Bo, nil
+			}
+			// the most recent version written before t had been deleted by then
+			return nil, nil
+		}
+		if len(entries) < historyPageSize {
+			return nil, nil
+		}
+	}
+}
+
+// historySaver is implemented by DAOs able to restore archived versions.
+type historySaver interface {
+	SaveContext(ctx context.Context, bo *UniversalBo) (bool, *UniversalBo, error)
+}
+
+// restoreVersion saves the referenced version as the current version of a BO.
+func restoreVersion(ctx context.Context, dao historySaver, clock Clock, fetch historyFetcher, ref VersionRef) (*UniversalBo, error) {
+	entry, err := findVersion(fetch, ref)
+	if err != nil || entry == nil || entry.Bo == nil {
+		return nil, err
+	}
+	bo := entry.Bo
+	// the restored version is a new version, although its checksum is not
+	bo.SetTimeUpdated(clockOrDefault(clock).Now())
+	if _, _, err := dao.SaveContext(ctx, bo); err != nil {
+		return nil, err
+	}
+	return bo, nil
+}
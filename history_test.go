@@ -0,0 +1,134 @@
+package henge
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// testHistory builds n history entries of BO "id", most recent first: version i (1-based) is updated at hour i and
+// archived at hour i+1.
+func testHistory(n int) []*HistoryEntry {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	result := make([]*HistoryEntry, 0, n)
+	for i := n; i > 0; i-- {
+		bo := NewUniversalBo("id", uint64(i))
+		bo.SetDataAttr("v", i)
+		bo.SetTimeUpdated(t0.Add(time.Duration(i) * time.Hour))
+		result = append(result, &HistoryEntry{Seq: uint64(i), Op: OpUpdate, TimeArchived: t0.Add(time.Duration(i+1) * time.Hour), Bo: bo})
+	}
+	return result
+}
+
+func testHistoryFetcher(entries []*HistoryEntry, numFetches *int) historyFetcher {
+	return func(fromOffset, maxNumRows int) ([]*HistoryEntry, error) {
+		*numFetches++
+		if fromOffset >= len(entries) {
+			return nil, nil
+		}
+		end := fromOffset + maxNumRows
+		if maxNumRows <= 0 || end > len(entries) {
+			end = len(entries)
+		}
+		return entries[fromOffset:end], nil
+	}
+}
+
+func TestFindVersion(t *testing.T) {
+	name := "TestFindVersion"
+	entries := testHistory(250)
+	numFetches := 0
+	fetch := testHistoryFetcher(entries, &numFetches)
+
+	if entry, err := findVersion(fetch, VersionSeq(120)); err != nil || entry == nil || entry.Seq != 120 {
+		t.Fatalf("%s failed: expected seq 120 but received %#v (%s)", name, entry, err)
+	}
+	if numFetches != 2 {
+		t.Fatalf("%s failed: expected %#v fetches but received %#v", name, 2, numFetches)
+	}
+	csum := entries[len(entries)-1].Bo.GetChecksum()
+	if entry, err := findVersion(fetch, VersionChecksum(csum)); err != nil || entry == nil || entry.Seq != 1 {
+		t.Fatalf("%s failed: expected seq 1 but received %#v (%s)", name, entry, err)
+	}
+	if entry, err := findVersion(fetch, VersionChecksum("not-found")); err != nil || entry != nil {
+		t.Fatalf("%s failed: expected nil but received %#v (%s)", name, entry, err)
+	}
+	numFetches = 0
+	if entry, err := findVersion(fetch, VersionSeq(0)); err != nil || entry != nil || numFetches != 3 {
+		t.Fatalf("%s failed: expected nil but received %#v (%s)", name, entry, err)
+	}
+	if s := VersionSeq(3).String(); s != "seq:3" {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, "seq:3", s)
+	}
+	if s := VersionChecksum("abc").String(); s != "checksum:abc" {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, "checksum:abc", s)
+	}
+}
+
+func TestVersionAt(t *testing.T) {
+	name := "TestVersionAt"
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries := testHistory(3)
+	numFetches := 0
+	fetch := testHistoryFetcher(entries, &numFetches)
+	current := NewUniversalBo("id", 4)
+	current.SetTimeUpdated(t0.Add(4 * time.Hour))
+
+	if bo, err := versionAt(current, fetch, t0.Add(5*time.Hour)); err != nil || bo != current {
+		t.Fatalf("%s failed: expected current version but received %#v (%s)", name, bo, err)
+	}
+	if bo, err := versionAt(current, fetch, t0.Add(150*time.Minute)); err != nil || bo == nil || bo.GetTagVersion() != 2 {
+		t.Fatalf("%s failed: expected version 2 but received %#v (%s)", name, bo, err)
+	}
+	if bo, err := versionAt(current, fetch, t0.Add(30*time.Minute)); err != nil || bo != nil {
+		t.Fatalf("%s failed: expected nil but received %#v (%s)", name, bo, err)
+	}
+
+	// the BO was deleted at hour 4, then re-created at hour 6
+	entries[0].TimeArchived = t0.Add(4 * time.Hour)
+	entries[0].Op = OpDelete
+	current.SetTimeUpdated(t0.Add(6 * time.Hour))
+	if bo, err := versionAt(current, fetch, t0.Add(5*time.Hour)); err != nil || bo != nil {
+		t.Fatalf("%s failed: expected nil but received %#v (%s)", name, bo, err)
+	}
+	if bo, err := versionAt(nil, fetch, t0.Add(210*time.Minute)); err != nil || bo == nil || bo.GetTagVersion() != 3 {
+		t.Fatalf("%s failed: expected version 3 but received %#v (%s)", name, bo, err)
+	}
+}
+
+// historySaverFunc adapts a function to historySaver.
+type historySaverFunc func(ctx context.Context, bo *UniversalBo) (bool, *UniversalBo, error)
+
+func (fn historySaverFunc) SaveContext(ctx context.Context, bo *UniversalBo) (bool, *UniversalBo, error) {
+	return fn(ctx, bo)
+}
+
+func TestRestoreVersion(t *testing.T) {
+	name := "TestRestoreVersion"
+	entries := testHistory(3)
+	numFetches := 0
+	fetch := testHistoryFetcher(entries, &numFetches)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var saved *UniversalBo
+	saver := historySaverFunc(func(ctx context.Context, bo *UniversalBo) (bool, *UniversalBo, error) {
+		saved = bo
+		return true, nil, nil
+	})
+
+	bo, err := restoreVersion(context.Background(), saver, NewFixedClock(now), fetch, VersionSeq(2))
+	if err != nil || bo == nil || bo != saved || bo.GetTagVersion() != 2 || !bo.GetTimeUpdated().Equal(now) {
+		t.Fatalf("%s failed: expected version 2 restored at %s but received %#v (%s)", name, now, bo, err)
+	}
+	saved = nil
+	if bo, err := restoreVersion(context.Background(), saver, nil, fetch, VersionSeq(10)); err != nil || bo != nil || saved != nil {
+		t.Fatalf("%s failed: expected nil but received %#v (%s)", name, bo, err)
+	}
+
+	unchanged := historySaverFunc(func(ctx context.Context, bo *UniversalBo) (bool, *UniversalBo, error) {
+		ok, err := unchangedWrite(bo)
+		return ok, nil, err
+	})
+	if bo, err := restoreVersion(context.Background(), unchanged, nil, fetch, VersionSeq(3)); err != nil || bo == nil || bo.GetTagVersion() != 3 || !bo.WasUnchanged() {
+		t.Fatalf("%s failed: expected unchanged version 3 but received %#v (%s)", name, bo, err)
+	}
+}
@@ -0,0 +1,189 @@
+package henge
+
+import (
+	"context"
+	"fmt"
+)
+
+// HookFunc is a lifecycle hook invoked by UniversalDao implementations around their operations, see DaoHooks.
+//
+// Available since v0.7.0
+type HookFunc func(ctx context.Context, bo *UniversalBo) error
+
+// HookEvent identifies the point of a DAO operation at which hooks are invoked.
+//
+// Available since v0.7.0
+type HookEvent int
+
+const (
+	HookBeforeCreate HookEvent = iota // invoked before a BO is created
+	HookAfterCreate                   // invoked after a BO has been created
+	HookBeforeUpdate                  // invoked before a BO is updated
+	HookAfterUpdate                   // invoked after a BO has been updated
+	HookBeforeSave                    // invoked before a BO is saved
+	HookAfterSave                     // invoked after a BO has been saved
+	HookBeforeDelete                  // invoked before a BO is deleted
+	HookAfterDelete                   // invoked after a BO has been deleted
+	HookAfterLoad                     // invoked for each BO loaded by Get/GetN/GetAll
+	numHookEvents
+)
+
+var hookEventNames = [numHookEvents]string{
+	"BeforeCreate", "AfterCreate", "BeforeUpdate", "AfterUpdate", "BeforeSave", "AfterSave",
+	"BeforeDelete", "AfterDelete", "AfterLoad",
+}
+
+// String implements fmt.Stringer.
+func (e HookEvent) String() string {
+	if e >= 0 && e < numHookEvents {
+		return hookEventNames[e]
+	}
+	return fmt.Sprintf("HookEvent(%d)", int(e))
+}
+
+// DaoHooks holds lifecycle hooks of a DAO. It is embedded in UniversalDao implementations of this package, hence hooks
+// can be registered directly on DAOs, e.g. dao.OnBeforeCreate(normalizeEmail).OnAfterLoad(countLoads).
+//
+// Hooks are invoked in the order they were registered, with the context passed to the XxxContext functions of the DAO
+// (context.Background() for functions without context):
+//   - before-hooks are invoked after the BO has been assigned an id (see IdGenerator) but before it is validated (see
+//     DataSchema) and written, so they can still modify the BO (e.g. normalize data, stamp audit attributes). The first
+//     before-hook returning an error aborts the operation, the error is returned to the caller as-is and nothing is
+//     written.
+//   - after-hooks are invoked only if the BO has actually been written (or deleted), and they see the BO as persisted
+//     (checksum and timestamps synced). Invocation stops at the first after-hook returning an error; the error is
+//     returned to the caller, wrapped in a HookError, along with the operation's result as the write cannot be undone.
+//   - after-load hooks are invoked for each BO returned by Get, GetN and GetAll. An error aborts the load and is
+//     returned to the caller, wrapped in a HookError.
+//
+// Save invokes only the save-hooks, neither the create- nor the update-hooks. Writes skipped because the BO is
+// unchanged (see UniversalBo.WasUnchanged) do not invoke after-hooks.
+//
+// Hooks should be registered before the DAO is used, DaoHooks is not safe for concurrent registration.
+//
+// Available since v0.7.0
+type DaoHooks struct {
+	hooks [numHookEvents][]HookFunc
+}
+
+// AddHook registers a hook to be invoked at the specified event.
+func (h *DaoHooks) AddHook(event HookEvent, hook HookFunc) *DaoHooks {
+	if event >= 0 && event < numHookEvents && hook != nil {
+		h.hooks[event] = append(h.hooks[event], hook)
+	}
+	return h
+}
+
+// GetHooks returns the hooks registered for the specified event.
+func (h *DaoHooks) GetHooks(event HookEvent) []HookFunc {
+	if event < 0 || event >= numHookEvents {
+		return nil
+	}
+	return append([]HookFunc{}, h.hooks[event]...)
+}
+
+// ClearHooks unregisters all hooks.
+func (h *DaoHooks) ClearHooks() *DaoHooks {
+	h.hooks = [numHookEvents][]HookFunc{}
+	return h
+}
+
+// OnBeforeCreate registers a hook to be invoked before a BO is created.
+func (h *DaoHooks) OnBeforeCreate(hook HookFunc) *DaoHooks {
+	return h.AddHook(HookBeforeCreate, hook)
+}
+
+// OnAfterCreate registers a hook to be invoked after a BO has been created.
+func (h *DaoHooks) OnAfterCreate(hook HookFunc) *DaoHooks {
+	return h.AddHook(HookAfterCreate, hook)
+}
+
+// OnBeforeUpdate registers a hook to be invoked before a BO is updated.
+func (h *DaoHooks) OnBeforeUpdate(hook HookFunc) *DaoHooks {
+	return h.AddHook(HookBeforeUpdate, hook)
+}
+
+// OnAfterUpdate registers a hook to be invoked after a BO has been updated.
+func (h *DaoHooks) OnAfterUpdate(hook HookFunc) *DaoHooks {
+	return h.AddHook(HookAfterUpdate, hook)
+}
+
+// OnBeforeSave registers a hook to be invoked before a BO is saved.
+func (h *DaoHooks) OnBeforeSave(hook HookFunc) *DaoHooks {
+	return h.AddHook(HookBeforeSave, hook)
+}
+
+// OnAfterSave registers a hook to be invoked after a BO has been saved.
+func (h *DaoHooks) OnAfterSave(hook HookFunc) *DaoHooks {
+	return h.AddHook(HookAfterSave, hook)
+}
+
+// OnBeforeDelete registers a hook to be invoked before a BO is deleted.
+func (h *DaoHooks) OnBeforeDelete(hook HookFunc) *DaoHooks {
+	return h.AddHook(HookBeforeDelete, hook)
+}
+
+// OnAfterDelete registers a hook to be invoked after a BO has been deleted.
+func (h *DaoHooks) OnAfterDelete(hook HookFunc) *DaoHooks {
+	return h.AddHook(HookAfterDelete, hook)
+}
+
+// OnAfterLoad registers a hook to be invoked for each BO loaded by Get, GetN and GetAll.
+func (h *DaoHooks) OnAfterLoad(hook HookFunc) *DaoHooks {
+	return h.AddHook(HookAfterLoad, hook)
+}
+
+// HookError is returned by DAO operations when an after-hook fails.
+//
+// Available since v0.7.0
+type HookError struct {
+	Event HookEvent
+	Err   error
+}
+
+// Error implements error.Error.
+func (e *HookError) Error() string {
+	return fmt.Sprintf("%s hook failed: %s", e.Event, e.Err)
+}
+
+// Unwrap returns the error returned by the hook.
+func (e *HookError) Unwrap() error {
+	return e.Err
+}
+
+// run invokes the hooks registered for the event, stopping at the first error.
+func (h *DaoHooks) run(ctx context.Context, event HookEvent, bo *UniversalBo) error {
+	for _, hook := range h.hooks[event] {
+		if err := hook(ctx, bo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// after invokes the after-hooks of a write operation if the BO has been written.
+func (h *DaoHooks) after(ctx context.Context, event HookEvent, bo *UniversalBo, ok bool, err error) (bool, error) {
+	if err != nil || !ok {
+		return ok, err
+	}
+	if err := h.run(ctx, event, bo); err != nil {
+		return ok, &HookError{Event: event, Err: err}
+	}
+	return ok, nil
+}
+
+// afterLoad invokes the after-load hooks for each loaded BO.
+func (h *DaoHooks) afterLoad(ctx context.Context, bos ...*UniversalBo) error {
+	if len(h.hooks[HookAfterLoad]) == 0 {
+		return nil
+	}
+	for _, bo := range bos {
+		if bo == nil {
+			continue
+		}
+		if err := h.run(ctx, HookAfterLoad, bo); err != nil {
+			return &HookError{Event: HookAfterLoad, Err: err}
+		}
+	}
+	return nil
+}
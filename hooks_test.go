@@ -0,0 +1,83 @@
+package henge
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+var (
+	_ UniversalDaoContext = (*UniversalDaoSql)(nil)
+	_ UniversalDaoContext = (*UniversalDaoCosmosdbSql)(nil)
+	_ UniversalDaoContext = (*UniversalDaoMongo)(nil)
+	_ UniversalDaoContext = (*UniversalDaoDynamodb)(nil)
+)
+
+type hookCtxKey struct{}
+
+func TestHookEvent_String(t *testing.T) {
+	name := "TestHookEvent_String"
+	testCases := map[HookEvent]string{HookBeforeCreate: "BeforeCreate", HookAfterLoad: "AfterLoad", HookEvent(-1): "HookEvent(-1)", numHookEvents: "HookEvent(9)"}
+	for event, expected := range testCases {
+		if v := event.String(); v != expected {
+			t.Fatalf("%s failed: expected %#v but received %#v", name, expected, v)
+		}
+	}
+}
+
+func TestDaoHooks(t *testing.T) {
+	name := "TestDaoHooks"
+	var calls []string
+	hook := func(tag string, err error) HookFunc {
+		return func(ctx context.Context, bo *UniversalBo) error {
+			calls = append(calls, tag+":"+bo.GetId()+":"+ctx.Value(hookCtxKey{}).(string))
+			return err
+		}
+	}
+	h := &DaoHooks{}
+	h.OnBeforeCreate(hook("b1", nil)).OnBeforeCreate(hook("b2", nil)).OnAfterCreate(hook("a1", nil)).AddHook(HookEvent(99), hook("x", nil))
+	if len(h.GetHooks(HookBeforeCreate)) != 2 || len(h.GetHooks(HookAfterCreate)) != 1 || h.GetHooks(HookEvent(99)) != nil {
+		t.Fatalf("%s failed: unexpected registered hooks", name)
+	}
+
+	ctx := context.WithValue(context.Background(), hookCtxKey{}, "ctx")
+	bo := NewUniversalBo("id", 1)
+	if err := h.run(ctx, HookBeforeCreate, bo); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if expected := []string{"b1:id:ctx", "b2:id:ctx"}; !reflect.DeepEqual(calls, expected) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, expected, calls)
+	}
+
+	// after-hooks are invoked only if the BO has been written
+	calls = nil
+	if ok, err := h.after(ctx, HookAfterCreate, bo, false, nil); ok || err != nil || len(calls) != 0 {
+		t.Fatalf("%s failed: after-hooks should not be invoked if nothing was written", name)
+	}
+	errWrite := errors.New("write error")
+	if _, err := h.after(ctx, HookAfterCreate, bo, true, errWrite); err != errWrite || len(calls) != 0 {
+		t.Fatalf("%s failed: after-hooks should not be invoked if the write failed", name)
+	}
+	if ok, err := h.after(ctx, HookAfterCreate, bo, true, nil); !ok || err != nil || len(calls) != 1 {
+		t.Fatalf("%s failed: after-hooks should be invoked if the BO was written (%#v)", name, calls)
+	}
+
+	errHook := errors.New("hook error")
+	h.ClearHooks().OnAfterUpdate(hook("u1", errHook)).OnAfterUpdate(hook("u2", nil))
+	calls = nil
+	ok, err := h.after(ctx, HookAfterUpdate, bo, true, nil)
+	var hookErr *HookError
+	if !ok || !errors.As(err, &hookErr) || hookErr.Event != HookAfterUpdate || !errors.Is(err, errHook) || len(calls) != 1 {
+		t.Fatalf("%s failed: expected HookError wrapping %#v but received %#v / %#v", name, errHook, err, calls)
+	}
+
+	h.ClearHooks().OnAfterLoad(hook("l", nil))
+	calls = nil
+	if err := h.afterLoad(ctx, bo, nil, NewUniversalBo("id2", 1)); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if expected := []string{"l:id:ctx", "l:id2:ctx"}; !reflect.DeepEqual(calls, expected) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, expected, calls)
+	}
+}
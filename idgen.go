@@ -0,0 +1,348 @@
+package henge
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// IdGenerator generates ids for BOs. UniversalDao implementations configured with an IdGenerator (see SetIdGenerator)
+// assign a generated id to BOs being created with a blank id.
+//
+// Implementations must be safe for concurrent use.
+//
+// Available since v0.7.0
+type IdGenerator interface {
+	// NextId generates a new id.
+	NextId() (string, error)
+}
+
+// IdGeneratorFunc is a function that implements IdGenerator.
+//
+// Available since v0.7.0
+type IdGeneratorFunc func() (string, error)
+
+// NextId implements IdGenerator.NextId.
+func (f IdGeneratorFunc) NextId() (string, error) {
+	return f()
+}
+
+var (
+	// IdGeneratorUuidV4 generates random UUIDs (version 4, RFC 9562), e.g. "9b2f3c1e-4a5d-4e6f-8a7b-0c1d2e3f4a5b".
+	//
+	// Available since v0.7.0
+	IdGeneratorUuidV4 IdGenerator = IdGeneratorFunc(newUuidV4)
+
+	// IdGeneratorUuidV7 generates time-ordered UUIDs (version 7, RFC 9562): ids generated by the same generator sort
+	// in the order they were generated.
+	//
+	// Available since v0.7.0
+	IdGeneratorUuidV7 IdGenerator = &uuidV7Generator{}
+
+	// IdGeneratorUlid generates ULIDs (https://github.com/ulid/spec), 26-character time-ordered ids encoded in
+	// Crockford's base32, e.g. "01ARZ3NDEKTSV4RRFFQ69G5FAV". Ids generated by the same generator within the same
+	// millisecond are monotonically increasing.
+	//
+	// Available since v0.7.0
+	IdGeneratorUlid IdGenerator = &ulidGenerator{}
+
+	// IdGeneratorKsuid generates KSUIDs (https://github.com/segmentio/ksuid), 27-character ids encoded in base62 that
+	// are roughly ordered by generation time (second precision).
+	//
+	// Available since v0.7.0
+	IdGeneratorKsuid IdGenerator = IdGeneratorFunc(newKsuid)
+)
+
+// randomBytes returns n cryptographically secure random bytes.
+func randomBytes(n int) ([]byte, error) {
+	buf := make([]byte, n)
+	_, err := rand.Read(buf)
+	return buf, err
+}
+
+// formatUuid formats 16 bytes in the canonical textual representation of UUIDs.
+func formatUuid(b []byte) string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], b[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], b[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], b[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], b[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], b[10:16])
+	return string(buf[:])
+}
+
+func newUuidV4() (string, error) {
+	b, err := randomBytes(16)
+	if err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // variant RFC 9562
+	return formatUuid(b), nil
+}
+
+// uuidV7Generator generates UUIDv7s, using a 12-bit counter seeded randomly at each millisecond to keep ids
+// generated within the same millisecond ordered (RFC 9562, section 6.2, method 1).
+type uuidV7Generator struct {
+	lock   sync.Mutex
+	clock  Clock
+	lastMs int64
+	seq    uint16
+}
+
+// NextId implements IdGenerator.NextId.
+func (g *uuidV7Generator) NextId() (string, error) {
+	b, err := randomBytes(16)
+	if err != nil {
+		return "", err
+	}
+	g.lock.Lock()
+	ms := clockOrDefault(g.clock).Now().UnixMilli()
+	if ms <= g.lastMs {
+		if g.seq++; g.seq > 0xfff {
+			// counter overflows: borrow the next millisecond
+			g.lastMs++
+			g.seq = uint16(b[6]&0x07)<<8 | uint16(b[7])
+		}
+		ms = g.lastMs
+	} else {
+		g.lastMs, g.seq = ms, uint16(b[6]&0x07)<<8|uint16(b[7])
+	}
+	seq := g.seq
+	g.lock.Unlock()
+	b[0], b[1], b[2], b[3], b[4], b[5] = byte(ms>>40), byte(ms>>32), byte(ms>>24), byte(ms>>16), byte(ms>>8), byte(ms)
+	b[6] = 0x70 | byte(seq>>8) // version 7
+	b[7] = byte(seq)
+	b[8] = b[8]&0x3f | 0x80 // variant RFC 9562
+	return formatUuid(b), nil
+}
+
+// crockfordBase32 is the alphabet of Crockford's base32 encoding, used by ULIDs.
+const crockfordBase32 = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ulidGenerator generates ULIDs, incrementing the random part of the previous id when generating ids within the same
+// millisecond.
+type ulidGenerator struct {
+	lock    sync.Mutex
+	clock   Clock
+	lastMs  int64
+	lastRnd [10]byte
+}
+
+// NextId implements IdGenerator.NextId.
+func (g *ulidGenerator) NextId() (string, error) {
+	rnd, err := randomBytes(10)
+	if err != nil {
+		return "", err
+	}
+	g.lock.Lock()
+	ms := clockOrDefault(g.clock).Now().UnixMilli()
+	if ms <= g.lastMs {
+		ms = g.lastMs
+		i := len(g.lastRnd) - 1
+		for ; i >= 0; i-- {
+			if g.lastRnd[i]++; g.lastRnd[i] != 0 {
+				break
+			}
+		}
+		if i < 0 {
+			g.lock.Unlock()
+			return "", errors.New("ULID random component overflows within the same millisecond")
+		}
+	} else {
+		g.lastMs = ms
+		copy(g.lastRnd[:], rnd)
+	}
+	var b [16]byte
+	b[0], b[1], b[2], b[3], b[4], b[5] = byte(ms>>40), byte(ms>>32), byte(ms>>24), byte(ms>>16), byte(ms>>8), byte(ms)
+	copy(b[6:], g.lastRnd[:])
+	g.lock.Unlock()
+
+	// 128 bits are encoded in 26 characters of 5 bits, the first character carries the 3 most significant bits only
+	hi, lo := binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockfordBase32[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:]), nil
+}
+
+const (
+	// ksuidEpoch is the epoch of KSUID timestamps (seconds since Unix epoch).
+	ksuidEpoch = 1400000000
+
+	// base62 is the alphabet used to encode KSUIDs.
+	base62 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+func newKsuid() (string, error) {
+	return newKsuidAt(time.Now())
+}
+
+func newKsuidAt(t time.Time) (string, error) {
+	b, err := randomBytes(20)
+	if err != nil {
+		return "", err
+	}
+	binary.BigEndian.PutUint32(b[:4], uint32(t.Unix()-ksuidEpoch))
+	n, radix, mod := new(big.Int).SetBytes(b), big.NewInt(62), new(big.Int)
+	out := []byte(strings.Repeat("0", 27))
+	for i := len(out) - 1; i >= 0 && n.Sign() > 0; i-- {
+		n.DivMod(n, radix, mod)
+		out[i] = base62[mod.Int64()]
+	}
+	return string(out), nil
+}
+
+const (
+	// snowflakeNodeBits is the number of bits of the node id in snowflake ids.
+	snowflakeNodeBits = 10
+
+	// snowflakeSeqBits is the number of bits of the sequence number in snowflake ids.
+	snowflakeSeqBits = 12
+
+	// SnowflakeMaxNodeId is the maximum node id of snowflake ids.
+	//
+	// Available since v0.7.0
+	SnowflakeMaxNodeId = 1<<snowflakeNodeBits - 1
+)
+
+// SnowflakeEpoch is the epoch of snowflake id timestamps.
+//
+// Available since v0.7.0
+var SnowflakeEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// SnowflakeIdGenerator generates Snowflake-style ids: 63-bit integers, formatted in decimal, composed of a 41-bit
+// timestamp (milliseconds since SnowflakeEpoch), a 10-bit node id and a 12-bit sequence number. Ids generated by the
+// same node are unique and increasing; each node of the application must use a distinct node id.
+//
+// Available since v0.7.0
+type SnowflakeIdGenerator struct {
+	lock   sync.Mutex
+	nodeId int64
+	clock  Clock
+	lastMs int64
+	seq    int64
+}
+
+// NewSnowflakeIdGenerator creates a new SnowflakeIdGenerator for the specified node id (from 0 to SnowflakeMaxNodeId).
+// Time is taken from the specified clock, SystemClock if nil.
+//
+// Available since v0.7.0
+func NewSnowflakeIdGenerator(nodeId int64, clock Clock) (*SnowflakeIdGenerator, error) {
+	if nodeId < 0 || nodeId > SnowflakeMaxNodeId {
+		return nil, fmt.Errorf("node id must be between 0 and %d, got %d", SnowflakeMaxNodeId, nodeId)
+	}
+	return &SnowflakeIdGenerator{nodeId: nodeId, clock: clock}, nil
+}
+
+// GetNodeId returns the node id of the generator.
+func (g *SnowflakeIdGenerator) GetNodeId() int64 {
+	return g.nodeId
+}
+
+// NextId implements IdGenerator.NextId.
+//
+// If the clock moves backward, the timestamp of the last generated id is reused; if the sequence number overflows, the
+// timestamp is moved to the next millisecond.
+func (g *SnowflakeIdGenerator) NextId() (string, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	ms := clockOrDefault(g.clock).Now().Sub(SnowflakeEpoch).Milliseconds()
+	if ms < 0 {
+		return "", errors.New("current time is before SnowflakeEpoch")
+	}
+	if ms <= g.lastMs {
+		if g.seq++; g.seq >= 1<<snowflakeSeqBits {
+			g.lastMs++
+			g.seq = 0
+		}
+	} else {
+		g.lastMs, g.seq = ms, 0
+	}
+	if g.lastMs >= 1<<(63-snowflakeNodeBits-snowflakeSeqBits) {
+		return "", errors.New("snowflake timestamp overflows")
+	}
+	id := g.lastMs<<(snowflakeNodeBits+snowflakeSeqBits) | g.nodeId<<snowflakeSeqBits | g.seq
+	return strconv.FormatInt(id, 10), nil
+}
+
+/*----------------------------------------------------------------------*/
+
+var (
+	// ErrEmptyId is returned by UniversalDao's write functions when the BO's id is blank and the DAO has no IdGenerator.
+	//
+	// Available since v0.7.0
+	ErrEmptyId = errors.New("business object's id is empty")
+
+	// ErrIdTooLong is returned by UniversalDao's write functions when the BO's id exceeds the id length limit of the
+	// storage (see SetMaxIdLength of UniversalDao implementations).
+	//
+	// Available since v0.7.0
+	ErrIdTooLong = errors.New("business object's id is too long")
+)
+
+const (
+	// MaxIdLengthSql is the maximum length (in characters) of BO's id in tables created by the Init*Table helpers,
+	// whose id column is VARCHAR(64). SQL DAOs do not enforce it unless configured via UniversalDaoSql.SetMaxIdLength.
+	//
+	// Available since v0.7.0
+	MaxIdLengthSql = 64
+
+	// MaxIdLengthCosmosdb is the maximum length (in characters) of documents' id in Azure Cosmos DB.
+	//
+	// Available since v0.7.0
+	MaxIdLengthCosmosdb = 255
+
+	// MaxIdLengthDynamodb is the maximum length (in bytes) of partition keys in AWS DynamoDB.
+	//
+	// Available since v0.7.0
+	MaxIdLengthDynamodb = 2048
+
+	// MaxIdLengthDynamodbSortKey is the maximum length (in bytes) of sort keys in AWS DynamoDB, which limits BO's id if
+	// the table's PK is { pkPrefix, FieldId }.
+	//
+	// Available since v0.7.0
+	MaxIdLengthDynamodbSortKey = 1024
+)
+
+// prepareBoId assigns an id generated by gen to bo if its id is blank, then validates the id against the id length
+// limit of the storage (maxLength <= 0 means no limit), counted in bytes if inBytes is true, in characters otherwise.
+func prepareBoId(bo *UniversalBo, gen IdGenerator, maxLength int, inBytes bool) error {
+	if bo.GetId() == "" && gen != nil {
+		id, err := gen.NextId()
+		if err != nil {
+			return fmt.Errorf("cannot generate id: %w", err)
+		}
+		bo.SetId(id)
+	}
+	id := bo.GetId()
+	if id == "" {
+		return ErrEmptyId
+	}
+	if maxLength <= 0 {
+		return nil
+	}
+	length, unit := utf8.RuneCountInString(id), "characters"
+	if inBytes {
+		length, unit = len(id), "bytes"
+	}
+	if length > maxLength {
+		return fmt.Errorf("%w: id is %d %s long, the storage allows at most %d %s", ErrIdTooLong, length, unit, maxLength, unit)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package henge
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIdGenerator_format(t *testing.T) {
+	name := "TestIdGenerator_format"
+	testCases := map[string]struct {
+		gen     IdGenerator
+		pattern string
+	}{
+		"uuidv4": {IdGeneratorUuidV4, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+		"uuidv7": {IdGeneratorUuidV7, `^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+		"ulid":   {IdGeneratorUlid, `^[0-7][0-9A-HJKMNP-TV-Z]{25}$`},
+		"ksuid":  {IdGeneratorKsuid, `^[0-9A-Za-z]{27}$`},
+	}
+	for genName, testCase := range testCases {
+		re := regexp.MustCompile(testCase.pattern)
+		ids := make(map[string]bool)
+		for i := 0; i < 1000; i++ {
+			id, err := testCase.gen.NextId()
+			if err != nil || !re.MatchString(id) {
+				t.Fatalf("%s failed: invalid id %#v / %s (%s)", name, id, err, genName)
+			}
+			if ids[id] {
+				t.Fatalf("%s failed: duplicated id %#v (%s)", name, id, genName)
+			}
+			ids[id] = true
+		}
+	}
+}
+
+func TestIdGenerator_monotonic(t *testing.T) {
+	name := "TestIdGenerator_monotonic"
+	clock := NewFixedClock(time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC))
+	snowflake, _ := NewSnowflakeIdGenerator(1, clock)
+	gens := map[string]IdGenerator{
+		"uuidv7":    &uuidV7Generator{clock: clock},
+		"ulid":      &ulidGenerator{clock: clock},
+		"snowflake": snowflake,
+	}
+	for genName, gen := range gens {
+		prev := ""
+		for i := 0; i < 10000; i++ {
+			if i%1000 == 0 {
+				clock.Advance(time.Millisecond)
+			}
+			id, err := gen.NextId()
+			if err != nil {
+				t.Fatalf("%s failed: %s (%s)", name, err, genName)
+			}
+			if len(id) < len(prev) || (len(id) == len(prev) && id <= prev) {
+				t.Fatalf("%s failed: id %#v should be greater than %#v (%s)", name, id, prev, genName)
+			}
+			prev = id
+		}
+	}
+}
+
+func TestKsuid_timestamp(t *testing.T) {
+	name := "TestKsuid_timestamp"
+	id1, _ := newKsuidAt(time.Unix(1400000000, 0))
+	id2, _ := newKsuidAt(time.Unix(1400001000, 0))
+	if len(id1) != 27 || !strings.HasPrefix(id1, "00000") || id1 >= id2 {
+		t.Fatalf("%s failed: unexpected ids %#v / %#v", name, id1, id2)
+	}
+}
+
+func TestSnowflakeIdGenerator(t *testing.T) {
+	name := "TestSnowflakeIdGenerator"
+	for _, nodeId := range []int64{-1, SnowflakeMaxNodeId + 1} {
+		if _, err := NewSnowflakeIdGenerator(nodeId, nil); err == nil {
+			t.Fatalf("%s failed: node id %d should result in error", name, nodeId)
+		}
+	}
+	clock := NewFixedClock(SnowflakeEpoch.Add(5 * time.Millisecond))
+	gen, err := NewSnowflakeIdGenerator(3, clock)
+	if err != nil || gen.GetNodeId() != 3 {
+		t.Fatalf("%s failed: %#v / %s", name, gen, err)
+	}
+	for seq := int64(0); seq < 2; seq++ {
+		id, _ := gen.NextId()
+		if expected := strconv.FormatInt(5<<22|3<<12|seq, 10); id != expected {
+			t.Fatalf("%s failed: expected %#v but received %#v", name, expected, id)
+		}
+	}
+	// clock moving backward must not result in duplicated ids
+	clock.Set(SnowflakeEpoch.Add(time.Millisecond))
+	if id, _ := gen.NextId(); id != strconv.FormatInt(5<<22|3<<12|2, 10) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, strconv.FormatInt(5<<22|3<<12|2, 10), id)
+	}
+	clock.Set(SnowflakeEpoch.Add(-time.Millisecond))
+	if _, err := gen.NextId(); err == nil {
+		t.Fatalf("%s failed: time before SnowflakeEpoch should result in error", name)
+	}
+}
+
+func TestPrepareBoId(t *testing.T) {
+	name := "TestPrepareBoId"
+	ubo := NewUniversalBo("", 1)
+	if err := prepareBoId(ubo, nil, 0, false); !errors.Is(err, ErrEmptyId) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, ErrEmptyId, err)
+	}
+	gen := IdGeneratorFunc(func() (string, error) { return "generated", nil })
+	if err := prepareBoId(ubo, gen, 0, false); err != nil || ubo.GetId() != "generated" {
+		t.Fatalf("%s failed: expected %#v but received %#v / %s", name, "generated", ubo.GetId(), err)
+	}
+	failingGen := IdGeneratorFunc(func() (string, error) { return "", errors.New("dummy") })
+	if err := prepareBoId(ubo, failingGen, 0, false); err != nil || ubo.GetId() != "generated" {
+		t.Fatalf("%s failed: existing id should be kept (%s)", name, err)
+	}
+	if err := prepareBoId(NewUniversalBo("", 1), failingGen, 0, false); err == nil {
+		t.Fatalf("%s failed: generator error should be returned", name)
+	}
+
+	ubo.SetId("ngườidùng") // 9 characters, 13 bytes
+	if err := prepareBoId(ubo, nil, 9, false); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if err := prepareBoId(ubo, nil, 9, true); !errors.Is(err, ErrIdTooLong) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, ErrIdTooLong, err)
+	}
+	if err := prepareBoId(ubo, nil, 8, false); !errors.Is(err, ErrIdTooLong) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, ErrIdTooLong, err)
+	}
+}
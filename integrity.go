@@ -0,0 +1,208 @@
+package henge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/btnguyen2k/godal"
+)
+
+// ErrChecksumMismatch is returned by UniversalDao's read functions in strict-load mode when the stored checksum of a
+// BO does not match its content, e.g. the row was modified directly in the storage or has been corrupted.
+//
+// Available since v0.7.0
+var ErrChecksumMismatch = errors.New("stored checksum does not match BO's content")
+
+// _verifyChecksum checks if the specified checksum matches bo's content. The checksum is recalculated using the
+// algorithm the specified checksum was calculated with.
+//
+// Caller is responsible for locking and syncing the BO.
+func (ubo *UniversalBo) _verifyChecksum(csum string) bool {
+	if csum == "" {
+		return false
+	}
+	alg := ChecksumAlgorithmOf(csum)
+	if !alg.IsSupported() {
+		return false
+	}
+	if alg != ChecksumAlgorithmOf(ubo.checksum) {
+		if csum == ubo._calcChecksum(alg, ubo._checksumInput(alg)) {
+			return true
+		}
+	} else if csum == ubo.checksum {
+		return true
+	}
+	return alg == ChecksumMd5 && ubo._verifyMd5Checksum(csum, ubo._checksumInput(alg))
+}
+
+// _verifyLoadedChecksum verifies the stored checksum of a BO just loaded from storage, dataJs being its data in JSON.
+//
+// Storages return extra attributes that were not set when the BO was written as nil (e.g. columns of extra attributes
+// in SQL tables), hence if the stored checksum does not match, nil extra attributes are dropped if this makes it match.
+//
+// Caller is responsible for locking the BO.
+func (ubo *UniversalBo) _verifyLoadedChecksum(storedChecksum string, dataJs []byte) bool {
+	if ubo._verifyChecksum(storedChecksum) {
+		return true
+	}
+	extras := make(map[string]interface{}, len(ubo._extraAttrs))
+	for k, v := range ubo._extraAttrs {
+		if v != nil {
+			extras[k] = v
+		}
+	}
+	if storedChecksum == "" || len(extras) == len(ubo._extraAttrs) {
+		return false
+	}
+	loadedExtras, loadedChecksum := ubo._extraAttrs, ubo.checksum
+	ubo._extraAttrs = extras
+	ubo.checksum = ubo._calcChecksum(ubo._checksumAlgorithm, dataJs)
+	if !ubo._verifyChecksum(storedChecksum) {
+		ubo._extraAttrs, ubo.checksum = loadedExtras, loadedChecksum
+		return false
+	}
+	if ubo._original != nil {
+		if originalExtras, ok := ubo._original.extras.(map[string]interface{}); ok {
+			for k, v := range loadedExtras {
+				if v == nil {
+					delete(originalExtras, k)
+				}
+			}
+		}
+	}
+	return true
+}
+
+// _markPersisted records that the BO's current checksum is the one stored in the storage.
+func (ubo *UniversalBo) _markPersisted() *UniversalBo {
+	ubo._lock.Lock()
+	defer ubo._lock.Unlock()
+	ubo._resolveChecksum()
+	ubo._storedChecksum = ubo.checksum
+	ubo._checksumMismatch = false
+	return ubo
+}
+
+// GetStoredChecksum returns the checksum of the BO as it was loaded from storage (or as it was last written to storage).
+//
+// Unlike GetChecksum, which returns the checksum recalculated from the BO's current content, the stored checksum is
+// not changed when the BO is modified. It is empty if the BO has not been loaded from or written to storage.
+//
+// Available since v0.7.0
+func (ubo *UniversalBo) GetStoredChecksum() string {
+	ubo._lock.RLock()
+	defer ubo._lock.RUnlock()
+	return ubo._storedChecksum
+}
+
+// IsChecksumMismatched returns true if the BO's stored checksum did not match its content at the time the BO was loaded
+// from storage (a missing stored checksum is also considered a mismatch).
+//
+// The flag is cleared once the BO is successfully written back to storage.
+//
+// (since v0.7.0) Stored MD5 checksums are verified on the first call to this function or GetChecksum rather than when
+// the BO is loaded.
+//
+// Available since v0.7.0
+func (ubo *UniversalBo) IsChecksumMismatched() bool {
+	ubo._lock.RLock()
+	defer ubo._lock.RUnlock()
+	_, mismatch := ubo._checksumState()
+	return mismatch
+}
+
+// checkLoadedChecksum returns ErrChecksumMismatch (wrapped with BO's id) if strict is true and the loaded BO's stored
+// checksum does not match its content.
+func checkLoadedChecksum(bo *UniversalBo, strict bool) error {
+	if strict && bo != nil && bo.IsChecksumMismatched() {
+		return fmt.Errorf("%w: id [%s]", ErrChecksumMismatch, bo.GetId())
+	}
+	return nil
+}
+
+// uncheckedLoader is implemented by UniversalDao implementations that can load BOs bypassing strict-load mode.
+type uncheckedLoader interface {
+	getN(ctx context.Context, fromOffset, maxNumRows int, filter godal.FilterOpt, sorting *godal.SortingOpt, strict bool) ([]*UniversalBo, error)
+}
+
+// scanSorter is implemented by UniversalDao implementations that can sort BOs by id when scanning them.
+type scanSorter interface {
+	scanSorting() *godal.SortingOpt
+}
+
+// scanBos loads BOs matching the filter in batches and passes them to the callback, bypassing strict-load mode if
+// supported by the DAO.
+//
+// BOs are sorted by id if supported by the DAO, so that offset-based paging is stable while the callback rewrites them.
+func scanBos(dao UniversalDao, filter godal.FilterOpt, batchSize int, callback func(bo *UniversalBo) error) error {
+	if batchSize <= 0 {
+		batchSize = DefaultScanBatchSize
+	}
+	var sorting *godal.SortingOpt
+	if sorter, ok := dao.(scanSorter); ok {
+		sorting = sorter.scanSorting()
+	}
+	for offset := 0; ; offset += batchSize {
+		var boList []*UniversalBo
+		var err error
+		if loader, ok := dao.(uncheckedLoader); ok {
+			boList, err = loader.getN(context.Background(), offset, batchSize, filter, sorting, false)
+		} else {
+			boList, err = dao.GetN(offset, batchSize, filter, sorting)
+		}
+		if err != nil {
+			return err
+		}
+		for _, bo := range boList {
+			if bo == nil {
+				continue
+			}
+			if err = callback(bo); err != nil {
+				return err
+			}
+		}
+		if len(boList) < batchSize {
+			return nil
+		}
+	}
+}
+
+// ChecksumMismatch reports a BO whose stored checksum does not match its content, found by VerifyAll.
+//
+// Available since v0.7.0
+type ChecksumMismatch struct {
+	Id             string // BO's id
+	StoredChecksum string // checksum stored in the storage
+	Checksum       string // checksum calculated from BO's content
+	Repaired       bool   // true if the stored checksum has been overwritten with the calculated one
+}
+
+// VerifyAll scans all BOs in the storage and reports those whose stored checksum does not match their content.
+//   - BOs are loaded in batches of DefaultScanBatchSize, bypassing the DAO's strict-load mode.
+//   - If repair is true, the content of each mismatching BO is accepted as-is and written back to the storage with
+//     its recalculated checksum.
+//
+// This function returns the list of mismatching BOs found before any error occurred.
+//
+// Available since v0.7.0
+func VerifyAll(dao UniversalDao, repair bool) ([]ChecksumMismatch, error) {
+	result := make([]ChecksumMismatch, 0)
+	err := scanBos(dao, nil, DefaultScanBatchSize, func(bo *UniversalBo) error {
+		if !bo.IsChecksumMismatched() {
+			return nil
+		}
+		mismatch := ChecksumMismatch{Id: bo.GetId(), StoredChecksum: bo.GetStoredChecksum(), Checksum: bo.GetChecksum()}
+		if repair {
+			ok, err := dao.Update(bo)
+			if err != nil {
+				result = append(result, mismatch)
+				return err
+			}
+			mismatch.Repaired = ok
+		}
+		result = append(result, mismatch)
+		return nil
+	})
+	return result, err
+}
@@ -0,0 +1,98 @@
+package henge
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/btnguyen2k/godal"
+)
+
+func TestUniversalBo_IsChecksumMismatched(t *testing.T) {
+	name := "TestUniversalBo_IsChecksumMismatched"
+	for _, alg := range []ChecksumAlgorithm{ChecksumMd5, ChecksumSha256} {
+		ubo := NewUniversalBo("id", 1, UboOpt{ChecksumAlgorithm: alg, TimestampRounding: TimestampRoundingSettingSecond})
+		ubo.SetDataAttr("a", 1)
+		ubo.SetExtraAttr("b", "x")
+		if ubo.IsChecksumMismatched() || ubo.GetStoredChecksum() != "" {
+			t.Fatalf("%s failed: new BO should not be flagged", name)
+		}
+
+		gbo := ubo.ToGenericBo()
+		loaded := NewUniversalBoFromGbo(gbo)
+		if loaded.IsChecksumMismatched() || loaded.GetStoredChecksum() != ubo.GetChecksum() {
+			t.Fatalf("%s failed: loaded BO should not be flagged (%#v / %#v)", name, loaded.GetStoredChecksum(), ubo.GetChecksum())
+		}
+
+		// stored checksum is verified with its own algorithm
+		loaded = NewUniversalBoFromGbo(gbo, UboOpt{ChecksumAlgorithm: ChecksumXxhash64})
+		if loaded.IsChecksumMismatched() || ChecksumAlgorithmOf(loaded.GetChecksum()) != ChecksumXxhash64 {
+			t.Fatalf("%s failed: loaded BO should not be flagged (%#v)", name, loaded.GetChecksum())
+		}
+
+		gbo.GboSetAttr(FieldData, `{"a":2}`)
+		tampered := NewUniversalBoFromGbo(gbo)
+		if !tampered.IsChecksumMismatched() || !tampered.Clone().IsChecksumMismatched() {
+			t.Fatalf("%s failed: tampered BO should be flagged", name)
+		}
+		if err := checkLoadedChecksum(tampered, true); !errors.Is(err, ErrChecksumMismatch) {
+			t.Fatalf("%s failed: expected %#v but received %#v", name, ErrChecksumMismatch, err)
+		}
+		if err := checkLoadedChecksum(tampered, false); err != nil {
+			t.Fatalf("%s failed: %s", name, err)
+		}
+		afterWrite(tampered, true, nil)
+		if tampered.IsChecksumMismatched() || tampered.GetStoredChecksum() != tampered.GetChecksum() {
+			t.Fatalf("%s failed: flag should be cleared after writing", name)
+		}
+
+		gbo.GboSetAttr(FieldChecksum, "")
+		if !NewUniversalBoFromGbo(gbo).IsChecksumMismatched() {
+			t.Fatalf("%s failed: BO without stored checksum should be flagged", name)
+		}
+	}
+}
+
+func TestUniversalBo_ChecksumNilExtraAttrs(t *testing.T) {
+	name := "TestUniversalBo_ChecksumNilExtraAttrs"
+	for _, alg := range []ChecksumAlgorithm{ChecksumMd5, ChecksumSha256} {
+		ubo := NewUniversalBo("id", 1, UboOpt{ChecksumAlgorithm: alg, TimestampRounding: TimestampRoundingSettingSecond})
+		ubo.SetDataAttr("a", 1)
+		ubo.SetExtraAttr("b", "x")
+		ubo.Sync()
+		withNil := ubo.Clone()
+		withNil.SetExtraAttr("c", nil)
+		withNil.Sync()
+		if withNil.GetChecksum() == ubo.GetChecksum() {
+			t.Fatalf("%s failed: nil extra attributes should be taken into account", name)
+		}
+
+		// storages (e.g. SQL tables) return unset extra attributes as nil
+		for _, bo := range []*UniversalBo{ubo, withNil} {
+			m := make(map[string]interface{})
+			bo.ToGenericBo().GboTransferViaJson(&m)
+			m["c"] = nil
+			gbo := godal.NewGenericBo()
+			gbo.GboImportViaJson(m)
+			loaded := NewUniversalBoFromGbo(gbo)
+			if loaded.IsChecksumMismatched() || loaded.IsDirty() {
+				t.Fatalf("%s failed: loaded BO should not be flagged", name)
+			}
+			if alg == ChecksumMd5 {
+				// stored MD5 checksum is verified lazily, nil extra attributes are dropped when the BO is synced
+				loaded.SetTagVersion(loaded.GetTagVersion()).Sync()
+			}
+			if len(loaded.GetExtraAttrs()) != len(bo.GetExtraAttrs()) {
+				t.Fatalf("%s failed: expected %#v but received %#v", name, bo.GetExtraAttrs(), loaded.GetExtraAttrs())
+			}
+		}
+	}
+}
+
+func TestVerifyAll_unchanged(t *testing.T) {
+	name := "TestVerifyAll_unchanged"
+	dao := Chain(&memDao{bos: map[string]*UniversalBo{"id": newMismatchedBo("id")}}, unchangedInterceptor)
+	mismatches, err := VerifyAll(dao, true)
+	if err != nil || len(mismatches) != 1 || mismatches[0].Repaired {
+		t.Fatalf("%s failed: unexpected result %#v (error: %s)", name, mismatches, err)
+	}
+}
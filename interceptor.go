@@ -0,0 +1,369 @@
+package henge
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"time"
+
+	"github.com/btnguyen2k/godal"
+)
+
+// DaoOp identifies an operation of UniversalDao.
+//
+// Available since v0.7.0
+type DaoOp string
+
+const (
+	OpCreate DaoOp = "Create"
+	OpGet    DaoOp = "Get"
+	OpGetN   DaoOp = "GetN"
+	OpGetAll DaoOp = "GetAll"
+	OpUpdate DaoOp = "Update"
+	OpSave   DaoOp = "Save"
+	OpDelete DaoOp = "Delete"
+)
+
+// DaoCall holds the arguments and results of a UniversalDao operation being intercepted, see Interceptor.
+//
+// Only fields relevant to the operation are used:
+//   - Create, Update, Delete: argument Bo, result Ok.
+//   - Save: argument Bo, results Ok and Result (the existing BO).
+//   - Get: argument Id, result Result.
+//   - GetN: arguments FromOffset, MaxNumRows, Filter and Sorting, result Results.
+//   - GetAll: arguments Filter and Sorting, result Results.
+//
+// Available since v0.7.0
+type DaoCall struct {
+	Op DaoOp
+
+	// arguments
+	Bo         *UniversalBo
+	Id         string
+	FromOffset int
+	MaxNumRows int
+	Filter     godal.FilterOpt
+	Sorting    *godal.SortingOpt
+
+	// results, populated once the operation has been invoked
+	Ok      bool
+	Result  *UniversalBo
+	Results []*UniversalBo
+
+	unchecked bool  // GetN bypasses strict-load mode of the wrapped DAO, see uncheckedLoader
+	clock     Clock // clock of the wrapped DAO, nil if it has none, see clockSource
+}
+
+// String implements fmt.Stringer.
+func (c *DaoCall) String() string {
+	switch c.Op {
+	case OpGet:
+		return fmt.Sprintf("%s(%q)", c.Op, c.Id)
+	case OpGetN:
+		return fmt.Sprintf("%s(%d, %d)", c.Op, c.FromOffset, c.MaxNumRows)
+	case OpGetAll:
+		return fmt.Sprintf("%s()", c.Op)
+	}
+	if c.Bo != nil {
+		return fmt.Sprintf("%s(%q)", c.Op, c.Bo.GetId())
+	}
+	return fmt.Sprintf("%s(nil)", c.Op)
+}
+
+// Invoker invokes a UniversalDao operation, storing its results into call.
+//
+// Available since v0.7.0
+type Invoker func(ctx context.Context, call *DaoCall) error
+
+// Interceptor intercepts UniversalDao operations, similar to gRPC interceptors: it can inspect or modify the call's
+// arguments, invoke the next interceptor in chain (or the operation itself) via next, then inspect or modify the
+// results and the returned error. An interceptor can also short-circuit the operation by not calling next.
+//
+// Available since v0.7.0
+type Interceptor func(ctx context.Context, call *DaoCall, next Invoker) error
+
+// Chain wraps a UniversalDao so that its operations (Create, Get, GetN, GetAll, Update, Save and Delete) go through the
+// interceptors. The first interceptor is the outermost one, i.e. interceptors are invoked in the order they are
+// specified, and see the results in reverse order.
+//
+// The returned DAO implements UniversalDaoContext. The context passed to the XxxContext functions (context.Background()
+// for functions without context) is passed to interceptors, and to dao if it implements UniversalDaoContext.
+// ToUniversalBo and ToGenericBo are not intercepted.
+//
+// Available since v0.7.0
+func Chain(dao UniversalDao, interceptors ...Interceptor) UniversalDao {
+	if chained, ok := dao.(*chainedDao); ok {
+		// flatten nested chains
+		all := append(append([]Interceptor{}, interceptors...), chained.interceptors...)
+		return &chainedDao{UniversalDao: chained.UniversalDao, interceptors: all}
+	}
+	return &chainedDao{UniversalDao: dao, interceptors: append([]Interceptor{}, interceptors...)}
+}
+
+// chainedDao is the UniversalDao returned by Chain.
+type chainedDao struct {
+	UniversalDao
+	interceptors []Interceptor
+}
+
+// invoke invokes the operation through the interceptors.
+func (dao *chainedDao) invoke(ctx context.Context, call *DaoCall) error {
+	if src, ok := dao.UniversalDao.(clockSource); ok {
+		call.clock = src.GetClock()
+	}
+	invoker := dao.call
+	for i := len(dao.interceptors) - 1; i >= 0; i-- {
+		interceptor, next := dao.interceptors[i], invoker
+		invoker = func(ctx context.Context, call *DaoCall) error {
+			return interceptor(ctx, call, next)
+		}
+	}
+	return invoker(ctx, call)
+}
+
+// call invokes the operation on the wrapped DAO.
+func (dao *chainedDao) call(ctx context.Context, call *DaoCall) error {
+	var err error
+	daoCtx, withCtx := dao.UniversalDao.(UniversalDaoContext)
+	switch call.Op {
+	case OpCreate:
+		if withCtx {
+			call.Ok, err = daoCtx.CreateContext(ctx, call.Bo)
+		} else {
+			call.Ok, err = dao.UniversalDao.Create(call.Bo)
+		}
+	case OpGet:
+		if withCtx {
+			call.Result, err = daoCtx.GetContext(ctx, call.Id)
+		} else {
+			call.Result, err = dao.UniversalDao.Get(call.Id)
+		}
+	case OpGetN:
+		if loader, ok := dao.UniversalDao.(uncheckedLoader); ok && call.unchecked {
+			call.Results, err = loader.getN(ctx, call.FromOffset, call.MaxNumRows, call.Filter, call.Sorting, false)
+		} else if withCtx {
+			call.Results, err = daoCtx.GetNContext(ctx, call.FromOffset, call.MaxNumRows, call.Filter, call.Sorting)
+		} else {
+			call.Results, err = dao.UniversalDao.GetN(call.FromOffset, call.MaxNumRows, call.Filter, call.Sorting)
+		}
+	case OpGetAll:
+		if withCtx {
+			call.Results, err = daoCtx.GetAllContext(ctx, call.Filter, call.Sorting)
+		} else {
+			call.Results, err = dao.UniversalDao.GetAll(call.Filter, call.Sorting)
+		}
+	case OpUpdate:
+		if withCtx {
+			call.Ok, err = daoCtx.UpdateContext(ctx, call.Bo)
+		} else {
+			call.Ok, err = dao.UniversalDao.Update(call.Bo)
+		}
+	case OpSave:
+		if withCtx {
+			call.Ok, call.Result, err = daoCtx.SaveContext(ctx, call.Bo)
+		} else {
+			call.Ok, call.Result, err = dao.UniversalDao.Save(call.Bo)
+		}
+	case OpDelete:
+		if withCtx {
+			call.Ok, err = daoCtx.DeleteContext(ctx, call.Bo)
+		} else {
+			call.Ok, err = dao.UniversalDao.Delete(call.Bo)
+		}
+	default:
+		err = fmt.Errorf("unsupported operation %q", call.Op)
+	}
+	return err
+}
+
+// Delete implements UniversalDao.Delete.
+func (dao *chainedDao) Delete(bo *UniversalBo) (bool, error) {
+	return dao.DeleteContext(context.Background(), bo)
+}
+
+// DeleteContext implements UniversalDaoContext.DeleteContext.
+func (dao *chainedDao) DeleteContext(ctx context.Context, bo *UniversalBo) (bool, error) {
+	call := &DaoCall{Op: OpDelete, Bo: bo}
+	err := dao.invoke(ctx, call)
+	return call.Ok, err
+}
+
+// Create implements UniversalDao.Create.
+func (dao *chainedDao) Create(bo *UniversalBo) (bool, error) {
+	return dao.CreateContext(context.Background(), bo)
+}
+
+// CreateContext implements UniversalDaoContext.CreateContext.
+func (dao *chainedDao) CreateContext(ctx context.Context, bo *UniversalBo) (bool, error) {
+	call := &DaoCall{Op: OpCreate, Bo: bo}
+	err := dao.invoke(ctx, call)
+	return call.Ok, err
+}
+
+// Get implements UniversalDao.Get.
+func (dao *chainedDao) Get(id string) (*UniversalBo, error) {
+	return dao.GetContext(context.Background(), id)
+}
+
+// GetContext implements UniversalDaoContext.GetContext.
+func (dao *chainedDao) GetContext(ctx context.Context, id string) (*UniversalBo, error) {
+	call := &DaoCall{Op: OpGet, Id: id}
+	err := dao.invoke(ctx, call)
+	return call.Result, err
+}
+
+// GetN implements UniversalDao.GetN.
+func (dao *chainedDao) GetN(fromOffset, maxNumRows int, filter godal.FilterOpt, sorting *godal.SortingOpt) ([]*UniversalBo, error) {
+	return dao.GetNContext(context.Background(), fromOffset, maxNumRows, filter, sorting)
+}
+
+// GetNContext implements UniversalDaoContext.GetNContext.
+func (dao *chainedDao) GetNContext(ctx context.Context, fromOffset, maxNumRows int, filter godal.FilterOpt, sorting *godal.SortingOpt) ([]*UniversalBo, error) {
+	call := &DaoCall{Op: OpGetN, FromOffset: fromOffset, MaxNumRows: maxNumRows, Filter: filter, Sorting: sorting}
+	err := dao.invoke(ctx, call)
+	return call.Results, err
+}
+
+// getN implements uncheckedLoader, loading BOs through the interceptors while bypassing strict-load mode of the
+// wrapped DAO if supported.
+func (dao *chainedDao) getN(ctx context.Context, fromOffset, maxNumRows int, filter godal.FilterOpt, sorting *godal.SortingOpt, strict bool) ([]*UniversalBo, error) {
+	call := &DaoCall{Op: OpGetN, FromOffset: fromOffset, MaxNumRows: maxNumRows, Filter: filter, Sorting: sorting, unchecked: !strict}
+	err := dao.invoke(ctx, call)
+	return call.Results, err
+}
+
+// scanSorting implements scanSorter, forwarding to the wrapped DAO. nil is returned if the wrapped DAO does not
+// support sorting BOs by id.
+func (dao *chainedDao) scanSorting() *godal.SortingOpt {
+	if sorter, ok := dao.UniversalDao.(scanSorter); ok {
+		return sorter.scanSorting()
+	}
+	return nil
+}
+
+// GetClock implements clockSource, forwarding to the wrapped DAO. nil is returned if the wrapped DAO has no clock.
+func (dao *chainedDao) GetClock() Clock {
+	if src, ok := dao.UniversalDao.(clockSource); ok {
+		return src.GetClock()
+	}
+	return nil
+}
+
+// GetAll implements UniversalDao.GetAll.
+func (dao *chainedDao) GetAll(filter godal.FilterOpt, sorting *godal.SortingOpt) ([]*UniversalBo, error) {
+	return dao.GetAllContext(context.Background(), filter, sorting)
+}
+
+// GetAllContext implements UniversalDaoContext.GetAllContext.
+func (dao *chainedDao) GetAllContext(ctx context.Context, filter godal.FilterOpt, sorting *godal.SortingOpt) ([]*UniversalBo, error) {
+	call := &DaoCall{Op: OpGetAll, Filter: filter, Sorting: sorting}
+	err := dao.invoke(ctx, call)
+	return call.Results, err
+}
+
+// Update implements UniversalDao.Update.
+func (dao *chainedDao) Update(bo *UniversalBo) (bool, error) {
+	return dao.UpdateContext(context.Background(), bo)
+}
+
+// UpdateContext implements UniversalDaoContext.UpdateContext.
+func (dao *chainedDao) UpdateContext(ctx context.Context, bo *UniversalBo) (bool, error) {
+	call := &DaoCall{Op: OpUpdate, Bo: bo}
+	err := dao.invoke(ctx, call)
+	return call.Ok, err
+}
+
+// Save implements UniversalDao.Save.
+func (dao *chainedDao) Save(bo *UniversalBo) (bool, *UniversalBo, error) {
+	return dao.SaveContext(context.Background(), bo)
+}
+
+// SaveContext implements UniversalDaoContext.SaveContext.
+func (dao *chainedDao) SaveContext(ctx context.Context, bo *UniversalBo) (bool, *UniversalBo, error) {
+	call := &DaoCall{Op: OpSave, Bo: bo}
+	err := dao.invoke(ctx, call)
+	return call.Ok, call.Result, err
+}
+
+/*----------------------------------------------------------------------*/
+
+// LoggingInterceptor returns an Interceptor that logs each operation, along with its duration and error if any, to
+// the specified logger (slog.Default() if nil). Successful operations are logged at debug level, failed ones at error
+// level. Writes skipped because the BO is unchanged (see UniversalBo.WasUnchanged) are successful operations.
+//
+// Available since v0.7.0
+func LoggingInterceptor(logger *slog.Logger) Interceptor {
+	return func(ctx context.Context, call *DaoCall, next Invoker) error {
+		l := logger
+		if l == nil {
+			l = slog.Default()
+		}
+		start := time.Now()
+		err := next(ctx, call)
+		attrs := []slog.Attr{slog.String("op", string(call.Op)), slog.Duration("duration", time.Since(start))}
+		if call.Bo != nil {
+			attrs = append(attrs, slog.String("id", call.Bo.GetId()))
+		} else if call.Op == OpGet {
+			attrs = append(attrs, slog.String("id", call.Id))
+		}
+		if err != nil {
+			l.LogAttrs(ctx, slog.LevelError, "henge: "+call.String()+" failed", append(attrs, slog.Any("error", err))...)
+		} else {
+			l.LogAttrs(ctx, slog.LevelDebug, "henge: "+call.String(), attrs...)
+		}
+		return err
+	}
+}
+
+// TimingInterceptor returns an Interceptor that reports the duration of each operation to observe, e.g. to record
+// metrics.
+//
+// Available since v0.7.0
+func TimingInterceptor(observe func(ctx context.Context, call *DaoCall, duration time.Duration, err error)) Interceptor {
+	return func(ctx context.Context, call *DaoCall, next Invoker) error {
+		start := time.Now()
+		err := next(ctx, call)
+		if observe != nil {
+			observe(ctx, call, time.Since(start), err)
+		}
+		return err
+	}
+}
+
+// PanicError is returned by operations intercepted by RecoveryInterceptor when they panic.
+//
+// Available since v0.7.0
+type PanicError struct {
+	Op    DaoOp
+	Value interface{} // the value passed to panic
+	Stack []byte      // stack trace of the goroutine at the time of the panic
+}
+
+// Error implements error.Error.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("henge: panic in %s: %v", e.Op, e.Value)
+}
+
+// Unwrap returns the value passed to panic if it is an error, nil otherwise.
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// RecoveryInterceptor returns an Interceptor that recovers from panics raised by the next interceptors or the
+// operation itself, returning them as PanicError. It should be the first interceptor of the chain.
+//
+// Available since v0.7.0
+func RecoveryInterceptor() Interceptor {
+	return func(ctx context.Context, call *DaoCall, next Invoker) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = &PanicError{Op: call.Op, Value: r, Stack: debug.Stack()}
+			}
+		}()
+		return next(ctx, call)
+	}
+}
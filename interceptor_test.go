@@ -0,0 +1,239 @@
+package henge
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/btnguyen2k/godal"
+)
+
+// memDao is a minimal in-memory UniversalDao, used to test interceptors.
+type memDao struct {
+	bos map[string]*UniversalBo
+}
+
+func (dao *memDao) ToUniversalBo(gbo godal.IGenericBo) *UniversalBo {
+	return NewUniversalBoFromGbo(gbo)
+}
+
+func (dao *memDao) ToGenericBo(ubo *UniversalBo) godal.IGenericBo {
+	return ubo.ToGenericBo()
+}
+
+func (dao *memDao) Delete(bo *UniversalBo) (bool, error) {
+	_, ok := dao.bos[bo.GetId()]
+	delete(dao.bos, bo.GetId())
+	return ok, nil
+}
+
+func (dao *memDao) Create(bo *UniversalBo) (bool, error) {
+	if _, ok := dao.bos[bo.GetId()]; ok {
+		return false, godal.ErrGdaoDuplicatedEntry
+	}
+	dao.bos[bo.GetId()] = bo.Clone()
+	return true, nil
+}
+
+func (dao *memDao) Get(id string) (*UniversalBo, error) {
+	if id == "panic" {
+		panic("boom")
+	}
+	if bo, ok := dao.bos[id]; ok {
+		return bo.Clone(), nil
+	}
+	return nil, nil
+}
+
+func (dao *memDao) GetN(fromOffset, maxNumRows int, _ godal.FilterOpt, _ *godal.SortingOpt) ([]*UniversalBo, error) {
+	result := make([]*UniversalBo, 0)
+	for _, bo := range dao.bos {
+		result = append(result, bo.Clone())
+	}
+	return result, nil
+}
+
+func (dao *memDao) GetAll(filter godal.FilterOpt, sorting *godal.SortingOpt) ([]*UniversalBo, error) {
+	return dao.GetN(0, 0, filter, sorting)
+}
+
+func (dao *memDao) Update(bo *UniversalBo) (bool, error) {
+	if _, ok := dao.bos[bo.GetId()]; !ok {
+		return false, nil
+	}
+	dao.bos[bo.GetId()] = bo.Clone()
+	return true, nil
+}
+
+func (dao *memDao) Save(bo *UniversalBo) (bool, *UniversalBo, error) {
+	existing := dao.bos[bo.GetId()]
+	dao.bos[bo.GetId()] = bo.Clone()
+	return true, existing, nil
+}
+
+// unchangedInterceptor short-circuits updates as if the BO was unchanged.
+func unchangedInterceptor(ctx context.Context, call *DaoCall, next Invoker) error {
+	if call.Op == OpUpdate {
+		var err error
+		call.Ok, err = unchangedWrite(call.Bo)
+		return err
+	}
+	return next(ctx, call)
+}
+
+// newMismatchedBo creates a BO whose stored checksum does not match its content.
+func newMismatchedBo(id string) *UniversalBo {
+	ubo := NewUniversalBo(id, 1)
+	ubo.SetDataAttr("a", 1)
+	gbo := ubo.ToGenericBo()
+	gbo.GboSetAttr(FieldChecksum, "invalid")
+	return NewUniversalBoFromGbo(gbo)
+}
+
+func TestChain(t *testing.T) {
+	name := "TestChain"
+	var trace []string
+	tracer := func(tag string) Interceptor {
+		return func(ctx context.Context, call *DaoCall, next Invoker) error {
+			trace = append(trace, tag+">"+call.String())
+			err := next(ctx, call)
+			trace = append(trace, tag+"<"+call.String())
+			return err
+		}
+	}
+	dao := Chain(Chain(&memDao{bos: map[string]*UniversalBo{}}, tracer("inner")), tracer("outer"))
+	if ok, err := dao.Create(NewUniversalBo("id1", 1)); !ok || err != nil {
+		t.Fatalf("%s failed: %#v / %s", name, ok, err)
+	}
+	expected := []string{`outer>Create("id1")`, `inner>Create("id1")`, `inner<Create("id1")`, `outer<Create("id1")`}
+	if !reflect.DeepEqual(trace, expected) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, expected, trace)
+	}
+	if ok, err := dao.Create(NewUniversalBo("id1", 1)); ok || err != godal.ErrGdaoDuplicatedEntry {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, godal.ErrGdaoDuplicatedEntry, err)
+	}
+	if bo, err := dao.Get("id1"); err != nil || bo == nil || bo.GetId() != "id1" {
+		t.Fatalf("%s failed: %#v / %s", name, bo, err)
+	}
+	if ok, existing, err := dao.Save(NewUniversalBo("id1", 2)); !ok || err != nil || existing == nil || existing.GetTagVersion() != 1 {
+		t.Fatalf("%s failed: %#v / %#v / %s", name, ok, existing, err)
+	}
+	if ok, err := dao.Update(NewUniversalBo("id2", 1)); ok || err != nil {
+		t.Fatalf("%s failed: %#v / %s", name, ok, err)
+	}
+	if bos, err := dao.GetAll(nil, nil); err != nil || len(bos) != 1 {
+		t.Fatalf("%s failed: %#v / %s", name, bos, err)
+	}
+	if bos, err := dao.GetN(0, 10, nil, nil); err != nil || len(bos) != 1 {
+		t.Fatalf("%s failed: %#v / %s", name, bos, err)
+	}
+	if ok, err := dao.Delete(NewUniversalBo("id1", 1)); !ok || err != nil {
+		t.Fatalf("%s failed: %#v / %s", name, ok, err)
+	}
+}
+
+func TestChain_modifyAndShortCircuit(t *testing.T) {
+	name := "TestChain_modifyAndShortCircuit"
+	errDenied := errors.New("denied")
+	cache := NewUniversalBo("cached", 1)
+	dao := Chain(&memDao{bos: map[string]*UniversalBo{}},
+		func(ctx context.Context, call *DaoCall, next Invoker) error {
+			if call.Op == OpDelete {
+				return errDenied
+			}
+			if call.Op == OpGet && call.Id == "cached" {
+				call.Result = cache
+				return nil
+			}
+			if call.Bo != nil {
+				call.Bo.SetExtraAttr("stamped", true)
+			}
+			return next(ctx, call)
+		})
+	bo := NewUniversalBo("id", 1)
+	dao.Create(bo)
+	if loaded, _ := dao.Get("id"); loaded.GetExtraAttr("stamped") != true {
+		t.Fatalf("%s failed: interceptors should be able to modify arguments", name)
+	}
+	if loaded, _ := dao.Get("cached"); loaded != cache {
+		t.Fatalf("%s failed: interceptors should be able to short-circuit operations", name)
+	}
+	if ok, err := dao.Delete(bo); ok || err != errDenied {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, errDenied, err)
+	}
+}
+
+func TestChain_context(t *testing.T) {
+	name := "TestChain_context"
+	var received context.Context
+	dao := Chain(&memDao{bos: map[string]*UniversalBo{}}, func(ctx context.Context, call *DaoCall, next Invoker) error {
+		received = ctx
+		return next(ctx, call)
+	})
+	ctx := context.WithValue(context.Background(), hookCtxKey{}, "v")
+	daoCtx, ok := dao.(UniversalDaoContext)
+	if !ok {
+		t.Fatalf("%s failed: chained DAO should implement UniversalDaoContext", name)
+	}
+	daoCtx.CreateContext(ctx, NewUniversalBo("id", 1))
+	if received != ctx {
+		t.Fatalf("%s failed: context should be passed to interceptors", name)
+	}
+}
+
+func TestRecoveryInterceptor(t *testing.T) {
+	name := "TestRecoveryInterceptor"
+	dao := Chain(&memDao{bos: map[string]*UniversalBo{}}, RecoveryInterceptor())
+	_, err := dao.Get("panic")
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) || panicErr.Op != OpGet || panicErr.Value != "boom" || len(panicErr.Stack) == 0 {
+		t.Fatalf("%s failed: expected PanicError but received %#v", name, err)
+	}
+	if bo, err := dao.Get("id"); bo != nil || err != nil {
+		t.Fatalf("%s failed: %#v / %s", name, bo, err)
+	}
+}
+
+func TestLoggingInterceptor(t *testing.T) {
+	name := "TestLoggingInterceptor"
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	dao := Chain(&memDao{bos: map[string]*UniversalBo{}}, LoggingInterceptor(logger))
+	dao.Create(NewUniversalBo("id", 1))
+	dao.Create(NewUniversalBo("id", 1))
+	logs := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(logs) != 2 || !strings.Contains(logs[0], "level=DEBUG") || !strings.Contains(logs[0], "op=Create") || !strings.Contains(logs[0], "id=id") {
+		t.Fatalf("%s failed: unexpected logs %#v", name, logs)
+	}
+	if !strings.Contains(logs[1], "level=ERROR") || !strings.Contains(logs[1], "error=") {
+		t.Fatalf("%s failed: unexpected logs %#v", name, logs)
+	}
+	// a write skipped because the BO is unchanged is not a failure
+	buf.Reset()
+	dao = Chain(&memDao{bos: map[string]*UniversalBo{}}, LoggingInterceptor(logger), unchangedInterceptor)
+	dao.Update(NewUniversalBo("id", 1))
+	if log := buf.String(); !strings.Contains(log, "level=DEBUG") || !strings.Contains(log, "op=Update") {
+		t.Fatalf("%s failed: unexpected logs %#v", name, log)
+	}
+}
+
+func TestTimingInterceptor(t *testing.T) {
+	name := "TestTimingInterceptor"
+	var ops []DaoOp
+	dao := Chain(&memDao{bos: map[string]*UniversalBo{}}, TimingInterceptor(func(ctx context.Context, call *DaoCall, d time.Duration, err error) {
+		if d < 0 {
+			t.Fatalf("%s failed: invalid duration %s", name, d)
+		}
+		ops = append(ops, call.Op)
+	}))
+	dao.Create(NewUniversalBo("id", 1))
+	dao.GetAll(nil, nil)
+	if expected := []DaoOp{OpCreate, OpGetAll}; !reflect.DeepEqual(ops, expected) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, expected, ops)
+	}
+}
@@ -0,0 +1,95 @@
+package henge
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// JsonCodec encodes/decodes JSON. It is used to parse and encode BO's data, as well as to encode/decode BOs to/from
+// JSON (e.g. MarshalJSON/UnmarshalJSON) and rows of storages that need it. A faster implementation (e.g. goccy/go-json,
+// sonic or a json/v2 implementation) can be plugged in to replace JsonCodecStd, the default one backed by encoding/json.
+//
+// Implementations must be compatible with encoding/json: values are decoded to the same types (map[string]interface{},
+// []interface{}, float64 or json.Number, string, bool and nil), and map keys are sorted when encoding so that encoded
+// data is deterministic. Checksums do not depend on the codec: data is re-encoded by encoding/json to calculate them.
+//
+// Available since v0.7.0
+type JsonCodec interface {
+	// Marshal encodes v to JSON.
+	Marshal(v interface{}) ([]byte, error)
+
+	// Unmarshal decodes JSON-encoded data to v.
+	Unmarshal(data []byte, v interface{}) error
+
+	// UnmarshalUseNumber is similar to Unmarshal, but numbers are decoded as json.Number instead of float64.
+	UnmarshalUseNumber(data []byte, v interface{}) error
+}
+
+type stdJsonCodec struct{}
+
+// Marshal implements JsonCodec.Marshal.
+func (stdJsonCodec) Marshal(v interface{}) ([]byte, error) { return json.Marshal(v) }
+
+// Unmarshal implements JsonCodec.Unmarshal.
+func (stdJsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+// UnmarshalUseNumber implements JsonCodec.UnmarshalUseNumber.
+func (stdJsonCodec) UnmarshalUseNumber(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if dec.More() {
+		return fmt.Errorf("invalid JSON data: unexpected data after top-level value")
+	}
+	return nil
+}
+
+var (
+	// JsonCodecStd is the JsonCodec backed by encoding/json.
+	//
+	// Available since v0.7.0
+	JsonCodecStd JsonCodec = stdJsonCodec{}
+
+	// DefaultJsonCodec is the JsonCodec used by BOs and DAOs that are not configured with their own (see UboOpt.JsonCodec
+	// and SetJsonCodec of UniversalDao implementations). It can be replaced at package level, e.g. at program start-up.
+	//
+	// Available since v0.7.0
+	DefaultJsonCodec = JsonCodecStd
+)
+
+func _extractJsonCodec(opts ...UboOpt) JsonCodec {
+	for _, opt := range opts {
+		if opt.JsonCodec != nil {
+			return opt.JsonCodec
+		}
+	}
+	return nil
+}
+
+// jsonCodecOrDefault returns the codec if not nil, DefaultJsonCodec otherwise (JsonCodecStd if DefaultJsonCodec is nil).
+func jsonCodecOrDefault(codec JsonCodec) JsonCodec {
+	if codec != nil {
+		return codec
+	}
+	if DefaultJsonCodec != nil {
+		return DefaultJsonCodec
+	}
+	return JsonCodecStd
+}
+
+// _json returns the JsonCodec used by the BO.
+func (ubo *UniversalBo) _json() JsonCodec {
+	return jsonCodecOrDefault(ubo._jsonCodec)
+}
+
+// GetJsonCodec returns the JsonCodec used by this BO (nil means DefaultJsonCodec).
+//
+// Available since v0.7.0
+func (ubo *UniversalBo) GetJsonCodec() JsonCodec {
+	ubo._lock.RLock()
+	defer ubo._lock.RUnlock()
+	return ubo._jsonCodec
+}
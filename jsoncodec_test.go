@@ -0,0 +1,161 @@
+package henge
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingJsonCodec is a JsonCodec backed by encoding/json that counts its calls.
+type countingJsonCodec struct {
+	marshal, unmarshal int64
+}
+
+func (c *countingJsonCodec) Marshal(v interface{}) ([]byte, error) {
+	atomic.AddInt64(&c.marshal, 1)
+	return JsonCodecStd.Marshal(v)
+}
+
+func (c *countingJsonCodec) Unmarshal(data []byte, v interface{}) error {
+	atomic.AddInt64(&c.unmarshal, 1)
+	return JsonCodecStd.Unmarshal(data, v)
+}
+
+func (c *countingJsonCodec) UnmarshalUseNumber(data []byte, v interface{}) error {
+	atomic.AddInt64(&c.unmarshal, 1)
+	return JsonCodecStd.UnmarshalUseNumber(data, v)
+}
+
+// prettyJsonCodec is a JsonCodec backed by encoding/json that encodes in indented form without escaping HTML characters.
+type prettyJsonCodec struct{}
+
+func (prettyJsonCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(v)
+	return bytes.TrimSpace(buf.Bytes()), err
+}
+
+func (prettyJsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return JsonCodecStd.Unmarshal(data, v)
+}
+
+func (prettyJsonCodec) UnmarshalUseNumber(data []byte, v interface{}) error {
+	return JsonCodecStd.UnmarshalUseNumber(data, v)
+}
+
+func TestJsonCodecStd(t *testing.T) {
+	name := "TestJsonCodecStd"
+	js, err := JsonCodecStd.Marshal(map[string]interface{}{"b": 1, "a": []interface{}{"x", true}})
+	if err != nil || string(js) != `{"a":["x",true],"b":1}` {
+		t.Fatalf("%s failed: %s / %s", name, js, err)
+	}
+	var v interface{}
+	if err := JsonCodecStd.UnmarshalUseNumber([]byte(`{"n":9007199254740993}`), &v); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if expected := map[string]interface{}{"n": json.Number("9007199254740993")}; !reflect.DeepEqual(v, expected) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, expected, v)
+	}
+	if err := JsonCodecStd.UnmarshalUseNumber([]byte(`1 2`), &v); err == nil {
+		t.Fatalf("%s failed: trailing data should result in error", name)
+	}
+}
+
+func TestUboOpt_JsonCodec(t *testing.T) {
+	name := "TestUboOpt_JsonCodec"
+	for _, alg := range []ChecksumAlgorithm{ChecksumMd5, ChecksumSha256} {
+		codec := &countingJsonCodec{}
+		ubo := NewUniversalBo("id", 1, UboOpt{JsonCodec: codec, ChecksumAlgorithm: alg, TimestampRounding: TimestampRoundingSettingSecond})
+		ubo.SetDataAttr("a.b", 1)
+		ubo.SetExtraAttr("owner", "me")
+		ubo.Sync()
+		if ubo.GetJsonCodec() != codec || atomic.LoadInt64(&codec.marshal) == 0 {
+			t.Fatalf("%s failed: BO's data should be encoded with the configured codec (%s)", name, alg)
+		}
+		expected := NewUniversalBo("id", 1, UboOpt{ChecksumAlgorithm: alg, TimestampRounding: TimestampRoundingSettingSecond})
+		expected.SetDataAttr("a.b", 1)
+		expected.SetExtraAttr("owner", "me")
+		expected.timeCreated = ubo.timeCreated
+		expected.Sync()
+		if ubo.GetChecksum() != expected.GetChecksum() || ubo.GetDataJson() != expected.GetDataJson() {
+			t.Fatalf("%s failed: expected %#v but received %#v (%s)", name, expected.GetChecksum(), ubo.GetChecksum(), alg)
+		}
+
+		codec = &countingJsonCodec{}
+		bo := NewUniversalBoFromGbo(ubo.ToGenericBo(), UboOpt{JsonCodec: codec})
+		if bo.IsChecksumMismatched() || bo.Clone().GetJsonCodec() != codec {
+			t.Fatalf("%s failed: loaded BO should not be flagged (%s)", name, alg)
+		}
+		unmarshal := atomic.LoadInt64(&codec.unmarshal)
+		if v := bo.GetDataAttrUnsafe("a.b"); v != 1.0 || atomic.LoadInt64(&codec.unmarshal) <= unmarshal {
+			t.Fatalf("%s failed: BO's data should be parsed with the configured codec (%s)", name, alg)
+		}
+	}
+}
+
+func TestDefaultJsonCodec(t *testing.T) {
+	name := "TestDefaultJsonCodec"
+	codec := &countingJsonCodec{}
+	DefaultJsonCodec = codec
+	defer func() { DefaultJsonCodec = JsonCodecStd }()
+	ubo := NewUniversalBo("id", 1)
+	ubo.SetDataAttr("a", "x")
+	js, err := json.Marshal(ubo)
+	if err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if ubo.GetJsonCodec() != nil || atomic.LoadInt64(&codec.marshal) == 0 {
+		t.Fatalf("%s failed: DefaultJsonCodec should be used by BOs having no codec of their own", name)
+	}
+	unmarshal := atomic.LoadInt64(&codec.unmarshal)
+	if _, err := (UboJsonDecoder{}).Unmarshal(js); err != nil || atomic.LoadInt64(&codec.unmarshal) <= unmarshal {
+		t.Fatalf("%s failed: DefaultJsonCodec should be used to decode BOs (%s)", name, err)
+	}
+
+	DefaultJsonCodec = nil
+	if jsonCodecOrDefault(nil) != JsonCodecStd {
+		t.Fatalf("%s failed: JsonCodecStd should be used if DefaultJsonCodec is nil", name)
+	}
+}
+
+func TestUboOpt_JsonCodec_checksum(t *testing.T) {
+	name := "TestUboOpt_JsonCodec_checksum"
+	clock := NewFixedClock(time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC))
+	for alg := range checksumHashFuncs {
+		var expected string
+		for _, codec := range []JsonCodec{JsonCodecStd, prettyJsonCodec{}} {
+			ubo := NewUniversalBo("id", 1, UboOpt{ChecksumAlgorithm: alg, JsonCodec: codec, Clock: clock})
+			ubo.SetDataAttr("a.b", "<x&y>")
+			ubo.SetDataAttr("a.c", []interface{}{1, 2.5})
+			csum := ubo.Sync().GetChecksum()
+			if expected == "" {
+				expected = csum
+			} else if csum != expected {
+				t.Fatalf("%s failed [%s]: expected %#v but received %#v", name, alg, expected, csum)
+			}
+			if bo := NewUniversalBoFromGbo(ubo.ToGenericBo(), UboOpt{JsonCodec: codec}); bo.IsChecksumMismatched() || bo.GetChecksum() != expected {
+				t.Fatalf("%s failed [%s]: expected %#v but received %#v", name, alg, expected, bo.GetChecksum())
+			}
+		}
+	}
+}
+
+func TestBoCodec_JsonCodec(t *testing.T) {
+	name := "TestBoCodec_JsonCodec"
+	codec := &countingJsonCodec{}
+	ubo := NewUniversalBo("id", 1)
+	ubo.SetDataAttr("a", "x")
+	bo, err := boCodec{jsonCodec: codec}.toUniversalBo(ubo.ToGenericBo())
+	if err != nil || bo.GetJsonCodec() != codec {
+		t.Fatalf("%s failed: BOs loaded by DAO should use the DAO's codec (%s)", name, err)
+	}
+	if dao := (&UniversalDaoSql{}).SetJsonCodec(codec); dao.GetJsonCodec() != codec || dao.boCodec().jsonCodec != codec {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, codec, dao.GetJsonCodec())
+	}
+}
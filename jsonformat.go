@@ -0,0 +1,267 @@
+package henge
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/btnguyen2k/consu/reddo"
+	"github.com/btnguyen2k/godal"
+)
+
+// uboSnapshot is a point-in-time copy of bo's top-level attributes, as they would be after syncing the BO.
+type uboSnapshot struct {
+	id          string
+	dataJson    string
+	tagVersion  uint64
+	checksum    string
+	timeCreated time.Time
+	timeUpdated time.Time
+	createdBy   string
+	updatedBy   string
+	extras      map[string]interface{}
+}
+
+// _snapshot takes a snapshot of bo's top-level attributes without modifying the BO: pending changes are synced to the
+// snapshot only (and the last-updated timestamp is bumped if checksum changes), the same way Sync would do.
+//
+// Caller is responsible for locking the BO.
+func (ubo *UniversalBo) _snapshot() uboSnapshot {
+	s := uboSnapshot{
+		id:          ubo.id,
+		dataJson:    ubo.dataJson,
+		tagVersion:  ubo.tagVersion,
+		checksum:    ubo.checksum,
+		timeCreated: ubo.timeCreated,
+		timeUpdated: ubo.timeUpdated,
+		createdBy:   ubo.createdBy,
+		updatedBy:   ubo.updatedBy,
+		extras:      cloneMap(ubo._extraAttrs),
+	}
+	if ubo._dirty {
+		js, _ := ubo._currentDataJson()
+		csumJs := js
+		if string(js) == ubo.dataJson {
+			csumJs = ubo._checksumInput(ubo._checksumAlgorithm)
+		}
+		s.dataJson = string(js)
+		s.checksum = ubo._calcChecksum(ubo._checksumAlgorithm, csumJs)
+		if s.checksum != ubo.checksum {
+			s.timeUpdated = ubo.RoundTimestamp(clockOrDefault(ubo._clock).Now())
+		}
+	}
+	if s.extras == nil {
+		s.extras = make(map[string]interface{})
+	}
+	return s
+}
+
+// UboJsonEncoder exports UniversalBo to JSON with a configurable representation, e.g. for API responses.
+// The zero value produces the same output as UniversalBo.MarshalJSON.
+//
+// The BO is not modified: pending changes are exported as if the BO had been synced.
+//
+// Available since v0.7.0
+type UboJsonEncoder struct {
+	InlineData         bool   // if true, data is emitted as a nested JSON value instead of a JSON-encoded string
+	FlattenExtras      bool   // if true, extra attributes are emitted at the top level instead of under FieldExtras (those clashing with top level fields are dropped)
+	TimeLayout         string // layout used to format timestamps, DefaultTimeLayout if empty
+	EpochMillis        bool   // if true, timestamps are emitted as milliseconds since Unix epoch and TimeLayout is ignored
+	OmitInternalFields bool   // if true, FieldChecksum and FieldTagVersion are omitted
+}
+
+func (e UboJsonEncoder) formatTime(t time.Time) interface{} {
+	if e.EpochMillis {
+		return t.UnixMilli()
+	}
+	if e.TimeLayout != "" {
+		return t.Format(e.TimeLayout)
+	}
+	return t.Format(DefaultTimeLayout)
+}
+
+// ToMap exports the BO to a map, ready to be encoded to JSON.
+func (e UboJsonEncoder) ToMap(ubo *UniversalBo) map[string]interface{} {
+	if ubo == nil {
+		return nil
+	}
+	ubo._lock.RLock()
+	s := ubo._snapshot()
+	ubo._lock.RUnlock()
+	m := map[string]interface{}{
+		FieldId:          s.id,
+		FieldData:        s.dataJson,
+		FieldTagVersion:  s.tagVersion,
+		FieldChecksum:    s.checksum,
+		FieldTimeCreated: e.formatTime(s.timeCreated),
+		FieldTimeUpdated: e.formatTime(s.timeUpdated),
+	}
+	if s.createdBy != "" {
+		m[FieldCreatedBy] = s.createdBy
+	}
+	if s.updatedBy != "" {
+		m[FieldUpdatedBy] = s.updatedBy
+	}
+	if e.InlineData {
+		m[FieldData] = json.RawMessage(s.dataJson)
+	}
+	if e.OmitInternalFields {
+		delete(m, FieldChecksum)
+		delete(m, FieldTagVersion)
+	}
+	if !e.FlattenExtras {
+		m[FieldExtras] = s.extras
+		return m
+	}
+	for k, v := range s.extras {
+		if _, ok := m[k]; !ok && !containsString(topLevelFieldList, k) {
+			m[k] = v
+		}
+	}
+	return m
+}
+
+// Marshal exports the BO to JSON.
+func (e UboJsonEncoder) Marshal(ubo *UniversalBo) ([]byte, error) {
+	if ubo == nil {
+		return []byte("null"), nil
+	}
+	return ubo._json().Marshal(e.ToMap(ubo))
+}
+
+// defaultJsonTimeLayouts are the layouts UboJsonDecoder tries to parse timestamps with.
+var defaultJsonTimeLayouts = []string{time.RFC3339Nano, time.RFC3339, "2006-01-02 15:04:05Z07:00", "2006-01-02T15:04:05", "2006-01-02 15:04:05"}
+
+// parseJsonTime parses a timestamp, either a string in one of the specified layouts (or defaultJsonTimeLayouts), or a
+// number of milliseconds since Unix epoch. nil results in zero time.
+func parseJsonTime(v interface{}, layouts []string) (time.Time, error) {
+	switch t := v.(type) {
+	case nil:
+		return time.Time{}, nil
+	case time.Time:
+		return t, nil
+	case json.Number:
+		ms, err := t.Int64()
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp [%s]: %s", t, err)
+		}
+		return time.UnixMilli(ms).UTC(), nil
+	case float64:
+		return time.UnixMilli(int64(t)).UTC(), nil
+	case string:
+		for _, layouts := range [][]string{layouts, defaultJsonTimeLayouts} {
+			for _, layout := range layouts {
+				if result, err := time.Parse(layout, t); err == nil {
+					return result, nil
+				}
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp [%v]", v)
+}
+
+// decodeJsonDataField returns the JSON-encoded form of the data field, which is either a JSON-encoded string or a
+// nested JSON value.
+func decodeJsonDataField(codec JsonCodec, v interface{}) (string, error) {
+	switch d := v.(type) {
+	case nil:
+		return "null", nil
+	case string:
+		return d, nil
+	}
+	js, err := codec.Marshal(v)
+	return string(js), err
+}
+
+// decodeJsonObject decodes a JSON object, numbers are decoded as json.Number.
+func decodeJsonObject(codec JsonCodec, data []byte) (map[string]interface{}, error) {
+	var m map[string]interface{}
+	if err := codec.UnmarshalUseNumber(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// UboJsonDecoder constructs UniversalBo from JSON produced by UboJsonEncoder or UniversalBo.MarshalJSON.
+//   - Data is accepted both as a JSON-encoded string and as a nested JSON value.
+//   - Timestamps are accepted as strings in one of TimeLayouts, RFC3339Nano, RFC3339 or "2006-01-02 15:04:05Z07:00"
+//     (among others), or as numbers of milliseconds since Unix epoch.
+//   - Extra attributes are read from FieldExtras as well as from unknown top-level fields.
+//   - BO's checksum is recalculated; the one in the input, if any, is verified (see UniversalBo.IsChecksumMismatched).
+//
+// Available since v0.7.0
+type UboJsonDecoder struct {
+	TimeLayouts []string // additional layouts to parse timestamps, tried first
+	UboOpts     []UboOpt // options used to create BOs
+}
+
+// Unmarshal constructs a BO from JSON.
+func (d UboJsonDecoder) Unmarshal(data []byte) (*UniversalBo, error) {
+	codec := jsonCodecOrDefault(_extractJsonCodec(d.UboOpts...))
+	m, err := decodeJsonObject(codec, data)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return nil, errors.New("input is not a JSON object")
+	}
+	gbo := godal.NewGenericBo()
+	for k, v := range m {
+		if k != FieldExtras && !containsString(topLevelFieldList, k) {
+			gbo.GboSetAttr(k, v)
+		}
+	}
+	if extras, ok := m[FieldExtras].(map[string]interface{}); ok {
+		for k, v := range extras {
+			gbo.GboSetAttr(k, v)
+		}
+	}
+	id, err := reddo.ToString(m[FieldId])
+	if err != nil {
+		return nil, err
+	}
+	gbo.GboSetAttr(FieldId, id)
+	dataJson, err := decodeJsonDataField(codec, m[FieldData])
+	if err != nil {
+		return nil, err
+	}
+	gbo.GboSetAttr(FieldData, dataJson)
+	tagVersion := uint64(0)
+	if m[FieldTagVersion] != nil {
+		if tagVersion, err = reddo.ToUint(m[FieldTagVersion]); err != nil {
+			return nil, err
+		}
+	}
+	gbo.GboSetAttr(FieldTagVersion, tagVersion)
+	csum, err := reddo.ToString(m[FieldChecksum])
+	if err != nil {
+		return nil, err
+	}
+	gbo.GboSetAttr(FieldChecksum, csum)
+	for _, field := range []string{FieldTimeCreated, FieldTimeUpdated} {
+		t, err := parseJsonTime(m[field], d.TimeLayouts)
+		if err != nil {
+			return nil, err
+		}
+		gbo.GboSetAttr(field, t)
+	}
+	for _, field := range []string{FieldCreatedBy, FieldUpdatedBy} {
+		if m[field] != nil {
+			principal, err := reddo.ToString(m[field])
+			if err != nil {
+				return nil, err
+			}
+			gbo.GboSetAttr(field, principal)
+		}
+	}
+	bo := NewUniversalBoFromGbo(gbo, d.UboOpts...)
+	if bo == nil {
+		return nil, errors.New("invalid data: " + dataJson)
+	}
+	if csum == "" {
+		// no checksum to verify against
+		bo._checksumMismatch = false
+	}
+	return bo, nil
+}
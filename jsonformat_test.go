@@ -0,0 +1,114 @@
+package henge
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestUniversalBo_MarshalJSON_NoMutation(t *testing.T) {
+	name := "TestUniversalBo_MarshalJSON_NoMutation"
+	clock := NewFixedClock(time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC))
+	ubo := NewUniversalBo("id", 1, UboOpt{Clock: clock})
+	csum := ubo.GetChecksum()
+	clock.Advance(time.Hour)
+	ubo.SetDataAttr("a", 1)
+	js, err := json.Marshal(ubo)
+	if err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if !ubo._dirty || ubo.checksum != csum || ubo.dataJson != "null" {
+		t.Fatalf("%s failed: BO should not be modified", name)
+	}
+	var m map[string]interface{}
+	json.Unmarshal(js, &m)
+	ubo.Sync(UboSyncOpts{UpdateTimestampIfChecksumChange: true})
+	expected := map[string]interface{}{
+		FieldId: "id", FieldData: `{"a":1}`, FieldTagVersion: 1.0, FieldChecksum: ubo.GetChecksum(),
+		FieldTimeCreated: "2021-01-02T03:04:05Z", FieldTimeUpdated: "2021-01-02T04:04:05Z", FieldExtras: map[string]interface{}{},
+	}
+	if !reflect.DeepEqual(m, expected) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, expected, m)
+	}
+}
+
+func TestUboJsonEncoder(t *testing.T) {
+	name := "TestUboJsonEncoder"
+	now := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	ubo := NewUniversalBo("id", 1, UboOpt{Clock: NewFixedClock(now)})
+	ubo.SetDataJson(`{"a":{"b":1}}`)
+	ubo.SetExtraAttr("owner", "me")
+	ubo.SetExtraAttr(FieldId, "clash")
+	testCases := []struct {
+		encoder  UboJsonEncoder
+		expected string
+	}{
+		{
+			UboJsonEncoder{InlineData: true, FlattenExtras: true, EpochMillis: true, OmitInternalFields: true},
+			`{"data":{"a":{"b":1}},"id":"id","owner":"me","tcre":1609556645000,"tupd":1609556645000}`,
+		},
+		{
+			UboJsonEncoder{TimeLayout: "2006-01-02 15:04:05", OmitInternalFields: true},
+			`{"_ext":{"id":"clash","owner":"me"},"data":"{\"a\":{\"b\":1}}","id":"id","tcre":"2021-01-02 03:04:05","tupd":"2021-01-02 03:04:05"}`,
+		},
+	}
+	for i, tc := range testCases {
+		if js, err := tc.encoder.Marshal(ubo); err != nil || string(js) != tc.expected {
+			t.Fatalf("%s failed [%d]: expected %s but received %s (error: %s)", name, i, tc.expected, js, err)
+		}
+	}
+}
+
+func TestUboJsonDecoder(t *testing.T) {
+	name := "TestUboJsonDecoder"
+	now := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	ubo := NewUniversalBo("id", 1, UboOpt{Clock: NewFixedClock(now)})
+	ubo.SetDataJson(`{"a":{"b":1},"n":9007199254740993}`)
+	ubo.SetExtraAttr("owner", "me")
+	ubo.Sync()
+	decoder := UboJsonDecoder{UboOpts: []UboOpt{{UseNumber: true}}}
+	for i, encoder := range []UboJsonEncoder{{}, {InlineData: true, FlattenExtras: true, EpochMillis: true}} {
+		js, _ := encoder.Marshal(ubo)
+		bo, err := decoder.Unmarshal(js)
+		if err != nil || bo.GetId() != "id" || bo.GetTagVersion() != 1 || bo.GetDataJson() != ubo.GetDataJson() {
+			t.Fatalf("%s failed [%d]: expected %#v but received %#v (error: %s)", name, i, ubo, bo, err)
+		}
+		if !bo.GetTimeCreated().Equal(now) || !bo.GetTimeUpdated().Equal(now) || bo.GetExtraAttr("owner") != "me" {
+			t.Fatalf("%s failed [%d]: expected %#v but received %#v", name, i, ubo, bo)
+		}
+		if bo.IsChecksumMismatched() {
+			t.Fatalf("%s failed [%d]: checksum should match", name, i)
+		}
+	}
+
+	testCases := map[string]bool{
+		`{"id":"x","data":{"a":1},"tcre":"2021-01-02 03:04:05Z","tupd":"02/01/2021"}`:       false,
+		`{"id":"x","data":{"a":1},"tcre":1609556645000,"tupd":"2021-01-02T03:04:05+07:00"}`: false,
+		`{"id":"x","data":{"a":1},"csum":"0123456789abcdef0123456789abcdef"}`:               true,
+	}
+	decoder = UboJsonDecoder{TimeLayouts: []string{"02/01/2006"}}
+	for input, mismatched := range testCases {
+		bo, err := decoder.Unmarshal([]byte(input))
+		if err != nil || bo.GetDataJson() != `{"a":1}` || bo.IsChecksumMismatched() != mismatched {
+			t.Fatalf("%s failed for %s: received %#v (error: %s)", name, input, bo, err)
+		}
+	}
+	for _, input := range []string{`[]`, `{"tcre":"yesterday"}`, `{"data":"{"}`} {
+		if bo, err := decoder.Unmarshal([]byte(input)); err == nil {
+			t.Fatalf("%s failed: expected error for %s but received %#v", name, input, bo)
+		}
+	}
+}
+
+func TestUniversalBo_UnmarshalJSON_InlineData(t *testing.T) {
+	name := "TestUniversalBo_UnmarshalJSON_InlineData"
+	ubo := &UniversalBo{}
+	if err := json.Unmarshal([]byte(`{"id":"x","data":{"a":1},"tver":2,"tcre":1609556645000,"tupd":"2021-01-02 03:04:05Z"}`), ubo); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	now := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	if ubo.GetDataJson() != `{"a":1}` || ubo.GetTagVersion() != 2 || !ubo.GetTimeCreated().Equal(now) {
+		t.Fatalf("%s failed: received %#v", name, ubo)
+	}
+}
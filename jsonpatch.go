@@ -0,0 +1,465 @@
+package henge
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parseJsonPointer splits a JSON Pointer (RFC 6901) into unescaped reference tokens.
+//
+// The empty pointer "" references the whole document and results in an empty token list.
+func parseJsonPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer [%s]", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// toJsonPointer builds a JSON Pointer (RFC 6901) from a list of reference tokens.
+func toJsonPointer(tokens []string) string {
+	var sb strings.Builder
+	for _, token := range tokens {
+		sb.WriteString("/")
+		sb.WriteString(strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1"))
+	}
+	return sb.String()
+}
+
+// parseJsonArrayIndex parses a reference token as index of an array of length n.
+//   - if allowEnd is true, "-" and n are accepted and point to the position after the last element.
+func parseJsonArrayIndex(token string, n int, allowEnd bool) (int, error) {
+	if token == "-" && allowEnd {
+		return n, nil
+	}
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return -1, fmt.Errorf("invalid array index [%s]", token)
+	}
+	for _, c := range token {
+		if c < '0' || c > '9' {
+			return -1, fmt.Errorf("invalid array index [%s]", token)
+		}
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index > n || (index == n && !allowEnd) {
+		return -1, fmt.Errorf("array index [%s] out of bound", token)
+	}
+	return index, nil
+}
+
+// cloneJsonValue deep-clones a value of a JSON data tree.
+func cloneJsonValue(v interface{}) interface{} {
+	switch v.(type) {
+	case map[string]interface{}:
+		return cloneMap(v.(map[string]interface{}))
+	case []interface{}:
+		return cloneSlice(v.([]interface{}))
+	}
+	return v
+}
+
+// jsonTreeGet returns the value located at the path specified by tokens.
+func jsonTreeGet(node interface{}, tokens []string) (interface{}, error) {
+	for i, token := range tokens {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			v, ok := n[token]
+			if !ok {
+				return nil, fmt.Errorf("path [%s] not found", toJsonPointer(tokens[:i+1]))
+			}
+			node = v
+		case []interface{}:
+			index, err := parseJsonArrayIndex(token, len(n), false)
+			if err != nil {
+				return nil, fmt.Errorf("path [%s] not found: %s", toJsonPointer(tokens[:i+1]), err)
+			}
+			node = n[index]
+		default:
+			return nil, fmt.Errorf("path [%s] not found", toJsonPointer(tokens[:i+1]))
+		}
+	}
+	return node, nil
+}
+
+// jsonTreeContainerFunc modifies the container (map or slice) at "key" and returns the modified container.
+type jsonTreeContainerFunc func(container interface{}, key string) (interface{}, error)
+
+// jsonTreeModify walks down the tree to the parent of the location specified by tokens and applies f on it.
+//
+// Since slices may be re-allocated, the (possibly new) root of the tree is returned.
+func jsonTreeModify(node interface{}, tokens []string, f jsonTreeContainerFunc) (interface{}, error) {
+	if len(tokens) == 1 {
+		return f(node, tokens[0])
+	}
+	switch n := node.(type) {
+	case map[string]interface{}:
+		child, ok := n[tokens[0]]
+		if !ok {
+			return nil, fmt.Errorf("path element [%s] not found", tokens[0])
+		}
+		child, err := jsonTreeModify(child, tokens[1:], f)
+		if err != nil {
+			return nil, err
+		}
+		n[tokens[0]] = child
+		return n, nil
+	case []interface{}:
+		index, err := parseJsonArrayIndex(tokens[0], len(n), false)
+		if err != nil {
+			return nil, err
+		}
+		child, err := jsonTreeModify(n[index], tokens[1:], f)
+		if err != nil {
+			return nil, err
+		}
+		n[index] = child
+		return n, nil
+	}
+	return nil, fmt.Errorf("path element [%s] not found", tokens[0])
+}
+
+// jsonTreeAdd implements the "add" operation of RFC 6902 and returns the new root of the tree.
+func jsonTreeAdd(root interface{}, tokens []string, value interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	return jsonTreeModify(root, tokens, func(container interface{}, key string) (interface{}, error) {
+		switch c := container.(type) {
+		case map[string]interface{}:
+			c[key] = value
+			return c, nil
+		case []interface{}:
+			index, err := parseJsonArrayIndex(key, len(c), true)
+			if err != nil {
+				return nil, err
+			}
+			c = append(c, nil)
+			copy(c[index+1:], c[index:])
+			c[index] = value
+			return c, nil
+		}
+		return nil, fmt.Errorf("cannot add value at [%s]: parent is neither object nor array", key)
+	})
+}
+
+// jsonTreeRemove implements the "remove" operation of RFC 6902 and returns the new root of the tree.
+func jsonTreeRemove(root interface{}, tokens []string) (interface{}, error) {
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+	return jsonTreeModify(root, tokens, func(container interface{}, key string) (interface{}, error) {
+		switch c := container.(type) {
+		case map[string]interface{}:
+			if _, ok := c[key]; !ok {
+				return nil, fmt.Errorf("path element [%s] not found", key)
+			}
+			delete(c, key)
+			return c, nil
+		case []interface{}:
+			index, err := parseJsonArrayIndex(key, len(c), false)
+			if err != nil {
+				return nil, err
+			}
+			return append(c[:index], c[index+1:]...), nil
+		}
+		return nil, fmt.Errorf("path element [%s] not found", key)
+	})
+}
+
+// jsonTreeReplace implements the "replace" operation of RFC 6902 and returns the new root of the tree.
+func jsonTreeReplace(root interface{}, tokens []string, value interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	return jsonTreeModify(root, tokens, func(container interface{}, key string) (interface{}, error) {
+		switch c := container.(type) {
+		case map[string]interface{}:
+			if _, ok := c[key]; !ok {
+				return nil, fmt.Errorf("path element [%s] not found", key)
+			}
+			c[key] = value
+			return c, nil
+		case []interface{}:
+			index, err := parseJsonArrayIndex(key, len(c), false)
+			if err != nil {
+				return nil, err
+			}
+			c[index] = value
+			return c, nil
+		}
+		return nil, fmt.Errorf("path element [%s] not found", key)
+	})
+}
+
+/*----------------------------------------------------------------------*/
+
+// jsonPatchOperation is an operation of a JSON Patch document (RFC 6902).
+type jsonPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+func (op jsonPatchOperation) value(useNumber bool) (interface{}, error) {
+	if len(op.Value) == 0 {
+		return nil, fmt.Errorf("missing \"value\" for operation \"%s\"", op.Op)
+	}
+	var v interface{}
+	err := unmarshalJsonData(nil, op.Value, useNumber, &v)
+	return v, err
+}
+
+func (op jsonPatchOperation) from() ([]string, error) {
+	if op.From == nil {
+		return nil, fmt.Errorf("missing \"from\" for operation \"%s\"", op.Op)
+	}
+	return parseJsonPointer(*op.From)
+}
+
+// apply applies the operation to the document and returns the result; numbers of the operation's value are decoded as
+// json.Number if useNumber is true.
+func (op jsonPatchOperation) apply(doc interface{}, useNumber bool) (interface{}, error) {
+	if op.Path == nil {
+		return nil, fmt.Errorf("missing \"path\" for operation \"%s\"", op.Op)
+	}
+	path, err := parseJsonPointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch op.Op {
+	case "add":
+		v, err := op.value(useNumber)
+		if err != nil {
+			return nil, err
+		}
+		return jsonTreeAdd(doc, path, v)
+	case "remove":
+		return jsonTreeRemove(doc, path)
+	case "replace":
+		v, err := op.value(useNumber)
+		if err != nil {
+			return nil, err
+		}
+		return jsonTreeReplace(doc, path, v)
+	case "move":
+		from, err := op.from()
+		if err != nil {
+			return nil, err
+		}
+		if *op.From == *op.Path {
+			return doc, nil
+		}
+		if strings.HasPrefix(*op.Path, *op.From+"/") {
+			return nil, fmt.Errorf("cannot move [%s] to its child [%s]", *op.From, *op.Path)
+		}
+		v, err := jsonTreeGet(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if doc, err = jsonTreeRemove(doc, from); err != nil {
+			return nil, err
+		}
+		return jsonTreeAdd(doc, path, v)
+	case "copy":
+		from, err := op.from()
+		if err != nil {
+			return nil, err
+		}
+		v, err := jsonTreeGet(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return jsonTreeAdd(doc, path, cloneJsonValue(v))
+	case "test":
+		expected, err := op.value(useNumber)
+		if err != nil {
+			return nil, err
+		}
+		v, err := jsonTreeGet(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !jsonValuesEqual(expected, v) {
+			return nil, fmt.Errorf("test failed: value at [%s] is %s", *op.Path, string(op.Value))
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("invalid operation \"%s\"", op.Op)
+}
+
+// ApplyJsonPatch applies a JSON Patch document (RFC 6902) to bo's data.
+//
+// The operations are applied atomically: if any of them fails, an error is returned and bo's data is left unchanged.
+//
+// Available since v0.7.0
+func (ubo *UniversalBo) ApplyJsonPatch(ops []byte) error {
+	var patch []jsonPatchOperation
+	if err := json.Unmarshal(ops, &patch); err != nil {
+		return fmt.Errorf("invalid JSON patch: %s", err)
+	}
+	ubo._lock.Lock()
+	defer ubo._lock.Unlock()
+	doc, err := ubo._cloneData()
+	if err != nil {
+		return err
+	}
+	for i, op := range patch {
+		if doc, err = op.apply(doc, ubo._useNumber); err != nil {
+			return fmt.Errorf("JSON patch operation #%d failed: %s", i, err)
+		}
+	}
+	return ubo._setData(doc)
+}
+
+// jsonMergePatch applies a JSON Merge Patch (RFC 7386) to target and returns the result.
+func jsonMergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = make(map[string]interface{})
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+		} else {
+			t[k] = jsonMergePatch(t[k], v)
+		}
+	}
+	return t
+}
+
+// ApplyMergePatch applies a JSON Merge Patch document (RFC 7386) to bo's data.
+//
+// If the patch document is not a valid JSON, an error is returned and bo's data is left unchanged.
+//
+// Available since v0.7.0
+func (ubo *UniversalBo) ApplyMergePatch(doc []byte) error {
+	var patch interface{}
+	if err := unmarshalJsonData(ubo._jsonCodec, doc, ubo._useNumber, &patch); err != nil {
+		return fmt.Errorf("invalid JSON merge patch: %s", err)
+	}
+	ubo._lock.Lock()
+	defer ubo._lock.Unlock()
+	data, err := ubo._cloneData()
+	if err != nil {
+		return err
+	}
+	return ubo._setData(jsonMergePatch(data, patch))
+}
+
+/*----------------------------------------------------------------------*/
+
+const (
+	jsonDiffOpAdd     = "add"
+	jsonDiffOpRemove  = "remove"
+	jsonDiffOpReplace = "replace"
+)
+
+// jsonDiffFunc receives differences found by diffJsonTrees.
+type jsonDiffFunc func(op string, tokens []string, oldValue, newValue interface{})
+
+// diffJsonTrees compares two JSON data trees and reports the differences, in an order that can be used as a JSON patch.
+//   - object members are compared key by key (in sorted order).
+//   - array elements are compared index by index; extra elements are reported as removed (from the last one)
+//     or added (from the first one).
+//   - otherwise, different values are reported as replaced.
+func diffJsonTrees(tokens []string, a, b interface{}, f jsonDiffFunc) {
+	childTokens := func(token string) []string {
+		return append(append(make([]string, 0, len(tokens)+1), tokens...), token)
+	}
+	switch va := a.(type) {
+	case map[string]interface{}:
+		if vb, ok := b.(map[string]interface{}); ok {
+			keys := make([]string, 0, len(va)+len(vb))
+			for k := range va {
+				keys = append(keys, k)
+			}
+			for k := range vb {
+				if _, ok := va[k]; !ok {
+					keys = append(keys, k)
+				}
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				vak, okA := va[k]
+				vbk, okB := vb[k]
+				if !okB {
+					f(jsonDiffOpRemove, childTokens(k), vak, nil)
+				} else if !okA {
+					f(jsonDiffOpAdd, childTokens(k), nil, vbk)
+				} else {
+					diffJsonTrees(childTokens(k), vak, vbk, f)
+				}
+			}
+			return
+		}
+	case []interface{}:
+		if vb, ok := b.([]interface{}); ok {
+			n := len(va)
+			if len(vb) < n {
+				n = len(vb)
+			}
+			for i := 0; i < n; i++ {
+				diffJsonTrees(childTokens(strconv.Itoa(i)), va[i], vb[i], f)
+			}
+			for i := len(va) - 1; i >= n; i-- {
+				f(jsonDiffOpRemove, childTokens(strconv.Itoa(i)), va[i], nil)
+			}
+			for i := n; i < len(vb); i++ {
+				f(jsonDiffOpAdd, childTokens(strconv.Itoa(i)), nil, vb[i])
+			}
+			return
+		}
+	}
+	if !jsonValuesEqual(a, b) {
+		f(jsonDiffOpReplace, tokens, a, b)
+	}
+}
+
+// DiffJsonPatch generates a JSON Patch document (RFC 6902) that transforms data of BO a to data of BO b.
+//
+// The result can be applied to a (or a copy of a) via UniversalBo.ApplyJsonPatch. Nil BO is treated as having no data.
+//
+// Available since v0.7.0
+func DiffJsonPatch(a, b *UniversalBo) ([]byte, error) {
+	dataA, err := a._lockAndCloneData()
+	if err != nil {
+		return nil, err
+	}
+	dataB, err := b._lockAndCloneData()
+	if err != nil {
+		return nil, err
+	}
+	patch := make([]map[string]interface{}, 0)
+	diffJsonTrees([]string{}, dataA, dataB, func(op string, tokens []string, _, newValue interface{}) {
+		item := map[string]interface{}{"op": op, "path": toJsonPointer(tokens)}
+		if op != jsonDiffOpRemove {
+			item["value"] = newValue
+		}
+		patch = append(patch, item)
+	})
+	return json.Marshal(patch)
+}
+
+// _lockAndCloneData is similar to _cloneData but takes care of locking; nil BO results in nil data.
+func (ubo *UniversalBo) _lockAndCloneData() (interface{}, error) {
+	if ubo == nil {
+		return nil, nil
+	}
+	ubo._lock.RLock()
+	defer ubo._lock.RUnlock()
+	return ubo._cloneData()
+}
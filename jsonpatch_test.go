@@ -0,0 +1,176 @@
+package henge
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func Test_parseJsonPointer(t *testing.T) {
+	name := "Test_parseJsonPointer"
+	testCases := map[string][]string{
+		"":        {},
+		"/":       {""},
+		"/a/b":    {"a", "b"},
+		"/a~1b/c": {"a/b", "c"},
+		"/m~0n":   {"m~n"},
+		"/~01":    {"~1"},
+	}
+	for pointer, expected := range testCases {
+		tokens, err := parseJsonPointer(pointer)
+		if err != nil || !reflect.DeepEqual(tokens, expected) {
+			t.Fatalf("%s failed: expected %#v but received %#v (error: %s)", name, expected, tokens, err)
+		}
+		if toJsonPointer(tokens) != pointer {
+			t.Fatalf("%s failed: expected %#v but received %#v", name, pointer, toJsonPointer(tokens))
+		}
+	}
+	if _, err := parseJsonPointer("a/b"); err == nil {
+		t.Fatalf("%s failed: expected error for pointer not starting with /", name)
+	}
+}
+
+func TestUniversalBo_ApplyJsonPatch(t *testing.T) {
+	name := "TestUniversalBo_ApplyJsonPatch"
+	testCases := []struct {
+		data, patch, expected string
+	}{
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":"qux"}]`, `{"foo":["bar","qux"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{`{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{`{"foo":{"bar":[1,2]}}`, `[{"op":"copy","from":"/foo/bar","path":"/baz"},{"op":"add","path":"/baz/-","value":3}]`, `{"baz":[1,2,3],"foo":{"bar":[1,2]}}`},
+		{`{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, `{"baz":"qux","foo":["a",2,"c"]}`},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"","value":[1,2]}]`, `[1,2]`},
+	}
+	for i, testCase := range testCases {
+		ubo := NewUniversalBo("id", 0)
+		ubo.SetDataJson(testCase.data)
+		if err := ubo.ApplyJsonPatch([]byte(testCase.patch)); err != nil {
+			t.Fatalf("%s failed: case #%d - %s", name, i, err)
+		}
+		if ubo.GetDataJson() != testCase.expected {
+			t.Fatalf("%s failed: case #%d - expected %#v but received %#v", name, i, testCase.expected, ubo.GetDataJson())
+		}
+	}
+
+	// "test" compares numbers by value
+	for _, useNumber := range []bool{false, true} {
+		ubo := NewUniversalBo("id", 0, UboOpt{UseNumber: useNumber})
+		ubo.SetDataJson(`{"a":1.0,"b":[2]}`)
+		if err := ubo.ApplyJsonPatch([]byte(`[{"op":"test","path":"/a","value":1},{"op":"test","path":"/b","value":[2.0]}]`)); err != nil {
+			t.Fatalf("%s failed: UseNumber %#v - %s", name, useNumber, err)
+		}
+		if err := ubo.ApplyJsonPatch([]byte(`[{"op":"test","path":"/a","value":1.5}]`)); err == nil {
+			t.Fatalf("%s failed: UseNumber %#v - expected test to fail", name, useNumber)
+		}
+	}
+}
+
+func TestUniversalBo_ApplyJsonPatch_atomic(t *testing.T) {
+	name := "TestUniversalBo_ApplyJsonPatch_atomic"
+	data := `{"foo":["bar"],"obj":{"a":1}}`
+	patches := []string{
+		`not a json`,
+		`[{"op":"add","path":"/baz","value":1},{"op":"test","path":"/foo/0","value":"qux"}]`,
+		`[{"op":"remove","path":"/obj/a"},{"op":"remove","path":"/notfound"}]`,
+		`[{"op":"replace","path":"/foo/0","value":1},{"op":"replace","path":"/foo/1","value":2}]`,
+		`[{"op":"add","path":"/foo/01","value":1}]`,
+		`[{"op":"move","from":"/obj","path":"/obj/b"}]`,
+		`[{"op":"copy","path":"/baz"}]`,
+		`[{"op":"add","path":"/baz"}]`,
+		`[{"op":"invalid","path":"/baz"}]`,
+	}
+	for i, patch := range patches {
+		ubo := NewUniversalBo("id", 0)
+		ubo.SetDataJson(data)
+		if err := ubo.ApplyJsonPatch([]byte(patch)); err == nil {
+			t.Fatalf("%s failed: case #%d - expected error", name, i)
+		}
+		if ubo.GetDataJson() != data {
+			t.Fatalf("%s failed: case #%d - expected %#v but received %#v", name, i, data, ubo.GetDataJson())
+		}
+	}
+}
+
+func TestUniversalBo_ApplyMergePatch(t *testing.T) {
+	name := "TestUniversalBo_ApplyMergePatch"
+	// examples from RFC 7386, Appendix A
+	testCases := []struct {
+		data, patch, expected string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for i, testCase := range testCases {
+		ubo := NewUniversalBo("id", 0)
+		ubo.SetDataJson(testCase.data)
+		if err := ubo.ApplyMergePatch([]byte(testCase.patch)); err != nil {
+			t.Fatalf("%s failed: case #%d - %s", name, i, err)
+		}
+		if ubo.GetDataJson() != testCase.expected {
+			t.Fatalf("%s failed: case #%d - expected %#v but received %#v", name, i, testCase.expected, ubo.GetDataJson())
+		}
+	}
+
+	ubo := NewUniversalBo("id", 0)
+	ubo.SetDataJson(`{"a":1}`)
+	if err := ubo.ApplyMergePatch([]byte(`{invalid`)); err == nil || ubo.GetDataJson() != `{"a":1}` {
+		t.Fatalf("%s failed: expected error and unchanged data but received %#v / %#v", name, err, ubo.GetDataJson())
+	}
+}
+
+func TestDiffJsonPatch(t *testing.T) {
+	name := "TestDiffJsonPatch"
+	testCases := []struct{ a, b string }{
+		{`{"a":1}`, `{"a":1}`},
+		{`{"a":1,"b":"x"}`, `{"a":2,"c":true}`},
+		{`{"arr":[1,2,3,4]}`, `{"arr":[1,5]}`},
+		{`{"arr":[1]}`, `{"arr":[1,{"x":"y"},[2,3]]}`},
+		{`{"a/b":{"m~n":1}}`, `{"a/b":{"m~n":2}}`},
+		{`{"a":{"b":[1,{"c":null}]}}`, `{"a":[{"b":1}]}`},
+		{`[1,2]`, `{"a":1}`},
+		{`null`, `{"a":1}`},
+	}
+	for i, testCase := range testCases {
+		a := NewUniversalBo("id", 0)
+		a.SetDataJson(testCase.a)
+		b := NewUniversalBo("id", 0)
+		b.SetDataJson(testCase.b)
+		patch, err := DiffJsonPatch(a, b)
+		if err != nil {
+			t.Fatalf("%s failed: case #%d - %s", name, i, err)
+		}
+		if err := a.ApplyJsonPatch(patch); err != nil {
+			t.Fatalf("%s failed: case #%d - %s", name, i, err)
+		}
+		if a.GetDataJson() != b.GetDataJson() {
+			t.Fatalf("%s failed: case #%d - expected %#v but received %#v", name, i, b.GetDataJson(), a.GetDataJson())
+		}
+	}
+
+	a := NewUniversalBo("id", 0)
+	a.SetDataJson(`{"a":1}`)
+	patch, _ := DiffJsonPatch(a, a.Clone())
+	var ops []interface{}
+	if json.Unmarshal(patch, &ops); len(ops) != 0 {
+		t.Fatalf("%s failed: expected empty patch but received %s", name, patch)
+	}
+}
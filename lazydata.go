@@ -0,0 +1,285 @@
+package henge
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+
+	"github.com/btnguyen2k/consu/semita"
+)
+
+/*
+Data of UniversalBo is handled lazily so that large documents are cheap to load, clone and export:
+  - data loaded from storage is kept in its JSON-encoded form (dataJson) and parsed only when a data attribute is first
+    accessed (see _ensureData);
+  - dataJson is re-encoded from the data tree only when the tree has been changed (_dataChanged);
+  - clones share dataJson and, if not modified since being parsed, the data tree; the tree is copied before being
+    modified (see _unshareData), and objects/arrays handed out to callers are copied while it is shared
+    (see _detachValue);
+  - a stored MD5 checksum, which can only be verified against parsed data, is trusted when loaded and verified when
+    the checksum or the mismatch flag is first needed (see pendingChecksum).
+*/
+
+// _loadDataJson sets bo's data, as loaded from storage, without parsing it.
+//
+// The JSON is compacted but otherwise kept as-is, it might not be in the canonical form produced by encoding the data
+// tree (e.g. JSONB columns reorder object keys) until being re-encoded (see _canonicalizeDataJson).
+//
+// Caller is responsible for locking the BO.
+func (ubo *UniversalBo) _loadDataJson(js string) error {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, []byte(js)); err != nil {
+		return err
+	}
+	ubo.dataJson = buf.String()
+	ubo._data, ubo._sdata = nil, nil
+	ubo._dataParsed.Store(false)
+	ubo._dataShared.Store(false)
+	ubo._dataPristine, ubo._dataChanged, ubo._dataRaw = false, false, true
+	return nil
+}
+
+// _syncLoaded syncs a BO whose data has just been loaded by _loadDataJson, then verifies the stored checksum against
+// BO's content. The last-updated timestamp is not bumped.
+//
+// Checksum is calculated from the loaded JSON whenever possible so that data does not need to be parsed: for
+// algorithms other than MD5, the loaded JSON is in canonical form if it matches the stored checksum. MD5 checksum
+// depends on the parsed data, hence a stored MD5 checksum is trusted and verified only when needed (see
+// _resolveChecksum).
+//
+// Caller is responsible for locking the BO.
+func (ubo *UniversalBo) _syncLoaded(storedChecksum string) {
+	ubo.timeCreated = ubo.RoundTimestamp(ubo.timeCreated)
+	ubo.timeUpdated = ubo.RoundTimestamp(ubo.timeUpdated)
+	ubo._pendingChecksum = nil
+	alg := ubo._checksumAlgorithm
+	if _, hashed := checksumHashFuncs[alg]; !hashed {
+		if storedChecksum != "" && ChecksumAlgorithmOf(storedChecksum) == ChecksumMd5 {
+			ubo.checksum, ubo._dirty, ubo._checksumMismatch = storedChecksum, false, false
+			ubo._pendingChecksum = ubo._newPendingChecksum(storedChecksum)
+			return
+		}
+		ubo.checksum = ubo._calcChecksum(alg, []byte(ubo.dataJson))
+	} else {
+		if ubo._checksumCoverage.coversAllData() {
+			if csum := ubo._calcChecksum(alg, []byte(ubo.dataJson)); csum == storedChecksum {
+				ubo.checksum, ubo._dataRaw = csum, false
+			}
+		}
+		if ubo._dataRaw {
+			ubo._canonicalizeDataJson()
+			ubo.checksum = ubo._calcChecksum(alg, []byte(ubo.dataJson))
+		}
+	}
+	ubo._dirty = false
+	ubo._checksumMismatch = !ubo._verifyLoadedChecksum(storedChecksum, []byte(ubo.dataJson))
+}
+
+// pendingChecksum is the pending verification of the stored MD5 checksum of a BO loaded from storage.
+//
+// Verifying an MD5 checksum requires data to be parsed (twice if data contains integral numbers, see
+// _verifyMd5Checksum), which is deferred until the checksum or the mismatch flag is needed: the verification is
+// performed once against a snapshot of the BO as loaded, so that it is not affected by later changes, and its result
+// is shared with clones. Getters only read the result, it is applied to the BO when the BO is synced (see
+// _resolveChecksum).
+type pendingChecksum struct {
+	once           sync.Once
+	loaded         *UniversalBo // snapshot of the BO as loaded, data not parsed; released once verified
+	storedChecksum string
+	checksum       string   // checksum calculated from the BO as loaded
+	mismatch       bool     // true if the stored checksum does not match the BO as loaded
+	droppedExtras  []string // nil extra attributes dropped to match the stored checksum
+}
+
+// _newPendingChecksum takes a snapshot of a BO just loaded from storage to verify its stored checksum later.
+//
+// Caller is responsible for locking the BO.
+func (ubo *UniversalBo) _newPendingChecksum(storedChecksum string) *pendingChecksum {
+	loaded := &UniversalBo{
+		id:                 ubo.id,
+		dataJson:           ubo.dataJson,
+		tagVersion:         ubo.tagVersion,
+		checksum:           storedChecksum,
+		timeCreated:        ubo.timeCreated,
+		timeUpdated:        ubo.timeUpdated,
+		_extraAttrs:        cloneMap(ubo._extraAttrs),
+		_timestampRounding: ubo._timestampRounding,
+		_checksumAlgorithm: ubo._checksumAlgorithm,
+		_storedChecksum:    storedChecksum,
+		_useNumber:         ubo._useNumber,
+		_checksumCoverage:  ubo._checksumCoverage,
+		_jsonCodec:         ubo._jsonCodec,
+		_dataRaw:           true,
+	}
+	return &pendingChecksum{loaded: loaded, storedChecksum: storedChecksum}
+}
+
+// verify calculates the checksum of the loaded BO and verifies the stored checksum against it, only once.
+func (p *pendingChecksum) verify() *pendingChecksum {
+	p.once.Do(func() {
+		bo, loadedExtras := p.loaded, p.loaded._extraAttrs
+		bo.checksum = bo._calcChecksum(bo._checksumAlgorithm, []byte(bo.dataJson))
+		p.mismatch = !bo._verifyLoadedChecksum(p.storedChecksum, []byte(bo.dataJson))
+		p.checksum = bo.checksum
+		if !p.mismatch {
+			// MD5 checksum depends on the Go types of numbers, which are not preserved by JSON: the verified stored
+			// checksum is kept rather than the one calculated from reloaded data
+			p.checksum = p.storedChecksum
+		}
+		for k, v := range loadedExtras {
+			if _, kept := bo._extraAttrs[k]; v == nil && !kept {
+				p.droppedExtras = append(p.droppedExtras, k)
+			}
+		}
+		p.loaded = nil
+	})
+	return p
+}
+
+// _checksumState returns bo's checksum and mismatch flag, taking the pending verification into account without
+// modifying the BO.
+//
+// Caller is responsible for locking the BO.
+func (ubo *UniversalBo) _checksumState() (string, bool) {
+	if p := ubo._pendingChecksum; p != nil {
+		p.verify()
+		return p.checksum, p.mismatch
+	}
+	return ubo.checksum, ubo._checksumMismatch
+}
+
+// _resolveChecksum applies the pending verification of the stored checksum, if any: bo's checksum, mismatch flag and
+// extra attributes are set as if the stored checksum had been verified when the BO was loaded.
+//
+// Caller is responsible for locking the BO.
+func (ubo *UniversalBo) _resolveChecksum() {
+	p := ubo._pendingChecksum
+	if p == nil {
+		return
+	}
+	ubo._pendingChecksum = nil
+	p.verify()
+	ubo.checksum, ubo._checksumMismatch = p.checksum, p.mismatch
+	if len(p.droppedExtras) == 0 {
+		return
+	}
+	for _, k := range p.droppedExtras {
+		if v, ok := ubo._extraAttrs[k]; ok && v == nil {
+			delete(ubo._extraAttrs, k)
+		}
+	}
+	// the snapshot of the original content might be shared with clones, hence is copied rather than modified
+	if o := ubo._original; o != nil {
+		if extras, ok := o.extras.(map[string]interface{}); ok {
+			original := &uboContent{id: o.id, tagVersion: o.tagVersion, dataJson: o.dataJson, useNumber: o.useNumber,
+				jsonCodec: o.jsonCodec, extras: cloneMap(extras)}
+			if o.dataJson == "" {
+				original.data = o.data
+			}
+			for _, k := range p.droppedExtras {
+				delete(original.extras.(map[string]interface{}), k)
+			}
+			ubo._original = original
+		}
+	}
+}
+
+// _ensureData parses dataJson into the data tree if it has not been parsed yet.
+//
+// Readers call this function holding the read lock only, concurrent parsing is serialized by _parseLock.
+func (ubo *UniversalBo) _ensureData() {
+	if ubo._dataParsed.Load() {
+		return
+	}
+	ubo._parseLock.Lock()
+	defer ubo._parseLock.Unlock()
+	if !ubo._dataParsed.Load() {
+		ubo._parseDataJson(dataInitNone)
+	}
+}
+
+// _unshareData prepares the data tree to be modified in place: data is parsed if needed, and copied if it is shared
+// with clones.
+//
+// Caller is responsible for locking the BO.
+func (ubo *UniversalBo) _unshareData() {
+	ubo._ensureData()
+	if ubo._dataShared.Load() {
+		ubo._data, ubo._sdata = cloneDataTree(ubo._data), nil
+		if ubo._data != nil {
+			ubo._sdata = semita.NewSemita(&ubo._data)
+		}
+		ubo._dataShared.Store(false)
+	}
+}
+
+// _detachValue returns a value of the data tree to be handed out to callers: objects and arrays are copied if the tree
+// is shared with clones, so that modifying them in place does not affect the clones.
+//
+// Caller is responsible for locking the BO.
+func (ubo *UniversalBo) _detachValue(v interface{}) interface{} {
+	if !ubo._dataShared.Load() {
+		return v
+	}
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		return cloneDataTree(v)
+	}
+	return v
+}
+
+// _markDataChanged records that the data tree has been modified, hence dataJson must be re-encoded on the next sync.
+//
+// Caller is responsible for locking the BO.
+func (ubo *UniversalBo) _markDataChanged() {
+	ubo._dataPristine = false
+	ubo._dataChanged = true
+	ubo._dirty = true
+}
+
+// _currentDataJson returns the JSON encoding of bo's current data: dataJson if the data tree has not been changed since
+// dataJson was last encoded, otherwise the encoding of the data tree.
+//
+// Caller is responsible for locking the BO.
+func (ubo *UniversalBo) _currentDataJson() ([]byte, error) {
+	if !ubo._dataChanged && ubo.dataJson != "" {
+		return []byte(ubo.dataJson), nil
+	}
+	ubo._ensureData()
+	return ubo._json().Marshal(ubo._data)
+}
+
+// _canonicalDataJson re-encodes dataJson the same way the data tree is encoded when the BO is synced.
+func (ubo *UniversalBo) _canonicalDataJson() ([]byte, error) {
+	var data interface{}
+	if err := unmarshalJsonData(ubo._jsonCodec, []byte(ubo.dataJson), ubo._useNumber, &data); err != nil {
+		return nil, err
+	}
+	return ubo._json().Marshal(data)
+}
+
+// _canonicalizeDataJson re-encodes dataJson in canonical form if it is kept as loaded from storage.
+//
+// Caller is responsible for locking the BO (write lock).
+func (ubo *UniversalBo) _canonicalizeDataJson() {
+	if !ubo._dataRaw {
+		return
+	}
+	if js, err := ubo._canonicalDataJson(); err == nil {
+		ubo.dataJson = string(js)
+	}
+	ubo._dataRaw = false
+}
+
+// _checksumInput returns dataJson in the form used to calculate checksum with the specified algorithm: MD5 checksum
+// is calculated over the data tree, while other algorithms hash the canonical JSON encoding of data.
+//
+// Caller is responsible for locking the BO.
+func (ubo *UniversalBo) _checksumInput(alg ChecksumAlgorithm) []byte {
+	if _, hashed := checksumHashFuncs[alg]; hashed && ubo._dataRaw {
+		if js, err := ubo._canonicalDataJson(); err == nil {
+			return js
+		}
+	}
+	return []byte(ubo.dataJson)
+}
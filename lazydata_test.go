@@ -0,0 +1,246 @@
+package henge
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/btnguyen2k/godal"
+)
+
+func TestUniversalBo_lazyParsing(t *testing.T) {
+	name := "TestUniversalBo_lazyParsing"
+	for _, alg := range []ChecksumAlgorithm{ChecksumMd5, ChecksumSha256} {
+		ubo := NewUniversalBo("id", 1, UboOpt{ChecksumAlgorithm: alg, TimestampRounding: TimestampRoundingSettingSecond})
+		ubo.SetDataJson(`{"a":{"b":[1,"x"]},"c":true}`)
+		ubo.Sync()
+		bo := NewUniversalBoFromGbo(ubo.ToGenericBo())
+		if bo._dataParsed.Load() {
+			t.Fatalf("%s failed: data should not be parsed when loaded (%s)", name, alg)
+		}
+		if bo.IsChecksumMismatched() || bo.GetChecksum() != ubo.GetChecksum() {
+			t.Fatalf("%s failed: expected checksum %#v but received %#v (%s)", name, ubo.GetChecksum(), bo.GetChecksum(), alg)
+		}
+		bo.SetExtraAttr("owner", "me")
+		bo.Sync()
+		if bo._dataParsed.Load() {
+			t.Fatalf("%s failed: data should not be parsed when syncing unchanged data (%s)", name, alg)
+		}
+		if v, err := bo.GetDataAttr("a.b[1]"); err != nil || v != "x" {
+			t.Fatalf("%s failed: expected %#v but received %#v/%s (%s)", name, "x", v, err, alg)
+		}
+		if !bo._dataParsed.Load() {
+			t.Fatalf("%s failed: data should be parsed when accessed (%s)", name, alg)
+		}
+	}
+}
+
+func TestUniversalBo_lazyMd5Verification(t *testing.T) {
+	name := "TestUniversalBo_lazyMd5Verification"
+	ubo := NewUniversalBo("id", 1, UboOpt{ChecksumAlgorithm: ChecksumMd5, TimestampRounding: TimestampRoundingSettingSecond})
+	ubo.SetDataJson(`{"a":{"b":[1,"x"]},"c":2.5}`)
+	ubo.Sync()
+	gbo := ubo.ToGenericBo()
+
+	// stored MD5 checksum is trusted when loaded: data is parsed once, when accessed
+	codec := &countingJsonCodec{}
+	bo := NewUniversalBoFromGbo(gbo, UboOpt{JsonCodec: codec})
+	bo.Clone()
+	bo.ToGenericBo()
+	bo.ToMap(nil, nil)
+	if n := atomic.LoadInt64(&codec.unmarshal); n != 0 {
+		t.Fatalf("%s failed: data should not be parsed when loaded, cloned or exported (%d)", name, n)
+	}
+	if v, err := bo.GetDataAttr("a.b[1]"); err != nil || v != "x" {
+		t.Fatalf("%s failed: expected %#v but received %#v/%s", name, "x", v, err)
+	}
+	if n := atomic.LoadInt64(&codec.unmarshal); n != 1 {
+		t.Fatalf("%s failed: data should be parsed once when accessed (%d)", name, n)
+	}
+	if bo.IsChecksumMismatched() || bo.GetChecksum() != ubo.GetChecksum() {
+		t.Fatalf("%s failed: expected checksum %#v but received %#v", name, ubo.GetChecksum(), bo.GetChecksum())
+	}
+
+	// tampered BO is flagged on first access, even if modified or cloned since being loaded
+	gbo.GboSetAttr(FieldData, `{"a":{"b":[1,"y"]},"c":2.5}`)
+	tampered := NewUniversalBoFromGbo(gbo)
+	clone := tampered.Clone()
+	tampered.SetDataAttr("a.b[1]", "x")
+	tampered.Sync()
+	if !tampered.IsChecksumMismatched() || !clone.IsChecksumMismatched() {
+		t.Fatalf("%s failed: tampered BO should be flagged", name)
+	}
+	if err := checkLoadedChecksum(NewUniversalBoFromGbo(gbo), true); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, ErrChecksumMismatch, err)
+	}
+
+	// tampered BO written back as loaded has its checksum recalculated
+	tampered = NewUniversalBoFromGbo(gbo)
+	written, err := boCodec{}.toGenericBo(tampered)
+	if err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if csum := written.GboGetAttrUnsafe(FieldChecksum, nil); csum == ubo.GetChecksum() || csum != tampered.GetChecksum() {
+		t.Fatalf("%s failed: expected checksum %#v but received %#v", name, tampered.GetChecksum(), csum)
+	}
+}
+
+func TestUniversalBo_lazyParsingRawData(t *testing.T) {
+	name := "TestUniversalBo_lazyParsingRawData"
+	for _, alg := range []ChecksumAlgorithm{ChecksumMd5, ChecksumSha256} {
+		ubo := NewUniversalBo("id", 1, UboOpt{ChecksumAlgorithm: alg, TimestampRounding: TimestampRoundingSettingSecond})
+		ubo.SetDataJson(`{"a":1,"b":{"c":"x","d":[true]}}`)
+		ubo.Sync()
+		gbo := ubo.ToGenericBo()
+		// storages such as JSONB columns might return data in a different, yet equivalent, form
+		gbo.GboSetAttr(FieldData, ` { "b" : { "d" : [ true ], "c" : "x" }, "a" : 1.0 } `)
+		bo := NewUniversalBoFromGbo(gbo)
+		if bo.IsChecksumMismatched() || bo.GetChecksum() != ubo.GetChecksum() {
+			t.Fatalf("%s failed: expected checksum %#v but received %#v (%s)", name, ubo.GetChecksum(), bo.GetChecksum(), alg)
+		}
+		if bo.GetDataJson() != ubo.GetDataJson() {
+			t.Fatalf("%s failed: expected %#v but received %#v (%s)", name, ubo.GetDataJson(), bo.GetDataJson(), alg)
+		}
+		bo.SetChecksumAlgorithm(ChecksumBlake2b256).Sync()
+		ubo.SetChecksumAlgorithm(ChecksumBlake2b256).Sync()
+		if bo.GetChecksum() != ubo.GetChecksum() {
+			t.Fatalf("%s failed: expected checksum %#v but received %#v (%s)", name, ubo.GetChecksum(), bo.GetChecksum(), alg)
+		}
+	}
+
+	gbo := NewUniversalBo("id", 1).ToGenericBo()
+	gbo.GboSetAttr(FieldData, `{"a":`)
+	if bo := NewUniversalBoFromGbo(gbo); bo != nil {
+		t.Fatalf("%s failed: invalid data should result in nil BO", name)
+	}
+}
+
+func TestUniversalBo_copyOnWrite(t *testing.T) {
+	name := "TestUniversalBo_copyOnWrite"
+	ubo := NewUniversalBo("id", 1)
+	ubo.SetDataJson(`{"a":{"b":[1,"x"]}}`)
+	ubo.Sync()
+	ubo.GetDataAttr("a")
+	clone := ubo.Clone()
+	if !clone._dataShared.Load() || !ubo._dataShared.Load() {
+		t.Fatalf("%s failed: parsed data should be shared with clones", name)
+	}
+	if err := clone.SetDataAttr("a.b[0]", 2); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if v := ubo.GetDataAttrUnsafe("a.b[0]"); v != 1.0 {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, 1.0, v)
+	}
+	if v := clone.GetDataAttrUnsafe("a.b[0]"); v != 2 {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, 2, v)
+	}
+	ubo.SetDataAttr("a.c", "y")
+	if v := clone.GetDataAttrUnsafe("a.c"); v != nil {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, nil, v)
+	}
+
+	// modified data is not shared, clones see the normalized form of data
+	clone = ubo.Clone()
+	if clone._dataShared.Load() {
+		t.Fatalf("%s failed: modified data should not be shared with clones", name)
+	}
+	if v := clone.GetDataAttrUnsafe("a.b"); !reflect.DeepEqual(v, []interface{}{1.0, "x"}) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, []interface{}{1.0, "x"}, v)
+	}
+}
+
+func TestUniversalBo_copyOnWriteIsolation(t *testing.T) {
+	name := "TestUniversalBo_copyOnWriteIsolation"
+	ubo := NewUniversalBo("id", 1)
+	ubo.SetDataJson(`{"a":{"b":[1,"x"]}}`)
+	ubo.Sync()
+	ubo.GetDataAttr("a")
+	clone := ubo.Clone()
+
+	// objects and arrays handed out while data is shared must not be the clones' ones
+	if m, ok := clone.GetDataAttrUnsafe("a").(map[string]interface{}); ok {
+		m["c"] = "y"
+	}
+	if list, ok := clone.GetDataAttrUnsafe("a.b").([]interface{}); ok {
+		list[0] = 2
+	}
+	if result, err := clone.QueryData("$.a"); err == nil && len(result) == 1 {
+		if m, ok := result[0].(map[string]interface{}); ok {
+			m["d"] = "z"
+		}
+	}
+	if result, err := clone.QueryData("$"); err == nil && len(result) == 1 {
+		if m, ok := result[0].(map[string]interface{}); ok {
+			m["e"] = "w"
+		}
+	}
+	expected := map[string]interface{}{"a": map[string]interface{}{"b": []interface{}{1.0, "x"}}}
+	for _, bo := range []*UniversalBo{ubo, clone} {
+		if v := bo.GetDataAttrUnsafe(""); !reflect.DeepEqual(v, expected) {
+			t.Fatalf("%s failed: expected %#v but received %#v", name, expected, v)
+		}
+	}
+}
+
+func TestUniversalBo_lazyParsingConcurrent(t *testing.T) {
+	name := "TestUniversalBo_lazyParsingConcurrent"
+	ubo := NewUniversalBo("id", 1)
+	ubo.SetDataJson(`{"a":{"b":[1,"x"]}}`)
+	bo := NewUniversalBoFromGbo(ubo.Sync().ToGenericBo())
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v := bo.Clone().GetDataAttrUnsafe("a.b[1]"); v != "x" {
+				t.Errorf("%s failed: expected %#v but received %#v", name, "x", v)
+			}
+			if v := bo.GetDataAttrUnsafe("a.b[1]"); v != "x" {
+				t.Errorf("%s failed: expected %#v but received %#v", name, "x", v)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestUniversalBo_lazyMd5VerificationConcurrent(t *testing.T) {
+	name := "TestUniversalBo_lazyMd5VerificationConcurrent"
+	ubo := NewUniversalBo("id", 1, UboOpt{ChecksumAlgorithm: ChecksumMd5, TimestampRounding: TimestampRoundingSettingSecond})
+	ubo.SetDataJson(`{"a":{"b":[1,"x"]}}`)
+	ubo.SetExtraAttr("owner", "me")
+	gbo := ubo.Sync().ToGenericBo()
+	// storages (e.g. SQL tables) return unset extra attributes as nil
+	m := make(map[string]interface{})
+	gbo.GboTransferViaJson(&m)
+	m["c"] = nil
+	gbo = godal.NewGenericBo()
+	gbo.GboImportViaJson(m)
+	bo := NewUniversalBoFromGbo(gbo)
+	clone := bo.Clone()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(bo *UniversalBo) {
+			defer wg.Done()
+			if bo.IsChecksumMismatched() || bo.GetChecksum() != ubo.GetChecksum() {
+				t.Errorf("%s failed: expected checksum %#v but received %#v", name, ubo.GetChecksum(), bo.GetChecksum())
+			}
+			bo.GetExtraAttrs()
+		}([]*UniversalBo{bo, clone}[i%2])
+	}
+	wg.Wait()
+
+	// getters do not modify the BO
+	if _, ok := bo.GetExtraAttrs()["c"]; !ok || bo.IsDirty() {
+		t.Fatalf("%s failed: BO should not be modified by getters: %#v", name, bo.GetExtraAttrs())
+	}
+	bo.SetTagVersion(bo.GetTagVersion()).Sync()
+	if _, ok := bo.GetExtraAttrs()["c"]; ok || bo.IsChecksumMismatched() {
+		t.Fatalf("%s failed: nil extra attributes should be dropped when BO is synced: %#v", name, bo.GetExtraAttrs())
+	}
+	if _, ok := clone.GetExtraAttrs()["c"]; !ok {
+		t.Fatalf("%s failed: clone should not be modified: %#v", name, clone.GetExtraAttrs())
+	}
+}
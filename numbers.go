@@ -0,0 +1,178 @@
+package henge
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+
+	"github.com/btnguyen2k/consu/reddo"
+)
+
+var (
+	// TypeBigInt is the type of *big.Int, used with UniversalBo.GetDataAttrAs to retrieve integers of arbitrary precision.
+	//
+	// Available since v0.7.0
+	TypeBigInt = reflect.TypeOf((*big.Int)(nil))
+
+	// TypeBigFloat is the type of *big.Float, used with UniversalBo.GetDataAttrAs to retrieve decimals of arbitrary precision.
+	//
+	// Available since v0.7.0
+	TypeBigFloat = reflect.TypeOf((*big.Float)(nil))
+)
+
+// bigFloatPrecision is the precision (in bits) of *big.Float values parsed from json.Number.
+const bigFloatPrecision = 256
+
+// unmarshalJsonData decodes JSON-encoded data with the specified codec (DefaultJsonCodec if nil); numbers are decoded as
+// json.Number if useNumber is true, float64 otherwise.
+func unmarshalJsonData(codec JsonCodec, js []byte, useNumber bool, v *interface{}) error {
+	if !useNumber {
+		return jsonCodecOrDefault(codec).Unmarshal(js, v)
+	}
+	return jsonCodecOrDefault(codec).UnmarshalUseNumber(js, v)
+}
+
+// parseJsonNumber parses a json.Number as a *big.Float.
+func parseJsonNumber(n json.Number) (*big.Float, error) {
+	f, _, err := big.ParseFloat(n.String(), 10, bigFloatPrecision, big.ToNearestEven)
+	if err != nil {
+		return nil, fmt.Errorf("cannot convert [%s] to number: %s", n, err)
+	}
+	return f, nil
+}
+
+// convertJsonNumber converts a json.Number to the specified type without going through float64, so that precision is
+// preserved. Fractional parts are truncated when converting to integer types.
+func convertJsonNumber(n json.Number, typ reflect.Type) (interface{}, error) {
+	switch typ {
+	case TypeBigInt:
+		if i, ok := new(big.Int).SetString(n.String(), 10); ok {
+			return i, nil
+		}
+		f, err := parseJsonNumber(n)
+		if err != nil {
+			return nil, err
+		}
+		i, _ := f.Int(nil)
+		return i, nil
+	case TypeBigFloat:
+		return parseJsonNumber(n)
+	}
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i, err := strconv.ParseInt(n.String(), 10, 64); err == nil {
+			return i, nil
+		}
+		f, err := parseJsonNumber(n)
+		if err != nil {
+			return nil, err
+		}
+		if i, _ := f.Int(nil); i.IsInt64() {
+			return i.Int64(), nil
+		}
+		return nil, fmt.Errorf("cannot convert [%s] to int64: value out of range", n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if u, err := strconv.ParseUint(n.String(), 10, 64); err == nil {
+			return u, nil
+		}
+		f, err := parseJsonNumber(n)
+		if err != nil {
+			return nil, err
+		}
+		if i, _ := f.Int(nil); i.IsUint64() {
+			return i.Uint64(), nil
+		}
+		return nil, fmt.Errorf("cannot convert [%s] to uint64: value out of range", n)
+	case reflect.Float32, reflect.Float64:
+		return n.Float64()
+	case reflect.String:
+		return n.String(), nil
+	}
+	return reddo.Convert(jsonNumberToNative(n), typ)
+}
+
+// jsonNumberToNative converts a json.Number to int64, uint64 or float64, whichever represents it best.
+func jsonNumberToNative(n json.Number) interface{} {
+	if i, err := n.Int64(); err == nil {
+		return i
+	}
+	if u, err := strconv.ParseUint(n.String(), 10, 64); err == nil {
+		return u
+	}
+	f, _ := n.Float64()
+	return f
+}
+
+// jsonNumbersToNative converts, in place, json.Number values of the data tree to int64, uint64 or float64.
+func jsonNumbersToNative(data interface{}) interface{} {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = jsonNumbersToNative(e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = jsonNumbersToNative(e)
+		}
+	case json.Number:
+		return jsonNumberToNative(v)
+	}
+	return data
+}
+
+// jsonNumberRat returns the value of a number in a data tree (json.Number, float64 or other Go number types) as
+// *big.Rat, nil if v is not a number.
+func jsonNumberRat(v interface{}) *big.Rat {
+	switch n := v.(type) {
+	case json.Number:
+		if r, ok := new(big.Rat).SetString(n.String()); ok {
+			return r
+		}
+		return nil
+	case float32, float64:
+		return new(big.Rat).SetFloat64(reflect.ValueOf(n).Float())
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return new(big.Rat).SetInt64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return new(big.Rat).SetUint64(rv.Uint())
+	}
+	return nil
+}
+
+// jsonValuesEqual returns true if two data trees are equal, numbers being compared by value regardless of their types
+// (e.g. 1 vs 1.0, or json.Number vs float64).
+func jsonValuesEqual(a, b interface{}) bool {
+	switch va := a.(type) {
+	case map[string]interface{}:
+		vb, ok := b.(map[string]interface{})
+		if !ok || len(va) != len(vb) {
+			return false
+		}
+		for k, v := range va {
+			if w, ok := vb[k]; !ok || !jsonValuesEqual(v, w) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		vb, ok := b.([]interface{})
+		if !ok || len(va) != len(vb) {
+			return false
+		}
+		for i := range va {
+			if !jsonValuesEqual(va[i], vb[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	if ra, rb := jsonNumberRat(a), jsonNumberRat(b); ra != nil && rb != nil {
+		return ra.Cmp(rb) == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
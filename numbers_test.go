@@ -0,0 +1,90 @@
+package henge
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/btnguyen2k/consu/reddo"
+)
+
+const testNumbersDataJson = `{"big":123456789012345678901234567890,"id":9007199254740993,"neg":-42,"price":19.99}`
+
+func TestUboOpt_UseNumber(t *testing.T) {
+	name := "TestUboOpt_UseNumber"
+	ubo := NewUniversalBo("id", 1, UboOpt{UseNumber: true})
+	ubo.SetDataJson(testNumbersDataJson)
+	if ubo.GetDataJson() != testNumbersDataJson {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, testNumbersDataJson, ubo.GetDataJson())
+	}
+	if v := ubo.GetDataAttrUnsafe("id"); v != json.Number("9007199254740993") {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, json.Number("9007199254740993"), v)
+	}
+	if clone := ubo.Clone(); clone.GetDataAttrUnsafe("id") != json.Number("9007199254740993") {
+		t.Fatalf("%s failed: clone should decode numbers as json.Number", name)
+	}
+
+	// without UseNumber, precision is lost
+	legacy := NewUniversalBo("id", 1)
+	legacy.SetDataJson(testNumbersDataJson)
+	if v := legacy.GetDataAttrAsUnsafe("id", reddo.TypeInt); v == int64(9007199254740993) {
+		t.Fatalf("%s failed: expected precision loss without UseNumber", name)
+	}
+}
+
+func TestUniversalBo_GetDataAttrAs_JsonNumber(t *testing.T) {
+	name := "TestUniversalBo_GetDataAttrAs_JsonNumber"
+	ubo := NewUniversalBo("id", 1, UboOpt{UseNumber: true})
+	ubo.SetDataJson(testNumbersDataJson)
+	bigInt, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	testCases := []struct {
+		path     string
+		typ      reflect.Type
+		expected interface{}
+	}{
+		{"id", reddo.TypeInt, int64(9007199254740993)},
+		{"id", reddo.TypeUint, uint64(9007199254740993)},
+		{"id", reddo.TypeString, "9007199254740993"},
+		{"neg", reddo.TypeInt, int64(-42)},
+		{"price", reddo.TypeInt, int64(19)},
+		{"price", reddo.TypeFloat, 19.99},
+		{"price", reddo.TypeBool, true},
+		{"big", TypeBigInt, bigInt},
+	}
+	for _, tc := range testCases {
+		v, err := ubo.GetDataAttrAs(tc.path, tc.typ)
+		if err != nil || !reflect.DeepEqual(v, tc.expected) {
+			t.Fatalf("%s failed for [%s/%s]: expected %#v but received %#v (error: %s)", name, tc.path, tc.typ, tc.expected, v, err)
+		}
+	}
+	if v, err := ubo.GetDataAttrAs("price", TypeBigFloat); err != nil || v.(*big.Float).Text('f', 2) != "19.99" {
+		t.Fatalf("%s failed: expected %#v but received %#v (error: %s)", name, "19.99", v, err)
+	}
+	for path, typ := range map[string]reflect.Type{"big": reddo.TypeInt, "neg": reddo.TypeUint} {
+		if v, err := ubo.GetDataAttrAs(path, typ); err == nil {
+			t.Fatalf("%s failed: expected out-of-range error for [%s/%s] but received %#v", name, path, typ, v)
+		}
+	}
+}
+
+func TestUboOpt_UseNumber_Roundtrip(t *testing.T) {
+	name := "TestUboOpt_UseNumber_Roundtrip"
+	opts := []UboOpt{{UseNumber: true, ChecksumAlgorithm: ChecksumSha256, TimestampRounding: TimestampRoundingSettingSecond}}
+	ubo := NewUniversalBo("id", 1, opts...)
+	ubo.SetDataJson(testNumbersDataJson)
+	bo := NewUniversalBoFromGbo(ubo.ToGenericBo(), opts...)
+	if bo.IsChecksumMismatched() || bo.GetChecksum() != ubo.GetChecksum() || bo.GetDataJson() != testNumbersDataJson {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, testNumbersDataJson, bo.GetDataJson())
+	}
+	if err := bo.ApplyMergePatch([]byte(`{"id":9007199254740995}`)); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if err := bo.ApplyJsonPatch([]byte(`[{"op":"test","path":"/id","value":9007199254740995},{"op":"replace","path":"/neg","value":9007199254740997}]`)); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	expected := `{"big":123456789012345678901234567890,"id":9007199254740995,"neg":9007199254740997,"price":19.99}`
+	if bo.Sync().GetDataJson() != expected {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, expected, bo.GetDataJson())
+	}
+}
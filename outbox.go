@@ -0,0 +1,233 @@
+package henge
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"reflect"
+	"time"
+
+	"github.com/btnguyen2k/consu/reddo"
+	"github.com/btnguyen2k/godal"
+)
+
+// OutboxEvent is a ChangeEvent pending in an outbox.
+//
+// Available since v0.7.0
+type OutboxEvent struct {
+	*ChangeEvent
+	Seq uint64 // sequence number of the event among the pending events of the same BO
+}
+
+// Outbox is implemented by DAOs that can write ChangeEvents to an outbox in the same transaction as the BO writes,
+// see UniversalDaoSql.SetOutboxTable and UniversalDaoMongo.SetOutboxCollection. Pending events are delivered to an
+// EventSink by an OutboxRelay.
+//
+// Available since v0.7.0
+type Outbox interface {
+	// FetchOutbox returns up to maxEvents pending events, in the order they must be delivered: by timestamp, then by
+	// BO id and sequence number. Events of the same BO are always returned in the order the changes were made.
+	FetchOutbox(ctx context.Context, maxEvents int) ([]*OutboxEvent, error)
+
+	// AckOutbox removes a delivered event from the outbox.
+	AckOutbox(ctx context.Context, ev *OutboxEvent) error
+}
+
+// ErrOutboxDisabled is returned by Outbox's functions if outbox mode is not enabled on the DAO.
+//
+// Available since v0.7.0
+var ErrOutboxDisabled = errors.New("outbox mode is not enabled")
+
+const (
+	// OutboxColId is name of the outbox record's attribute to store the BO's id (MongoDB).
+	//
+	// Available since v0.7.0
+	OutboxColId = "oid"
+
+	// OutboxColSeq is name of the outbox record's attribute to store the event's sequence number (MongoDB).
+	//
+	// Available since v0.7.0
+	OutboxColSeq = "oseq"
+
+	// OutboxColOp is name of the outbox record's attribute to store the operation (MongoDB).
+	//
+	// Available since v0.7.0
+	OutboxColOp = "oop"
+
+	// OutboxColTime is name of the outbox record's attribute to store the event's timestamp (MongoDB).
+	//
+	// Available since v0.7.0
+	OutboxColTime = "otime"
+
+	// OutboxColBefore is name of the outbox record's attribute to store the BO before the change, in JSON format (MongoDB).
+	//
+	// Available since v0.7.0
+	OutboxColBefore = "obefore"
+
+	// OutboxColAfter is name of the outbox record's attribute to store the BO after the change, in JSON format (MongoDB).
+	//
+	// Available since v0.7.0
+	OutboxColAfter = "oafter"
+)
+
+// nextOutboxSeqAndTime returns the sequence number and timestamp of a new event, given the last pending event of the
+// same BO (if any): the timestamp never goes backward so that ordering by timestamp preserves the order of the BO's
+// events.
+func nextOutboxSeqAndTime(last []godal.IGenericBo, seqField string, lastTime time.Time, now time.Time) (uint64, time.Time) {
+	if len(last) == 0 || last[0] == nil {
+		return 1, now
+	}
+	seq, _ := last[0].GboGetAttrUnsafe(seqField, reddo.TypeUint).(uint64)
+	if now.Before(lastTime) {
+		now = lastTime
+	}
+	return seq + 1, now
+}
+
+// gboGetString returns the string value of a field of gbo, empty if the field is missing or NULL.
+func gboGetString(gbo godal.IGenericBo, field string) string {
+	v := reflect.ValueOf(gbo.GboGetAttrUnsafe(field, nil))
+	if !v.IsValid() || (v.Kind() == reflect.Ptr && v.IsNil()) {
+		return ""
+	}
+	s, _ := reddo.ToString(v.Interface())
+	return s
+}
+
+// sealBo serializes a BO to be stored in an outbox, in JSON format with the configured attributes encrypted.
+// nil is serialized to an empty string.
+func (c boCodec) sealBo(bo *UniversalBo) (string, error) {
+	if bo == nil {
+		return "", nil
+	}
+	if c.fieldEncryptor != nil {
+		gbo := bo.ToGenericBo()
+		if err := c.fieldEncryptor.encryptGbo(gbo); err != nil {
+			return "", err
+		}
+		bo = NewUniversalBoFromGbo(gbo)
+	}
+	js, err := bo.MarshalJSON()
+	return string(js), err
+}
+
+// openBo deserializes a BO serialized by sealBo.
+func (c boCodec) openBo(js string, opts ...UboOpt) (*UniversalBo, error) {
+	if js == "" {
+		return nil, nil
+	}
+	bo := &UniversalBo{}
+	if err := bo.UnmarshalJSON([]byte(js)); err != nil {
+		return nil, err
+	}
+	return c.toUniversalBo(bo.ToGenericBo(), opts...)
+}
+
+// defaultOutboxBatchSize is the default number of events fetched at a time by OutboxRelay.
+const defaultOutboxBatchSize = 100
+
+// OutboxRelay delivers the events pending in an Outbox to an EventSink.
+//
+// Events are removed from the outbox only after the sink has accepted them, hence delivery is at-least-once: an event
+// is delivered again if the relay stops between the delivery and the removal. Events are delivered in the order they
+// are fetched and the relay stops at the first failure, hence events of the same BO are delivered in the order the
+// changes were made. To keep this ordering, only one relay should drain an outbox at a time.
+//
+// Available since v0.7.0
+type OutboxRelay struct {
+	outbox    Outbox
+	sink      EventSink
+	batchSize int
+	logger    *slog.Logger
+}
+
+// NewOutboxRelay creates a new OutboxRelay instance.
+//
+// Available since v0.7.0
+func NewOutboxRelay(outbox Outbox, sink EventSink) *OutboxRelay {
+	return &OutboxRelay{outbox: outbox, sink: sink, batchSize: defaultOutboxBatchSize}
+}
+
+// GetBatchSize returns the number of events fetched from the outbox at a time.
+//
+// Available since v0.7.0
+func (r *OutboxRelay) GetBatchSize() int {
+	return r.batchSize
+}
+
+// SetBatchSize sets the number of events fetched from the outbox at a time (default 100). Values <= 0 are ignored.
+//
+// Available since v0.7.0
+func (r *OutboxRelay) SetBatchSize(batchSize int) *OutboxRelay {
+	if batchSize > 0 {
+		r.batchSize = batchSize
+	}
+	return r
+}
+
+// GetLogger returns the logger used by Run to report failures.
+//
+// Available since v0.7.0
+func (r *OutboxRelay) GetLogger() *slog.Logger {
+	return r.logger
+}
+
+// SetLogger sets the logger used by Run to report failures (slog.Default() if nil).
+//
+// Available since v0.7.0
+func (r *OutboxRelay) SetLogger(logger *slog.Logger) *OutboxRelay {
+	r.logger = logger
+	return r
+}
+
+// Drain delivers the pending events until the outbox is empty, returning the number of delivered events. It stops at
+// the first error, leaving the failed event and the following ones in the outbox.
+//
+// Available since v0.7.0
+func (r *OutboxRelay) Drain(ctx context.Context) (int, error) {
+	numEvents := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return numEvents, err
+		}
+		events, err := r.outbox.FetchOutbox(ctx, r.batchSize)
+		if err != nil {
+			return numEvents, err
+		}
+		for _, ev := range events {
+			if err := r.sink.Emit(ctx, ev.ChangeEvent); err != nil {
+				return numEvents, err
+			}
+			if err := r.outbox.AckOutbox(ctx, ev); err != nil {
+				return numEvents, err
+			}
+			numEvents++
+		}
+		if len(events) < r.batchSize {
+			return numEvents, nil
+		}
+	}
+}
+
+// Run drains the outbox every interval until ctx is done, then returns ctx's error. Failures are logged and retried
+// at the next round.
+//
+// Available since v0.7.0
+func (r *OutboxRelay) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := r.Drain(ctx); err != nil && ctx.Err() == nil {
+			l := r.logger
+			if l == nil {
+				l = slog.Default()
+			}
+			l.ErrorContext(ctx, "failed to relay outbox events", slog.String("error", err.Error()))
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package henge
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/btnguyen2k/godal"
+)
+
+// memOutbox is a minimal in-memory Outbox, used to test OutboxRelay.
+type memOutbox struct {
+	events  []*OutboxEvent
+	fetches int
+}
+
+func (o *memOutbox) FetchOutbox(_ context.Context, maxEvents int) ([]*OutboxEvent, error) {
+	o.fetches++
+	if maxEvents > len(o.events) {
+		maxEvents = len(o.events)
+	}
+	return append([]*OutboxEvent{}, o.events[:maxEvents]...), nil
+}
+
+func (o *memOutbox) AckOutbox(_ context.Context, ev *OutboxEvent) error {
+	for i, e := range o.events {
+		if e == ev {
+			o.events = append(o.events[:i], o.events[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("not found")
+}
+
+func testOutbox(n int) *memOutbox {
+	outbox := &memOutbox{}
+	for i := 1; i <= n; i++ {
+		outbox.events = append(outbox.events, &OutboxEvent{ChangeEvent: &ChangeEvent{Op: OpUpdate, Id: "id"}, Seq: uint64(i)})
+	}
+	return outbox
+}
+
+func TestOutboxRelay_Drain(t *testing.T) {
+	name := "TestOutboxRelay_Drain"
+	outbox := testOutbox(25)
+	var delivered []*ChangeEvent
+	relay := NewOutboxRelay(outbox, EventSinkFunc(func(ctx context.Context, ev *ChangeEvent) error {
+		delivered = append(delivered, ev)
+		return nil
+	})).SetBatchSize(10)
+	if relay.GetBatchSize() != 10 {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, 10, relay.GetBatchSize())
+	}
+	if n, err := relay.Drain(context.Background()); err != nil || n != 25 {
+		t.Fatalf("%s failed: expected %#v but received %#v (%s)", name, 25, n, err)
+	}
+	if len(outbox.events) != 0 || outbox.fetches != 3 {
+		t.Fatalf("%s failed: expected empty outbox after 3 fetches but received %#v / %#v", name, len(outbox.events), outbox.fetches)
+	}
+	for i, ev := range delivered {
+		if i > 0 && ev == delivered[i-1] {
+			t.Fatalf("%s failed: event #%d delivered twice", name, i)
+		}
+	}
+
+	// delivery stops at the first failure, leaving the failed event and the following ones in the outbox
+	outbox = testOutbox(5)
+	errSink := errors.New("sink failure")
+	numCalls := 0
+	relay = NewOutboxRelay(outbox, EventSinkFunc(func(ctx context.Context, ev *ChangeEvent) error {
+		numCalls++
+		if numCalls == 3 {
+			return errSink
+		}
+		return nil
+	}))
+	if n, err := relay.Drain(context.Background()); err != errSink || n != 2 {
+		t.Fatalf("%s failed: expected %#v / %#v but received %#v / %#v", name, 2, errSink, n, err)
+	}
+	if len(outbox.events) != 3 || outbox.events[0].Seq != 3 {
+		t.Fatalf("%s failed: expected 3 pending events starting at seq 3 but received %#v", name, outbox.events)
+	}
+	if n, err := relay.Drain(context.Background()); err != nil || n != 3 || len(outbox.events) != 0 {
+		t.Fatalf("%s failed: expected %#v but received %#v (%s)", name, 3, n, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := relay.Drain(ctx); err != context.Canceled {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, context.Canceled, err)
+	}
+}
+
+func TestOutboxRelay_Run(t *testing.T) {
+	name := "TestOutboxRelay_Run"
+	outbox := testOutbox(3)
+	ctx, cancel := context.WithCancel(context.Background())
+	relay := NewOutboxRelay(outbox, EventSinkFunc(func(ctx context.Context, ev *ChangeEvent) error {
+		return nil
+	}))
+	done := make(chan error)
+	go func() {
+		done <- relay.Run(ctx, 10*time.Millisecond)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, context.Canceled, err)
+	}
+	if len(outbox.events) != 0 {
+		t.Fatalf("%s failed: expected empty outbox but received %#v", name, outbox.events)
+	}
+}
+
+func TestNextOutboxSeqAndTime(t *testing.T) {
+	name := "TestNextOutboxSeqAndTime"
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if seq, tm := nextOutboxSeqAndTime(nil, SqlColOutboxSeq, time.Time{}, t0); seq != 1 || !tm.Equal(t0) {
+		t.Fatalf("%s failed: expected %#v / %s but received %#v / %s", name, 1, t0, seq, tm)
+	}
+	last := godal.NewGenericBo()
+	last.GboSetAttr(SqlColOutboxSeq, 4)
+	if seq, tm := nextOutboxSeqAndTime([]godal.IGenericBo{last}, SqlColOutboxSeq, t0.Add(-time.Second), t0); seq != 5 || !tm.Equal(t0) {
+		t.Fatalf("%s failed: expected %#v / %s but received %#v / %s", name, 5, t0, seq, tm)
+	}
+	// the timestamp never goes backward
+	if seq, tm := nextOutboxSeqAndTime([]godal.IGenericBo{last}, SqlColOutboxSeq, t0.Add(time.Second), t0); seq != 5 || !tm.Equal(t0.Add(time.Second)) {
+		t.Fatalf("%s failed: expected %#v / %s but received %#v / %s", name, 5, t0.Add(time.Second), seq, tm)
+	}
+}
+
+func TestBoCodec_SealOpenBo(t *testing.T) {
+	name := "TestBoCodec_SealOpenBo"
+	kp, _ := NewStaticKeyProvider("k1", map[string][]byte{"k1": testEncKey1})
+	codec := boCodec{fieldEncryptor: NewFieldEncryptor(kp).EncryptDataPath("secret", false).EncryptExtraAttr("email", true)}
+
+	if js, err := codec.sealBo(nil); err != nil || js != "" {
+		t.Fatalf("%s failed: expected empty string but received %#v (%s)", name, js, err)
+	}
+	if bo, err := codec.openBo(""); err != nil || bo != nil {
+		t.Fatalf("%s failed: expected nil but received %#v (%s)", name, bo, err)
+	}
+
+	bo := NewUniversalBo("id", 3)
+	bo.SetDataAttr("name", "henge")
+	bo.SetDataAttr("secret", "s3cr3t")
+	bo.SetExtraAttr("email", "user@domain.com")
+	bo.Sync()
+	js, err := codec.sealBo(bo)
+	if err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if strings.Contains(js, "s3cr3t") || strings.Contains(js, "user@domain.com") || !strings.Contains(js, "henge") {
+		t.Fatalf("%s failed: expected encrypted attributes but received %s", name, js)
+	}
+	opened, err := codec.openBo(js)
+	if err != nil || opened == nil {
+		t.Fatalf("%s failed: %#v (%s)", name, opened, err)
+	}
+	if opened.GetId() != "id" || opened.GetTagVersion() != 3 || opened.GetChecksum() != bo.GetChecksum() {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, bo, opened)
+	}
+	if v := opened.GetDataAttrAsUnsafe("secret", nil); v != "s3cr3t" {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, "s3cr3t", v)
+	}
+	if v := opened.GetExtraAttr("email"); v != "user@domain.com" {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, "user@domain.com", v)
+	}
+	if !opened.GetTimeUpdated().Equal(bo.GetTimeUpdated()) {
+		t.Fatalf("%s failed: expected %s but received %s", name, bo.GetTimeUpdated(), opened.GetTimeUpdated())
+	}
+}
@@ -0,0 +1,178 @@
+package henge
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/btnguyen2k/consu/semita"
+	"github.com/ohler55/ojg/jp"
+)
+
+// parseJsonPath parses a JSONPath expression.
+func parseJsonPath(expr string) (jp.Expr, error) {
+	x, err := jp.ParseString(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSONPath expression [%s]: %s", expr, err)
+	}
+	return x, nil
+}
+
+// isJsonPathRoot returns true if the JSONPath expression references the whole document.
+func isJsonPathRoot(x jp.Expr) bool {
+	if len(x) == 0 {
+		return true
+	}
+	_, ok := x[0].(jp.Root)
+	return ok && len(x) == 1
+}
+
+// locateJsonPath returns the normalized paths (consisting of jp.Child and jp.Nth fragments only) of the nodes matching
+// the JSONPath expression, in document order (object members are ordered by key). Numbers decoded as json.Number are
+// taken into account as numbers by filter expressions.
+func locateJsonPath(data interface{}, x jp.Expr) []jp.Expr {
+	locs := x.Locate(jsonNumbersToNative(cloneDataTree(data)), 0)
+	sort.SliceStable(locs, func(i, j int) bool {
+		a, b := locs[i], locs[j]
+		for k := 0; k < len(a) && k < len(b); k++ {
+			switch ka := a[k].(type) {
+			case jp.Nth:
+				if kb, ok := b[k].(jp.Nth); ok && ka != kb {
+					return ka < kb
+				}
+			case jp.Child:
+				if kb, ok := b[k].(jp.Child); ok && ka != kb {
+					return ka < kb
+				}
+			}
+		}
+		return len(a) < len(b)
+	})
+	return locs
+}
+
+// jsonPathContainer returns the container (map or slice) and the key of the node located by a normalized path.
+func jsonPathContainer(data interface{}, loc jp.Expr) (interface{}, jp.Frag) {
+	if len(loc) < 2 {
+		return nil, nil
+	}
+	parent := loc[:len(loc)-1]
+	if isJsonPathRoot(parent) {
+		return data, loc[len(loc)-1]
+	}
+	return parent.First(data), loc[len(loc)-1]
+}
+
+// setJsonPathNode sets value of the node specified by key in the container, returns false if key does not fit the container.
+func setJsonPathNode(container interface{}, key jp.Frag, value interface{}) bool {
+	switch c := container.(type) {
+	case map[string]interface{}:
+		if k, ok := key.(jp.Child); ok {
+			c[string(k)] = value
+			return true
+		}
+	case []interface{}:
+		if n, ok := key.(jp.Nth); ok {
+			i := int(n)
+			if i < 0 {
+				i += len(c)
+			}
+			if i >= 0 && i < len(c) {
+				c[i] = value
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// QueryData returns values of all data attributes matching the JSONPath expression, e.g. "$.items[*].price",
+// "$..price" or "$.items[?(@.price > 10)]". Wildcards, recursive descent, slices and filter expressions are supported.
+//
+// An empty result is returned if no attribute matches. If the BO's data is shared with clones (see Clone), objects and
+// arrays are returned as copies.
+//
+// Available since v0.7.0
+func (ubo *UniversalBo) QueryData(jsonPathExpr string) ([]interface{}, error) {
+	x, err := parseJsonPath(jsonPathExpr)
+	if err != nil {
+		return nil, err
+	}
+	ubo._lock.RLock()
+	defer ubo._lock.RUnlock()
+	ubo._ensureData()
+	if isJsonPathRoot(x) {
+		return []interface{}{ubo._detachValue(ubo._data)}, nil
+	}
+	locs := locateJsonPath(ubo._data, x)
+	result := make([]interface{}, 0, len(locs))
+	for _, loc := range locs {
+		result = append(result, ubo._detachValue(loc.First(ubo._data)))
+	}
+	return result, nil
+}
+
+// SetDataAttrs sets value of all data attributes matching the JSONPath expression (see QueryData), and returns the
+// number of attributes that have been set.
+//   - If the expression ends with a child name or an array index (e.g. "$.items[*].discount"), the attribute is set
+//     on every matching parent object/array, created if it does not exist yet.
+//   - Otherwise (e.g. "$..price" or "$.items[?(@.price > 10)]"), only existing attributes matching the expression
+//     are replaced.
+//   - Values are normalized the same way as SetDataAttr; each attribute receives its own copy of the value.
+//
+// Available since v0.7.0
+func (ubo *UniversalBo) SetDataAttrs(jsonPathExpr string, value interface{}) (int, error) {
+	x, err := parseJsonPath(jsonPathExpr)
+	if err != nil {
+		return 0, err
+	}
+	ubo._lock.Lock()
+	defer ubo._lock.Unlock()
+	ubo._unshareData()
+	ubo._initSdata("")
+	value = ubo._normalizeDataValue(value)
+	if isJsonPathRoot(x) {
+		ubo._data, ubo._sdata = cloneDataTree(value), nil
+		if ubo._data != nil {
+			ubo._sdata = semita.NewSemita(&ubo._data)
+		}
+		ubo._markDataChanged()
+		return 1, nil
+	}
+
+	type target struct {
+		container interface{}
+		key       jp.Frag
+	}
+	targets := make([]target, 0)
+	last, parent := x[len(x)-1], x[:len(x)-1]
+	_, isChild := last.(jp.Child)
+	_, isNth := last.(jp.Nth)
+	recursive := false
+	if len(parent) > 0 {
+		_, recursive = parent[len(parent)-1].(jp.Descent)
+	}
+	switch {
+	case (isChild || isNth) && isJsonPathRoot(parent):
+		targets = append(targets, target{ubo._data, last})
+	case (isChild || isNth) && !recursive:
+		for _, loc := range locateJsonPath(ubo._data, parent) {
+			targets = append(targets, target{loc.First(ubo._data), last})
+		}
+	default:
+		// e.g. "$..price" or "$.items[?(@.price > 10)]": replace existing attributes only
+		for _, loc := range locateJsonPath(ubo._data, x) {
+			container, key := jsonPathContainer(ubo._data, loc)
+			targets = append(targets, target{container, key})
+		}
+	}
+	count := 0
+	for _, t := range targets {
+		if setJsonPathNode(t.container, t.key, cloneDataTree(value)) {
+			count++
+		}
+	}
+	if count > 0 {
+		ubo._markDataChanged()
+	}
+	return count, nil
+}
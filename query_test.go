@@ -0,0 +1,83 @@
+package henge
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const testQueryDataJson = `{"store":{"name":"henge","items":[{"name":"a","price":5},{"name":"b","price":15},{"name":"c","price":25,"tags":["x"]}]},"price":1}`
+
+func TestUniversalBo_QueryData(t *testing.T) {
+	name := "TestUniversalBo_QueryData"
+	ubo := NewUniversalBo("id", 1)
+	ubo.SetDataJson(testQueryDataJson)
+	testCases := map[string][]interface{}{
+		"$.store.items[*].price":              {5.0, 15.0, 25.0},
+		"$.store.items[?(@.price > 10)].name": {"b", "c"},
+		"$.store.items[-1].tags[0]":           {"x"},
+		"$.store.items[0:2].name":             {"a", "b"},
+		"$.store.nothing":                     {},
+		"store.name":                          {"henge"},
+	}
+	for expr, expected := range testCases {
+		if result, err := ubo.QueryData(expr); err != nil || !reflect.DeepEqual(result, expected) {
+			t.Fatalf("%s failed for [%s]: expected %#v but received %#v (error: %s)", name, expr, expected, result, err)
+		}
+	}
+	if result, _ := ubo.QueryData("$..price"); len(result) != 4 {
+		t.Fatalf("%s failed: expected 4 prices but received %#v", name, result)
+	}
+	if result, _ := ubo.QueryData("$"); len(result) != 1 || !reflect.DeepEqual(result[0], ubo._data) {
+		t.Fatalf("%s failed: expected whole data but received %#v", name, result)
+	}
+	if _, err := ubo.QueryData("$.store[?(@.price >"); err == nil {
+		t.Fatalf("%s failed: expected error for invalid expression", name)
+	}
+
+	// filter expressions work with json.Number as well
+	bo := NewUniversalBo("id", 1, UboOpt{UseNumber: true})
+	bo.SetDataJson(testQueryDataJson)
+	if result, err := bo.QueryData("$.store.items[?(@.price > 10)].price"); err != nil || !reflect.DeepEqual(result, []interface{}{json.Number("15"), json.Number("25")}) {
+		t.Fatalf("%s failed: received %#v (error: %s)", name, result, err)
+	}
+}
+
+func TestUniversalBo_SetDataAttrs(t *testing.T) {
+	name := "TestUniversalBo_SetDataAttrs"
+	testCases := []struct {
+		expr     string
+		value    interface{}
+		count    int
+		expected string
+	}{
+		{"$.store.items[*].discount", 0.1, 3, `{"price":1,"store":{"items":[{"discount":0.1,"name":"a","price":5},{"discount":0.1,"name":"b","price":15},{"discount":0.1,"name":"c","price":25,"tags":["x"]}],"name":"henge"}}`},
+		{"$..price", 0, 4, `{"price":0,"store":{"items":[{"name":"a","price":0},{"name":"b","price":0},{"name":"c","price":0,"tags":["x"]}],"name":"henge"}}`},
+		{"$.store.items[?(@.price > 10)]", map[string]interface{}{"name": "sold"}, 2, `{"price":1,"store":{"items":[{"name":"a","price":5},{"name":"sold"},{"name":"sold"}],"name":"henge"}}`},
+		{"$.store.items[?(@.price > 10)].sold", true, 2, `{"price":1,"store":{"items":[{"name":"a","price":5},{"name":"b","price":15,"sold":true},{"name":"c","price":25,"sold":true,"tags":["x"]}],"name":"henge"}}`},
+		{"$.store.items[1]", nil, 1, `{"price":1,"store":{"items":[{"name":"a","price":5},null,{"name":"c","price":25,"tags":["x"]}],"name":"henge"}}`},
+		{"$.store.items[5]", 1, 0, `{"price":1,"store":{"items":[{"name":"a","price":5},{"name":"b","price":15},{"name":"c","price":25,"tags":["x"]}],"name":"henge"}}`},
+		{"owner", "me", 1, `{"owner":"me","price":1,"store":{"items":[{"name":"a","price":5},{"name":"b","price":15},{"name":"c","price":25,"tags":["x"]}],"name":"henge"}}`},
+		{"$", []interface{}{1}, 1, `[1]`},
+	}
+	for _, tc := range testCases {
+		ubo := NewUniversalBo("id", 1)
+		ubo.SetDataJson(testQueryDataJson)
+		count, err := ubo.SetDataAttrs(tc.expr, tc.value)
+		if err != nil || count != tc.count {
+			t.Fatalf("%s failed for [%s]: expected %#v but received %#v (error: %s)", name, tc.expr, tc.count, count, err)
+		}
+		if js := ubo.Sync().GetDataJson(); js != tc.expected {
+			t.Fatalf("%s failed for [%s]: expected %#v but received %#v", name, tc.expr, tc.expected, js)
+		}
+	}
+
+	// each attribute receives its own copy of the value
+	ubo := NewUniversalBo("id", 1)
+	ubo.SetDataJson(testQueryDataJson)
+	ubo.SetDataAttrs("$.store.items[*].meta", map[string]interface{}{"v": 1})
+	ubo.SetDataAttr("store.items[0].meta.v", 2)
+	if v := ubo.GetDataAttrUnsafe("store.items[1].meta.v"); v != 1 {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, 1, v)
+	}
+}
@@ -0,0 +1,211 @@
+package henge
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// DataSchema is a compiled JSON Schema (draft 2020-12 by default) used to validate BO's user-defined data.
+//
+// Available since v0.7.0
+type DataSchema struct {
+	schema *jsonschema.Schema
+}
+
+// NewDataSchema compiles a JSON Schema document to validate BO's user-defined data.
+//
+// Schemas that do not declare "$schema" are treated as draft 2020-12. Only self-contained schemas are supported,
+// i.e. "$ref" must point to locations within the same document.
+//
+// Available since v0.7.0
+func NewDataSchema(schema []byte) (*DataSchema, error) {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(schema))
+	if err != nil {
+		return nil, err
+	}
+	const url = "henge://data-schema.json"
+	compiler := jsonschema.NewCompiler()
+	compiler.DefaultDraft(jsonschema.Draft2020)
+	compiler.UseLoader(jsonschema.SchemeURLLoader{})
+	if err = compiler.AddResource(url, doc); err != nil {
+		return nil, err
+	}
+	s, err := compiler.Compile(url)
+	if err != nil {
+		return nil, err
+	}
+	return &DataSchema{schema: s}, nil
+}
+
+// DataViolation describes a location in BO's data that fails the schema validation.
+//
+// Available since v0.7.0
+type DataViolation struct {
+	Path    string // location of the failing value, in the same syntax as of UniversalBo.GetDataAttr (e.g. "a.b[0].c"), empty for the root
+	Pointer string // location of the failing value, in JSON Pointer syntax (e.g. "/a/b/0/c")
+	Keyword string // location of the failing keyword within the schema, in JSON Pointer syntax (e.g. "/properties/a/type")
+	Message string // description of the failure
+}
+
+// DataValidationError is returned by UniversalDao's write functions when BO's data does not conform to the configured
+// DataSchema.
+//
+// Available since v0.7.0
+type DataValidationError struct {
+	Id         string          // BO's id
+	TagVersion uint64          // BO's tag-version, used to look up the schema
+	Violations []DataViolation // list of failures
+}
+
+// Error implements error.Error.
+func (e *DataValidationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = fmt.Sprintf("[%s]: %s", v.Pointer, v.Message)
+	}
+	return fmt.Sprintf("data of BO [%s] does not conform to schema: %s", e.Id, strings.Join(msgs, "; "))
+}
+
+// Validate validates the JSON-encoded data against the schema, returning the list of failures (nil if data is valid).
+//
+// Available since v0.7.0
+func (s *DataSchema) Validate(dataJson []byte) ([]DataViolation, error) {
+	data, err := jsonschema.UnmarshalJSON(bytes.NewReader(dataJson))
+	if err != nil {
+		return nil, err
+	}
+	err = s.schema.Validate(data)
+	if err == nil {
+		return nil, nil
+	}
+	verr, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return nil, err
+	}
+	violations := make([]DataViolation, 0)
+	for _, unit := range verr.BasicOutput().Errors {
+		if unit.Error == nil || len(unit.Errors) > 0 {
+			continue
+		}
+		tokens, _ := parseJsonPointer(unit.InstanceLocation)
+		violations = append(violations, DataViolation{
+			Path:    toSemitaPath(data, tokens),
+			Pointer: unit.InstanceLocation,
+			Keyword: unit.KeywordLocation,
+			Message: unit.Error.String(),
+		})
+	}
+	return violations, nil
+}
+
+// FillDefaults sets missing object properties of data to their default values declared in the schema.
+//
+// Declarations under "properties", "$ref", "allOf", "prefixItems" and "items" are taken into account.
+// This function returns true if data has been modified.
+//
+// Available since v0.7.0
+func (s *DataSchema) FillDefaults(data interface{}) bool {
+	return fillSchemaDefaults(s.schema, data, map[*jsonschema.Schema]bool{})
+}
+
+func fillSchemaDefaults(s *jsonschema.Schema, data interface{}, visiting map[*jsonschema.Schema]bool) bool {
+	if s == nil || visiting[s] {
+		return false
+	}
+	visiting[s] = true
+	defer delete(visiting, s)
+	modified := fillSchemaDefaults(s.Ref, data, visiting)
+	for _, sub := range s.AllOf {
+		modified = fillSchemaDefaults(sub, data, visiting) || modified
+	}
+	switch node := data.(type) {
+	case map[string]interface{}:
+		for k, prop := range s.Properties {
+			if v, ok := node[k]; ok {
+				modified = fillSchemaDefaults(prop, v, visiting) || modified
+			} else if prop.Default != nil {
+				node[k] = cloneJsonValue(*prop.Default)
+				modified = true
+			}
+		}
+	case []interface{}:
+		for i, v := range node {
+			if i < len(s.PrefixItems) {
+				modified = fillSchemaDefaults(s.PrefixItems[i], v, visiting) || modified
+			} else {
+				modified = fillSchemaDefaults(s.Items2020, v, visiting) || modified
+			}
+		}
+	}
+	return modified
+}
+
+// dataSchemaRegistry holds the DataSchema instances configured for a DAO.
+type dataSchemaRegistry struct {
+	defaultSchema *DataSchema
+	byTagVersion  map[uint64]*DataSchema
+}
+
+// lookup returns the schema for the specified tag-version, falling back to the default schema.
+func (r *dataSchemaRegistry) lookup(tagVersion uint64) *DataSchema {
+	if s, ok := r.byTagVersion[tagVersion]; ok {
+		return s
+	}
+	return r.defaultSchema
+}
+
+// setForTagVersion registers a schema for a tag-version; nil schema removes the registration.
+func (r *dataSchemaRegistry) setForTagVersion(tagVersion uint64, schema *DataSchema) {
+	if schema == nil {
+		delete(r.byTagVersion, tagVersion)
+		return
+	}
+	if r.byTagVersion == nil {
+		r.byTagVersion = make(map[uint64]*DataSchema)
+	}
+	r.byTagVersion[tagVersion] = schema
+}
+
+// _schemaInput optionally fills in default values declared by the schema, then returns bo's data in JSON format.
+//
+// Caller is responsible for locking the BO.
+func (ubo *UniversalBo) _schemaInput(s *DataSchema, fillDefaults bool) ([]byte, error) {
+	if fillDefaults {
+		data, err := ubo._cloneData()
+		if err == nil && s.FillDefaults(data) {
+			err = ubo._setData(data)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ubo._currentDataJson()
+}
+
+// validate validates bo's data against the schema configured for its tag-version, optionally filling in default values first.
+func (r *dataSchemaRegistry) validate(bo *UniversalBo, fillDefaults bool) error {
+	if bo == nil {
+		return nil
+	}
+	s := r.lookup(bo.GetTagVersion())
+	if s == nil {
+		return nil
+	}
+	bo._lock.Lock()
+	js, err := bo._schemaInput(s, fillDefaults)
+	bo._lock.Unlock()
+	if err != nil {
+		return err
+	}
+	violations, err := s.Validate(js)
+	if err != nil {
+		return err
+	}
+	if len(violations) > 0 {
+		return &DataValidationError{Id: bo.GetId(), TagVersion: bo.GetTagVersion(), Violations: violations}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package henge
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const testDataSchema = `{
+	"type": "object",
+	"required": ["name"],
+	"properties": {
+		"name": {"type": "string", "minLength": 1},
+		"active": {"type": "boolean", "default": true},
+		"tags": {"type": "array", "items": {"$ref": "#/$defs/tag"}},
+		"address": {
+			"type": "object",
+			"properties": {"country": {"type": "string", "default": "VN"}}
+		}
+	},
+	"$defs": {
+		"tag": {
+			"type": "object",
+			"required": ["key"],
+			"properties": {"key": {"type": "string"}, "weight": {"type": "integer", "default": 1}}
+		}
+	}
+}`
+
+func TestNewDataSchema(t *testing.T) {
+	name := "TestNewDataSchema"
+	if _, err := NewDataSchema([]byte(testDataSchema)); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if _, err := NewDataSchema([]byte(`{"type":`)); err == nil {
+		t.Fatalf("%s failed: expected error for invalid JSON", name)
+	}
+	if _, err := NewDataSchema([]byte(`{"type": 1}`)); err == nil {
+		t.Fatalf("%s failed: expected error for invalid schema", name)
+	}
+}
+
+func TestDataSchema_Validate(t *testing.T) {
+	name := "TestDataSchema_Validate"
+	schema, _ := NewDataSchema([]byte(testDataSchema))
+	if violations, err := schema.Validate([]byte(`{"name":"henge","tags":[{"key":"a"}]}`)); err != nil || violations != nil {
+		t.Fatalf("%s failed: expected no violation but received %#v (error: %s)", name, violations, err)
+	}
+	violations, err := schema.Validate([]byte(`{"name":"henge","tags":[{"key":"a"},{"weight":1.5}]}`))
+	if err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	paths := make(map[string]string)
+	for _, v := range violations {
+		paths[v.Path] = v.Pointer
+	}
+	expected := map[string]string{"tags[1]": "/tags/1", "tags[1].weight": "/tags/1/weight"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, expected, violations)
+	}
+	if violations, _ = schema.Validate([]byte(`[]`)); len(violations) != 1 || violations[0].Path != "" || violations[0].Keyword != "/type" {
+		t.Fatalf("%s failed: unexpected violations %#v", name, violations)
+	}
+}
+
+func TestDataSchema_FillDefaults(t *testing.T) {
+	name := "TestDataSchema_FillDefaults"
+	schema, _ := NewDataSchema([]byte(testDataSchema))
+	ubo := NewUniversalBo("id", 1)
+	ubo.SetDataJson(`{"name":"henge","active":false,"tags":[{"key":"a"},{"key":"b","weight":2}],"address":{}}`)
+	registry := dataSchemaRegistry{defaultSchema: schema}
+	if err := registry.validate(ubo, true); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	expected := NewUniversalBo("id", 1)
+	expected.SetDataJson(`{"name":"henge","active":false,"tags":[{"key":"a","weight":1},{"key":"b","weight":2}],"address":{"country":"VN"}}`)
+	if !ContentEqual(ubo, expected) {
+		t.Fatalf("%s failed: %#v", name, Diff(expected, ubo))
+	}
+
+	ubo.SetDataJson(`{"name":"henge"}`)
+	if err := registry.validate(ubo, false); err != nil || ubo.GetDataAttrUnsafe("active") != nil {
+		t.Fatalf("%s failed: defaults should not be filled (error: %s)", name, err)
+	}
+}
+
+func TestDataSchemaRegistry(t *testing.T) {
+	name := "TestDataSchemaRegistry"
+	schema, _ := NewDataSchema([]byte(testDataSchema))
+	schemaV2, _ := NewDataSchema([]byte(`{"type":"object","required":["fullname"]}`))
+	registry := dataSchemaRegistry{}
+	if registry.lookup(1) != nil {
+		t.Fatalf("%s failed: expected no schema", name)
+	}
+	registry.defaultSchema = schema
+	registry.setForTagVersion(2, schemaV2)
+	if registry.lookup(1) != schema || registry.lookup(2) != schemaV2 {
+		t.Fatalf("%s failed: invalid lookup result", name)
+	}
+
+	ubo := NewUniversalBo("id", 2)
+	ubo.SetDataJson(`{"name":"henge"}`)
+	err := registry.validate(ubo, false)
+	var verr *DataValidationError
+	if !errors.As(err, &verr) || verr.Id != "id" || verr.TagVersion != 2 || len(verr.Violations) != 1 {
+		t.Fatalf("%s failed: unexpected error %#v", name, err)
+	}
+
+	registry.setForTagVersion(2, nil)
+	if err = registry.validate(ubo, false); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+}